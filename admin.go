@@ -0,0 +1,184 @@
+package sqlt
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// AdminOption configures AdminHandler.
+type AdminOption func(*adminConfig)
+
+type adminConfig struct {
+	writes      bool
+	authorize   func(*http.Request) bool
+	logger      func(format string, args ...any)
+	maxBodySize int64
+}
+
+// DefaultAdminMaxBodySize is the largest schema, in bytes, the POST endpoints of AdminHandler accept
+// unless WithAdminMaxBodySize sets another limit.
+const DefaultAdminMaxBodySize = 1 << 20
+
+// WithAdminWrites enables the POST endpoints of AdminHandler, which check a schema against the database.
+// They never change the database, but parse and compare client-supplied schemas.
+func WithAdminWrites() AdminOption {
+	return func(cfg *adminConfig) {
+		cfg.writes = true
+	}
+}
+
+// WithAdminAuth makes AdminHandler answer 403 Forbidden to the requests authorize rejects,
+// before running any of its endpoints.
+func WithAdminAuth(authorize func(r *http.Request) bool) AdminOption {
+	return func(cfg *adminConfig) {
+		cfg.authorize = authorize
+	}
+}
+
+// WithAdminLogger sends the failures AdminHandler cannot report to the client, such as a response
+// it could not write, to logf instead of standard output.
+func WithAdminLogger(logf func(format string, args ...any)) AdminOption {
+	return func(cfg *adminConfig) {
+		cfg.logger = logf
+	}
+}
+
+// WithAdminMaxBodySize sets the largest schema, in bytes, the POST endpoints of AdminHandler accept.
+// Larger request bodies are answered with 413 Request Entity Too Large. Defaults to DefaultAdminMaxBodySize.
+func WithAdminMaxBodySize(n int64) AdminOption {
+	return func(cfg *adminConfig) {
+		cfg.maxBodySize = n
+	}
+}
+
+func (cfg *adminConfig) logf(format string, args ...any) {
+	if cfg.logger != nil {
+		cfg.logger(format, args...)
+		return
+	}
+	fmt.Printf(format+"\n", args...)
+}
+
+// AdminHandler returns an http.Handler exposing read-only information about db as JSON:
+//
+//	GET /schema   the schema, see FetchDBSchema
+//	GET /history  the AutoMigrate runs, see WithHistory
+//	GET /health   pings the database, 503 if it is unreachable
+//	GET /stats    the connection pool statistics
+//
+// With WithAdminWrites, the request body of these endpoints is a schema:
+//
+//	POST /verify  runs Verify against the schema
+//	POST /plan    returns the statements AutoMigrate would execute, without running them,
+//	              and their steps, see AutoMigratePlan;
+//	              add ?allow_table_deletes=true to plan table and column drops
+//
+// Errors are returned as {"error": "..."}. Without WithAdminAuth the handler has no authentication;
+// mount it behind an internal router, using http.StripPrefix if it is not served from the root.
+func AdminHandler(db DB, opts ...AdminOption) http.Handler {
+	cfg := &adminConfig{maxBodySize: DefaultAdminMaxBodySize}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /schema", func(w http.ResponseWriter, r *http.Request) {
+		schema, err := FetchDBSchema(r.Context(), db)
+		cfg.writeJSON(w, schema, err)
+	})
+	mux.HandleFunc("GET /history", func(w http.ResponseWriter, r *http.Request) {
+		entries, err := MigrationHistory(r.Context(), db)
+		if err != nil && strings.Contains(err.Error(), "no such table") {
+			entries, err = []MigrationLogEntry{}, nil
+		}
+		cfg.writeJSON(w, entries, err)
+	})
+	mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
+		if err := db.SQLX().PingContext(r.Context()); err != nil {
+			cfg.writeError(w, http.StatusServiceUnavailable, err)
+			return
+		}
+		cfg.writeJSON(w, map[string]string{"status": "ok"}, nil)
+	})
+	mux.HandleFunc("GET /stats", func(w http.ResponseWriter, r *http.Request) {
+		cfg.writeJSON(w, db.SQLX().Stats(), nil)
+	})
+	if cfg.writes {
+		mux.HandleFunc("POST /verify", func(w http.ResponseWriter, r *http.Request) {
+			schema, ok := cfg.readSchema(w, r)
+			if !ok {
+				return
+			}
+			if err := Verify(r.Context(), db, schema); err != nil {
+				cfg.writeJSON(w, map[string]any{"ok": false, "error": err.Error()}, nil)
+				return
+			}
+			cfg.writeJSON(w, map[string]any{"ok": true}, nil)
+		})
+		mux.HandleFunc("POST /plan", func(w http.ResponseWriter, r *http.Request) {
+			schema, ok := cfg.readSchema(w, r)
+			if !ok {
+				return
+			}
+			plan, err := AutoMigratePlan(r.Context(), db, schema, r.URL.Query().Get("allow_table_deletes") == "true")
+			if err != nil {
+				cfg.writeError(w, http.StatusUnprocessableEntity, err)
+				return
+			}
+			cfg.writeJSON(w, map[string]any{"statements": plan.Statements(), "steps": plan.Steps}, nil)
+		})
+	}
+	if cfg.authorize == nil {
+		return mux
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !cfg.authorize(r) {
+			cfg.writeError(w, http.StatusForbidden, errors.New("forbidden"))
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// readSchema reads the schema in the request body, answering 413 if it is larger than the
+// configured limit and 400 if it cannot be read. ok is false if an error response was written.
+func (cfg *adminConfig) readSchema(w http.ResponseWriter, r *http.Request) (schema io.Reader, ok bool) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, cfg.maxBodySize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			cfg.writeError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("schema larger than %d bytes", tooLarge.Limit))
+		} else {
+			cfg.writeError(w, http.StatusBadRequest, fmt.Errorf("could not read schema: %w", err))
+		}
+		return nil, false
+	}
+	return bytes.NewReader(body), true
+}
+
+func (cfg *adminConfig) writeJSON(w http.ResponseWriter, v any, err error) {
+	if err != nil {
+		cfg.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		cfg.logf("could not write admin response: %v", err)
+	}
+}
+
+func (cfg *adminConfig) writeError(w http.ResponseWriter, status int, err error) {
+	var conflict *SchemaConflictError
+	if errors.As(err, &conflict) {
+		status = http.StatusConflict
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(map[string]string{"error": err.Error()}); err != nil {
+		cfg.logf("could not write admin response: %v", err)
+	}
+}
@@ -0,0 +1,144 @@
+package sqlt_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/james-darko/gort"
+	"github.com/james-darko/sqlt"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminHandler(t *testing.T) {
+	t.Parallel()
+	db := getTestDB(t)
+	defer db.Close()
+	require.NoError(t, sqlt.ExecString(gort.Context(), db, `CREATE TABLE users (id INTEGER PRIMARY KEY);`))
+
+	readOnly := sqlt.AdminHandler(db)
+	for _, path := range []string{"/schema", "/history", "/health", "/stats"} {
+		rec := httptest.NewRecorder()
+		readOnly.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusOK, rec.Code, path)
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"), path)
+	}
+	rec := httptest.NewRecorder()
+	readOnly.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/plan", strings.NewReader("")))
+	assert.Equal(t, http.StatusNotFound, rec.Code, "POST endpoints need WithAdminWrites")
+
+	admin := sqlt.AdminHandler(db, sqlt.WithAdminWrites())
+	rec = httptest.NewRecorder()
+	admin.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/plan", strings.NewReader(
+		`CREATE TABLE users (id INTEGER PRIMARY KEY); CREATE TABLE teams (id INTEGER PRIMARY KEY);`)))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var plan struct{ Statements []string }
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &plan))
+	require.Len(t, plan.Statements, 1)
+	assert.Contains(t, plan.Statements[0], "CREATE TABLE")
+	assert.Contains(t, plan.Statements[0], "teams")
+
+	rec = httptest.NewRecorder()
+	admin.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/verify", strings.NewReader(`CREATE TABLE teams (id INTEGER PRIMARY KEY);`)))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var verify struct {
+		OK    bool
+		Error string
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &verify))
+	assert.False(t, verify.OK)
+	assert.NotEmpty(t, verify.Error)
+	assert.False(t, objectExists(t, db, "table", "teams"), "the plan must not be applied")
+}
+
+func TestAdminHandler_Errors(t *testing.T) {
+	t.Parallel()
+	db := getTestDB(t)
+	defer db.Close()
+	require.NoError(t, sqlt.ExecString(gort.Context(), db, `CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT);`))
+	admin := sqlt.AdminHandler(db, sqlt.WithAdminWrites(), sqlt.WithAdminMaxBodySize(64))
+
+	tests := []struct {
+		name, method, path, body string
+		status                   int
+	}{
+		{"unparseable schema", http.MethodPost, "/plan", "CREATE TABLE (", http.StatusUnprocessableEntity},
+		{"conflict", http.MethodPost, "/plan", "CREATE TABLE users (id TEXT PRIMARY KEY);", http.StatusConflict},
+		{"body too large", http.MethodPost, "/plan", "CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT);" + strings.Repeat(" ", 64), http.StatusRequestEntityTooLarge},
+		{"verify body too large", http.MethodPost, "/verify", strings.Repeat(" ", 65), http.StatusRequestEntityTooLarge},
+		{"GET plan", http.MethodGet, "/plan", "", http.StatusMethodNotAllowed},
+		{"DELETE schema", http.MethodDelete, "/schema", "", http.StatusMethodNotAllowed},
+		{"unknown path", http.MethodGet, "/tables", "", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		admin.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+		assert.Equal(t, tt.status, rec.Code, "%s: %s", tt.name, rec.Body.String())
+		if tt.status != http.StatusMethodNotAllowed && tt.status != http.StatusNotFound {
+			var body struct{ Error string }
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body), tt.name)
+			assert.NotEmpty(t, body.Error, tt.name)
+		}
+	}
+
+	closed := getTestDB(t)
+	require.NoError(t, closed.Close())
+	rec := httptest.NewRecorder()
+	sqlt.AdminHandler(closed).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"error"`)
+}
+
+func TestAdminHandler_Auth(t *testing.T) {
+	t.Parallel()
+	db := getTestDB(t)
+	defer db.Close()
+	admin := sqlt.AdminHandler(db, sqlt.WithAdminWrites(), sqlt.WithAdminAuth(func(r *http.Request) bool {
+		return r.Header.Get("Authorization") == "Bearer secret"
+	}))
+
+	for _, path := range []string{"/schema", "/plan"} {
+		rec := httptest.NewRecorder()
+		admin.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusForbidden, rec.Code, path)
+		assert.JSONEq(t, `{"error": "forbidden"}`, rec.Body.String(), path)
+	}
+	rec := httptest.NewRecorder()
+	admin.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/plan", strings.NewReader("CREATE TABLE teams (id INTEGER PRIMARY KEY);")))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.False(t, objectExists(t, db, "table", "teams"))
+
+	req := httptest.NewRequest(http.MethodGet, "/schema", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec = httptest.NewRecorder()
+	admin.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+// failingResponseWriter is a ResponseWriter whose body writes fail, as when the client went away.
+type failingResponseWriter struct {
+	*httptest.ResponseRecorder
+}
+
+func (failingResponseWriter) Write([]byte) (int, error) {
+	return 0, errors.New("connection reset")
+}
+
+func TestAdminHandler_Logger(t *testing.T) {
+	t.Parallel()
+	db := getTestDB(t)
+	defer db.Close()
+	var logged []string
+	admin := sqlt.AdminHandler(db, sqlt.WithAdminLogger(func(format string, args ...any) {
+		logged = append(logged, fmt.Sprintf(format, args...))
+	}))
+
+	admin.ServeHTTP(failingResponseWriter{httptest.NewRecorder()}, httptest.NewRequest(http.MethodGet, "/stats", nil))
+	assert.Equal(t, []string{"could not write admin response: connection reset"}, logged)
+}
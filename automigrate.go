@@ -1,12 +1,20 @@
 package sqlt
 
 import (
+	"bytes"
 	"context"
+	"database/sql/driver"
 	"errors"
 	"fmt"
 	"io"
 	"os"
+	"regexp"
+	"runtime"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	rsql "github.com/rqlite/sql"
 )
@@ -28,279 +36,798 @@ func getTableNameForDependent(stmt rsql.Statement) string {
 	return ""
 }
 
-func AutoMigrateFromEnv(ctx context.Context, db DB, allowTableDeletes bool) error {
-	schemaFile, ok := os.LookupEnv("DATABASE_SCHEMA")
+// viewTables returns the folded names of the tables and views view selects from, subqueries included.
+func viewTables(view *rsql.CreateViewStatement) []string {
+	var tables []string
+	if view.Select == nil {
+		return nil
+	}
+	_, _ = rsql.Walk(rsql.VisitFunc(func(n rsql.Node) (rsql.Node, error) {
+		if src, ok := n.(*rsql.QualifiedTableName); ok {
+			tables = append(tables, foldIdent(rsql.IdentName(src.Name)))
+		}
+		return n, nil
+	}), view.Select)
+	return tables
+}
+
+// AutoMigrateFromEnv runs AutoMigrate with the schema at the location in the DATABASE_SCHEMA environment
+// variable: a local file path, an http(s):// URL, or a location read by a fetcher set with WithSchemaFetcher.
+// Schemas are only parsed again once their content changes. See WithSchemaPublicKey to require signed schemas.
+func AutoMigrateFromEnv(ctx context.Context, db DB, allowTableDeletes bool, opts ...MigrateOption) error {
+	location, ok := os.LookupEnv("DATABASE_SCHEMA")
 	if !ok {
 		return fmt.Errorf("DATABASE_SCHEMA env var not found")
 	}
-	schema, err := os.Open(schemaFile)
+	stmts, err := newMigrateConfig(opts).loadSchema(ctx, location)
 	if err != nil {
-		return fmt.Errorf("could not open schema file %s: %w", schemaFile, err)
+		return err
 	}
-	defer schema.Close()
-	return AutoMigrate(ctx, db, schema, allowTableDeletes)
+	return AutoMigrate(ctx, db, strings.NewReader(""), allowTableDeletes, append(slices.Clip(opts), func(cfg *migrateConfig) {
+		cfg.schemaStmts = stmts
+	})...)
 }
 
-// AutoMigrate automatically adjusts the database schema to match the provided schema.
-func AutoMigrate(ctx context.Context, db DB, schema io.Reader, allowTableDeletes bool) error {
-	return db.Txc(ctx, func(tx Tx) error {
-		dbObjects := make(map[string]rsql.Statement)
-		schemaObjectsMap := make(map[string]rsql.Statement)
-		processedSchemaObjects := make(map[string]bool)
-		rebuiltTables := make(map[string]bool)
-		tablesToDropIfDisallowed := []string{} // Moved to top to collect all table drop violations
+// AutoMigrate is AutoMigrateWith with WithAllowTableDeletes if allowTableDeletes is set.
+func AutoMigrate(ctx context.Context, db DB, schema io.Reader, allowTableDeletes bool, opts ...MigrateOption) error {
+	if allowTableDeletes {
+		opts = append(slices.Clip(opts), WithAllowTableDeletes())
+	}
+	return AutoMigrateWith(ctx, db, schema, opts...)
+}
 
-		dbMasterRows, err := masterRows(tx)
+// AutoMigrateWith automatically adjusts the database schema to match the provided schema.
+//
+// Tables and columns missing from the schema are kept unless WithAllowTableDeletes or
+// WithAllowColumnDrops is given. With WithColumnAdditions, columns added at the end of a table
+// are added with ALTER TABLE ADD COLUMN when it accepts them.
+//
+// Tables that cannot be changed to match the schema are all reported in an ErrSchemaConflicts,
+// which unwraps to a *SchemaConflictError per table.
+//
+// Once migrated, the database is compared with the schema as Verify would. If it still differs,
+// which is a bug in AutoMigrate, an ErrPostMigrationDrift is returned. See WithVerifyAfter to
+// check before committing instead.
+//
+// See MigrateOption for the available options, and WithDryRun to only plan the changes.
+func AutoMigrateWith(ctx context.Context, db DB, schema io.Reader, opts ...MigrateOption) error {
+	cfg := newMigrateConfig(opts)
+	allowTableDeletes := cfg.tableDeletes
+	if cfg.dryRun != nil {
+		plan, err := AutoMigratePlan(ctx, db, schema, allowTableDeletes, opts...)
 		if err != nil {
-			return fmt.Errorf("AutoMigrate: could not get master rows from DB: %w", err)
+			return err
 		}
-		for _, row := range dbMasterRows {
-			if strings.HasPrefix(row.Name, "sqlite_") {
-				continue
+		*cfg.dryRun = *plan
+		return nil
+	}
+	if d := cfg.dialectOf(db); d != nil {
+		return autoMigrateDialect(ctx, db, d, schema, cfg)
+	}
+	var executed []string
+	err := cfg.withLock(ctx, db, func() error {
+		started := time.Now()
+		var deferred []deferredIndex
+		err := autoMigrate(ctx, db, schema, allowTableDeletes, cfg, &executed, &deferred)
+		if err == nil {
+			err = cfg.buildDeferredIndexes(ctx, db, deferred, &executed)
+		}
+		if err == nil && !cfg.verifyAfter {
+			err = cfg.checkPostMigration(ctx, db, allowTableDeletes, executed, nil)
+		}
+		cfg.recordHistory(ctx, db, started, executed, err)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	cfg.analyzeTables(ctx, db, executed)
+	cfg.checkpoint(ctx, db)
+	if err := cfg.writeSQLLog(executed); err != nil {
+		return fmt.Errorf("AutoMigrate: %w", err)
+	}
+	return nil
+}
+
+// AutoMigrateObjects is AutoMigrate reconciling only the named objects of schema and their dependents,
+// the indexes and triggers of named tables, leaving every other object of the database untouched.
+// Named objects missing from schema are dropped, except tables, which return ErrTableDeletionNotAllowed.
+// It is meant for hotfixing a single view or index in production.
+func AutoMigrateObjects(ctx context.Context, db DB, schema io.Reader, names []string, opts ...MigrateOption) error {
+	if len(names) == 0 {
+		return fmt.Errorf("AutoMigrateObjects: no objects named")
+	}
+	objects := make(map[string]string, len(names))
+	for _, name := range names {
+		objects[foldIdent(name)] = name
+	}
+	return AutoMigrate(ctx, db, schema, false, append(slices.Clip(opts), func(cfg *migrateConfig) {
+		cfg.objects = objects
+	})...)
+}
+
+// selectObjects returns the statements of the objects selected by AutoMigrateObjects and their dependents.
+func (cfg *migrateConfig) selectObjects(dbStmts, schemaStmts []rsql.Statement) ([]rsql.Statement, []rsql.Statement, error) {
+	dbStmts, schemaStmts = cfg.filterObjects(dbStmts), cfg.filterObjects(schemaStmts)
+	found := make(map[string]bool, len(cfg.objects))
+	for _, stmt := range slices.Concat(dbStmts, schemaStmts) {
+		if name, err := getStatementName(stmt); err == nil {
+			found[foldIdent(name)] = true
+		}
+	}
+	for key, name := range cfg.objects {
+		if !found[key] {
+			return nil, nil, fmt.Errorf("object %s not found in schema or database", name)
+		}
+	}
+	return dbStmts, schemaStmts, nil
+}
+
+// filterObjects returns the statements of the objects named in AutoMigrateObjects and their dependents.
+func (cfg *migrateConfig) filterObjects(stmts []rsql.Statement) []rsql.Statement {
+	var kept []rsql.Statement
+	for _, stmt := range stmts {
+		name, err := getStatementName(stmt)
+		if err != nil {
+			continue
+		}
+		_, named := cfg.objects[foldIdent(name)]
+		_, dependent := cfg.objects[foldIdent(getTableNameForDependent(stmt))]
+		if named || dependent {
+			kept = append(kept, stmt)
+		}
+	}
+	return kept
+}
+
+func autoMigrate(ctx context.Context, db DB, schema io.Reader, allowTableDeletes bool, cfg *migrateConfig, executed *[]string, deferred *[]deferredIndex) error {
+	caps, err := db.Capabilities(ctx)
+	if err != nil {
+		return fmt.Errorf("AutoMigrate: %w", err)
+	}
+	return migrationTx(ctx, db, cfg, func(dbTx Tx) error {
+		if err := cfg.applyBusyTimeout(dbTx); err != nil {
+			return fmt.Errorf("AutoMigrate: %w", err)
+		}
+		tx := &recordingTx{Tx: dbTx, executed: executed}
+
+		dbStmts, schemaStmtsInOrder, err := cfg.migrationStatements(ctx, tx, schema)
+		if err != nil {
+			return fmt.Errorf("AutoMigrate: %w", err)
+		}
+		if !cfg.batchStatements {
+			if err := reconcileSchema(cfg.deferringExecer(qualifyingExecer(cfg.timeoutExecer(ctx, tx), cfg.targetSchema), deferred), dbStmts, schemaStmtsInOrder, allowTableDeletes, cfg, caps); err != nil {
+				return err
 			}
-			parser := rsql.NewParser(strings.NewReader(row.Sql))
-			stmt, parseErr := parser.ParseStatement()
-			if parseErr != nil {
-				if strings.Contains(row.Name, "sqlite_sequence") {
-					continue
-				}
-				return fmt.Errorf("AutoMigrate: could not parse SQL for DB object %s (SQL: %s): %w", row.Name, row.Sql, parseErr)
+		} else {
+			var batch statementBatch
+			if err := reconcileSchema(cfg.deferringExecer(qualifyingExecer(&batch, cfg.targetSchema), deferred), dbStmts, schemaStmtsInOrder, allowTableDeletes, cfg, caps); err != nil {
+				return err
+			}
+			batchCtx, cancel := cfg.statementContext(ctx)
+			defer cancel()
+			if err := tx.execBatch(batchCtx, &batch); err != nil {
+				return fmt.Errorf("AutoMigrate: %w", err)
+			}
+		}
+		if cfg.verifyAfter {
+			return cfg.checkPostMigration(ctx, tx, allowTableDeletes, *executed, *deferred)
+		}
+		return nil
+	})
+}
+
+// migrationStatements returns the objects of the database and of schema that AutoMigrate reconciles.
+// The schema is parsed once per configuration, the database is checked for drift against the
+// WithBaseline schema and both are narrowed to the objects selected by AutoMigrateObjects.
+func (cfg *migrateConfig) migrationStatements(ctx context.Context, db Reader, schema io.Reader) (dbStmts, schemaStmts []rsql.Statement, err error) {
+	if dbStmts, err = schemaStatements(ctx, db, cfg.targetSchema); err != nil {
+		return nil, nil, err
+	}
+	cfg.dbStmts = dbStmts
+	if cfg.schemaStmts == nil {
+		if cfg.schemaStmts, err = parseSchemaStatements(schema); err != nil {
+			return nil, nil, err
+		}
+	}
+	schemaStmts = cfg.schemaStmts
+	if cfg.baseline != nil {
+		if err := cfg.checkDrift(dbStmts, schemaStmts); err != nil {
+			return nil, nil, err
+		}
+	}
+	if cfg.objects != nil {
+		return cfg.selectObjects(dbStmts, schemaStmts)
+	}
+	return dbStmts, schemaStmts, nil
+}
+
+// migrationTx runs fn in a transaction set up for table rebuilds: on a pinned connection with
+// PRAGMA foreign_keys off, so dropping and recreating a referenced table neither fails nor cascades,
+// and legacy_alter_table on, so renaming a table does not rewrite the references of other tables to it.
+// If foreign keys were enforced, PRAGMA foreign_key_check runs before the commit, as in MigrateFunc.
+// Both pragmas are restored afterwards. Databases not opened by this package use a plain transaction.
+func migrationTx(ctx context.Context, db DB, cfg *migrateConfig, fn func(tx Tx) error) error {
+	schema := cfg.targetSchema
+	s, ok := db.(*sqlxDB)
+	if !ok {
+		return db.Txc(ctx, func(tx Tx) error {
+			var foreignKeys bool
+			if err := tx.GetContext(ctx, &foreignKeys, "PRAGMA foreign_keys"); err != nil {
+				return fmt.Errorf("could not read foreign_keys: %w", err)
+			}
+			if err := fn(tx); err != nil {
+				return err
+			}
+			if foreignKeys {
+				return checkForeignKeys(ctx, tx, schema)
 			}
-			dbObjects[strings.ToLower(row.Name)] = stmt
+			return nil
+		})
+	}
+	defer s.invalidateAll()
+	conn, err := s.db.Connx(ctx)
+	if err != nil {
+		return fmt.Errorf("could not get migration connection: %w", err)
+	}
+	defer conn.Close()
+	var foreignKeys, legacyAlterTable bool
+	if err := conn.GetContext(ctx, &foreignKeys, "PRAGMA foreign_keys"); err != nil {
+		return fmt.Errorf("could not read foreign_keys: %w", err)
+	}
+	if err := conn.GetContext(ctx, &legacyAlterTable, "PRAGMA legacy_alter_table"); err != nil {
+		return fmt.Errorf("could not read legacy_alter_table: %w", err)
+	}
+	if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF; PRAGMA legacy_alter_table = ON"); err != nil {
+		return fmt.Errorf("could not prepare migration connection: %w", err)
+	}
+	defer func() {
+		restore := fmt.Sprintf("PRAGMA foreign_keys = %t; PRAGMA legacy_alter_table = %t", foreignKeys, legacyAlterTable)
+		if _, err := conn.ExecContext(context.WithoutCancel(ctx), restore); err != nil {
+			cfg.logf("could not restore pragmas after migration, discarding the connection: %v", err)
+			_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+		}
+	}()
+	return transactionOn(ctx, s, conn, false, func(tx Tx) error {
+		if err := fn(tx); err != nil {
+			return err
 		}
+		if foreignKeys {
+			return checkForeignKeys(ctx, tx, schema)
+		}
+		return nil
+	})
+}
+
+// dbStatements returns the parsed definitions of the objects in the database.
+func dbStatements(ctx context.Context, db Reader) ([]rsql.Statement, error) {
+	return schemaStatements(ctx, db, "")
+}
+
+// schemaStatements returns the parsed definitions of the objects in the attached database schema,
+// or in the main database if it is empty.
+func schemaStatements(ctx context.Context, db Reader, schema string) ([]rsql.Statement, error) {
+	dbMasterRows, err := schemaMasterRows(ctx, db, schema)
+	if err != nil {
+		return nil, fmt.Errorf("could not get master rows from DB: %w", err)
+	}
+	var stmts []rsql.Statement
+	for _, row := range dbMasterRows {
+		parser := rsql.NewParser(strings.NewReader(row.Sql))
+		stmt, err := parser.ParseStatement()
+		if err != nil {
+			return nil, fmt.Errorf("could not parse SQL for DB object %s (SQL: %s): %w", row.Name, row.Sql, err)
+		}
+		stmts = append(stmts, stmt)
+	}
+	return stmts, nil
+}
 
-		var schemaStmtsInOrder []rsql.Statement
-		schemaParser := rsql.NewParser(schema)
-		for {
-			sStmt, parseErr := schemaParser.ParseStatement()
-			if errors.Is(parseErr, io.EOF) {
-				break
+// parseSchemaStatements parses the object definitions from schema, skipping data statements.
+func parseSchemaStatements(schema io.Reader) ([]rsql.Statement, error) {
+	script, err := io.ReadAll(schema)
+	if err != nil {
+		return nil, fmt.Errorf("could not read input schema: %w", err)
+	}
+	if runtime.GOMAXPROCS(0) > 1 {
+		if pieces := splitStatements(string(script)); len(pieces) >= parallelParseThreshold {
+			if stmts, ok := parseStatementsParallel(pieces); ok {
+				return stmts, nil
 			}
-			if parseErr != nil {
-				return fmt.Errorf("AutoMigrate: could not parse statement from input schema: %w", parseErr)
+		}
+	}
+	var stmts []rsql.Statement
+	schemaParser := rsql.NewParser(bytes.NewReader(script))
+	for {
+		stmt, err := parseSchemaStatement(schemaParser)
+		if errors.Is(err, io.EOF) {
+			return stmts, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not parse statement from input schema: %w", parseError(err))
+		}
+		if isSchemaStatement(stmt) {
+			stmts = append(stmts, stmt)
+		}
+	}
+}
+
+// parseSchemaStatement parses the next statement of a schema. The parser panics on some malformed
+// input instead of returning an error, and accepts some expressions its String methods panic on,
+// e.g. unknown operators, so both are recovered here and reported as errors: comparing or
+// migrating the statement later would render it.
+func parseSchemaStatement(parser *rsql.Parser) (stmt rsql.Statement, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			stmt, err = nil, fmt.Errorf("unsupported statement: %v", r)
+		}
+	}()
+	stmt, err = parser.ParseStatement()
+	if err == nil && isSchemaStatement(stmt) {
+		statementSQL(stmt)
+	}
+	return stmt, err
+}
+
+// isSchemaStatement reports whether stmt defines an object rather than reading or writing data.
+func isSchemaStatement(stmt rsql.Statement) bool {
+	switch stmt.(type) {
+	case *rsql.SelectStatement, *rsql.InsertStatement, *rsql.UpdateStatement, *rsql.DeleteStatement:
+		return false
+	default:
+		return true
+	}
+}
+
+// parallelParseThreshold is the number of statements from which parseSchemaStatements parses
+// a schema in parallel. Below it, starting the workers costs more than it saves.
+const parallelParseThreshold = 256
+
+// parseStatementsParallel parses the statements split from a schema with one worker per CPU,
+// returning them in the order of pieces. ok is false if any statement fails to parse or render, so the
+// caller parses the whole schema again to report the error with its position in the schema.
+func parseStatementsParallel(pieces []string) (stmts []rsql.Statement, ok bool) {
+	parsed := make([][]rsql.Statement, len(pieces))
+	var next atomic.Int64
+	var failed atomic.Bool
+	var wg sync.WaitGroup
+	for range min(runtime.GOMAXPROCS(0), len(pieces)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for !failed.Load() {
+				i := int(next.Add(1) - 1)
+				if i >= len(pieces) {
+					return
+				}
+				parser := rsql.NewParser(strings.NewReader(pieces[i]))
+				for {
+					stmt, err := parseSchemaStatement(parser)
+					if errors.Is(err, io.EOF) {
+						break
+					}
+					if err != nil {
+						failed.Store(true)
+						return
+					}
+					if isSchemaStatement(stmt) {
+						parsed[i] = append(parsed[i], stmt)
+					}
+				}
 			}
+		}()
+	}
+	wg.Wait()
+	if failed.Load() {
+		return nil, false
+	}
+	for _, p := range parsed {
+		stmts = append(stmts, p...)
+	}
+	return stmts, true
+}
 
-			switch sStmt.(type) {
-			case *rsql.SelectStatement, *rsql.InsertStatement, *rsql.UpdateStatement, *rsql.DeleteStatement:
+// droppableColumns returns the columns of dbTable missing from schemaTable if removing them
+// is all it takes for the tables to match, so they can be dropped with ALTER TABLE DROP COLUMN
+// instead of failing with a conflict.
+func droppableColumns(dbTable, schemaTable *rsql.CreateTableStatement, strictness Strictness) []string {
+	schemaColumns := make(map[string]bool, len(schemaTable.Columns))
+	for _, col := range schemaTable.Columns {
+		schemaColumns[foldIdent(col.Name.Name)] = true
+	}
+	trimmed := *dbTable
+	trimmed.Columns = nil
+	var dropped []string
+	for _, col := range dbTable.Columns {
+		if schemaColumns[foldIdent(col.Name.Name)] {
+			trimmed.Columns = append(trimmed.Columns, col)
+		} else {
+			dropped = append(dropped, col.Name.Name)
+		}
+	}
+	if len(dropped) == 0 {
+		return nil
+	}
+	if match, _, err := compareStatements(&trimmed, schemaTable, strictness); err != nil || match != statementMatchExact {
+		return nil
+	}
+	return dropped
+}
+
+// dropTableDependents drops the indexes and triggers of table and the views selecting from it, which
+// SQLite requires before ALTER TABLE DROP COLUMN when they use the column. It returns the folded
+// names of the objects dropped.
+func dropTableDependents(tx execer, dbStmts []rsql.Statement, table string) ([]string, error) {
+	var dropped []string
+	for _, stmt := range dbStmts {
+		var dropSQL string
+		name, _ := getStatementName(stmt)
+		switch s := stmt.(type) {
+		case *rsql.CreateIndexStatement, *rsql.CreateTriggerStatement:
+			if !strings.EqualFold(getTableNameForDependent(s), table) {
 				continue
-			default:
-				schemaStmtsInOrder = append(schemaStmtsInOrder, sStmt)
 			}
+			dropSQL = fmt.Sprintf("DROP %s IF EXISTS %s", getObjectType(s), quoteIdent(name))
+		case *rsql.CreateViewStatement:
+			if !slices.Contains(viewTables(s), foldIdent(table)) {
+				continue
+			}
+			dropSQL = fmt.Sprintf("DROP VIEW IF EXISTS %s", quoteIdent(name))
+		default:
+			continue
+		}
+		if _, err := tx.Exec(dropSQL); err != nil {
+			return nil, fmt.Errorf("AutoMigrate: error dropping %s %s of table %s: %w", getObjectType(stmt), name, table, err)
+		}
+		dropped = append(dropped, foldIdent(name))
+	}
+	return dropped, nil
+}
+
+// addableColumns returns the columns schemaTable adds after the columns of dbTable if adding them
+// is all it takes for the tables to match, and ALTER TABLE ADD COLUMN accepts all of them:
+// they are not part of the primary key or unique, have a constant default, are not STORED
+// generated columns, and have a non-NULL default if they are NOT NULL or a NULL one if they are
+// foreign keys. Otherwise it returns nil.
+func addableColumns(dbTable, schemaTable *rsql.CreateTableStatement, strictness Strictness) []*rsql.ColumnDefinition {
+	if len(schemaTable.Columns) <= len(dbTable.Columns) {
+		return nil
+	}
+	table := newTableDefinition(schemaTable)
+	for i := len(dbTable.Columns); i < len(schemaTable.Columns); i++ {
+		col := table.Columns[i]
+		nullDefault := col.DefaultValue == nil || strings.EqualFold(*col.DefaultValue, "NULL")
+		foreignKey := slices.ContainsFunc(table.ForeignKeys, func(fk *ForeignKeyDefinition) bool {
+			return slices.ContainsFunc(fk.Columns, func(c string) bool { return strings.EqualFold(c, col.Name) })
+		})
+		if col.PrimaryKey || col.Unique || col.Hidden == 3 || !constantDefault(col) ||
+			col.NotNull && nullDefault && col.Hidden < 2 || foreignKey && !nullDefault {
+			return nil
 		}
+	}
+	trimmed := *schemaTable
+	trimmed.Columns = schemaTable.Columns[:len(dbTable.Columns)]
+	if match, _, err := compareStatements(dbTable, &trimmed, strictness); err != nil || match != statementMatchExact {
+		return nil
+	}
+	return schemaTable.Columns[len(dbTable.Columns):]
+}
+
+// execer executes a statement. It is implemented by DB, Tx and the offline script builder.
+type execer interface {
+	Exec(query string, args ...any) (Result, error)
+}
+
+// schemaQualifiable matches the start of the statements reconcileSchema executes, up to the
+// name of the object they create, alter, drop or insert into.
+var schemaQualifiable = regexp.MustCompile(`(?is)^\s*(?:CREATE\s+(?:UNIQUE\s+|VIRTUAL\s+)?(?:TABLE|INDEX|VIEW|TRIGGER)\s+(?:IF\s+NOT\s+EXISTS\s+)?|DROP\s+(?:TABLE|INDEX|VIEW|TRIGGER)\s+(?:IF\s+EXISTS\s+)?|ALTER\s+TABLE\s+|INSERT\s+INTO\s+)`)
+
+// schemaExecer qualifies the object names of the statements it executes with schema.
+type schemaExecer struct {
+	execer
+	schema string
+}
 
-		for _, sStmt := range schemaStmtsInOrder {
-			sNameOriginal, nameErr := getStatementName(sStmt)
-			if nameErr != nil {
-				return fmt.Errorf("AutoMigrate: could not extract name from schema statement %s: %w", sStmt.String(), nameErr)
+func (e schemaExecer) Exec(query string, args ...any) (Result, error) {
+	if loc := schemaQualifiable.FindStringIndex(query); loc != nil {
+		query = query[:loc[1]] + quoteIdent(e.schema) + "." + query[loc[1]:]
+	}
+	return e.execer.Exec(query, args...)
+}
+
+// qualifyingExecer returns tx qualifying object names with schema, or tx itself if schema is empty.
+func qualifyingExecer(tx execer, schema string) execer {
+	if schema == "" {
+		return tx
+	}
+	return schemaExecer{execer: tx, schema: schema}
+}
+
+// reconcileSchema executes the statements that turn the objects in dbStmts into the objects in schemaStmtsInOrder.
+// Objects are compared with the strictness of cfg. caps selects the statements used for changes.
+func reconcileSchema(tx execer, dbStmts []rsql.Statement, schemaStmtsInOrder []rsql.Statement, allowTableDeletes bool, cfg *migrateConfig, caps *Capabilities) error {
+	strictness := cfg.strictness
+	sqls := make(statementSQLs)
+	dbObjects := make(map[string]rsql.Statement)
+	schemaObjectsMap := make(map[string]rsql.Statement)
+	processedSchemaObjects := make(map[string]bool)
+	rebuiltTables := make(map[string]bool)
+	conflictedTables := make(map[string]bool)
+	tablesToDropIfDisallowed := []string{} // Moved to top to collect all table drop violations
+	var conflicts []*SchemaConflictError
+
+	for _, dStmt := range dbStmts {
+		dName, err := getStatementName(dStmt)
+		if err != nil {
+			return fmt.Errorf("AutoMigrate: could not extract name from DB statement %s: %w", dStmt.String(), err)
+		}
+		dbObjects[foldIdent(dName)] = dStmt
+	}
+
+	for _, sStmt := range schemaStmtsInOrder {
+		sNameOriginal, nameErr := getStatementName(sStmt)
+		if nameErr != nil {
+			return fmt.Errorf("AutoMigrate: could not extract name from schema statement %s: %w", sqls.of(sStmt), nameErr)
+		}
+		sNameLower := foldIdent(sNameOriginal)
+		if _, exists := schemaObjectsMap[sNameLower]; exists {
+			return fmt.Errorf("AutoMigrate: duplicate object name '%s' found in schema definition", sNameOriginal)
+		}
+		schemaObjectsMap[sNameLower] = sStmt
+		if table, ok := sStmt.(*rsql.CreateTableStatement); ok && table.Strict.IsValid() && !caps.StrictTables {
+			return fmt.Errorf("AutoMigrate: table %s is STRICT, which SQLite %s does not support", sNameOriginal, caps.SQLiteVersion)
+		}
+	}
+
+	for _, sStmt := range schemaStmtsInOrder {
+		sNameOriginal, _ := getStatementName(sStmt)
+		sNameLower := foldIdent(sNameOriginal)
+		processedSchemaObjects[sNameLower] = true
+
+		dStmt, dExistsInDbInitially := dbObjects[sNameLower]
+
+		_, sIsTable := sStmt.(*rsql.CreateTableStatement)
+
+		forceRecreateDueToDependency := false
+		if !sIsTable {
+			dependentTableName := getTableNameForDependent(sStmt)
+			if dependentTableName != "" && conflictedTables[foldIdent(dependentTableName)] {
+				// The table keeps its old columns, which the dependent may not apply to.
+				continue
 			}
-			sNameLower := strings.ToLower(sNameOriginal)
-			if _, exists := schemaObjectsMap[sNameLower]; exists {
-				return fmt.Errorf("AutoMigrate: duplicate object name '%s' found in schema definition", sNameOriginal)
+			if dependentTableName != "" && rebuiltTables[foldIdent(dependentTableName)] {
+				forceRecreateDueToDependency = true
 			}
-			schemaObjectsMap[sNameLower] = sStmt
 		}
 
-		for _, sStmt := range schemaStmtsInOrder {
-			sNameOriginal, _ := getStatementName(sStmt)
-			sNameLower := strings.ToLower(sNameOriginal)
-			processedSchemaObjects[sNameLower] = true
+		if !dExistsInDbInitially {
+			if _, execErr := tx.Exec(sqls.of(sStmt)); execErr != nil {
+				return fmt.Errorf("AutoMigrate: error creating new object %s: %w. SQL: %s", sNameOriginal, execErr, sqls.of(sStmt))
+			}
+			if sIsTable {
+				rebuiltTables[sNameLower] = true
+			}
+		} else {
+			matchType, diffDescription, cmpErr := sqls.compare(dStmt, sStmt, strictness)
+			if cmpErr != nil {
+				return fmt.Errorf("AutoMigrate: error comparing object '%s': %w", sNameOriginal, cmpErr)
+			}
 
-			dStmt, dExistsInDbInitially := dbObjects[sNameLower]
+			if forceRecreateDueToDependency {
+				var dropSQLForRecreate string
+				originalDNameForDrop, _ := getStatementName(dStmt)
+				qOriginalDNameForDrop := quoteIdent(originalDNameForDrop)
+				dbObjTypeForRecreate := getObjectType(dStmt)
 
-			_, sIsTable := sStmt.(*rsql.CreateTableStatement)
+				// Check if this forced recreate involves dropping a table when not allowed
+				if dbObjTypeForRecreate == "TABLE" && !allowTableDeletes {
+					tablesToDropIfDisallowed = append(tablesToDropIfDisallowed, originalDNameForDrop)
+					// Do not proceed with drop or recreate for this object
+					// Mark that this schema object was not successfully reconciled
+					processedSchemaObjects[sNameLower] = false // Revert processing status
+					continue
+				}
 
-			forceRecreateDueToDependency := false
-			if !sIsTable {
-				dependentTableName := getTableNameForDependent(sStmt)
-				if dependentTableName != "" && rebuiltTables[strings.ToLower(dependentTableName)] {
-					forceRecreateDueToDependency = true
+				switch dStmt.(type) {
+				case *rsql.CreateTableStatement:
+					dropSQLForRecreate = fmt.Sprintf("DROP TABLE IF EXISTS %s", qOriginalDNameForDrop)
+				case *rsql.CreateIndexStatement:
+					dropSQLForRecreate = fmt.Sprintf("DROP INDEX IF EXISTS %s", qOriginalDNameForDrop)
+				case *rsql.CreateViewStatement:
+					dropSQLForRecreate = fmt.Sprintf("DROP VIEW IF EXISTS %s", qOriginalDNameForDrop)
+				case *rsql.CreateTriggerStatement:
+					dropSQLForRecreate = fmt.Sprintf("DROP TRIGGER IF EXISTS %s", qOriginalDNameForDrop)
+				default:
+					return fmt.Errorf("AutoMigrate: unknown type for DB object %s to drop for forced recreate", originalDNameForDrop)
 				}
-			}
 
-			if !dExistsInDbInitially {
-				if _, execErr := tx.Exec(sStmt.String()); execErr != nil {
-					return fmt.Errorf("AutoMigrate: error creating new object %s: %w. SQL: %s", sNameOriginal, execErr, sStmt.String())
+				if _, err := tx.Exec(dropSQLForRecreate); err != nil {
+					return fmt.Errorf("AutoMigrate: error dropping DB object %s %s for forced recreate: %w", dbObjTypeForRecreate, originalDNameForDrop, err)
 				}
-				if sIsTable {
-					rebuiltTables[sNameLower] = true
+				if _, execErr := tx.Exec(sqls.of(sStmt)); execErr != nil {
+					return fmt.Errorf("AutoMigrate: error recreating object %s after forced drop: %w. SQL: %s", sNameOriginal, execErr, sqls.of(sStmt))
 				}
 			} else {
-				matchType, diffDescription, cmpErr := compareStatements(dStmt, sStmt)
-				if cmpErr != nil {
-					return fmt.Errorf("AutoMigrate: error comparing object '%s': %w", sNameOriginal, cmpErr)
-				}
-
-				if forceRecreateDueToDependency {
-					var dropSQLForRecreate string
-					originalDNameForDrop, _ := getStatementName(dStmt)
-					qOriginalDNameForDrop := quoteIdent(originalDNameForDrop)
-					dbObjTypeForRecreate := getObjectType(dStmt)
-
-					// Check if this forced recreate involves dropping a table when not allowed
-					if dbObjTypeForRecreate == "TABLE" && !allowTableDeletes {
-						tablesToDropIfDisallowed = append(tablesToDropIfDisallowed, originalDNameForDrop)
-						// Do not proceed with drop or recreate for this object
-						// Mark that this schema object was not successfully reconciled
-						processedSchemaObjects[sNameLower] = false // Revert processing status
+				if query, ok := cfg.rebuildSelects[sNameLower]; ok && sIsTable && matchType != statementMatchExact {
+					if _, ok := dStmt.(*rsql.CreateTableStatement); ok {
+						if err := rebuildTableSelect(tx, sNameOriginal, sStmt.(*rsql.CreateTableStatement), query); err != nil {
+							return err
+						}
+						rebuiltTables[sNameLower] = true
 						continue
 					}
-
-					switch dStmt.(type) {
-					case *rsql.CreateTableStatement:
-						dropSQLForRecreate = fmt.Sprintf("DROP TABLE %s", qOriginalDNameForDrop)
-					case *rsql.CreateIndexStatement:
-						dropSQLForRecreate = fmt.Sprintf("DROP INDEX %s", qOriginalDNameForDrop)
-					case *rsql.CreateViewStatement:
-						dropSQLForRecreate = fmt.Sprintf("DROP VIEW %s", qOriginalDNameForDrop)
-					case *rsql.CreateTriggerStatement:
-						dropSQLForRecreate = fmt.Sprintf("DROP TRIGGER %s", qOriginalDNameForDrop)
-					default:
-						return fmt.Errorf("AutoMigrate: unknown type for DB object %s to drop for forced recreate", originalDNameForDrop)
-					}
-
-					if _, err := tx.Exec(dropSQLForRecreate); err != nil {
-						errStr := strings.ToLower(err.Error())
-						dbObjTypeStrLower := strings.ToLower(dbObjTypeForRecreate)
-						if !(strings.HasPrefix(errStr, "no such "+dbObjTypeStrLower) ||
-							(dbObjTypeForRecreate == "TABLE" && strings.Contains(errStr, "no such table")) ||
-							(dbObjTypeForRecreate == "INDEX" && strings.Contains(errStr, "no such index")) ||
-							(dbObjTypeForRecreate == "VIEW" && strings.Contains(errStr, "no such view")) ||
-							(dbObjTypeForRecreate == "TRIGGER" && strings.Contains(errStr, "no such trigger"))) {
-							return fmt.Errorf("AutoMigrate: error dropping DB object %s %s for forced recreate: %w", dbObjTypeForRecreate, originalDNameForDrop, err)
+				}
+				switch matchType {
+				case statementMatchExact:
+				case statementMatchReorderNeeded:
+					if schemaTableStmt, ok := sStmt.(*rsql.CreateTableStatement); ok {
+						colNames := make([]string, len(schemaTableStmt.Columns))
+						for i, colDef := range schemaTableStmt.Columns {
+							colNames[i] = quoteIdent(colDef.Name.Name)
+						}
+						if err := rebuildTable(tx, sNameOriginal, sStmt, colNames, colNames); err != nil {
+							return err
 						}
+						rebuiltTables[sNameLower] = true
+					} else {
+						return fmt.Errorf("AutoMigrate: internal error - statementMatchReorderNeeded for non-table object %s", sNameOriginal)
 					}
-					if _, execErr := tx.Exec(sStmt.String()); execErr != nil {
-						return fmt.Errorf("AutoMigrate: error recreating object %s after forced drop: %w. SQL: %s", sNameOriginal, execErr, sStmt.String())
+				case statementMatchNoMatch:
+					var dIsTable bool
+					if _, ok := dStmt.(*rsql.CreateTableStatement); ok {
+						dIsTable = true
 					}
-				} else {
-					switch matchType {
-					case statementMatchExact:
-					case statementMatchReorderNeeded:
-						if schemaTableStmt, ok := sStmt.(*rsql.CreateTableStatement); ok {
-							tempTableNameSuffix := "_temp_reorder_sqlt"
-							tempTableName := sNameOriginal + tempTableNameSuffix
-							qOldTableName := quoteIdent(sNameOriginal)
-							qTempTableName := quoteIdent(tempTableName)
-							if _, err := tx.Exec(fmt.Sprintf("ALTER TABLE %s RENAME TO %s", qOldTableName, qTempTableName)); err != nil {
-								return fmt.Errorf("AutoMigrate: error renaming table %s to %s for reorder: %w", sNameOriginal, tempTableName, err)
+
+					if sIsTable && dIsTable {
+						dTable, sTable := dStmt.(*rsql.CreateTableStatement), sStmt.(*rsql.CreateTableStatement)
+						if renamed, rebuilt, err := cfg.renameColumns(tx, dTable, sTable, strictness, caps); err != nil {
+							return err
+						} else if renamed {
+							rebuiltTables[sNameLower] = rebuilt
+							continue
+						}
+						if dropped := droppableColumns(dTable, sTable, strictness); len(dropped) > 0 && cfg.columnDrops && caps.DropColumn {
+							cleared, err := dropTableDependents(tx, dbStmts, sNameOriginal)
+							if err != nil {
+								return err
 							}
-							if _, err := tx.Exec(sStmt.String()); err != nil {
-								tx.Exec(fmt.Sprintf("ALTER TABLE %s RENAME TO %s", qTempTableName, qOldTableName))
-								return fmt.Errorf("AutoMigrate: error creating new table %s for reorder: %w. SQL: %s", sNameOriginal, err, sStmt.String())
+							for _, column := range dropped {
+								if _, err := tx.Exec(fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", quoteIdent(sNameOriginal), quoteIdent(column))); err != nil {
+									return fmt.Errorf("AutoMigrate: error dropping column %s of table %s: %w", column, sNameOriginal, err)
+								}
 							}
-							colNames := make([]string, len(schemaTableStmt.Columns))
-							for i, colDef := range schemaTableStmt.Columns {
-								colNames[i] = quoteIdent(colDef.Name.Name)
+							// Dependents already reconciled are created again now, the others when they are reached.
+							for _, name := range cleared {
+								delete(dbObjects, name)
+								if stmt, ok := schemaObjectsMap[name]; ok && processedSchemaObjects[name] {
+									if _, err := tx.Exec(sqls.of(stmt)); err != nil {
+										return fmt.Errorf("AutoMigrate: error recreating object %s after dropping columns of table %s: %w", name, sNameOriginal, err)
+									}
+								}
 							}
-							joinedColNames := strings.Join(colNames, ", ")
-							insertSQL := fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s", qOldTableName, joinedColNames, joinedColNames, qTempTableName)
-							if _, err := tx.Exec(insertSQL); err != nil {
-								return fmt.Errorf("AutoMigrate: error copying data to reordered table %s: %w. SQL: %s", sNameOriginal, err, insertSQL)
+							continue
+						}
+						if columns, exprs, ok := cfg.backfilledColumns(dTable, sTable, strictness); ok {
+							if err := rebuildTable(tx, sNameOriginal, sStmt, columns, exprs); err != nil {
+								return err
 							}
-							if _, err := tx.Exec(fmt.Sprintf("DROP TABLE %s", qTempTableName)); err != nil {
-								return fmt.Errorf("AutoMigrate: error dropping temporary table %s for reorder: %w", tempTableName, err)
+							rebuiltTables[sNameLower] = true
+							continue
+						}
+						if added := addableColumns(dTable, sTable, strictness); len(added) > 0 && cfg.addColumns {
+							for _, column := range added {
+								if _, err := tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", quoteIdent(sNameOriginal), column.String())); err != nil {
+									return fmt.Errorf("AutoMigrate: error adding column %s to table %s: %w", column.Name.Name, sNameOriginal, err)
+								}
+							}
+							continue
+						}
+						conflict := &SchemaConflictError{ObjectName: sNameOriginal, ObjectType: "TABLE", ExpectedSQL: sqls.of(sStmt), ActualSQL: sqls.of(dStmt), ConflictDetails: diffDescription,
+							TableOptions: compareTableOptions(dTable, sTable)}
+						switch resolution := cfg.resolveConflict(conflict); resolution {
+						case ResolutionSkip:
+							cfg.skippedTables = append(cfg.skippedTables, sNameLower)
+							continue
+						case ResolutionForceRebuild:
+							columns := sharedColumns(dTable, sTable)
+							if err := rebuildTable(tx, sNameOriginal, sStmt, columns, columns); err != nil {
+								return err
 							}
 							rebuiltTables[sNameLower] = true
-						} else {
-							return fmt.Errorf("AutoMigrate: internal error - statementMatchReorderNeeded for non-table object %s", sNameOriginal)
+							continue
+						case ResolutionAbort:
+							conflicts = append(conflicts, conflict)
+							conflictedTables[sNameLower] = true
+							continue
+						default:
+							return fmt.Errorf("AutoMigrate: unknown resolution %s for conflict on table %s: %w", resolution, sNameOriginal, conflict)
 						}
-					case statementMatchNoMatch:
-						var dIsTable bool
-						if _, ok := dStmt.(*rsql.CreateTableStatement); ok {
-							dIsTable = true
+					} else {
+						dNameOriginalForDrop, _ := getStatementName(dStmt)
+						if dIsTable && !allowTableDeletes {
+							tablesToDropIfDisallowed = append(tablesToDropIfDisallowed, dNameOriginalForDrop)
+							processedSchemaObjects[sNameLower] = false // This schema object cannot be reconciled
+							continue                                   // Skip drop and create
 						}
 
-						if sIsTable && dIsTable {
-							return &SchemaConflictError{ObjectName: sNameOriginal, ObjectType: "TABLE", ExpectedSQL: sStmt.String(), ActualSQL: dStmt.String(), ConflictDetails: diffDescription}
-						} else {
-							dNameOriginalForDrop, _ := getStatementName(dStmt)
-							if dIsTable && !allowTableDeletes {
-								tablesToDropIfDisallowed = append(tablesToDropIfDisallowed, dNameOriginalForDrop)
-								processedSchemaObjects[sNameLower] = false // This schema object cannot be reconciled
-								continue                                   // Skip drop and create
-							}
+						var dropSQLNoMatch string
+						qDNameOriginalForDrop := quoteIdent(dNameOriginalForDrop)
+						dbObjTypeForDrop := getObjectType(dStmt)
 
-							var dropSQLNoMatch string
-							qDNameOriginalForDrop := quoteIdent(dNameOriginalForDrop)
-							dbObjTypeForDrop := getObjectType(dStmt)
-
-							switch dStmt.(type) {
-							case *rsql.CreateTableStatement:
-								dropSQLNoMatch = fmt.Sprintf("DROP TABLE %s", qDNameOriginalForDrop)
-							case *rsql.CreateIndexStatement:
-								dropSQLNoMatch = fmt.Sprintf("DROP INDEX %s", qDNameOriginalForDrop)
-							case *rsql.CreateViewStatement:
-								dropSQLNoMatch = fmt.Sprintf("DROP VIEW %s", qDNameOriginalForDrop)
-							case *rsql.CreateTriggerStatement:
-								dropSQLNoMatch = fmt.Sprintf("DROP TRIGGER %s", qDNameOriginalForDrop)
-							default:
-								return fmt.Errorf("AutoMigrate: unknown type for DB object %s to drop for type/def change", dNameOriginalForDrop)
-							}
-							if _, err := tx.Exec(dropSQLNoMatch); err != nil {
-								return fmt.Errorf("AutoMigrate: error dropping DB object %s %s for type/def change: %w", dbObjTypeForDrop, dNameOriginalForDrop, err)
-							}
+						switch dStmt.(type) {
+						case *rsql.CreateTableStatement:
+							dropSQLNoMatch = fmt.Sprintf("DROP TABLE %s", qDNameOriginalForDrop)
+						case *rsql.CreateIndexStatement:
+							dropSQLNoMatch = fmt.Sprintf("DROP INDEX %s", qDNameOriginalForDrop)
+						case *rsql.CreateViewStatement:
+							dropSQLNoMatch = fmt.Sprintf("DROP VIEW %s", qDNameOriginalForDrop)
+						case *rsql.CreateTriggerStatement:
+							dropSQLNoMatch = fmt.Sprintf("DROP TRIGGER %s", qDNameOriginalForDrop)
+						default:
+							return fmt.Errorf("AutoMigrate: unknown type for DB object %s to drop for type/def change", dNameOriginalForDrop)
+						}
+						if _, err := tx.Exec(dropSQLNoMatch); err != nil {
+							return fmt.Errorf("AutoMigrate: error dropping DB object %s %s for type/def change: %w", dbObjTypeForDrop, dNameOriginalForDrop, err)
+						}
 
-							if _, execErr := tx.Exec(sStmt.String()); execErr != nil {
-								return fmt.Errorf("AutoMigrate: error creating schema object %s after dropping old version: %w. SQL: %s", sNameOriginal, execErr, sStmt.String())
-							}
-							if sIsTable {
-								rebuiltTables[sNameLower] = true
-							} else if dIsTable {
-								rebuiltTables[sNameLower] = true
-							}
+						if _, execErr := tx.Exec(sqls.of(sStmt)); execErr != nil {
+							return fmt.Errorf("AutoMigrate: error creating schema object %s after dropping old version: %w. SQL: %s", sNameOriginal, execErr, sqls.of(sStmt))
+						}
+						if sIsTable {
+							rebuiltTables[sNameLower] = true
+						} else if dIsTable {
+							rebuiltTables[sNameLower] = true
 						}
 					}
 				}
 			}
 		}
+	}
 
-		for dNameLower, dStmt := range dbObjects {
-			if !processedSchemaObjects[dNameLower] {
-				originalDName, nameErr := getStatementName(dStmt)
-				if nameErr != nil {
-					return fmt.Errorf("AutoMigrate: error getting original name for DB object to drop (key: %s): %w", dNameLower, nameErr)
-				}
+	for _, dStmt := range dbStmts {
+		originalDName, _ := getStatementName(dStmt)
+		if !processedSchemaObjects[foldIdent(originalDName)] {
 
-				objTypeStr := getObjectType(dStmt)
+			objTypeStr := getObjectType(dStmt)
 
-				isTableToDrop := false
-				if _, ok := dStmt.(*rsql.CreateTableStatement); ok {
-					isTableToDrop = true
-				}
+			isTableToDrop := false
+			if _, ok := dStmt.(*rsql.CreateTableStatement); ok {
+				isTableToDrop = true
+			}
 
-				if isTableToDrop && !allowTableDeletes {
-					tablesToDropIfDisallowed = append(tablesToDropIfDisallowed, originalDName)
-					continue
-				}
+			if isTableToDrop && !allowTableDeletes {
+				tablesToDropIfDisallowed = append(tablesToDropIfDisallowed, originalDName)
+				continue
+			}
 
-				var dropSQL string
-				qOriginalDName := quoteIdent(originalDName)
-				switch dStmt.(type) {
-				case *rsql.CreateTableStatement:
-					dropSQL = fmt.Sprintf("DROP TABLE %s", qOriginalDName)
-				case *rsql.CreateIndexStatement:
-					dropSQL = fmt.Sprintf("DROP INDEX %s", qOriginalDName)
-				case *rsql.CreateViewStatement:
-					dropSQL = fmt.Sprintf("DROP VIEW %s", qOriginalDName)
-				case *rsql.CreateTriggerStatement:
-					dropSQL = fmt.Sprintf("DROP TRIGGER %s", qOriginalDName)
-				default:
-					return fmt.Errorf("AutoMigrate: unknown type for DB object %s to drop from deletion loop", originalDName)
-				}
+			// Indexes and triggers may already be gone with their table, hence IF EXISTS.
+			var dropSQL string
+			qOriginalDName := quoteIdent(originalDName)
+			switch dStmt.(type) {
+			case *rsql.CreateTableStatement:
+				dropSQL = fmt.Sprintf("DROP TABLE IF EXISTS %s", qOriginalDName)
+			case *rsql.CreateIndexStatement:
+				dropSQL = fmt.Sprintf("DROP INDEX IF EXISTS %s", qOriginalDName)
+			case *rsql.CreateViewStatement:
+				dropSQL = fmt.Sprintf("DROP VIEW IF EXISTS %s", qOriginalDName)
+			case *rsql.CreateTriggerStatement:
+				dropSQL = fmt.Sprintf("DROP TRIGGER IF EXISTS %s", qOriginalDName)
+			default:
+				return fmt.Errorf("AutoMigrate: unknown type for DB object %s to drop from deletion loop", originalDName)
+			}
 
-				if _, err := tx.Exec(dropSQL); err != nil {
-					errStr := strings.ToLower(err.Error())
-					if !(strings.HasPrefix(errStr, "no such "+strings.ToLower(objTypeStr)) || (objTypeStr == "TABLE" && strings.Contains(errStr, "no such table")) || (objTypeStr == "INDEX" && strings.Contains(errStr, "no such index")) || (objTypeStr == "VIEW" && strings.Contains(errStr, "no such view")) || (objTypeStr == "TRIGGER" && strings.Contains(errStr, "no such trigger"))) {
-						return fmt.Errorf("AutoMigrate: error dropping object %s %s from database: %w", objTypeStr, originalDName, err)
-					}
-				}
+			if _, err := tx.Exec(dropSQL); err != nil {
+				return fmt.Errorf("AutoMigrate: error dropping object %s %s from database: %w", objTypeStr, originalDName, err)
 			}
 		}
+	}
 
-		if len(tablesToDropIfDisallowed) > 0 {
-			return ErrTableDeletionNotAllowed{Tables: tablesToDropIfDisallowed}
-		}
+	if len(conflicts) > 0 {
+		return ErrSchemaConflicts{Conflicts: conflicts}
+	}
+	if len(tablesToDropIfDisallowed) > 0 {
+		return ErrTableDeletionNotAllowed{Tables: tablesToDropIfDisallowed}
+	}
 
-		return nil
-	})
+	return nil
 }
@@ -1,13 +1,16 @@
 package sqlt_test
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
-	"github.com/jmoiron/sqlx"
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -17,9 +20,9 @@ import (
 )
 
 func getTestDB(t *testing.T) sqlt.DB {
-	db, err := sqlx.Open("sqlite3", "file::memory:?_foreign_keys=on")
+	db, err := sqlt.OpenMemory(t.Name())
 	require.NoError(t, err, "Failed to open in-memory database")
-	return sqlt.Wrap(db)
+	return db
 }
 
 // Helper function to check if a database object exists (can be shared)
@@ -1028,3 +1031,630 @@ func TestDisallowTableDeletes_TableReplacedByIndex_ConflictError(t *testing.T) {
 	assert.True(t, objectExists(t, wrappedDB, "table", "my_object"))
 	assert.False(t, objectExists(t, wrappedDB, "index", "my_object"))
 }
+
+func TestAutoMigrate_CheckpointTruncatesWAL(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "wal.db")
+	wrappedDB, err := sqlt.Open("sqlite3", "file:"+path+"?_journal_mode=WAL")
+	require.NoError(t, err)
+	defer wrappedDB.Close()
+	ctx := gort.Context()
+
+	targetSchema := `
+		CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT);
+		CREATE INDEX idx_items_name ON items (name);`
+	err = sqlt.AutoMigrate(ctx, wrappedDB, strings.NewReader(targetSchema), true, sqlt.WithBusyTimeout(time.Second), sqlt.WithCheckpointThreshold(0))
+	require.NoError(t, err)
+
+	info, err := os.Stat(path + "-wal")
+	require.NoError(t, err)
+	assert.Zero(t, info.Size(), "WAL should be truncated after AutoMigrate")
+
+	err = sqlt.Verify(ctx, wrappedDB, strings.NewReader(targetSchema))
+	assert.NoError(t, err)
+}
+
+func TestAutoMigrate_HistoryRecordsRuns(t *testing.T) {
+	t.Parallel()
+	wrappedDB := getTestDB(t)
+	defer wrappedDB.Close()
+	ctx := gort.Context()
+
+	targetSchema := `CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT);`
+	err := sqlt.AutoMigrate(ctx, wrappedDB, strings.NewReader(targetSchema), true, sqlt.WithHistory("v1.2.3"))
+	require.NoError(t, err)
+
+	err = sqlt.AutoMigrate(ctx, wrappedDB, strings.NewReader(`CREATE TABLE items (id INTEGER PRIMARY KEY, name INTEGER);`), true, sqlt.WithHistory("v1.2.4"))
+	require.Error(t, err)
+
+	history, err := sqlt.MigrationHistory(ctx, wrappedDB)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+
+	assert.Equal(t, "v1.2.4", history[0].AppVersion)
+	assert.False(t, history[0].Success)
+	require.NotNil(t, history[0].Error)
+	assert.Contains(t, *history[0].Error, "schema conflict")
+
+	assert.Equal(t, "v1.2.3", history[1].AppVersion)
+	assert.True(t, history[1].Success)
+	assert.Nil(t, history[1].Error)
+	assert.Contains(t, history[1].Plan, "CREATE TABLE")
+
+	err = sqlt.Verify(ctx, wrappedDB, strings.NewReader(targetSchema))
+	assert.NoError(t, err, "history table must not show up in the schema diff")
+}
+
+func TestAutoMigrate_SQLLogIsReplayable(t *testing.T) {
+	t.Parallel()
+	wrappedDB := getTestDB(t)
+	defer wrappedDB.Close()
+	ctx := gort.Context()
+
+	targetSchema := `
+		CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT);
+		CREATE INDEX idx_items_name ON items (name);`
+	var sqlLog strings.Builder
+	err := sqlt.AutoMigrate(ctx, wrappedDB, strings.NewReader(targetSchema), true, sqlt.WithSQLLog(&sqlLog))
+	require.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(sqlLog.String(), ";\n"))
+
+	replayDB := getTestDB(t)
+	defer replayDB.Close()
+	require.NoError(t, sqlt.ExecString(ctx, replayDB, sqlLog.String()))
+	assert.NoError(t, sqlt.Verify(ctx, replayDB, strings.NewReader(targetSchema)))
+}
+
+func TestAutoMigrate_TableOptions(t *testing.T) {
+	t.Parallel()
+	wrappedDB := getTestDB(t)
+	defer wrappedDB.Close()
+	ctx := gort.Context()
+
+	strictSchema := `CREATE TABLE kv (k TEXT PRIMARY KEY, v TEXT) WITHOUT ROWID, STRICT;`
+	err := sqlt.AutoMigrate(ctx, wrappedDB, strings.NewReader(strictSchema), true)
+	require.NoError(t, err)
+	assert.Contains(t, getObjectSQL(t, wrappedDB, "kv"), "STRICT", "table options must survive creation")
+	assert.NoError(t, sqlt.Verify(ctx, wrappedDB, strings.NewReader(strictSchema)))
+
+	err = sqlt.AutoMigrate(ctx, wrappedDB, strings.NewReader(`CREATE TABLE kv (k TEXT PRIMARY KEY, v TEXT) WITHOUT ROWID;`), true)
+	var conflictErr *sqlt.SchemaConflictError
+	require.True(t, errors.As(err, &conflictErr), "Error should be a SchemaConflictError")
+	assert.Equal(t, []sqlt.TableOptionConflict{{Option: "STRICT", Expected: false, Actual: true}}, conflictErr.TableOptions)
+	assert.Contains(t, conflictErr.ConflictDetails, "Table option STRICT mismatch")
+}
+
+func TestAutoMigrate_TypeAffinity(t *testing.T) {
+	t.Parallel()
+	wrappedDB := getTestDB(t)
+	defer wrappedDB.Close()
+	ctx := gort.Context()
+
+	require.NoError(t, sqlt.ExecString(ctx, wrappedDB, `CREATE TABLE notes (id BIGINT PRIMARY KEY, title VARCHAR(255), body CLOB);`))
+	targetSchema := `CREATE TABLE notes (id BIGINT PRIMARY KEY, title TEXT, body TEXT);`
+
+	var sqlLog strings.Builder
+	err := sqlt.AutoMigrate(ctx, wrappedDB, strings.NewReader(targetSchema), false, sqlt.WithTypeAffinity(), sqlt.WithSQLLog(&sqlLog))
+	require.NoError(t, err)
+	assert.Empty(t, sqlLog.String(), "types with the same affinity must not cause a rebuild")
+
+	err = sqlt.AutoMigrate(ctx, wrappedDB, strings.NewReader(targetSchema), false)
+	assert.Error(t, err, "types are compared by name without WithTypeAffinity")
+}
+
+func TestAutoMigrate_DropColumn(t *testing.T) {
+	t.Parallel()
+	wrappedDB := getTestDB(t)
+	defer wrappedDB.Close()
+	ctx := gort.Context()
+
+	require.NoError(t, sqlt.ExecString(ctx, wrappedDB, `CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, legacy TEXT);`))
+	wrappedDB.MustExec("INSERT INTO users (name, legacy) VALUES ('ada', 'x')")
+	targetSchema := `CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT);`
+
+	err := sqlt.AutoMigrate(ctx, wrappedDB, strings.NewReader(targetSchema), true)
+	var conflictErr *sqlt.SchemaConflictError
+	require.ErrorAs(t, err, &conflictErr, "columns are only dropped with WithAllowColumnDrops")
+
+	var sqlLog strings.Builder
+	require.NoError(t, sqlt.AutoMigrate(ctx, wrappedDB, strings.NewReader(targetSchema), false, sqlt.WithAllowColumnDrops(), sqlt.WithSQLLog(&sqlLog)))
+	assert.Equal(t, "ALTER TABLE \"users\" DROP COLUMN \"legacy\";\n", sqlLog.String())
+	assert.NoError(t, sqlt.Verify(ctx, wrappedDB, strings.NewReader(targetSchema)))
+	var name string
+	require.NoError(t, wrappedDB.Get(&name, "SELECT name FROM users"))
+	assert.Equal(t, "ada", name)
+}
+
+func TestAutoMigrate_DropColumnWithDependents(t *testing.T) {
+	t.Parallel()
+	wrappedDB := getTestDB(t)
+	defer wrappedDB.Close()
+	ctx := gort.Context()
+
+	require.NoError(t, sqlt.ExecString(ctx, wrappedDB, `
+		CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, legacy TEXT);
+		CREATE INDEX users_legacy ON users (legacy);
+		CREATE VIEW user_names AS SELECT name, legacy FROM users;
+		CREATE TRIGGER users_touch AFTER UPDATE OF legacy ON users BEGIN SELECT 1; END;
+	`))
+	wrappedDB.MustExec("INSERT INTO users (name, legacy) VALUES ('ada', 'x')")
+	targetSchema := `
+		CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT);
+		CREATE INDEX users_name ON users (name);
+		CREATE VIEW user_names AS SELECT name FROM users;
+	`
+
+	require.NoError(t, sqlt.AutoMigrate(ctx, wrappedDB, strings.NewReader(targetSchema), false, sqlt.WithAllowColumnDrops()))
+	assert.NoError(t, sqlt.Verify(ctx, wrappedDB, strings.NewReader(targetSchema)))
+	var name string
+	require.NoError(t, wrappedDB.Get(&name, "SELECT name FROM user_names"))
+	assert.Equal(t, "ada", name)
+}
+
+func TestAutoMigrate_DropColumnKeepsUnrelatedViews(t *testing.T) {
+	t.Parallel()
+	wrappedDB := getTestDB(t)
+	defer wrappedDB.Close()
+	ctx := gort.Context()
+
+	schema := `
+		CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, legacy TEXT);
+		CREATE TABLE users_archive (id INTEGER PRIMARY KEY, name TEXT);
+		CREATE VIEW archived_names AS SELECT name FROM users_archive;
+		CREATE VIEW active_names AS SELECT name FROM (SELECT name FROM users);
+	`
+	require.NoError(t, sqlt.ExecString(ctx, wrappedDB, schema))
+	targetSchema := strings.Replace(schema, ", legacy TEXT", "", 1)
+
+	var sqlLog strings.Builder
+	require.NoError(t, sqlt.AutoMigrate(ctx, wrappedDB, strings.NewReader(targetSchema), false, sqlt.WithAllowColumnDrops(), sqlt.WithSQLLog(&sqlLog)))
+	assert.Contains(t, sqlLog.String(), `DROP VIEW IF EXISTS "active_names"`, "views selecting from the table through a subquery are dropped")
+	assert.NotContains(t, sqlLog.String(), `"archived_names"`, "views of other tables sharing a prefix are left alone")
+	assert.NoError(t, sqlt.Verify(ctx, wrappedDB, strings.NewReader(targetSchema)))
+}
+
+func TestAutoMigrate_StatementBatching(t *testing.T) {
+	t.Parallel()
+	wrappedDB := getTestDB(t)
+	defer wrappedDB.Close()
+	ctx := gort.Context()
+
+	targetSchema := `
+CREATE TABLE authors (id INTEGER PRIMARY KEY, name TEXT);
+CREATE TABLE books (id INTEGER PRIMARY KEY, author_id INTEGER REFERENCES authors (id), title TEXT);
+CREATE INDEX books_author ON books (author_id);`
+	var sqlLog strings.Builder
+	err := sqlt.AutoMigrate(ctx, wrappedDB, strings.NewReader(targetSchema), false, sqlt.WithStatementBatching(), sqlt.WithSQLLog(&sqlLog))
+	require.NoError(t, err)
+	assert.Equal(t, 3, strings.Count(sqlLog.String(), ";\n"), "batched statements are logged one by one")
+	assert.NoError(t, sqlt.Verify(ctx, wrappedDB, strings.NewReader(targetSchema)))
+
+	err = sqlt.ExecString(ctx, wrappedDB, `CREATE TABLE extra (id INTEGER); CREATE INDEX broken ON missing (id);`, sqlt.WithStatementBatching())
+	require.Error(t, err)
+	assert.False(t, objectExists(t, wrappedDB, "table", "extra"), "a failed batch must be rolled back")
+}
+
+func TestAutoMigrate_DeferredIndexes(t *testing.T) {
+	t.Parallel()
+	db := getTestDB(t)
+	defer db.Close()
+	ctx := gort.Context()
+
+	schema := `
+CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT, price INTEGER);
+CREATE INDEX idx_items_name ON items (name);
+CREATE INDEX idx_items_price ON items (price);`
+	var builds []sqlt.IndexBuild
+	var sqlLog strings.Builder
+	err := sqlt.AutoMigrate(ctx, db, strings.NewReader(schema), false,
+		sqlt.WithDeferredIndexes("idx_items_price"),
+		sqlt.WithIndexProgress(func(b sqlt.IndexBuild) { builds = append(builds, b) }),
+		sqlt.WithSQLLog(&sqlLog))
+	require.NoError(t, err)
+
+	require.Len(t, builds, 1)
+	assert.Equal(t, "idx_items_price", builds[0].Index)
+	assert.Equal(t, 1, builds[0].Done)
+	assert.Equal(t, 1, builds[0].Total)
+	assert.NoError(t, builds[0].Err)
+	assert.True(t, objectExists(t, db, "index", "idx_items_price"))
+	assert.True(t, strings.HasSuffix(strings.TrimSpace(sqlLog.String()), `CREATE INDEX "idx_items_price" ON "items" ("price");`), sqlLog.String())
+	require.NoError(t, sqlt.VerifyString(ctx, db, schema))
+}
+
+func TestAutoMigrate_Analyze(t *testing.T) {
+	t.Parallel()
+	db := getTestDB(t)
+	defer db.Close()
+	ctx := gort.Context()
+
+	schema := `CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT);`
+	require.NoError(t, sqlt.AutoMigrate(ctx, db, strings.NewReader(schema), false))
+	db.MustExec("INSERT INTO items (name) VALUES ('a'), ('b'), ('c')")
+
+	schema += `CREATE INDEX idx_items_name ON items (name);`
+	require.NoError(t, sqlt.AutoMigrate(ctx, db, strings.NewReader(schema), false, sqlt.WithAnalyze(), sqlt.WithOptimize()))
+	var stats []string
+	require.NoError(t, db.Select(&stats, "SELECT tbl FROM sqlite_stat1"))
+	assert.Equal(t, []string{"items"}, stats)
+	require.NoError(t, sqlt.VerifyString(ctx, db, schema))
+}
+
+func TestAutoMigrate_RebuildKeepsReferencingRows(t *testing.T) {
+	t.Parallel()
+	db := getTestDB(t)
+	defer db.Close()
+	ctx := gort.Context()
+
+	initial := `
+CREATE TABLE parent (id INTEGER PRIMARY KEY, name TEXT);
+CREATE TABLE child (id INTEGER PRIMARY KEY, parent_id INTEGER REFERENCES parent (id) ON DELETE CASCADE);`
+	require.NoError(t, sqlt.ExecString(ctx, db, initial))
+	db.MustExec("INSERT INTO parent (id, name) VALUES (1, 'p')")
+	db.MustExec("INSERT INTO child (id, parent_id) VALUES (1, 1)")
+
+	// Reordering the columns rebuilds parent.
+	target := `
+CREATE TABLE parent (name TEXT, id INTEGER PRIMARY KEY);
+CREATE TABLE child (id INTEGER PRIMARY KEY, parent_id INTEGER REFERENCES parent (id) ON DELETE CASCADE);`
+	require.NoError(t, sqlt.AutoMigrate(ctx, db, strings.NewReader(target), false))
+	require.NoError(t, sqlt.VerifyString(ctx, db, target))
+
+	var children int
+	require.NoError(t, db.Get(&children, "SELECT COUNT(*) FROM child"))
+	assert.Equal(t, 1, children)
+	var foreignKeys bool
+	require.NoError(t, db.Get(&foreignKeys, "PRAGMA foreign_keys"))
+	assert.True(t, foreignKeys)
+
+	db.MustExec("INSERT INTO child (id, parent_id) VALUES (2, 1)")
+	_, err := db.Exec("INSERT INTO child (id, parent_id) VALUES (3, 42)")
+	assert.ErrorContains(t, err, "FOREIGN KEY constraint failed")
+}
+
+func TestAutoMigrate_Backfill(t *testing.T) {
+	t.Parallel()
+	db := getTestDB(t)
+	defer db.Close()
+	ctx := gort.Context()
+
+	require.NoError(t, sqlt.AutoMigrate(ctx, db, strings.NewReader(`CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT);`), false))
+	db.MustExec("INSERT INTO users (id, name) VALUES (1, 'alice'), (2, 'bob')")
+
+	target := `CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, tier TEXT NOT NULL, label TEXT NOT NULL, note TEXT);`
+	var conflict *sqlt.SchemaConflictError
+	require.ErrorAs(t, sqlt.AutoMigrate(ctx, db, strings.NewReader(target), false), &conflict)
+	require.ErrorAs(t, sqlt.AutoMigrate(ctx, db, strings.NewReader(target), false, sqlt.WithBackfill(map[string]string{"users.tier": "'free'"})), &conflict,
+		"label is NOT NULL without default or backfill")
+
+	require.NoError(t, sqlt.AutoMigrate(ctx, db, strings.NewReader(target), false,
+		sqlt.WithBackfill(map[string]string{"users.tier": "'free'", "Users.Label": "upper(name)"})))
+	require.NoError(t, sqlt.VerifyString(ctx, db, target))
+
+	var rows []struct {
+		Name  string  `db:"name"`
+		Tier  string  `db:"tier"`
+		Label string  `db:"label"`
+		Note  *string `db:"note"`
+	}
+	require.NoError(t, db.Select(&rows, "SELECT name, tier, label, note FROM users ORDER BY id"))
+	require.Len(t, rows, 2)
+	assert.Equal(t, "free", rows[0].Tier)
+	assert.Equal(t, "ALICE", rows[0].Label)
+	assert.Equal(t, "BOB", rows[1].Label)
+	assert.Nil(t, rows[1].Note)
+}
+
+func TestAutoMigrate_Renames(t *testing.T) {
+	t.Parallel()
+	db := getTestDB(t)
+	defer db.Close()
+	ctx := gort.Context()
+
+	initial := `CREATE TABLE users (id INTEGER PRIMARY KEY, fullname TEXT, mail TEXT);
+CREATE INDEX idx_users_fullname ON users (fullname);`
+	require.NoError(t, sqlt.AutoMigrate(ctx, db, strings.NewReader(initial), false))
+	db.MustExec("INSERT INTO users (id, fullname, mail) VALUES (1, 'alice', 'a@example.com')")
+
+	renamed := `CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, mail TEXT);
+CREATE INDEX idx_users_name ON users (name);`
+	var conflict *sqlt.SchemaConflictError
+	require.ErrorAs(t, sqlt.AutoMigrate(ctx, db, strings.NewReader(renamed), false), &conflict)
+
+	var log strings.Builder
+	require.NoError(t, sqlt.AutoMigrate(ctx, db, strings.NewReader(renamed), false,
+		sqlt.WithRenames(map[string]string{"Users.FullName": "name"}), sqlt.WithSQLLog(&log)))
+	assert.Contains(t, log.String(), `ALTER TABLE "users" RENAME COLUMN "fullname" TO "name";`)
+	require.NoError(t, sqlt.VerifyString(ctx, db, renamed))
+	var name string
+	require.NoError(t, db.Get(&name, "SELECT name FROM users WHERE id = 1"))
+	assert.Equal(t, "alice", name)
+
+	rebuilt := `CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT, name TEXT, note TEXT);
+CREATE INDEX idx_users_name ON users (name);`
+	require.NoError(t, sqlt.AutoMigrate(ctx, db, strings.NewReader(rebuilt), false, sqlt.WithRenames(map[string]string{"users.mail": "email"})))
+	require.NoError(t, sqlt.VerifyString(ctx, db, rebuilt))
+	var email string
+	require.NoError(t, db.Get(&email, "SELECT email FROM users WHERE id = 1 AND name = 'alice' AND note IS NULL"))
+	assert.Equal(t, "a@example.com", email)
+}
+
+func TestAutoMigrate_RebuildSelect(t *testing.T) {
+	t.Parallel()
+	db := getTestDB(t)
+	defer db.Close()
+	ctx := gort.Context()
+
+	initial := `
+CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL);
+CREATE INDEX idx_users_name ON users (name);`
+	require.NoError(t, sqlt.AutoMigrate(ctx, db, strings.NewReader(initial), false))
+	db.MustExec("INSERT INTO users (id, name) VALUES (1, 'Ada Lovelace'), (2, 'Alan Turing')")
+
+	target := `
+CREATE TABLE users (id INTEGER PRIMARY KEY, first_name TEXT NOT NULL, last_name TEXT NOT NULL);
+CREATE INDEX idx_users_name ON users (last_name, first_name);`
+	var conflict *sqlt.SchemaConflictError
+	require.ErrorAs(t, sqlt.AutoMigrate(ctx, db, strings.NewReader(target), false), &conflict)
+
+	split := sqlt.WithRebuildSelect(map[string]string{
+		"users": "SELECT id, substr(name, 1, instr(name, ' ') - 1), substr(name, instr(name, ' ') + 1) FROM users;",
+	})
+	require.NoError(t, sqlt.AutoMigrate(ctx, db, strings.NewReader(target), false, split))
+	require.NoError(t, sqlt.VerifyString(ctx, db, target))
+
+	var rows []struct {
+		FirstName string `db:"first_name"`
+		LastName  string `db:"last_name"`
+	}
+	require.NoError(t, db.Select(&rows, "SELECT first_name, last_name FROM users ORDER BY id"))
+	require.Len(t, rows, 2)
+	assert.Equal(t, "Ada", rows[0].FirstName)
+	assert.Equal(t, "Turing", rows[1].LastName)
+
+	// A table matching its schema is not rebuilt again.
+	var log strings.Builder
+	require.NoError(t, sqlt.AutoMigrate(ctx, db, strings.NewReader(target), false, split, sqlt.WithSQLLog(&log)))
+	assert.Empty(t, log.String())
+}
+
+func TestAutoMigrateObjects(t *testing.T) {
+	t.Parallel()
+	db := getTestDB(t)
+	defer db.Close()
+	ctx := gort.Context()
+
+	initial := `
+CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT);
+CREATE TABLE orders (id INTEGER PRIMARY KEY, user_id INTEGER, total REAL);
+CREATE VIEW big_orders AS SELECT * FROM orders WHERE total > 100;`
+	require.NoError(t, sqlt.AutoMigrate(ctx, db, strings.NewReader(initial), false))
+
+	target := `
+CREATE TABLE users (name TEXT, id INTEGER PRIMARY KEY);
+CREATE INDEX idx_users_name ON users (name);
+CREATE TABLE orders (id INTEGER PRIMARY KEY, user_id INTEGER, total REAL);
+CREATE INDEX idx_orders_user ON orders (user_id);
+CREATE VIEW big_orders AS SELECT * FROM orders WHERE total > 1000;`
+	var log strings.Builder
+	require.NoError(t, sqlt.AutoMigrateObjects(ctx, db, strings.NewReader(target), []string{"Big_Orders", "orders"}, sqlt.WithSQLLog(&log)))
+	assert.Contains(t, log.String(), "idx_orders_user")
+	assert.Contains(t, log.String(), "1000")
+	assert.NotContains(t, log.String(), "users")
+
+	err := sqlt.AutoMigrateObjects(ctx, db, strings.NewReader(target), []string{"missing"})
+	assert.ErrorContains(t, err, "missing")
+
+	require.NoError(t, sqlt.AutoMigrateObjects(ctx, db, strings.NewReader(target), []string{"users"}))
+	require.NoError(t, sqlt.VerifyString(ctx, db, target))
+}
+
+func TestAutoMigrate_OnConflict(t *testing.T) {
+	t.Parallel()
+	db := getTestDB(t)
+	defer db.Close()
+	ctx := gort.Context()
+
+	initial := `
+CREATE TABLE a (id INTEGER PRIMARY KEY, name TEXT);
+CREATE TABLE b (id INTEGER PRIMARY KEY, name TEXT);`
+	require.NoError(t, sqlt.AutoMigrate(ctx, db, strings.NewReader(initial), false))
+	db.MustExec("INSERT INTO a (id, name) VALUES (1, 'x')")
+	db.MustExec("INSERT INTO b (id, name) VALUES (1, 'y')")
+
+	target := `
+CREATE TABLE a (id INTEGER PRIMARY KEY, name TEXT, extra TEXT);
+CREATE TABLE b (id INTEGER PRIMARY KEY, name TEXT, extra TEXT);
+CREATE TABLE c (id INTEGER PRIMARY KEY);`
+	var conflicts []string
+	abort := func(c sqlt.SchemaConflictError) sqlt.Resolution {
+		conflicts = append(conflicts, c.ObjectName)
+		return sqlt.ResolutionAbort
+	}
+	var conflict *sqlt.SchemaConflictError
+	require.ErrorAs(t, sqlt.AutoMigrate(ctx, db, strings.NewReader(target), false, sqlt.WithOnConflict(abort)), &conflict)
+	assert.Equal(t, []string{"a", "b"}, conflicts)
+
+	resolve := func(c sqlt.SchemaConflictError) sqlt.Resolution {
+		if c.ObjectName == "a" {
+			return sqlt.ResolutionSkip
+		}
+		return sqlt.ResolutionForceRebuild
+	}
+	require.NoError(t, sqlt.AutoMigrate(ctx, db, strings.NewReader(target), false, sqlt.WithOnConflict(resolve)))
+
+	var columns int
+	require.NoError(t, db.Get(&columns, "SELECT COUNT(*) FROM pragma_table_info('a')"))
+	assert.Equal(t, 2, columns, "a was skipped")
+	var name string
+	require.NoError(t, db.Get(&name, "SELECT name FROM b WHERE id = 1 AND extra IS NULL"))
+	assert.Equal(t, "y", name)
+	require.NoError(t, db.Get(&columns, "SELECT COUNT(*) FROM pragma_table_info('c')"))
+	assert.Equal(t, 1, columns)
+}
+
+func TestAutoMigrate_CollectsConflicts(t *testing.T) {
+	t.Parallel()
+	db := getTestDB(t)
+	defer db.Close()
+	ctx := gort.Context()
+
+	initial := `
+CREATE TABLE a (id INTEGER PRIMARY KEY, name TEXT);
+CREATE TABLE b (id INTEGER PRIMARY KEY, total INTEGER);`
+	require.NoError(t, sqlt.AutoMigrate(ctx, db, strings.NewReader(initial), false))
+
+	target := `
+CREATE TABLE a (id INTEGER PRIMARY KEY, name TEXT, x TEXT, y TEXT);
+CREATE INDEX a_x ON a (x);
+CREATE TABLE b (id INTEGER PRIMARY KEY, total REAL);
+CREATE TABLE c (id INTEGER PRIMARY KEY);`
+	// The index on the conflicting table is not created, as its column is missing.
+	err := sqlt.AutoMigrate(ctx, db, strings.NewReader(target), false)
+	var conflicts sqlt.ErrSchemaConflicts
+	require.ErrorAs(t, err, &conflicts)
+	require.Len(t, conflicts.Conflicts, 2)
+	assert.Equal(t, "a", conflicts.Conflicts[0].ObjectName)
+	assert.Equal(t, "Missing Schema column: 'x'; Missing Schema column: 'y'", conflicts.Conflicts[0].ConflictDetails)
+	assert.Equal(t, "b", conflicts.Conflicts[1].ObjectName)
+	assert.ErrorContains(t, err, "2 schema conflicts: table a: ")
+
+	var conflict *sqlt.SchemaConflictError
+	require.ErrorAs(t, err, &conflict)
+	assert.Equal(t, "a", conflict.ObjectName)
+
+	var tables int
+	require.NoError(t, db.Get(&tables, "SELECT COUNT(*) FROM sqlite_master WHERE name = 'c'"))
+	assert.Zero(t, tables, "nothing is applied when a conflict remains")
+}
+
+func TestAutoMigrate_UniqueConstraintColumnOrder(t *testing.T) {
+	t.Parallel()
+	db := getTestDB(t)
+	defer db.Close()
+	ctx := gort.Context()
+
+	require.NoError(t, sqlt.ExecString(ctx, db, `CREATE TABLE memberships (user_id INTEGER, group_id INTEGER, CONSTRAINT unique_user_group UNIQUE (user_id, group_id));`))
+	target := `CREATE TABLE memberships (user_id INTEGER, group_id INTEGER, UNIQUE (group_id, USER_ID));`
+	var log strings.Builder
+	require.NoError(t, sqlt.AutoMigrate(ctx, db, strings.NewReader(target), false, sqlt.WithSQLLog(&log)))
+	assert.Empty(t, log.String())
+	require.NoError(t, sqlt.VerifyString(ctx, db, target))
+
+	var conflict *sqlt.SchemaConflictError
+	require.ErrorAs(t, sqlt.AutoMigrate(ctx, db, strings.NewReader(`CREATE TABLE memberships (user_id INTEGER, group_id INTEGER, UNIQUE (group_id));`), false), &conflict)
+}
+
+func TestAutoMigrate_IndexSortOrderAndCollation(t *testing.T) {
+	t.Parallel()
+	db := getTestDB(t)
+	defer db.Close()
+	ctx := gort.Context()
+
+	schema := `
+CREATE TABLE events (id INTEGER PRIMARY KEY, name TEXT, created_at INTEGER);
+CREATE INDEX idx_events_created ON events (created_at ASC);`
+	require.NoError(t, sqlt.AutoMigrate(ctx, db, strings.NewReader(schema), false))
+
+	var log strings.Builder
+	same := strings.Replace(schema, "created_at ASC", "created_at", 1)
+	require.NoError(t, sqlt.AutoMigrate(ctx, db, strings.NewReader(same), false, sqlt.WithSQLLog(&log)))
+	assert.Empty(t, log.String())
+
+	for _, target := range []string{
+		strings.Replace(schema, "created_at ASC", "created_at DESC", 1),
+		strings.Replace(schema, "created_at ASC", "created_at COLLATE NOCASE", 1),
+	} {
+		require.ErrorContains(t, sqlt.VerifyString(ctx, db, target), "idx_events_created")
+		require.NoError(t, sqlt.AutoMigrate(ctx, db, strings.NewReader(target), false))
+		require.NoError(t, sqlt.VerifyString(ctx, db, target))
+	}
+}
+
+func TestAutoMigrate_AddsColumns(t *testing.T) {
+	t.Parallel()
+	db := getTestDB(t)
+	defer db.Close()
+	ctx := gort.Context()
+
+	require.NoError(t, sqlt.AutoMigrate(ctx, db, strings.NewReader(`CREATE TABLE a (id INTEGER PRIMARY KEY, name TEXT);`), false))
+	db.MustExec("INSERT INTO a (id, name) VALUES (1, 'x')")
+
+	target := `CREATE TABLE a (id INTEGER PRIMARY KEY, name TEXT, note TEXT, score INTEGER NOT NULL DEFAULT 0);`
+	var log strings.Builder
+	require.NoError(t, sqlt.AutoMigrate(ctx, db, strings.NewReader(target), false, sqlt.WithColumnAdditions(true), sqlt.WithSQLLog(&log)))
+	assert.Equal(t, "ALTER TABLE \"a\" ADD COLUMN \"note\" TEXT;\nALTER TABLE \"a\" ADD COLUMN \"score\" INTEGER NOT NULL DEFAULT 0;\n", log.String())
+	var score int
+	require.NoError(t, db.Get(&score, "SELECT score FROM a WHERE id = 1 AND name = 'x'"))
+	assert.NoError(t, sqlt.VerifyString(ctx, db, target))
+
+	var conflict *sqlt.SchemaConflictError
+	err := sqlt.AutoMigrate(ctx, db, strings.NewReader(`CREATE TABLE a (id INTEGER PRIMARY KEY, name TEXT, note TEXT, score INTEGER NOT NULL DEFAULT 0, code TEXT NOT NULL);`), false, sqlt.WithColumnAdditions(true))
+	assert.ErrorAs(t, err, &conflict, "NOT NULL without default cannot be added")
+}
+
+func TestAutoMigrateWith(t *testing.T) {
+	t.Parallel()
+	db := getTestDB(t)
+	defer db.Close()
+	ctx := gort.Context()
+
+	initial := `CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, legacy TEXT);
+CREATE TABLE old (id INTEGER PRIMARY KEY);`
+	require.NoError(t, sqlt.AutoMigrateWith(ctx, db, strings.NewReader(initial)))
+
+	target := `CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT);`
+	var plan sqlt.MigrationPlan
+	require.NoError(t, sqlt.AutoMigrateWith(ctx, db, strings.NewReader(target), sqlt.WithAllowTableDeletes(), sqlt.WithAllowColumnDrops(), sqlt.WithDryRun(&plan)))
+	assert.Equal(t, []string{`ALTER TABLE "users" DROP COLUMN "legacy"`, `DROP TABLE IF EXISTS "old"`}, plan.Statements())
+	var legacy int
+	require.NoError(t, db.Get(&legacy, "SELECT COUNT(*) FROM pragma_table_info('users') WHERE name = 'legacy'"))
+	assert.Equal(t, 1, legacy, "a dry run must not change the database")
+
+	var notAllowed sqlt.ErrTableDeletionNotAllowed
+	require.ErrorAs(t, sqlt.AutoMigrateWith(ctx, db, strings.NewReader(target), sqlt.WithAllowColumnDrops()), &notAllowed)
+	assert.Equal(t, []string{"old"}, notAllowed.Tables)
+	assert.True(t, objectExists(t, db, "table", "old"))
+	require.NoError(t, db.Get(&legacy, "SELECT COUNT(*) FROM pragma_table_info('users') WHERE name = 'legacy'"))
+	assert.Equal(t, 1, legacy, "the failed migration is rolled back")
+
+	keepOld := target + "\nCREATE TABLE old (id INTEGER PRIMARY KEY);"
+	require.NoError(t, sqlt.AutoMigrateWith(ctx, db, strings.NewReader(keepOld), sqlt.WithAllowColumnDrops()))
+	assert.NoError(t, sqlt.VerifyString(ctx, db, keepOld))
+
+	require.NoError(t, sqlt.AutoMigrateWith(ctx, db, strings.NewReader(target), sqlt.WithAllowTableDeletes()))
+	assert.NoError(t, sqlt.VerifyString(ctx, db, target))
+}
+
+func TestAutoMigrateWith_StatementTimeoutAndLogger(t *testing.T) {
+	t.Parallel()
+	db := getTestDB(t)
+	defer db.Close()
+	ctx := gort.Context()
+
+	schema := `CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT);`
+	err := sqlt.AutoMigrateWith(ctx, db, strings.NewReader(schema), sqlt.WithStatementTimeout(time.Nanosecond))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.False(t, objectExists(t, db, "table", "users"))
+	require.NoError(t, sqlt.AutoMigrateWith(ctx, db, strings.NewReader(schema), sqlt.WithStatementTimeout(time.Minute)))
+
+	var logged []string
+	logf := func(format string, args ...any) { logged = append(logged, fmt.Sprintf(format, args...)) }
+	db.MustExec("CREATE TABLE version (version INTEGER NOT NULL); INSERT INTO version VALUES (1)")
+	versions := sqlt.MigrationMap{
+		1: func(ctx context.Context, db sqlt.DB) error {
+			_, err := db.Exec("UPDATE version SET version = 2")
+			return err
+		},
+		2: sqlt.Requires(sqlt.MigrationRequirement{Drivers: []string{"libsql"}, Skip: true}, nil),
+	}
+	require.NoError(t, sqlt.Migrate(ctx, db, versions, sqlt.WithLogger(logf)))
+	assert.Equal(t, []string{
+		"current database schema version: v1",
+		"migration to database schema v2 complete",
+		"current database schema version: v2",
+		"skipping migration: driver sqlite3 is not libsql",
+		"migration to database schema v3 complete",
+		"current database schema version: v3",
+	}, logged)
+}
@@ -0,0 +1,85 @@
+package sqlt
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// BaselineRecord is the schema of a database at the time it was adopted with Baseline.
+type BaselineRecord struct {
+	Version   int       `db:"version"`
+	CreatedAt time.Time `db:"created_at"`
+	// SchemaHash is the hex SHA-256 of the normalized schema, see SchemaDefinition.Normalize.
+	SchemaHash string `db:"schema_hash"`
+	// Schema is the SQL creating the objects of the database, see SchemaDefinition.SQL.
+	Schema string `db:"schema"`
+}
+
+// Baseline adopts a database that predates sqlt's versioned migrations as version, without executing
+// any migration: it creates the version table holding version, see WithVersionTable, and records the
+// current schema with its hash in the _sqlt_baseline table, so later migrations can be checked against it.
+// It fails if the database already has a version.
+func Baseline(ctx context.Context, db DB, version int, opts ...MigrateOption) error {
+	cfg := newMigrateConfig(opts)
+	return cfg.withLock(ctx, db, func() error {
+		schema, err := FetchDBSchema(ctx, db)
+		if err != nil {
+			return fmt.Errorf("Baseline: %w", err)
+		}
+		dump := schema.SQL()
+		if err := schema.Normalize(); err != nil {
+			return fmt.Errorf("Baseline: %w", err)
+		}
+		hash := sha256.Sum256([]byte(schema.SQL()))
+		return db.Txc(ctx, func(tx Tx) error {
+			var current int
+			err := tx.GetContext(ctx, &current, "SELECT version FROM "+cfg.versionTable+" LIMIT 1")
+			if err == nil {
+				return fmt.Errorf("Baseline: database already has version %d", current)
+			}
+			if !errors.Is(err, sql.ErrNoRows) && !strings.Contains(err.Error(), "no such table") {
+				return fmt.Errorf("Baseline: could not read version: %w", err)
+			}
+			if _, err := tx.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS "+cfg.versionTable+" (version INTEGER NOT NULL)"); err != nil {
+				return fmt.Errorf("Baseline: could not create version table: %w", err)
+			}
+			if _, err := tx.ExecContext(ctx, "INSERT INTO "+cfg.versionTable+" (version) VALUES (?)", version); err != nil {
+				return fmt.Errorf("Baseline: could not set version: %w", err)
+			}
+			_, err = tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS _sqlt_baseline (
+	version INTEGER NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	schema_hash TEXT NOT NULL,
+	schema TEXT NOT NULL
+)`)
+			if err != nil {
+				return fmt.Errorf("Baseline: could not create baseline table: %w", err)
+			}
+			_, err = tx.ExecContext(ctx, "INSERT INTO _sqlt_baseline (version, created_at, schema_hash, schema) VALUES (?, ?, ?, ?)",
+				version, time.Now().UTC(), hex.EncodeToString(hash[:]), dump)
+			if err != nil {
+				return fmt.Errorf("Baseline: could not record schema: %w", err)
+			}
+			return nil
+		})
+	})
+}
+
+// ReadBaseline returns the schema recorded by Baseline, or sql.ErrNoRows if the database was not baselined.
+func ReadBaseline(ctx context.Context, db DB) (*BaselineRecord, error) {
+	var record BaselineRecord
+	err := db.GetContext(ctx, &record, "SELECT version, created_at, schema_hash, schema FROM _sqlt_baseline ORDER BY rowid DESC LIMIT 1")
+	if err != nil {
+		if strings.Contains(err.Error(), "no such table") {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("could not read baseline: %w", err)
+	}
+	return &record, nil
+}
@@ -0,0 +1,120 @@
+package sqlt
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// BatchParamLimit is the maximum number of bound parameters NamedExecBatch puts into one statement.
+// It is SQLite's historical SQLITE_MAX_VARIABLE_NUMBER, so batches work on every SQLite build.
+const BatchParamLimit = 999
+
+// multiRowInsert matches INSERT statements ending in a single VALUES tuple, which sqlx can expand
+// into a multi-row VALUES list.
+var multiRowInsert = regexp.MustCompile(`(?is)^\s*(INSERT|REPLACE)\b.*\bVALUES\s*\([^()]*\)\s*;?\s*$`)
+
+// batchResult sums the results of the statements of a batch.
+type batchResult struct {
+	lastInsertID int64
+	rowsAffected int64
+}
+
+func (r *batchResult) add(res Result) error {
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	r.lastInsertID = id
+	r.rowsAffected += affected
+	return nil
+}
+
+// LastInsertId returns the last insert rowid of the last statement of the batch.
+func (r *batchResult) LastInsertId() (int64, error) {
+	return r.lastInsertID, nil
+}
+
+// RowsAffected returns the rows affected by all statements of the batch.
+func (r *batchResult) RowsAffected() (int64, error) {
+	return r.rowsAffected, nil
+}
+
+// statementBatch buffers statements so they can be sent to the database in a single Exec.
+// See WithStatementBatching.
+type statementBatch struct {
+	stmts []string
+}
+
+func (b *statementBatch) Exec(query string, args ...any) (Result, error) {
+	if len(args) > 0 {
+		return nil, fmt.Errorf("batched statement cannot have arguments: %s", query)
+	}
+	b.stmts = append(b.stmts, strings.TrimRight(strings.TrimSpace(query), ";"))
+	return &batchResult{}, nil
+}
+
+// sql returns the buffered statements as one script.
+func (b *statementBatch) sql() string {
+	return strings.Join(b.stmts, ";\n")
+}
+
+// namedExecBatch implements NamedExecBatch on a transaction. The caller applies the guard.
+//
+// INSERT ... VALUES (...) queries are expanded into multi-row VALUES lists of at most
+// BatchParamLimit parameters. Other queries are prepared once and executed for every element.
+func namedExecBatch(ctx context.Context, tx Tx, query string, slice any) (Result, error) {
+	v := reflect.ValueOf(slice)
+	if v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("NamedExecBatch: expected a slice, got %T", slice)
+	}
+	res := &batchResult{}
+	n := v.Len()
+	if n == 0 {
+		return res, nil
+	}
+	if multiRowInsert.MatchString(query) {
+		_, args, err := tx.BindNamed(query, v.Index(0).Interface())
+		if err != nil {
+			return nil, fmt.Errorf("NamedExecBatch: %w", err)
+		}
+		rows := n
+		if len(args) > 0 {
+			rows = max(1, BatchParamLimit/len(args))
+		}
+		for start := 0; start < n; start += rows {
+			r, err := tx.SQLXTx().NamedExecContext(ctx, query, v.Slice(start, min(n, start+rows)).Interface())
+			if err != nil {
+				return nil, fmt.Errorf("NamedExecBatch: rows %d-%d: %w", start, min(n, start+rows)-1, err)
+			}
+			if err := res.add(r); err != nil {
+				return nil, fmt.Errorf("NamedExecBatch: %w", err)
+			}
+		}
+		return res, nil
+	}
+	stmt, err := tx.SQLXTx().PrepareNamedContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("NamedExecBatch: %w", err)
+	}
+	defer stmt.Close()
+	for i := range n {
+		r, err := stmt.ExecContext(ctx, v.Index(i).Interface())
+		if err != nil {
+			return nil, fmt.Errorf("NamedExecBatch: row %d: %w", i, err)
+		}
+		if err := res.add(r); err != nil {
+			return nil, fmt.Errorf("NamedExecBatch: %w", err)
+		}
+	}
+	return res, nil
+}
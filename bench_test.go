@@ -0,0 +1,136 @@
+package sqlt_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/james-darko/gort"
+	"github.com/james-darko/sqlt"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/require"
+)
+
+// syntheticSchema returns a schema of tables with columns each, an index per table
+// and a view per ten tables. With reorder set, the first two columns of every table are swapped,
+// so migrating to it rebuilds every table.
+func syntheticSchema(tables, columns int, reorder bool) string {
+	var b strings.Builder
+	for i := range tables {
+		cols := make([]string, columns)
+		for j := range cols {
+			typ := "TEXT"
+			if j%3 == 1 {
+				typ = "INTEGER NOT NULL DEFAULT 0"
+			}
+			cols[j] = fmt.Sprintf("c%d %s", j, typ)
+		}
+		if reorder && columns > 1 {
+			cols[0], cols[1] = cols[1], cols[0]
+		}
+		fmt.Fprintf(&b, "CREATE TABLE t%d (id INTEGER PRIMARY KEY, %s);\n", i, strings.Join(cols, ", "))
+		fmt.Fprintf(&b, "CREATE INDEX idx_t%d_c0 ON t%d (c0);\n", i, i)
+		if i%10 == 0 {
+			fmt.Fprintf(&b, "CREATE VIEW v%d AS SELECT id, c0 FROM t%d;\n", i, i)
+		}
+	}
+	return b.String()
+}
+
+// benchmarkDB returns a database migrated to schema.
+func benchmarkDB(b *testing.B, schema string) sqlt.DB {
+	b.Helper()
+	db, err := sqlt.OpenMemory(b.Name())
+	require.NoError(b, err)
+	b.Cleanup(func() { db.Close() })
+	require.NoError(b, sqlt.ExecString(gort.Context(), db, schema))
+	return db
+}
+
+var benchmarkSizes = []struct{ tables, columns int }{{10, 10}, {200, 10}, {500, 20}}
+
+func BenchmarkVerify(b *testing.B) {
+	for _, size := range benchmarkSizes {
+		b.Run(fmt.Sprintf("%dx%d", size.tables, size.columns), func(b *testing.B) {
+			schema := syntheticSchema(size.tables, size.columns, false)
+			db := benchmarkDB(b, schema)
+			ctx := gort.Context()
+			b.ReportAllocs()
+			for b.Loop() {
+				require.NoError(b, sqlt.VerifyString(ctx, db, schema))
+			}
+		})
+	}
+}
+
+func BenchmarkFetchDBSchema(b *testing.B) {
+	for _, size := range benchmarkSizes {
+		b.Run(fmt.Sprintf("%dx%d", size.tables, size.columns), func(b *testing.B) {
+			db := benchmarkDB(b, syntheticSchema(size.tables, size.columns, false))
+			ctx := gort.Context()
+			b.ReportAllocs()
+			for b.Loop() {
+				_, err := sqlt.FetchDBSchema(ctx, db)
+				require.NoError(b, err)
+			}
+		})
+	}
+}
+
+func BenchmarkAutoMigratePlan(b *testing.B) {
+	for _, size := range benchmarkSizes {
+		b.Run(fmt.Sprintf("%dx%d", size.tables, size.columns), func(b *testing.B) {
+			from := syntheticSchema(size.tables, size.columns, false)
+			to := syntheticSchema(size.tables, size.columns, true)
+			b.ReportAllocs()
+			for b.Loop() {
+				_, err := sqlt.GenerateMigrationScript(strings.NewReader(from), strings.NewReader(to))
+				require.NoError(b, err)
+			}
+		})
+	}
+}
+
+func BenchmarkAutoMigrateApply(b *testing.B) {
+	for _, size := range benchmarkSizes {
+		b.Run(fmt.Sprintf("%dx%d", size.tables, size.columns), func(b *testing.B) {
+			schema := syntheticSchema(size.tables, size.columns, false)
+			ctx := gort.Context()
+			b.ReportAllocs()
+			for b.Loop() {
+				b.StopTimer()
+				db, err := sqlt.OpenMemory(b.Name())
+				require.NoError(b, err)
+				b.StartTimer()
+				require.NoError(b, sqlt.AutoMigrate(ctx, db, strings.NewReader(schema), false))
+				b.StopTimer()
+				db.Close()
+				b.StartTimer()
+			}
+		})
+	}
+}
+
+func BenchmarkAutoMigrateRebuild(b *testing.B) {
+	for _, rows := range []int{1_000, 100_000} {
+		b.Run(fmt.Sprintf("%drows", rows), func(b *testing.B) {
+			from := syntheticSchema(1, 10, false)
+			to := syntheticSchema(1, 10, true)
+			ctx := gort.Context()
+			b.ReportAllocs()
+			for b.Loop() {
+				b.StopTimer()
+				db, err := sqlt.OpenMemory(b.Name())
+				require.NoError(b, err)
+				require.NoError(b, sqlt.ExecString(ctx, db, from))
+				db.MustExec(fmt.Sprintf(`WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < %d)
+INSERT INTO t0 (c0, c1, c2) SELECT 'row ' || i, i, hex(randomblob(16)) FROM n`, rows))
+				b.StartTimer()
+				require.NoError(b, sqlt.AutoMigrate(ctx, db, strings.NewReader(to), false))
+				b.StopTimer()
+				db.Close()
+				b.StartTimer()
+			}
+		})
+	}
+}
@@ -0,0 +1,257 @@
+package sqlt
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheStore stores the encoded results of cached queries, see WithCache.
+// Implementations must be safe for concurrent use.
+type CacheStore interface {
+	// Get returns the value stored for key, or false if there is none or it expired.
+	Get(key string) ([]byte, bool)
+	// Set stores value for key until ttl elapses.
+	Set(key string, value []byte, ttl time.Duration)
+	// Delete removes the values stored for keys.
+	Delete(keys ...string)
+}
+
+// OpenOption configures a DB created by Open or Wrap.
+type OpenOption func(*sqlxDB)
+
+// WithCache caches the results of Get, GetContext, Select and SelectContext queries in store
+// for ttl, keyed by the query, its arguments and the type of the destination. Results are encoded
+// as JSON, so destinations must round-trip through encoding/json.
+//
+// Exec and NamedExec statements evict the cached queries reading the tables they write,
+// as found by looking for table names after FROM, JOIN, INTO and UPDATE.
+// Statements whose tables cannot be found, and every transaction, evict the whole cache.
+// A result is not cached if such an eviction happened while the query ran, as it may predate the write.
+// Writes made by other processes are only seen once the cached results expire.
+func WithCache(store CacheStore, ttl time.Duration) OpenOption {
+	return func(db *sqlxDB) {
+		db.cache = &queryCache{store: store, ttl: ttl, keysByTable: make(map[string]map[string]struct{}), generations: make(map[string]uint64)}
+	}
+}
+
+// queryCache tracks which cached keys read which tables, so writes can evict them.
+// Every eviction advances the generation of the table, or the global generation when the
+// whole cache is evicted, so a result fetched while a write evicted its tables is not stored.
+type queryCache struct {
+	store       CacheStore
+	ttl         time.Duration
+	mu          sync.Mutex
+	keysByTable map[string]map[string]struct{}
+	generations map[string]uint64
+	generation  uint64
+}
+
+var queryTables = regexp.MustCompile(`(?i)\b(?:FROM|JOIN|INTO|UPDATE)\s+((?:"[^"]+"|\x60[^\x60]+\x60|\[[^\]]+\]|[\w.]+))`)
+
+// tablesOf returns the folded names of the tables query refers to.
+func tablesOf(query string) []string {
+	var tables []string
+	for _, m := range queryTables.FindAllStringSubmatch(query, -1) {
+		name := strings.Trim(m[1], "\"`[]")
+		if i := strings.LastIndexByte(name, '.'); i >= 0 {
+			name = name[i+1:]
+		}
+		tables = append(tables, foldIdent(name))
+	}
+	return tables
+}
+
+// cacheable reports whether query only reads, so its result can be cached.
+func cacheable(query string) bool {
+	fields := strings.Fields(query)
+	if len(fields) == 0 || hasReturning(query) {
+		return false
+	}
+	first := strings.ToUpper(fields[0])
+	return first == "SELECT" || first == "WITH"
+}
+
+// load fills dest from the cache, or runs query with fetch and caches the result.
+func (c *queryCache) load(dest any, query string, args []any, fetch func() error) error {
+	if !cacheable(query) {
+		defer c.invalidate(query)
+		return fetch()
+	}
+	encodedArgs, err := json.Marshal(args)
+	if err != nil {
+		return fetch()
+	}
+	key := typeKey(reflect.TypeOf(dest)) + "\x00" + query + "\x00" + string(encodedArgs)
+	if value, ok := c.store.Get(key); ok && json.Unmarshal(value, dest) == nil {
+		return nil
+	}
+	tables := tablesOf(query)
+	generation := c.generationOf(tables)
+	if err := fetch(); err != nil {
+		return err
+	}
+	value, err := json.Marshal(dest)
+	if err != nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.generationLocked(tables) != generation {
+		return nil
+	}
+	for _, table := range tables {
+		if c.keysByTable[table] == nil {
+			c.keysByTable[table] = make(map[string]struct{})
+		}
+		c.keysByTable[table][key] = struct{}{}
+	}
+	c.store.Set(key, value, c.ttl)
+	return nil
+}
+
+// generationOf returns the sum of the global generation and those of tables, which changes
+// whenever a write evicts any of them.
+func (c *queryCache) generationOf(tables []string) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.generationLocked(tables)
+}
+
+// generationLocked is generationOf with c.mu held.
+func (c *queryCache) generationLocked(tables []string) uint64 {
+	generation := c.generation
+	for _, table := range tables {
+		generation += c.generations[table]
+	}
+	return generation
+}
+
+// typeKey returns the name of t qualified with the import paths of the named types it is made of,
+// so that destinations of the same name from different packages do not share cached results.
+func typeKey(t reflect.Type) string {
+	if t == nil {
+		return "nil"
+	}
+	if t.Name() != "" && t.PkgPath() != "" {
+		return t.PkgPath() + "." + t.Name()
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return "*" + typeKey(t.Elem())
+	case reflect.Slice:
+		return "[]" + typeKey(t.Elem())
+	case reflect.Array:
+		return fmt.Sprintf("[%d]%s", t.Len(), typeKey(t.Elem()))
+	case reflect.Map:
+		return "map[" + typeKey(t.Key()) + "]" + typeKey(t.Elem())
+	}
+	return t.String()
+}
+
+// invalidate evicts the cached queries reading the tables query writes.
+func (c *queryCache) invalidate(query string) {
+	tables := tablesOf(query)
+	if len(tables) == 0 {
+		c.invalidateAll()
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, table := range tables {
+		c.evict(table)
+	}
+}
+
+// invalidateAll evicts every cached query.
+func (c *queryCache) invalidateAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	for table := range c.keysByTable {
+		c.evict(table)
+	}
+}
+
+// evict deletes the keys of table and advances its generation. c.mu must be held.
+func (c *queryCache) evict(table string) {
+	c.generations[table]++
+	keys := make([]string, 0, len(c.keysByTable[table]))
+	for key := range c.keysByTable[table] {
+		keys = append(keys, key)
+	}
+	if len(keys) > 0 {
+		c.store.Delete(keys...)
+	}
+	delete(c.keysByTable, table)
+}
+
+// cached runs fetch through the query cache if the DB has one.
+func (s *sqlxDB) cached(dest any, query string, args []any, fetch func() error) error {
+	if s.cache == nil {
+		return fetch()
+	}
+	return s.cache.load(dest, query, args, fetch)
+}
+
+// invalidate evicts the cached queries reading the tables query writes, if the DB has a cache.
+func (s *sqlxDB) invalidate(query string) {
+	if s.cache != nil {
+		s.cache.invalidate(query)
+	}
+}
+
+// invalidateAll evicts every cached query, if the DB has a cache.
+func (s *sqlxDB) invalidateAll() {
+	if s.cache != nil {
+		s.cache.invalidateAll()
+	}
+}
+
+// MemoryCache is an in-process CacheStore.
+type MemoryCache struct {
+	mu      sync.Mutex
+	entries map[string]memoryCacheEntry
+}
+
+type memoryCacheEntry struct {
+	value   []byte
+	expires time.Time
+}
+
+// NewMemoryCache returns an empty MemoryCache.
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{entries: make(map[string]memoryCacheEntry)}
+}
+
+func (m *MemoryCache) Get(key string) ([]byte, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry, ok := m.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(entry.expires) {
+		delete(m.entries, key)
+		return nil, false
+	}
+	return entry.value, true
+}
+
+func (m *MemoryCache) Set(key string, value []byte, ttl time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries[key] = memoryCacheEntry{value: value, expires: time.Now().Add(ttl)}
+}
+
+func (m *MemoryCache) Delete(keys ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, key := range keys {
+		delete(m.entries, key)
+	}
+}
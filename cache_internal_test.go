@@ -0,0 +1,42 @@
+package sqlt
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQueryCache_WriteDuringFetch(t *testing.T) {
+	tests := []struct {
+		name  string
+		write string
+	}{
+		{"table", "UPDATE users SET name = 'grace'"},
+		{"whole cache", "PRAGMA optimize"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemoryCache()
+			c := &queryCache{store: store, ttl: time.Minute, keysByTable: make(map[string]map[string]struct{}), generations: make(map[string]uint64)}
+			var name string
+			err := c.load(&name, "SELECT name FROM users", nil, func() error {
+				name = "ada"
+				// The write commits and evicts after the query read the old row.
+				c.invalidate(tt.write)
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(store.entries) != 0 {
+				t.Fatalf("result fetched during a write was cached: %v", store.entries)
+			}
+
+			if err := c.load(&name, "SELECT name FROM users", nil, func() error { name = "grace"; return nil }); err != nil {
+				t.Fatal(err)
+			}
+			if len(store.entries) != 1 {
+				t.Fatalf("expected the result fetched after the write to be cached, got %d entries", len(store.entries))
+			}
+		})
+	}
+}
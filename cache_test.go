@@ -0,0 +1,83 @@
+package sqlt_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/james-darko/gort"
+	"github.com/james-darko/sqlt"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithCache(t *testing.T) {
+	t.Parallel()
+	ctx := gort.Context()
+	plain, err := sqlt.OpenMemory(t.Name())
+	require.NoError(t, err)
+	defer plain.Close()
+	db := sqlt.Wrap(plain.SQLX(), sqlt.WithCache(sqlt.NewMemoryCache(), time.Minute))
+
+	db.MustExec("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)")
+	db.MustExec("CREATE TABLE posts (id INTEGER PRIMARY KEY, title TEXT)")
+	db.MustExec("INSERT INTO users (name) VALUES ('ada')")
+	db.MustExec("INSERT INTO posts (title) VALUES ('first')")
+
+	type user struct {
+		ID   int64  `db:"id"`
+		Name string `db:"name"`
+	}
+	var users []user
+	require.NoError(t, db.SelectContext(ctx, &users, "SELECT id, name FROM users"))
+	require.Len(t, users, 1)
+	var title string
+	require.NoError(t, db.GetContext(ctx, &title, "SELECT title FROM posts WHERE id = ?", 1))
+
+	// Writes bypassing sqlt are not seen while the results are cached.
+	_, err = plain.SQLX().Exec("UPDATE users SET name = 'grace'")
+	require.NoError(t, err)
+	_, err = plain.SQLX().Exec("UPDATE posts SET title = 'changed'")
+	require.NoError(t, err)
+	users = nil
+	require.NoError(t, db.Select(&users, "SELECT id, name FROM users"))
+	assert.Equal(t, "ada", users[0].Name)
+
+	// A write through the cached DB evicts the queries of the tables it writes only.
+	db.MustExec("INSERT INTO users (name) VALUES ('linus')")
+	users = nil
+	require.NoError(t, db.Select(&users, "SELECT id, name FROM users"))
+	assert.Equal(t, []user{{1, "grace"}, {2, "linus"}}, users)
+	require.NoError(t, db.Get(&title, "SELECT title FROM posts WHERE id = ?", 1))
+	assert.Equal(t, "first", title)
+
+	// Transactions evict everything.
+	require.NoError(t, db.Txc(ctx, func(tx sqlt.Tx) error { return nil }))
+	require.NoError(t, db.Get(&title, "SELECT title FROM posts WHERE id = ?", 1))
+	assert.Equal(t, "changed", title)
+}
+
+func TestWithCache_KeyedByDestinationType(t *testing.T) {
+	t.Parallel()
+	ctx := gort.Context()
+	plain, err := sqlt.OpenMemory(t.Name())
+	require.NoError(t, err)
+	defer plain.Close()
+	db := sqlt.Wrap(plain.SQLX(), sqlt.WithCache(sqlt.NewMemoryCache(), time.Minute))
+	db.MustExec("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)")
+	db.MustExec("INSERT INTO users (name) VALUES ('ada')")
+
+	var users []struct {
+		ID   int64  `db:"id"`
+		Name string `db:"name"`
+	}
+	require.NoError(t, db.SelectContext(ctx, &users, "SELECT id, name FROM users"))
+	var labels []struct {
+		Key   int64  `db:"id"`
+		Label string `db:"name"`
+	}
+	require.NoError(t, db.SelectContext(ctx, &labels, "SELECT id, name FROM users"))
+	require.Len(t, labels, 1)
+	assert.Equal(t, int64(1), labels[0].Key)
+	assert.Equal(t, "ada", labels[0].Label)
+}
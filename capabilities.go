@@ -0,0 +1,76 @@
+package sqlt
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Capabilities reports the SQLite features available on a database.
+type Capabilities struct {
+	// SQLiteVersion is the version reported by sqlite_version(), e.g. "3.45.1".
+	SQLiteVersion string
+	// Returning reports support for RETURNING clauses (SQLite 3.35).
+	Returning bool
+	// DropColumn reports support for ALTER TABLE DROP COLUMN (SQLite 3.35).
+	DropColumn bool
+	// RenameColumn reports support for ALTER TABLE RENAME COLUMN (SQLite 3.25).
+	RenameColumn bool
+	// StrictTables reports support for STRICT tables (SQLite 3.37).
+	StrictTables bool
+	// JSON reports whether the JSON functions are available.
+	JSON bool
+	// FTS5 reports whether the FTS5 full-text search extension is compiled in.
+	FTS5 bool
+}
+
+// latestCapabilities is assumed when no database is at hand, e.g. when generating migration scripts.
+var latestCapabilities = &Capabilities{
+	Returning:    true,
+	DropColumn:   true,
+	RenameColumn: true,
+	StrictTables: true,
+	JSON:         true,
+	FTS5:         true,
+}
+
+// DetectCapabilities queries sqlite_version() and the compile options of the database behind r.
+func DetectCapabilities(ctx context.Context, r Reader) (*Capabilities, error) {
+	caps := &Capabilities{}
+	if err := r.GetContext(ctx, &caps.SQLiteVersion, "SELECT sqlite_version()"); err != nil {
+		return nil, fmt.Errorf("could not get SQLite version: %w", err)
+	}
+	version, err := parseSQLiteVersion(caps.SQLiteVersion)
+	if err != nil {
+		return nil, err
+	}
+	caps.Returning = version >= 3_035_000
+	caps.DropColumn = version >= 3_035_000
+	caps.RenameColumn = version >= 3_025_000
+	caps.StrictTables = version >= 3_037_000
+	// JSON is built in since 3.38 unless omitted, so probe it instead of reading compile options.
+	var j string
+	caps.JSON = r.GetContext(ctx, &j, "SELECT json('{}')") == nil
+	if err := r.GetContext(ctx, &caps.FTS5, "SELECT sqlite_compileoption_used('ENABLE_FTS5')"); err != nil {
+		return nil, fmt.Errorf("could not get compile options: %w", err)
+	}
+	return caps, nil
+}
+
+// parseSQLiteVersion returns version as SQLITE_VERSION_NUMBER, e.g. 3045001 for "3.45.1".
+func parseSQLiteVersion(version string) (int, error) {
+	parts := strings.SplitN(version, ".", 3)
+	number := 0
+	for i, scale := range []int{1_000_000, 1_000, 1} {
+		if i >= len(parts) {
+			break
+		}
+		n, err := strconv.Atoi(parts[i])
+		if err != nil {
+			return 0, fmt.Errorf("could not parse SQLite version %q: %w", version, err)
+		}
+		number += n * scale
+	}
+	return number, nil
+}
@@ -0,0 +1,166 @@
+package sqlt
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// cdcAnnotation matches a "-- sqlt:cdc" comment on the line before a CREATE TABLE statement
+// and captures the table name.
+var cdcAnnotation = regexp.MustCompile(`(?im)^[ \t]*--[ \t]*sqlt:cdc[ \t]*\r?\n\s*CREATE\s+TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?("(?:[^"]|"")+"|` + "`[^`]+`" + `|\[[^\]]+\]|[^\s(]+)`)
+
+const cdcTableSQL = `CREATE TABLE IF NOT EXISTS _sqlt_changes (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	changed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	change TEXT NOT NULL
+)`
+
+// GenerateCDCSQL returns the statements that log every change to the tables of schema annotated
+// with a "-- sqlt:cdc" comment on the line before their CREATE TABLE into the _sqlt_changes table,
+// for change data capture on drivers without update hooks:
+//
+//	-- sqlt:cdc
+//	CREATE TABLE orders (id INTEGER PRIMARY KEY, total INTEGER);
+//
+// The script creates _sqlt_changes if needed and replaces the AFTER INSERT, UPDATE and DELETE
+// triggers of each table, so it can be run again after the tables changed. The triggers are
+// internal objects and are ignored by AutoMigrate, but they are dropped with their table when
+// AutoMigrate rebuilds it. Each statement is terminated with a semicolon.
+func GenerateCDCSQL(schema io.Reader) (string, error) {
+	schemaSQL, err := io.ReadAll(schema)
+	if err != nil {
+		return "", fmt.Errorf("could not read schema: %w", err)
+	}
+	def, err := ParseSchemaReader(strings.NewReader(string(schemaSQL)))
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	b.WriteString(cdcTableSQL + ";\n")
+	for _, m := range cdcAnnotation.FindAllStringSubmatch(string(schemaSQL), -1) {
+		table := def.Table(unquoteIdent(m[1]))
+		if table == nil {
+			return "", fmt.Errorf("annotated table %s not found in schema", m[1])
+		}
+		keyCols := table.PrimaryKey
+		var columns []string
+		if len(keyCols) == 0 {
+			keyCols = []string{"rowid"}
+			columns = append(columns, "rowid")
+		}
+		for _, col := range table.Columns {
+			columns = append(columns, col.Name)
+		}
+		for _, kind := range []string{"insert", "update", "delete"} {
+			name := quoteIdent("_sqlt_cdc_" + table.Name + "_" + kind)
+			fmt.Fprintf(&b, "DROP TRIGGER IF EXISTS %s;\n", name)
+			fmt.Fprintf(&b, "CREATE TRIGGER %s AFTER %s ON %s BEGIN INSERT INTO _sqlt_changes (change) VALUES (%s); END;\n",
+				name, strings.ToUpper(kind), quoteIdent(table.Name), changeObject(table.Name, kind, keyCols, columns))
+		}
+	}
+	return b.String(), nil
+}
+
+// unquoteIdent removes the quotes SQLite accepts around an identifier.
+func unquoteIdent(name string) string {
+	if len(name) < 2 {
+		return name
+	}
+	switch {
+	case name[0] == '"' && name[len(name)-1] == '"':
+		return strings.ReplaceAll(name[1:len(name)-1], `""`, `"`)
+	case name[0] == '`' && name[len(name)-1] == '`', name[0] == '[' && name[len(name)-1] == ']':
+		return name[1 : len(name)-1]
+	}
+	return name
+}
+
+// EnableCDC runs the script of GenerateCDCSQL for schema on db in a transaction.
+func EnableCDC(ctx context.Context, db DB, schema io.Reader) error {
+	script, err := GenerateCDCSQL(schema)
+	if err != nil {
+		return err
+	}
+	return db.Txc(ctx, func(tx Tx) error {
+		if _, err := tx.ExecContext(ctx, script); err != nil {
+			return fmt.Errorf("could not create change data capture triggers: %w", err)
+		}
+		return nil
+	})
+}
+
+// ChangeRecord is a row of the _sqlt_changes table.
+type ChangeRecord struct {
+	// ID increases with every change and is never reused, even after pruning.
+	ID        int64
+	ChangedAt time.Time
+	Change
+}
+
+// ReadChanges returns up to limit logged changes with an ID above afterID, oldest first.
+// See GenerateCDCSQL for logging changes.
+func ReadChanges(ctx context.Context, r Reader, afterID int64, limit int) ([]ChangeRecord, error) {
+	var rows []struct {
+		ID        int64     `db:"id"`
+		ChangedAt time.Time `db:"changed_at"`
+		Change    string    `db:"change"`
+	}
+	err := r.SelectContext(ctx, &rows, "SELECT id, changed_at, change FROM _sqlt_changes WHERE id > ? ORDER BY id LIMIT ?", afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("could not read changes: %w", err)
+	}
+	records := make([]ChangeRecord, len(rows))
+	for i, row := range rows {
+		records[i] = ChangeRecord{ID: row.ID, ChangedAt: row.ChangedAt}
+		if err := decodeChangeJSON([]byte(row.Change), &records[i].Change); err != nil {
+			return nil, fmt.Errorf("could not decode change %d: %w", row.ID, err)
+		}
+		if err := records[i].decodeValues(); err != nil {
+			return nil, fmt.Errorf("change %d: %w", row.ID, err)
+		}
+	}
+	return records, nil
+}
+
+// StreamChanges calls fn with every logged change with an ID above afterID in order, then keeps
+// polling for new changes every WatchInterval until ctx is done or fn returns an error.
+// Consumers should persist the ID of the last change they handled and resume from it.
+func StreamChanges(ctx context.Context, db DB, afterID int64, fn func(ChangeRecord) error) error {
+	const batchSize = 100
+	ticker := time.NewTicker(WatchInterval)
+	defer ticker.Stop()
+	for {
+		records, err := ReadChanges(ctx, db, afterID, batchSize)
+		if err != nil {
+			return err
+		}
+		for _, record := range records {
+			if err := fn(record); err != nil {
+				return err
+			}
+			afterID = record.ID
+		}
+		if len(records) == batchSize {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// PruneChanges deletes the logged changes up to and including throughID, once every consumer has
+// handled them, and returns the number of deleted changes.
+func PruneChanges(ctx context.Context, db Handle, throughID int64) (int, error) {
+	n, err := db.AffectedExecContext(ctx, "DELETE FROM _sqlt_changes WHERE id <= ?", throughID)
+	if err != nil {
+		return 0, fmt.Errorf("could not prune changes: %w", err)
+	}
+	return n, nil
+}
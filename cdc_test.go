@@ -0,0 +1,77 @@
+package sqlt_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/james-darko/gort"
+	"github.com/james-darko/sqlt"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const cdcSchema = `
+-- sqlt:cdc
+CREATE TABLE orders (id INTEGER PRIMARY KEY, total INTEGER);
+CREATE TABLE notes (body TEXT);`
+
+func TestGenerateCDCSQL(t *testing.T) {
+	t.Parallel()
+	script, err := sqlt.GenerateCDCSQL(strings.NewReader(cdcSchema))
+	require.NoError(t, err)
+	assert.Contains(t, script, `CREATE TRIGGER "_sqlt_cdc_orders_insert" AFTER INSERT ON "orders"`)
+	assert.NotContains(t, script, "notes", "only annotated tables are logged")
+}
+
+func TestCDC(t *testing.T) {
+	t.Parallel()
+	db := getTestDB(t)
+	defer db.Close()
+	ctx := gort.Context()
+
+	require.NoError(t, sqlt.ExecString(ctx, db, cdcSchema))
+	require.NoError(t, sqlt.EnableCDC(ctx, db, strings.NewReader(cdcSchema)))
+	// Enabling again replaces the triggers instead of logging changes twice.
+	require.NoError(t, sqlt.EnableCDC(ctx, db, strings.NewReader(cdcSchema)))
+	db.MustExec("INSERT INTO orders (id, total) VALUES (1, 10)")
+	db.MustExec("UPDATE orders SET total = 20 WHERE id = 1")
+	db.MustExec("INSERT INTO notes (body) VALUES ('ignored')")
+	db.MustExec("DELETE FROM orders WHERE id = 1")
+
+	records, err := sqlt.ReadChanges(ctx, db, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, "insert", records[0].Kind)
+	assert.Equal(t, int64(10), records[0].Row["total"])
+	assert.Equal(t, "update", records[1].Kind)
+	assert.Equal(t, map[string]any{"id": int64(1)}, records[1].Key)
+	assert.Equal(t, "delete", records[2].Kind)
+	assert.False(t, records[2].ChangedAt.IsZero())
+
+	var streamed []int64
+	errStop := errors.New("stop")
+	err = sqlt.StreamChanges(ctx, db, records[0].ID, func(r sqlt.ChangeRecord) error {
+		streamed = append(streamed, r.ID)
+		if len(streamed) == 2 {
+			return errStop
+		}
+		return nil
+	})
+	assert.ErrorIs(t, err, errStop)
+	assert.Equal(t, []int64{records[1].ID, records[2].ID}, streamed)
+
+	n, err := sqlt.PruneChanges(ctx, db, records[1].ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	records, err = sqlt.ReadChanges(ctx, db, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, "delete", records[0].Kind)
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.ErrorIs(t, sqlt.StreamChanges(cancelled, db, records[0].ID, func(sqlt.ChangeRecord) error { return nil }), context.Canceled)
+}
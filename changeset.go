@@ -0,0 +1,267 @@
+package sqlt
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Change is a row written while changes were captured.
+type Change struct {
+	Table string `json:"table"`
+	// Kind is "insert", "update" or "delete".
+	Kind string `json:"kind"`
+	// Key holds the primary key columns of the row before the change, or rowid if the table has no
+	// primary key. It is not set for inserts.
+	Key map[string]any `json:"key,omitempty"`
+	// Row holds all columns of the row after the change. It is not set for deletes.
+	Row map[string]any `json:"row,omitempty"`
+}
+
+// Changeset is an encoded list of changes produced by ChangeCapture.Changeset.
+// It can be stored or sent elsewhere and applied to another database with Apply.
+type Changeset []byte
+
+// ChangeCapture records the changes made to a set of tables in a transaction, in the manner of
+// the SQLite session extension. It is started with Tx.CaptureChanges.
+type ChangeCapture struct {
+	tx       *txWrapper
+	triggers []string
+}
+
+// captureTable holds the captured changes. Triggers cannot refer to it with its schema name,
+// but a temp table shadows any main table of the same name.
+const captureTable = "_sqlt_capture"
+
+// CaptureChanges records every insert, update and delete on tables until the Changeset method of the
+// returned capture is called. If tables is empty, all tables are captured.
+//
+// Changes are recorded by temporary triggers into a temporary table, so they only see the writes made
+// in this transaction and work on drivers without the session extension, such as libsql.
+// A transaction captures one set of tables at a time. Virtual tables cannot be captured.
+func (tx *txWrapper) CaptureChanges(tables []string) (*ChangeCapture, error) {
+	if len(tables) == 0 {
+		rows, err := masterRows(context.Background(), tx)
+		if err != nil {
+			return nil, fmt.Errorf("could not list tables: %w", err)
+		}
+		for _, row := range rows {
+			if row.Type == "table" && !isVirtualTableSQL(row.Sql) {
+				tables = append(tables, row.Name)
+			}
+		}
+	}
+	if _, err := tx.tx.Exec(fmt.Sprintf("CREATE TEMP TABLE temp.%s (seq INTEGER PRIMARY KEY, change TEXT NOT NULL)", captureTable)); err != nil {
+		return nil, fmt.Errorf("could not start change capture: %w", err)
+	}
+	c := &ChangeCapture{tx: tx}
+	for _, table := range tables {
+		if err := c.addTriggers(table); err != nil {
+			c.stop()
+			return nil, fmt.Errorf("table %s: %w", table, err)
+		}
+	}
+	return c, nil
+}
+
+func (c *ChangeCapture) addTriggers(table string) error {
+	var columns []string
+	if err := c.tx.tx.Select(&columns, "SELECT name FROM pragma_table_info(?) ORDER BY cid", table); err != nil {
+		return fmt.Errorf("could not get columns: %w", err)
+	}
+	if len(columns) == 0 {
+		return fmt.Errorf("table does not exist")
+	}
+	var keyCols []string
+	if err := c.tx.tx.Select(&keyCols, "SELECT name FROM pragma_table_info(?) WHERE pk > 0 ORDER BY pk", table); err != nil {
+		return fmt.Errorf("could not get primary key: %w", err)
+	}
+	if len(keyCols) == 0 {
+		// The rowid is part of the row too, so later changes to it find it in the other database.
+		keyCols = []string{"rowid"}
+		columns = append(keyCols, columns...)
+	}
+	for _, kind := range []string{"insert", "update", "delete"} {
+		name := quoteIdent("_sqlt_capture_" + table + "_" + kind)
+		_, err := c.tx.tx.Exec(fmt.Sprintf("CREATE TEMP TRIGGER %s AFTER %s ON main.%s BEGIN INSERT INTO %s (change) VALUES (%s); END",
+			name, strings.ToUpper(kind), quoteIdent(table), captureTable, changeObject(table, kind, keyCols, columns)))
+		if err != nil {
+			return fmt.Errorf("could not create %s trigger: %w", kind, err)
+		}
+		c.triggers = append(c.triggers, name)
+	}
+	return nil
+}
+
+// changeObject returns the json_object expression a trigger uses to encode a Change of the given kind.
+// keyCols must be rowid if the table has no primary key, in which case columns must start with it.
+func changeObject(table, kind string, keyCols, columns []string) string {
+	fields := []string{"'table'", quoteString(table), "'kind'", quoteString(kind)}
+	if kind != "insert" {
+		fields = append(fields, "'key'", captureObject("OLD", keyCols))
+	}
+	if kind != "delete" {
+		fields = append(fields, "'row'", captureObject("NEW", columns))
+	}
+	return "json_object(" + strings.Join(fields, ", ") + ")"
+}
+
+// captureObject returns a json_object expression of the given columns of the OLD or NEW row.
+// JSON cannot hold blobs, so they are stored as {"blob": "<hex>"}.
+func captureObject(row string, columns []string) string {
+	fields := make([]string, 0, 2*len(columns))
+	for _, col := range columns {
+		ref := row + "." + quoteIdent(col)
+		fields = append(fields, quoteString(col), fmt.Sprintf("CASE typeof(%s) WHEN 'blob' THEN json_object('blob', hex(%s)) ELSE %s END", ref, ref, ref))
+	}
+	return "json_object(" + strings.Join(fields, ", ") + ")"
+}
+
+func quoteString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// Changeset stops the capture and returns the changes recorded since it started, in the order they were made.
+func (c *ChangeCapture) Changeset() (Changeset, error) {
+	var changes []string
+	err := c.tx.tx.Select(&changes, fmt.Sprintf("SELECT change FROM %s ORDER BY seq", captureTable))
+	if stopErr := c.stop(); err == nil {
+		err = stopErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read captured changes: %w", err)
+	}
+	return Changeset("[" + strings.Join(changes, ",") + "]"), nil
+}
+
+// stop drops the capture triggers and table.
+func (c *ChangeCapture) stop() error {
+	for _, name := range c.triggers {
+		if _, err := c.tx.tx.Exec("DROP TRIGGER temp." + name); err != nil {
+			return err
+		}
+	}
+	c.triggers = nil
+	_, err := c.tx.tx.Exec("DROP TABLE IF EXISTS temp." + captureTable)
+	return err
+}
+
+// Changes decodes the changeset.
+func (cs Changeset) Changes() ([]Change, error) {
+	var changes []Change
+	if err := decodeChangeJSON(cs, &changes); err != nil {
+		return nil, fmt.Errorf("could not decode changeset: %w", err)
+	}
+	for _, change := range changes {
+		if err := change.decodeValues(); err != nil {
+			return nil, err
+		}
+	}
+	return changes, nil
+}
+
+// decodeChangeJSON decodes changes encoded by changeObject, keeping integers exact.
+func decodeChangeJSON(data []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+func (c Change) decodeValues() error {
+	if err := decodeChangeValues(c.Key); err != nil {
+		return err
+	}
+	return decodeChangeValues(c.Row)
+}
+
+// decodeChangeValues turns the JSON values of a captured row back into the values SQLite stored.
+func decodeChangeValues(row map[string]any) error {
+	for col, v := range row {
+		switch v := v.(type) {
+		case json.Number:
+			if i, err := v.Int64(); err == nil {
+				row[col] = i
+			} else if f, err := v.Float64(); err == nil {
+				row[col] = f
+			} else {
+				return fmt.Errorf("column %s: invalid number %s", col, v)
+			}
+		case map[string]any:
+			s, _ := v["blob"].(string)
+			b, err := hex.DecodeString(s)
+			if err != nil {
+				return fmt.Errorf("column %s: invalid blob: %w", col, err)
+			}
+			row[col] = b
+		}
+	}
+	return nil
+}
+
+// Apply replays the changeset on h. Inserts replace existing rows with the same key, so a changeset
+// can be applied again after a partial failure. Call it in a transaction to apply all changes or none.
+func (cs Changeset) Apply(ctx context.Context, h Handle) error {
+	changes, err := cs.Changes()
+	if err != nil {
+		return err
+	}
+	for i, change := range changes {
+		query, args := changeStatement(change)
+		if query == "" {
+			return fmt.Errorf("change %d: unknown kind %q", i, change.Kind)
+		}
+		if _, err := h.ExecContext(ctx, query, args...); err != nil {
+			return fmt.Errorf("change %d on table %s: %w", i, change.Table, err)
+		}
+	}
+	return nil
+}
+
+// changeStatement returns the statement applying change, or an empty query if its kind is unknown.
+func changeStatement(change Change) (string, []any) {
+	table := quoteIdent(change.Table)
+	var args []any
+	var where []string
+	for _, col := range sortedKeys(change.Key) {
+		where = append(where, quoteIdent(col)+" IS ?")
+	}
+	switch change.Kind {
+	case "insert":
+		var cols, params []string
+		for _, col := range sortedKeys(change.Row) {
+			cols = append(cols, quoteIdent(col))
+			params = append(params, "?")
+			args = append(args, change.Row[col])
+		}
+		return fmt.Sprintf("INSERT OR REPLACE INTO %s (%s) VALUES (%s)", table, strings.Join(cols, ", "), strings.Join(params, ", ")), args
+	case "update":
+		var set []string
+		for _, col := range sortedKeys(change.Row) {
+			set = append(set, quoteIdent(col)+" = ?")
+			args = append(args, change.Row[col])
+		}
+		for _, col := range sortedKeys(change.Key) {
+			args = append(args, change.Key[col])
+		}
+		return fmt.Sprintf("UPDATE %s SET %s WHERE %s", table, strings.Join(set, ", "), strings.Join(where, " AND ")), args
+	case "delete":
+		for _, col := range sortedKeys(change.Key) {
+			args = append(args, change.Key[col])
+		}
+		return fmt.Sprintf("DELETE FROM %s WHERE %s", table, strings.Join(where, " AND ")), args
+	}
+	return "", nil
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,64 @@
+package sqlt_test
+
+import (
+	"testing"
+
+	"github.com/james-darko/gort"
+	"github.com/james-darko/sqlt"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCaptureChanges(t *testing.T) {
+	t.Parallel()
+	ctx := gort.Context()
+	schema := `
+CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, avatar BLOB);
+CREATE TABLE tags (label TEXT);`
+	local := getTestDB(t)
+	defer local.Close()
+	remote, err := sqlt.OpenMemory(t.Name() + "-remote")
+	require.NoError(t, err)
+	defer remote.Close()
+	require.NoError(t, sqlt.ExecString(ctx, local, schema))
+	require.NoError(t, sqlt.ExecString(ctx, remote, schema))
+	local.MustExec("INSERT INTO users (id, name) VALUES (1, 'ann'), (2, 'bob')")
+	remote.MustExec("INSERT INTO users (id, name) VALUES (1, 'ann'), (2, 'bob')")
+
+	var changeset sqlt.Changeset
+	require.NoError(t, local.Tx(func(tx sqlt.Tx) error {
+		capture, err := tx.CaptureChanges(nil)
+		if err != nil {
+			return err
+		}
+		tx.MustExec("INSERT INTO users (id, name, avatar) VALUES (3, 'cid', x'00ff')")
+		tx.MustExec("UPDATE users SET name = 'anne' WHERE id = 1")
+		tx.MustExec("DELETE FROM users WHERE id = 2")
+		tx.MustExec("INSERT INTO tags (rowid, label) VALUES (7, 'new')")
+		changeset, err = capture.Changeset()
+		if err != nil {
+			return err
+		}
+		var temps int
+		tx.MustGet(&temps, "SELECT COUNT(*) FROM sqlite_temp_master")
+		assert.Zero(t, temps, "the capture must clean up its temporary objects")
+		return nil
+	}))
+
+	changes, err := changeset.Changes()
+	require.NoError(t, err)
+	require.Len(t, changes, 4)
+	assert.Equal(t, "insert", changes[0].Kind)
+	assert.Equal(t, []byte{0x00, 0xff}, changes[0].Row["avatar"])
+	assert.Equal(t, map[string]any{"id": int64(1)}, changes[1].Key)
+	assert.Equal(t, "delete", changes[2].Kind)
+	assert.Equal(t, int64(7), changes[3].Row["rowid"])
+
+	require.NoError(t, remote.Tx(func(tx sqlt.Tx) error {
+		return changeset.Apply(ctx, tx)
+	}))
+	diffs, err := sqlt.DiffData(ctx, local, remote, nil)
+	require.NoError(t, err)
+	assert.Empty(t, diffs)
+}
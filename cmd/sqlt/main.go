@@ -0,0 +1,57 @@
+// Command sqlt works with the database configured by the DATABASE_* environment variables,
+// see sqlt.LoadDB. It reaches libsql remotes as well as local SQLite files.
+//
+// Usage:
+//
+//	sqlt shell    start an interactive SQL shell
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/james-darko/sqlt"
+	_ "github.com/mattn/go-sqlite3"
+	_ "github.com/tursodatabase/libsql-client-go/libsql"
+)
+
+const usage = `usage: sqlt <command>
+
+commands:
+  shell    start an interactive SQL shell
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	var err error
+	switch os.Args[1] {
+	case "shell":
+		err = runShell(ctx, os.Args[2:])
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "sqlt: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// loadDB opens the database configured in the environment.
+func loadDB() (sqlt.DB, error) {
+	cfg, err := sqlt.ConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range cfg.Unknown {
+		fmt.Fprintf(os.Stderr, "warning: unknown variable %s\n", name)
+	}
+	return sqlt.LoadDB()
+}
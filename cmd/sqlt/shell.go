@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/james-darko/sqlt"
+)
+
+const shellHelp = `Statements end with a semicolon. Commands:
+  .tables               list the tables
+  .schema               describe all objects with their SQL
+  .mode table|json|csv  set the output format
+  .history              list the previous statements
+  .help                 show this help
+  .quit                 exit
+`
+
+// shell is a minimal REPL executing statements and dot commands against a database.
+type shell struct {
+	db      sqlt.DB
+	out     io.Writer
+	mode    string
+	history []string
+	// historyFile keeps the history across sessions, if set.
+	historyFile string
+}
+
+func runShell(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("shell", flag.ContinueOnError)
+	mode := flags.String("mode", "table", "output format: table, json or csv")
+	historyFile := flags.String("history", defaultHistoryFile(), "file to keep the statement history in, empty to disable")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	db, err := loadDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	sh := &shell{db: db, out: os.Stdout, historyFile: *historyFile}
+	if err := sh.setMode(*mode); err != nil {
+		return err
+	}
+	sh.loadHistory()
+	return sh.run(ctx, os.Stdin, true)
+}
+
+func defaultHistoryFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".sqlt_history")
+}
+
+// run reads statements and commands from in until it is exhausted or .quit is entered.
+// Errors of single statements are printed and do not stop the shell.
+func (sh *shell) run(ctx context.Context, in io.Reader, prompt bool) error {
+	scanner := bufio.NewScanner(in)
+	var stmt strings.Builder
+	for {
+		if prompt {
+			if stmt.Len() == 0 {
+				fmt.Fprint(sh.out, "sqlt> ")
+			} else {
+				fmt.Fprint(sh.out, "   ...> ")
+			}
+		}
+		if !scanner.Scan() {
+			return scanner.Err()
+		}
+		line := scanner.Text()
+		if stmt.Len() == 0 && strings.HasPrefix(strings.TrimSpace(line), ".") {
+			quit, err := sh.command(ctx, strings.Fields(line))
+			if err != nil {
+				fmt.Fprintf(sh.out, "error: %v\n", err)
+			}
+			if quit {
+				return nil
+			}
+			continue
+		}
+		stmt.WriteString(line)
+		stmt.WriteString("\n")
+		if !strings.HasSuffix(strings.TrimSpace(line), ";") {
+			continue
+		}
+		query := strings.TrimSpace(stmt.String())
+		stmt.Reset()
+		sh.addHistory(query)
+		if err := sh.query(ctx, query); err != nil {
+			fmt.Fprintf(sh.out, "error: %v\n", err)
+		}
+	}
+}
+
+func (sh *shell) command(ctx context.Context, fields []string) (quit bool, err error) {
+	switch fields[0] {
+	case ".quit", ".exit":
+		return true, nil
+	case ".help":
+		fmt.Fprint(sh.out, shellHelp)
+	case ".tables":
+		tables, err := sh.db.Tables(ctx)
+		if err != nil {
+			return false, err
+		}
+		for _, table := range tables {
+			fmt.Fprintln(sh.out, table.Name)
+		}
+	case ".schema":
+		return false, sqlt.DescribeSchema(ctx, sh.db, sh.out, sqlt.WithoutRowCounts(), sqlt.WithObjectSQL())
+	case ".mode":
+		if len(fields) != 2 {
+			return false, fmt.Errorf("usage: .mode table|json|csv")
+		}
+		return false, sh.setMode(fields[1])
+	case ".history":
+		for i, query := range sh.history {
+			fmt.Fprintf(sh.out, "%4d  %s\n", i+1, query)
+		}
+	default:
+		return false, fmt.Errorf("unknown command %s, see .help", fields[0])
+	}
+	return false, nil
+}
+
+func (sh *shell) setMode(mode string) error {
+	switch mode {
+	case "table", "json", "csv":
+		sh.mode = mode
+		return nil
+	}
+	return fmt.Errorf("unknown mode %q, use table, json or csv", mode)
+}
+
+func (sh *shell) loadHistory() {
+	if sh.historyFile == "" {
+		return
+	}
+	data, err := os.ReadFile(sh.historyFile)
+	if err != nil {
+		return
+	}
+	var history []string
+	if json.Unmarshal(data, &history) == nil {
+		sh.history = history
+	}
+}
+
+// addHistory records query and rewrites the history file. Failures to write it are ignored,
+// the history is a convenience.
+func (sh *shell) addHistory(query string) {
+	sh.history = append(sh.history, query)
+	if sh.historyFile == "" {
+		return
+	}
+	if data, err := json.Marshal(sh.history); err == nil {
+		_ = os.WriteFile(sh.historyFile, data, 0o600)
+	}
+}
+
+// query runs query and prints the rows it returns, if any, in the current mode.
+func (sh *shell) query(ctx context.Context, query string) error {
+	rows, err := sh.db.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	var records [][]any
+	for rows.Next() {
+		values, err := rows.SliceScan()
+		if err != nil {
+			return err
+		}
+		for i, v := range values {
+			if b, ok := v.([]byte); ok {
+				values[i] = string(b)
+			}
+		}
+		records = append(records, values)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(columns) == 0 {
+		return nil
+	}
+	switch sh.mode {
+	case "json":
+		objects := make([]map[string]any, len(records))
+		for i, record := range records {
+			objects[i] = make(map[string]any, len(columns))
+			for j, col := range columns {
+				objects[i][col] = record[j]
+			}
+		}
+		enc := json.NewEncoder(sh.out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(objects)
+	case "csv":
+		w := csv.NewWriter(sh.out)
+		if err := w.Write(columns); err != nil {
+			return err
+		}
+		for _, record := range records {
+			if err := w.Write(formatValues(record, "")); err != nil {
+				return err
+			}
+		}
+		w.Flush()
+		return w.Error()
+	default:
+		w := tabwriter.NewWriter(sh.out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, strings.Join(columns, "\t"))
+		for _, record := range records {
+			fmt.Fprintln(w, strings.Join(formatValues(record, "NULL"), "\t"))
+		}
+		return w.Flush()
+	}
+}
+
+// formatValues formats the values of a row, writing NULL values as null.
+func formatValues(values []any, null string) []string {
+	formatted := make([]string, len(values))
+	for i, v := range values {
+		if v == nil {
+			formatted[i] = null
+		} else {
+			formatted[i] = fmt.Sprint(v)
+		}
+	}
+	return formatted
+}
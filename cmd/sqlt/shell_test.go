@@ -0,0 +1,56 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/james-darko/gort"
+	"github.com/james-darko/sqlt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShell(t *testing.T) {
+	t.Parallel()
+	db, err := sqlt.OpenMemory(t.Name())
+	require.NoError(t, err)
+	defer db.Close()
+	var out strings.Builder
+	sh := &shell{db: db, out: &out, mode: "table", historyFile: filepath.Join(t.TempDir(), "history")}
+
+	input := `CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT);
+INSERT INTO users (name)
+VALUES ('ann'), (NULL);
+.tables
+SELECT * FROM users;
+.mode csv
+SELECT * FROM users;
+.mode json
+SELECT name FROM users WHERE id = 1;
+SELECT * FROM missing;
+.nope
+.quit
+SELECT 'not reached';`
+	require.NoError(t, sh.run(gort.Context(), strings.NewReader(input), false))
+	assert.Equal(t, `users
+id  name
+1   ann
+2   NULL
+id,name
+1,ann
+2,
+[
+  {
+    "name": "ann"
+  }
+]
+error: no such table: missing
+error: unknown command .nope, see .help
+`, out.String())
+
+	reloaded := &shell{historyFile: sh.historyFile}
+	reloaded.loadHistory()
+	require.Len(t, reloaded.history, 6)
+	assert.Equal(t, "INSERT INTO users (name)\nVALUES ('ann'), (NULL);", reloaded.history[1])
+}
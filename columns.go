@@ -0,0 +1,58 @@
+package sqlt
+
+import (
+	"database/sql"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx/reflectx"
+)
+
+// AliasedColumns returns the select list of the columns of dest's struct type read from the table
+// or alias table, each aliased with prefix so a JOIN query scans them into a nested struct field
+// with the db name prefix, e.g. "u"."id" AS "user.id", "u"."name" AS "user.name" for table u and prefix user.
+// Column names come from the db tags of dest, or the default mapper, including embedded structs.
+// An empty prefix leaves the columns unaliased.
+func AliasedColumns(table, prefix string, dest any) string {
+	t := reflectx.Deref(reflect.TypeOf(dest))
+	var columns []string
+	for _, field := range scannedFields(structMapper().TypeMap(t).Tree.Children) {
+		column := quoteIdent(table) + "." + quoteIdent(field.Name)
+		if prefix != "" {
+			column += " AS " + quoteIdent(prefix+"."+field.Name)
+		}
+		columns = append(columns, column)
+	}
+	return strings.Join(columns, ", ")
+}
+
+// structMapper returns a mapper of struct fields to columns using the db tag and the default mapper.
+func structMapper() *reflectx.Mapper {
+	return reflectx.NewMapperFunc("db", *defaultMapper.Load())
+}
+
+var (
+	scannerType = reflect.TypeFor[sql.Scanner]()
+	timeType    = reflect.TypeFor[time.Time]()
+)
+
+// scannedFields returns the fields scanned from a column, in declaration order.
+// Embedded structs contribute their fields, other struct fields are skipped unless they scan
+// from a single column.
+func scannedFields(fields []*reflectx.FieldInfo) []*reflectx.FieldInfo {
+	var scanned []*reflectx.FieldInfo
+	for _, field := range fields {
+		if field == nil {
+			continue
+		}
+		t := reflectx.Deref(field.Field.Type)
+		switch {
+		case field.Embedded:
+			scanned = append(scanned, scannedFields(field.Children)...)
+		case t.Kind() != reflect.Struct || t == timeType || reflect.PointerTo(t).Implements(scannerType):
+			scanned = append(scanned, field)
+		}
+	}
+	return scanned
+}
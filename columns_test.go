@@ -0,0 +1,47 @@
+package sqlt_test
+
+import (
+	"testing"
+
+	"github.com/james-darko/sqlt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type joinUser struct {
+	ID   int    `db:"id"`
+	Name string `db:"name"`
+}
+
+type joinOrder struct {
+	ID    int `db:"id"`
+	Total int `db:"total"`
+}
+
+func TestAliasedColumns(t *testing.T) {
+	t.Parallel()
+	db, err := sqlt.OpenMemory(t.Name())
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec(`CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT);
+CREATE TABLE orders (id INTEGER PRIMARY KEY, user_id INTEGER, total INTEGER);
+INSERT INTO users (id, name) VALUES (1, 'alice');
+INSERT INTO orders (id, user_id, total) VALUES (10, 1, 5), (11, 1, 7);`)
+	require.NoError(t, err)
+
+	assert.Equal(t, `"u"."id" AS "user.id", "u"."name" AS "user.name"`, sqlt.AliasedColumns("u", "user", joinUser{}))
+	assert.Equal(t, `"o"."id", "o"."total"`, sqlt.AliasedColumns("o", "", &joinOrder{}))
+
+	var rows []struct {
+		User  joinUser  `db:"user"`
+		Order joinOrder `db:"order"`
+	}
+	query := "SELECT " + sqlt.AliasedColumns("u", "user", joinUser{}) + ", " + sqlt.AliasedColumns("o", "order", joinOrder{}) +
+		" FROM users u JOIN orders o ON o.user_id = u.id ORDER BY o.id"
+	require.NoError(t, db.Select(&rows, query))
+	require.Len(t, rows, 2)
+	assert.Equal(t, "alice", rows[0].User.Name)
+	assert.Equal(t, 11, rows[1].Order.ID)
+	assert.Equal(t, 7, rows[1].Order.Total)
+}
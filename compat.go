@@ -0,0 +1,103 @@
+package sqlt
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Incompatibility describes a schema change that breaks application versions written against the old schema.
+type Incompatibility struct {
+	Table string
+	// Column is empty for changes to the whole table.
+	Column string
+	// Kind is "dropped table", "dropped column", "narrowed type" or "not null without default".
+	Kind    string
+	Details string
+}
+
+func (i Incompatibility) String() string {
+	name := i.Table
+	if i.Column != "" {
+		name += "." + i.Column
+	}
+	if i.Details == "" {
+		return fmt.Sprintf("%s %s", i.Kind, name)
+	}
+	return fmt.Sprintf("%s %s: %s", i.Kind, name, i.Details)
+}
+
+// widenings lists the type affinity changes that keep every value the old schema accepts readable as before.
+var widenings = map[[2]string]bool{
+	{"INTEGER", "REAL"}:    true,
+	{"INTEGER", "NUMERIC"}: true,
+	{"REAL", "NUMERIC"}:    true,
+	{"INTEGER", "BLOB"}:    true,
+	{"REAL", "BLOB"}:       true,
+	{"NUMERIC", "BLOB"}:    true,
+	{"TEXT", "BLOB"}:       true,
+}
+
+// CheckBackwardCompatible returns the changes from the old to the new schema that break application
+// versions still running against the old schema during a rolling deploy:
+//
+//   - tables and columns that are dropped while the old version still selects them
+//   - columns whose type affinity is narrowed, e.g. from TEXT to INTEGER
+//   - new or existing columns becoming NOT NULL without a default, which inserts of the old version omit
+//
+// Tables are sorted by name, columns are in table order. Added tables and nullable columns are compatible.
+func CheckBackwardCompatible(old, new io.Reader) ([]Incompatibility, error) {
+	oldSchema, err := ParseSchemaReader(old)
+	if err != nil {
+		return nil, fmt.Errorf("old schema: %w", err)
+	}
+	newSchema, err := ParseSchemaReader(new)
+	if err != nil {
+		return nil, fmt.Errorf("new schema: %w", err)
+	}
+	var incompatible []Incompatibility
+	for _, oldTable := range sortedByName(oldSchema.Tables, func(t *TableDefinition) string { return t.Name }) {
+		newTable := newSchema.Table(oldTable.Name)
+		if newTable == nil {
+			incompatible = append(incompatible, Incompatibility{Table: oldTable.Name, Kind: "dropped table"})
+			continue
+		}
+		for _, oldCol := range oldTable.Columns {
+			newCol := newTable.Column(oldCol.Name)
+			if newCol == nil {
+				incompatible = append(incompatible, Incompatibility{Table: oldTable.Name, Column: oldCol.Name, Kind: "dropped column"})
+				continue
+			}
+			oldAffinity, newAffinity := typeAffinityOf(oldCol.Type), typeAffinityOf(newCol.Type)
+			if oldAffinity != newAffinity && !widenings[[2]string{oldAffinity, newAffinity}] {
+				incompatible = append(incompatible, Incompatibility{
+					Table:   oldTable.Name,
+					Column:  oldCol.Name,
+					Kind:    "narrowed type",
+					Details: fmt.Sprintf("%s (%s) to %s (%s)", oldCol.Type, strings.ToLower(oldAffinity), newCol.Type, strings.ToLower(newAffinity)),
+				})
+			}
+			if !oldCol.NotNull && requiresValue(newCol) {
+				incompatible = append(incompatible, Incompatibility{Table: oldTable.Name, Column: oldCol.Name, Kind: "not null without default"})
+			}
+		}
+		for _, newCol := range newTable.Columns {
+			if oldTable.Column(newCol.Name) == nil && requiresValue(newCol) {
+				incompatible = append(incompatible, Incompatibility{
+					Table:   oldTable.Name,
+					Column:  newCol.Name,
+					Kind:    "not null without default",
+					Details: "new column",
+				})
+			}
+		}
+	}
+	return incompatible, nil
+}
+
+// requiresValue reports whether inserts must set col, as it is NOT NULL without a default.
+// Rowid aliases and generated columns are filled in by SQLite.
+func requiresValue(col *ColumnDefinition) bool {
+	rowidAlias := col.RowidAlias || col.PrimaryKey && strings.EqualFold(col.Type, "INTEGER")
+	return col.NotNull && col.DefaultValue == nil && !rowidAlias && col.Hidden < 2
+}
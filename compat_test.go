@@ -0,0 +1,46 @@
+package sqlt_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/james-darko/sqlt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckBackwardCompatible(t *testing.T) {
+	t.Parallel()
+	old := `
+CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, email TEXT, age INTEGER, score INTEGER);
+CREATE TABLE sessions (id INTEGER PRIMARY KEY);`
+	new := `
+CREATE TABLE users (
+	id INTEGER PRIMARY KEY,
+	name TEXT NOT NULL,
+	age TEXT,
+	score REAL,
+	created_at TIMESTAMP NOT NULL,
+	active INTEGER NOT NULL DEFAULT 1,
+	note TEXT
+);
+CREATE TABLE posts (id INTEGER PRIMARY KEY, title TEXT NOT NULL);`
+
+	incompatible, err := sqlt.CheckBackwardCompatible(strings.NewReader(old), strings.NewReader(new))
+	require.NoError(t, err)
+	var got []string
+	for _, i := range incompatible {
+		got = append(got, i.String())
+	}
+	assert.Equal(t, []string{
+		"dropped table sessions",
+		"not null without default users.name",
+		"dropped column users.email",
+		"narrowed type users.age: INTEGER (integer) to TEXT (text)",
+		"not null without default users.created_at: new column",
+	}, got)
+
+	incompatible, err = sqlt.CheckBackwardCompatible(strings.NewReader(old), strings.NewReader(old))
+	require.NoError(t, err)
+	assert.Empty(t, incompatible)
+}
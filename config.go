@@ -0,0 +1,141 @@
+package sqlt
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Config is the database configuration read from the environment by LoadDB.
+// See LoadDB for the variables.
+type Config struct {
+	Driver string
+	URL    string
+	// Token is the libsql auth token. It is appended to URL when connecting.
+	Token string
+	// Key is the encryption key, see OpenEncrypted.
+	Key string
+	// SchemaFile is the location of the schema verified by FullLoadDB, a file path or a URL.
+	SchemaFile string
+	// Unknown lists the set DATABASE_* variables sqlt does not recognize, usually typos.
+	Unknown []string
+}
+
+// knownEnvVars are the variables read by LoadDB, FullLoadDB and AutoMigrateFromEnv.
+var knownEnvVars = []string{
+	"DATABASE_DRIVER",
+	"DATABASE_URL", "DATABASE_URL_FILE",
+	"DATABASE_TOKEN", "DATABASE_TOKEN_FILE",
+	"DATABASE_KEY", "DATABASE_KEY_FILE",
+	"DATABASE_SCHEMA", "DATABASE_SCHEMA_PUBLIC_KEY",
+}
+
+// ConfigFromEnv reads and validates the configuration LoadDB uses. It fails if a required variable
+// is missing, a variable is set both inline and as a _FILE, the driver is not registered or the
+// schema is a local file that cannot be read. Unknown DATABASE_* variables are reported in
+// Config.Unknown.
+//
+// Config's String method masks secrets, so the config can be printed in startup logs.
+func ConfigFromEnv() (Config, error) {
+	var cfg Config
+	var errs []string
+	for _, env := range os.Environ() {
+		name, _, _ := strings.Cut(env, "=")
+		if strings.HasPrefix(name, "DATABASE_") && !slices.Contains(knownEnvVars, name) {
+			cfg.Unknown = append(cfg.Unknown, name)
+		}
+	}
+	slices.Sort(cfg.Unknown)
+	for _, name := range []string{"DATABASE_URL", "DATABASE_TOKEN", "DATABASE_KEY"} {
+		if os.Getenv(name) != "" && os.Getenv(name+"_FILE") != "" {
+			errs = append(errs, fmt.Sprintf("both %s and %s_FILE are set", name, name))
+		}
+	}
+
+	cfg.Driver = os.Getenv("DATABASE_DRIVER")
+	if cfg.Driver == "" {
+		cfg.Driver = "sqlite3"
+	}
+	var err error
+	if cfg.URL, err = envSecret("DATABASE_URL"); err != nil {
+		errs = append(errs, err.Error())
+	} else if cfg.URL == "" {
+		errs = append(errs, "DATABASE_URL env var not found")
+	}
+	if strings.HasPrefix(cfg.URL, "libsql:") {
+		cfg.Driver = "libsql"
+		if cfg.Token, err = envSecret("DATABASE_TOKEN"); err != nil {
+			errs = append(errs, err.Error())
+		} else if cfg.Token == "" {
+			errs = append(errs, "DATABASE_TOKEN env var not found")
+		}
+	}
+	if !slices.Contains(sql.Drivers(), cfg.Driver) {
+		errs = append(errs, fmt.Sprintf("driver %q is not registered, import it", cfg.Driver))
+	}
+	if cfg.Key, err = envSecret("DATABASE_KEY"); err != nil {
+		errs = append(errs, err.Error())
+	}
+	cfg.SchemaFile = os.Getenv("DATABASE_SCHEMA")
+	// Locations with a scheme other than file:// are fetched when migrating, see WithSchemaFetcher.
+	if path, ok := strings.CutPrefix(cfg.SchemaFile, "file://"); cfg.SchemaFile != "" && (ok || !strings.Contains(path, "://")) {
+		if _, err := os.Stat(path); err != nil {
+			errs = append(errs, fmt.Sprintf("could not read DATABASE_SCHEMA: %v", err))
+		}
+	}
+	if len(errs) > 0 {
+		return cfg, fmt.Errorf("invalid database config: %s", strings.Join(errs, "; "))
+	}
+	return cfg, nil
+}
+
+// dsn returns the data source name to open, including the libsql auth token.
+func (c Config) dsn() string {
+	if c.Driver == "libsql" {
+		return c.URL + "?authToken=" + c.Token
+	}
+	return c.URL
+}
+
+// String returns a summary of the config with the token, key and URL credentials masked.
+func (c Config) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "driver=%s url=%s token=%s key=%s", c.Driver, redactURL(c.URL), redactSecret(c.Token), redactSecret(c.Key))
+	if c.SchemaFile != "" {
+		fmt.Fprintf(&b, " schema=%s", c.SchemaFile)
+	}
+	if len(c.Unknown) > 0 {
+		fmt.Fprintf(&b, " unknown=%s", strings.Join(c.Unknown, ","))
+	}
+	return b.String()
+}
+
+func redactSecret(s string) string {
+	if s == "" {
+		return "<unset>"
+	}
+	return "REDACTED"
+}
+
+// redactURL masks the password and the query parameters that may hold credentials in a URL.
+func redactURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil || (u.User == nil && u.RawQuery == "") {
+		return s
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), "REDACTED")
+	}
+	query := u.Query()
+	for name := range query {
+		lower := strings.ToLower(name)
+		if strings.Contains(lower, "token") || strings.Contains(lower, "key") || strings.Contains(lower, "pass") || strings.Contains(lower, "auth") {
+			query.Set(name, "REDACTED")
+		}
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
@@ -0,0 +1,62 @@
+package sqlt
+
+import (
+	"fmt"
+
+	rsql "github.com/rqlite/sql"
+)
+
+// Resolution is the action AutoMigrate takes for a schema conflict, see WithOnConflict.
+type Resolution int
+
+const (
+	// ResolutionAbort fails the migration, reporting the conflict in ErrSchemaConflicts. It is the default.
+	ResolutionAbort Resolution = iota
+	// ResolutionSkip leaves the conflicting table as it is and continues with the other objects.
+	ResolutionSkip
+	// ResolutionForceRebuild recreates the table from its schema, copying the columns the old and new
+	// table have in common. Columns missing from the schema are dropped with their data.
+	ResolutionForceRebuild
+)
+
+// String returns the name of the resolution.
+func (r Resolution) String() string {
+	switch r {
+	case ResolutionAbort:
+		return "abort"
+	case ResolutionSkip:
+		return "skip"
+	case ResolutionForceRebuild:
+		return "force rebuild"
+	default:
+		return fmt.Sprintf("Resolution(%d)", int(r))
+	}
+}
+
+// WithOnConflict calls fn for every table AutoMigrate cannot reconcile, letting tools ask an operator
+// how to resolve each conflict instead of failing the whole run.
+func WithOnConflict(fn func(SchemaConflictError) Resolution) MigrateOption {
+	return func(cfg *migrateConfig) {
+		cfg.onConflict = fn
+	}
+}
+
+// resolveConflict returns the resolution chosen for conflict, ResolutionAbort without a WithOnConflict callback.
+func (cfg *migrateConfig) resolveConflict(conflict *SchemaConflictError) Resolution {
+	if cfg.onConflict == nil {
+		return ResolutionAbort
+	}
+	return cfg.onConflict(*conflict)
+}
+
+// sharedColumns returns the quoted columns of schemaTable that dbTable has too, leaving out generated columns.
+func sharedColumns(dbTable, schemaTable *rsql.CreateTableStatement) []string {
+	old := newTableDefinition(dbTable)
+	var columns []string
+	for _, col := range newTableDefinition(schemaTable).Columns {
+		if oldCol := old.Column(col.Name); oldCol != nil && oldCol.Hidden < 2 && col.Hidden < 2 {
+			columns = append(columns, quoteIdent(col.Name))
+		}
+	}
+	return columns
+}
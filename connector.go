@@ -0,0 +1,218 @@
+package sqlt
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"time"
+)
+
+// ConnectorOption configures the connector returned by NewConnector.
+type ConnectorOption func(*connector)
+
+// WithConnectPragmas runs the given pragmas, e.g. "busy_timeout = 5000", on every new connection before it is used.
+func WithConnectPragmas(pragmas ...string) ConnectorOption {
+	return func(c *connector) {
+		c.pragmas = append(c.pragmas, pragmas...)
+	}
+}
+
+// WithConnectorRetry retries statements up to attempts times, waiting delay between attempts,
+// while they fail because the database is busy or locked, as WithRetry does for a DB.
+// Statements in transactions are not retried, and neither are those executed with a WithNoRetry context.
+func WithConnectorRetry(attempts int, delay time.Duration) ConnectorOption {
+	return func(c *connector) {
+		c.retries = attempts
+		c.retryDelay = delay
+	}
+}
+
+// StatementHook is called after each statement executed through a connector returned by NewConnector,
+// with the time it took and its error, if any.
+type StatementHook func(ctx context.Context, query string, elapsed time.Duration, err error)
+
+// WithStatementHook calls hook after each statement, e.g. for logging or metrics.
+// Statements run through prepared statements are not reported.
+func WithStatementHook(hook StatementHook) ConnectorOption {
+	return func(c *connector) {
+		c.hooks = append(c.hooks, hook)
+	}
+}
+
+// NewConnector returns a driver.Connector opening connections with the driver registered as driverName,
+// applying the given options at the driver layer. It is meant for frameworks that insist on a *sql.DB:
+// pass it to sql.OpenDB, and to WrapStd to use the result with sqlt as well.
+func NewConnector(driverName, dataSourceName string, opts ...ConnectorOption) (driver.Connector, error) {
+	probe, err := sql.Open(driverName, dataSourceName)
+	if err != nil {
+		return nil, err
+	}
+	drv := probe.Driver()
+	probe.Close()
+	c := &connector{driver: drv, dsn: dataSourceName}
+	if dc, ok := drv.(driver.DriverContext); ok {
+		if c.base, err = dc.OpenConnector(dataSourceName); err != nil {
+			return nil, err
+		}
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+type connector struct {
+	driver driver.Driver
+	dsn    string
+	// base is the driver's own connector, if it provides one.
+	base       driver.Connector
+	pragmas    []string
+	retries    int
+	retryDelay time.Duration
+	hooks      []StatementHook
+}
+
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	var conn driver.Conn
+	var err error
+	if c.base != nil {
+		conn, err = c.base.Connect(ctx)
+	} else {
+		conn, err = c.driver.Open(c.dsn)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, pragma := range c.pragmas {
+		if err := execDriverConn(ctx, conn, "PRAGMA "+pragma); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("could not set PRAGMA %s: %w", pragma, err)
+		}
+	}
+	return &hookedConn{Conn: conn, connector: c}, nil
+}
+
+func (c *connector) Driver() driver.Driver {
+	return c.driver
+}
+
+// hookedConn applies the retries and hooks of its connector to the statements executed on it.
+type hookedConn struct {
+	driver.Conn
+	connector *connector
+	inTx      bool
+}
+
+// run calls fn with the retry policy of the connector and reports the statement to its hooks.
+func (c *hookedConn) run(ctx context.Context, query string, fn func() error) error {
+	started := time.Now()
+	err := c.retry(ctx, fn)
+	for _, hook := range c.connector.hooks {
+		hook(ctx, query, time.Since(started), err)
+	}
+	return err
+}
+
+func (c *hookedConn) retry(ctx context.Context, fn func() error) error {
+	retries := c.connector.retries
+	if c.inTx || ctx.Value(noRetryKey{}) != nil {
+		retries = 0
+	}
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= retries || !isBusy(err) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(c.connector.retryDelay):
+		}
+	}
+}
+
+func (c *hookedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	var result driver.Result
+	err := c.run(ctx, query, func() (err error) {
+		result, err = execer.ExecContext(ctx, query, args)
+		return err
+	})
+	return result, err
+}
+
+func (c *hookedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	var rows driver.Rows
+	err := c.run(ctx, query, func() (err error) {
+		rows, err = queryer.QueryContext(ctx, query, args)
+		return err
+	})
+	return rows, err
+}
+
+func (c *hookedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return preparer.PrepareContext(ctx, query)
+	}
+	return c.Conn.Prepare(query)
+}
+
+func (c *hookedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	var tx driver.Tx
+	var err error
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		tx, err = beginner.BeginTx(ctx, opts)
+	} else {
+		// Drivers without ConnBeginTx only have the deprecated Begin.
+		tx, err = c.Conn.Begin()
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.inTx = true
+	return &hookedTx{Tx: tx, conn: c}, nil
+}
+
+func (c *hookedConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+func (c *hookedConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *hookedConn) IsValid() bool {
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+type hookedTx struct {
+	driver.Tx
+	conn *hookedConn
+}
+
+func (tx *hookedTx) Commit() error {
+	tx.conn.inTx = false
+	return tx.Tx.Commit()
+}
+
+func (tx *hookedTx) Rollback() error {
+	tx.conn.inTx = false
+	return tx.Tx.Rollback()
+}
@@ -0,0 +1,67 @@
+package sqlt_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/james-darko/gort"
+	"github.com/james-darko/sqlt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewConnector(t *testing.T) {
+	t.Parallel()
+	ctx := gort.Context()
+	path := filepath.Join(t.TempDir(), "connector.db")
+
+	var mu sync.Mutex
+	var queries []string
+	var failures int
+	connector, err := sqlt.NewConnector("sqlite3", "file:"+path,
+		sqlt.WithConnectPragmas("foreign_keys = on", "busy_timeout = 0"),
+		sqlt.WithConnectorRetry(50, 10*time.Millisecond),
+		sqlt.WithStatementHook(func(ctx context.Context, query string, elapsed time.Duration, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			queries = append(queries, query)
+			if err != nil {
+				failures++
+			}
+		}))
+	require.NoError(t, err)
+	db := sqlt.WrapStd(sql.OpenDB(connector), "sqlite3")
+	defer db.Close()
+
+	var foreignKeys bool
+	require.NoError(t, db.GetContext(ctx, &foreignKeys, "PRAGMA foreign_keys"))
+	assert.True(t, foreignKeys)
+	_, err = db.ExecContext(ctx, "CREATE TABLE items (id INTEGER PRIMARY KEY)")
+	require.NoError(t, err)
+
+	locker, err := sqlt.Open("sqlite3", "file:"+path)
+	require.NoError(t, err)
+	defer locker.Close()
+	locked := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- locker.TxImm(func(tx sqlt.Tx) error {
+			close(locked)
+			time.Sleep(100 * time.Millisecond)
+			return nil
+		})
+	}()
+	<-locked
+	_, err = db.ExecContext(ctx, "INSERT INTO items (id) VALUES (1)")
+	require.NoError(t, err, "the busy insert is retried until the lock is released")
+	require.NoError(t, <-done)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"PRAGMA foreign_keys", "CREATE TABLE items (id INTEGER PRIMARY KEY)", "INSERT INTO items (id) VALUES (1)"}, queries)
+	assert.Zero(t, failures)
+}
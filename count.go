@@ -0,0 +1,56 @@
+package sqlt
+
+import (
+	"context"
+	"strings"
+)
+
+// existsQuery wraps query in SELECT EXISTS, which returns a single row whether query has rows or not.
+func existsQuery(query string) string {
+	return "SELECT EXISTS (" + strings.TrimRight(strings.TrimSpace(query), ";") + ")"
+}
+
+// countQuery wraps query in a SELECT COUNT(*) counting its rows.
+func countQuery(query string) string {
+	return "SELECT COUNT(*) FROM (" + strings.TrimRight(strings.TrimSpace(query), ";") + ")"
+}
+
+func (s *sqlxDB) Exists(query string, args ...any) (bool, error) {
+	return s.ExistsContext(context.Background(), query, args...)
+}
+
+func (s *sqlxDB) ExistsContext(ctx context.Context, query string, args ...any) (bool, error) {
+	var exists bool
+	err := s.GetContext(ctx, &exists, existsQuery(query), args...)
+	return exists, err
+}
+
+func (s *sqlxDB) Count(query string, args ...any) (int64, error) {
+	return s.CountContext(context.Background(), query, args...)
+}
+
+func (s *sqlxDB) CountContext(ctx context.Context, query string, args ...any) (int64, error) {
+	var count int64
+	err := s.GetContext(ctx, &count, countQuery(query), args...)
+	return count, err
+}
+
+func (tx *txWrapper) Exists(query string, args ...any) (bool, error) {
+	return tx.ExistsContext(context.Background(), query, args...)
+}
+
+func (tx *txWrapper) ExistsContext(ctx context.Context, query string, args ...any) (bool, error) {
+	var exists bool
+	err := tx.GetContext(ctx, &exists, existsQuery(query), args...)
+	return exists, err
+}
+
+func (tx *txWrapper) Count(query string, args ...any) (int64, error) {
+	return tx.CountContext(context.Background(), query, args...)
+}
+
+func (tx *txWrapper) CountContext(ctx context.Context, query string, args ...any) (int64, error) {
+	var count int64
+	err := tx.GetContext(ctx, &count, countQuery(query), args...)
+	return count, err
+}
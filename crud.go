@@ -0,0 +1,85 @@
+package sqlt
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/jmoiron/sqlx/reflectx"
+)
+
+// InsertStruct inserts v, a struct or pointer to one, as a row of table. The columns are the fields
+// of v mapped by their db tag or the default mapper, as for AliasedColumns. Fields tagged omitempty,
+// e.g. `db:"id,omitempty"`, are left out while they hold their zero value, so the column gets its
+// default or, for an INTEGER PRIMARY KEY, a new rowid.
+func InsertStruct(ctx context.Context, db Writer, table string, v any) (Result, error) {
+	columns, values, err := structValues(v)
+	if err != nil {
+		return nil, fmt.Errorf("InsertStruct: %w", err)
+	}
+	if len(columns) == 0 {
+		return db.ExecContext(ctx, fmt.Sprintf("INSERT INTO %s DEFAULT VALUES", quoteIdent(table)))
+	}
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = quoteIdent(column)
+	}
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", quoteIdent(table), strings.Join(quoted, ", "),
+		strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", "))
+	return db.ExecContext(ctx, query, values...)
+}
+
+// UpdateStruct updates the rows of table whose whereColumns equal the fields of v mapped to them,
+// setting the other columns of v as InsertStruct would insert them. At least one where column is required.
+func UpdateStruct(ctx context.Context, db Writer, table string, v any, whereColumns ...string) (Result, error) {
+	if len(whereColumns) == 0 {
+		return nil, fmt.Errorf("UpdateStruct: no where columns")
+	}
+	columns, values, err := structValues(v)
+	if err != nil {
+		return nil, fmt.Errorf("UpdateStruct: %w", err)
+	}
+	var set, where []string
+	var setArgs, whereArgs []any
+	for i, column := range columns {
+		if slices.Contains(whereColumns, column) {
+			continue
+		}
+		set = append(set, quoteIdent(column)+" = ?")
+		setArgs = append(setArgs, values[i])
+	}
+	for _, column := range whereColumns {
+		i := slices.Index(columns, column)
+		if i < 0 {
+			return nil, fmt.Errorf("UpdateStruct: where column %s is not a field of %T or is empty", column, v)
+		}
+		where = append(where, quoteIdent(column)+" = ?")
+		whereArgs = append(whereArgs, values[i])
+	}
+	if len(set) == 0 {
+		return nil, fmt.Errorf("UpdateStruct: no columns to set")
+	}
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s", quoteIdent(table), strings.Join(set, ", "), strings.Join(where, " AND "))
+	return db.ExecContext(ctx, query, append(setArgs, whereArgs...)...)
+}
+
+// structValues returns the columns of the fields of v and their values, leaving out the empty omitempty fields.
+func structValues(v any) ([]string, []any, error) {
+	value := reflect.Indirect(reflect.ValueOf(v))
+	if value.Kind() != reflect.Struct {
+		return nil, nil, fmt.Errorf("%T is not a struct", v)
+	}
+	var columns []string
+	var values []any
+	for _, field := range scannedFields(structMapper().TypeMap(value.Type()).Tree.Children) {
+		fieldValue := reflectx.FieldByIndexesReadOnly(value, field.Index)
+		if _, omitEmpty := field.Options["omitempty"]; omitEmpty && fieldValue.IsZero() {
+			continue
+		}
+		columns = append(columns, field.Name)
+		values = append(values, fieldValue.Interface())
+	}
+	return columns, values, nil
+}
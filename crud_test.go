@@ -0,0 +1,48 @@
+package sqlt_test
+
+import (
+	"testing"
+
+	"github.com/james-darko/gort"
+	"github.com/james-darko/sqlt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInsertUpdateStruct(t *testing.T) {
+	t.Parallel()
+	db, err := sqlt.OpenMemory(t.Name())
+	require.NoError(t, err)
+	defer db.Close()
+	ctx := gort.Context()
+
+	_, err = db.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL, tier TEXT NOT NULL DEFAULT 'free')")
+	require.NoError(t, err)
+
+	type user struct {
+		ID   int64  `db:"id,omitempty"`
+		Name string `db:"name"`
+		Tier string `db:"tier,omitempty"`
+	}
+	res, err := sqlt.InsertStruct(ctx, db, "users", &user{Name: "alice"})
+	require.NoError(t, err)
+	id, err := res.LastInsertId()
+	require.NoError(t, err)
+
+	var got user
+	require.NoError(t, db.Get(&got, "SELECT * FROM users WHERE id = ?", id))
+	assert.Equal(t, user{ID: id, Name: "alice", Tier: "free"}, got)
+
+	err = db.Tx(func(tx sqlt.Tx) error {
+		_, err := sqlt.UpdateStruct(ctx, tx, "users", user{ID: id, Name: "alicia", Tier: "pro"}, "id")
+		return err
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.Get(&got, "SELECT * FROM users WHERE id = ?", id))
+	assert.Equal(t, user{ID: id, Name: "alicia", Tier: "pro"}, got)
+
+	_, err = sqlt.UpdateStruct(ctx, db, "users", user{Name: "bob"})
+	assert.ErrorContains(t, err, "no where columns")
+	_, err = sqlt.UpdateStruct(ctx, db, "users", user{Name: "bob"}, "id")
+	assert.ErrorContains(t, err, "where column id")
+}
@@ -0,0 +1,149 @@
+package sqlt
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// RowDiff is a row that differs between two databases, described as the change turning the first into the second.
+type RowDiff struct {
+	Table string
+	// Kind is "insert" for rows only in the second database, "delete" for rows only in the first
+	// and "update" for rows in both whose values differ.
+	Kind string
+	// Key holds the primary key columns, or rowid if the table has no primary key.
+	Key map[string]any
+	// Columns lists the differing columns of an update.
+	Columns []string
+	// A and B hold the row in the first and second database. Only one of them is set for inserts and deletes.
+	A map[string]any
+	B map[string]any
+}
+
+// DiffData compares the rows of tables in a and b, matched by primary key, and reports the
+// inserts, updates and deletes turning a into b. If tables is empty, all tables in a are compared.
+// The rows of each table are loaded into memory.
+func DiffData(ctx context.Context, a, b DB, tables []string) ([]RowDiff, error) {
+	if len(tables) == 0 {
+		rows, err := masterRows(ctx, a)
+		if err != nil {
+			return nil, fmt.Errorf("could not list tables: %w", err)
+		}
+		for _, row := range rows {
+			if row.Type == "table" {
+				tables = append(tables, row.Name)
+			}
+		}
+		slices.Sort(tables)
+	}
+	var diffs []RowDiff
+	for _, table := range tables {
+		tableDiffs, err := diffTableData(ctx, a, b, table)
+		if err != nil {
+			return nil, fmt.Errorf("table %s: %w", table, err)
+		}
+		diffs = append(diffs, tableDiffs...)
+	}
+	return diffs, nil
+}
+
+func diffTableData(ctx context.Context, a, b DB, table string) ([]RowDiff, error) {
+	var keyCols []string
+	err := a.SelectContext(ctx, &keyCols, "SELECT name FROM pragma_table_info(?) WHERE pk > 0 ORDER BY pk", table)
+	if err != nil {
+		return nil, fmt.Errorf("could not get primary key: %w", err)
+	}
+	if len(keyCols) == 0 {
+		keyCols = []string{"rowid"}
+	}
+	aRows, aKeys, err := loadTableRows(ctx, a, table, keyCols)
+	if err != nil {
+		return nil, err
+	}
+	bRows, bKeys, err := loadTableRows(ctx, b, table, keyCols)
+	if err != nil {
+		return nil, err
+	}
+
+	var diffs []RowDiff
+	for _, key := range aKeys {
+		aRow := aRows[key]
+		bRow, ok := bRows[key]
+		if !ok {
+			diffs = append(diffs, RowDiff{Table: table, Kind: "delete", Key: rowKey(aRow, keyCols), A: aRow})
+			continue
+		}
+		if cols := differingColumns(aRow, bRow); len(cols) > 0 {
+			diffs = append(diffs, RowDiff{Table: table, Kind: "update", Key: rowKey(aRow, keyCols), Columns: cols, A: aRow, B: bRow})
+		}
+	}
+	for _, key := range bKeys {
+		if _, ok := aRows[key]; !ok {
+			bRow := bRows[key]
+			diffs = append(diffs, RowDiff{Table: table, Kind: "insert", Key: rowKey(bRow, keyCols), B: bRow})
+		}
+	}
+	return diffs, nil
+}
+
+// loadTableRows returns the rows of table keyed by their encoded primary key, and the keys in primary key order.
+func loadTableRows(ctx context.Context, db DB, table string, keyCols []string) (map[string]map[string]any, []string, error) {
+	quotedKeys := make([]string, len(keyCols))
+	for i, col := range keyCols {
+		quotedKeys[i] = quoteIdent(col)
+	}
+	query := fmt.Sprintf("SELECT %s, * FROM %s ORDER BY %s", strings.Join(quotedKeys, ", "), quoteIdent(table), strings.Join(quotedKeys, ", "))
+	rows, err := db.SQLX().QueryxContext(ctx, query)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not query rows: %w", err)
+	}
+	defer rows.Close()
+	byKey := make(map[string]map[string]any)
+	var keys []string
+	for rows.Next() {
+		row := make(map[string]any)
+		if err := rows.MapScan(row); err != nil {
+			return nil, nil, fmt.Errorf("could not scan row: %w", err)
+		}
+		for col, v := range row {
+			if bs, ok := v.([]byte); ok {
+				row[col] = string(bs)
+			}
+		}
+		key := fmt.Sprintf("%#v", rowKey(row, keyCols))
+		byKey[key] = row
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	return byKey, keys, nil
+}
+
+func rowKey(row map[string]any, keyCols []string) map[string]any {
+	key := make(map[string]any, len(keyCols))
+	for _, col := range keyCols {
+		key[col] = row[col]
+	}
+	return key
+}
+
+// differingColumns returns the sorted names of the columns whose values differ between the rows.
+func differingColumns(a, b map[string]any) []string {
+	var cols []string
+	for col, av := range a {
+		if bv, ok := b[col]; !ok || !reflect.DeepEqual(av, bv) {
+			cols = append(cols, col)
+		}
+	}
+	for col := range b {
+		if _, ok := a[col]; !ok {
+			cols = append(cols, col)
+		}
+	}
+	slices.Sort(cols)
+	return cols
+}
@@ -0,0 +1,46 @@
+package sqlt_test
+
+import (
+	"testing"
+
+	"github.com/james-darko/gort"
+	"github.com/james-darko/sqlt"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffData(t *testing.T) {
+	t.Parallel()
+	ctx := gort.Context()
+	schema := `
+CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT);
+CREATE TABLE tags (label TEXT);`
+
+	a := getTestDB(t)
+	defer a.Close()
+	b := getTestDB(t)
+	defer b.Close()
+	require.NoError(t, sqlt.ExecString(ctx, a, schema+`
+INSERT INTO users (id, name) VALUES (1, 'ann'), (2, 'bob'), (3, 'cid');
+INSERT INTO tags (label) VALUES ('x');`))
+	require.NoError(t, sqlt.ExecString(ctx, b, schema+`
+INSERT INTO users (id, name) VALUES (1, 'ann'), (2, 'bobby'), (4, 'dee');
+INSERT INTO tags (label) VALUES ('x');`))
+
+	diffs, err := sqlt.DiffData(ctx, a, b, nil)
+	require.NoError(t, err)
+	require.Len(t, diffs, 3)
+
+	assert.Equal(t, "update", diffs[0].Kind)
+	assert.Equal(t, map[string]any{"id": int64(2)}, diffs[0].Key)
+	assert.Equal(t, []string{"name"}, diffs[0].Columns)
+	assert.Equal(t, "bobby", diffs[0].B["name"])
+
+	assert.Equal(t, "delete", diffs[1].Kind)
+	assert.Equal(t, map[string]any{"id": int64(3)}, diffs[1].Key)
+
+	assert.Equal(t, "insert", diffs[2].Kind)
+	assert.Equal(t, map[string]any{"id": int64(4)}, diffs[2].Key)
+	assert.Equal(t, "users", diffs[2].Table)
+}
@@ -3,18 +3,25 @@ package sqlt
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"net/url"
 	"strings"
 	"sync/atomic"
+	"time"
 	"unicode"
 
 	"github.com/jmoiron/sqlx"
 )
 
-func Wrap(db *sqlx.DB) DB {
-	return &sqlxDB{db: db}
+func Wrap(db *sqlx.DB, opts ...OpenOption) DB {
+	s := &sqlxDB{db: db, state: &dbState{}}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
-func Open(driverName, dataSourceName string) (DB, error) {
+func Open(driverName, dataSourceName string, opts ...OpenOption) (DB, error) {
 	db, err := sqlx.Open(driverName, dataSourceName)
 	if err != nil {
 		return nil, err
@@ -23,9 +30,37 @@ func Open(driverName, dataSourceName string) (DB, error) {
 	if mapper != nil {
 		db.MapperFunc(*mapper)
 	}
-	return &sqlxDB{db: db}, nil
+	return Wrap(db, opts...), nil
 }
 
+// WrapStd returns a DB using db, opened with the driver registered as driverName,
+// for code holding a *sql.DB without sqlx. The default mapper is applied as by Open.
+func WrapStd(db *sql.DB, driverName string, opts ...OpenOption) DB {
+	x := sqlx.NewDb(db, driverName)
+	if mapper := defaultMapper.Load(); mapper != nil {
+		x.MapperFunc(*mapper)
+	}
+	return Wrap(x, opts...)
+}
+
+// OpenMemory opens a new in-memory SQLite database with foreign keys enabled, using the
+// "sqlite3" driver, which must be registered by the caller.
+//
+// Every call gets its own database, even for the same name, so parallel tests are isolated.
+// All connections of the returned DB share the database through SQLite's shared cache.
+// The database is destroyed once its last connection is closed.
+// name only identifies the database in DSNs and errors, e.g. t.Name().
+func OpenMemory(name string) (DB, error) {
+	dsn := fmt.Sprintf("file:%s-%d?mode=memory&cache=shared&_foreign_keys=on", url.PathEscape(name), memoryDBSeq.Add(1))
+	db, err := Open("sqlite3", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("could not open in-memory database %s: %w", name, err)
+	}
+	return db, nil
+}
+
+var memoryDBSeq atomic.Uint64
+
 func SetDefaultMapper(mapper func(string) string) {
 	defaultMapper.Store(&mapper)
 }
@@ -53,33 +88,33 @@ var camalCaseMapper = func(s string) string {
 	return buf.String()
 }
 
+// DB is a database handle. Its query helpers are those of Handle.
 type DB interface {
+	Handle
 	SQLX() *sqlx.DB
-	Exec(query string, args ...any) (Result, error)
-	ExecContext(ctx context.Context, query string, args ...any) (Result, error)
-	IDExec(query string, args ...any) (int64, error)
-	IDExecContext(ctx context.Context, query string, args ...any) (int64, error)
-	AffectedExec(query string, args ...any) (int, error)
-	AffectedExecContext(ctx context.Context, query string, args ...any) (int, error)
-	Query(query string, args ...any) (*sqlx.Rows, error)
-	QueryRow(query string, args ...any) *sqlx.Row
-	Prepare(query string) (*sqlx.Stmt, error)
-	Preparex(query string) (*sqlx.Stmt, error)
-	Rebind(query string) string
-	DriverName() string
-	BindNamed(query string, arg any) (string, []any, error)
-	Get(dest any, query string, args ...any) error
-	GetIn(dest any, query string, args ...any) error
-	GetContext(ctx context.Context, dest any, query string, args ...any) error
-	GetInContext(ctx context.Context, dest any, query string, args ...any) error
-	Select(dest any, query string, args ...any) error
-	SelectIn(dest any, query string, args ...any) error
-	SelectContext(ctx context.Context, dest any, query string, args ...any) error
-	SelectInSeq(query string, args ...any) *RowsSeq
-	SelectSeq(query string, args ...any) *RowsSeq
-	NamedExec(query string, arg any) (sql.Result, error)
-	NamedQuery(query string, arg any) (*sqlx.Rows, error)
+	// Std returns the underlying *sql.DB, for libraries that need database/sql.
+	// Queries made through it bypass the options the DB was opened with.
+	Std() *sql.DB
+	// Unsafe returns a DB sharing the connections and options of this one whose queries, and the
+	// transactions it starts, scan rows into structs missing fields for some of the columns,
+	// ignoring those columns. See WithUnsafeScan for single queries.
+	Unsafe() DB
 	Close() error
+	// Capabilities returns the SQLite features the database supports.
+	// They are detected on the first call and cached.
+	Capabilities(ctx context.Context) (*Capabilities, error)
+	// Watch sends an event whenever a commit from another connection or process may have changed
+	// the given tables, or any table if tables is empty. Events are coalesced per WatchInterval.
+	// The channel is closed when ctx is done. Watch holds a connection of the pool until then,
+	// so it fails if MaxOpenConns is 1.
+	Watch(ctx context.Context, tables []string) <-chan ChangeEvent
+	// Tables returns the definitions of the tables, sorted by name. See FetchDBSchema.
+	Tables(ctx context.Context) ([]*TableDefinition, error)
+	// Columns returns the column definitions of table in declaration order.
+	Columns(ctx context.Context, table string) ([]*ColumnDefinition, error)
+	// Indexes returns the indexes of table sorted by name, including the implicit indexes
+	// of its PRIMARY KEY and UNIQUE constraints, see IndexDefinition.Origin.
+	Indexes(ctx context.Context, table string) ([]*IndexDefinition, error)
 
 	Tx(fn func(tx Tx) error) error
 	TxImm(fn func(tx Tx) error) error
@@ -96,22 +131,55 @@ type DBReader interface {
 type sqlxDB struct {
 	db         *sqlx.DB
 	immidateDB *sqlx.DB
+	state      *dbState
+	cache      *queryCache
+	// keys is set if the database was opened with OpenEncrypted.
+	keys *keyConnector
+	// guard is set by WithGuard.
+	guard bool
+	// redact is set by RedactParams.
+	redact ParamRedactor
+	// timeout, retries and retryDelay are set by WithQueryTimeout and WithRetry.
+	timeout    time.Duration
+	retries    int
+	retryDelay time.Duration
+}
+
+// dbState is the state of a database shared with the DBs returned by its Unsafe method.
+type dbState struct {
+	caps atomic.Pointer[Capabilities]
+	// writers counts the open transactions that have written, see LockError.
+	writers atomic.Int32
 }
 
 func (s *sqlxDB) SQLX() *sqlx.DB {
 	return s.db
 }
 
-func (s *sqlxDB) Exec(query string, args ...any) (Result, error) {
-	r, err := s.db.Exec(query, args...)
-	if err != nil {
-		return nil, err
+func (s *sqlxDB) Std() *sql.DB {
+	return s.db.DB
+}
+
+func (s *sqlxDB) Unsafe() DB {
+	unsafe := *s
+	unsafe.db = s.db.Unsafe()
+	return &unsafe
+}
+
+// reader returns the sqlx handle for the read queries executed with ctx, see WithUnsafeScan.
+func (s *sqlxDB) reader(ctx context.Context) *sqlx.DB {
+	if unsafeScan(ctx) {
+		return s.db.Unsafe()
 	}
-	return sqltResult{r}, nil
+	return s.db
+}
+
+func (s *sqlxDB) Exec(query string, args ...any) (Result, error) {
+	return s.ExecContext(context.Background(), query, args...)
 }
 
 func (s *sqlxDB) ExecContext(ctx context.Context, query string, args ...any) (Result, error) {
-	r, err := s.db.ExecContext(ctx, query, args...)
+	r, err := s.exec(ctx, query, args)
 	if err != nil {
 		return nil, err
 	}
@@ -119,19 +187,11 @@ func (s *sqlxDB) ExecContext(ctx context.Context, query string, args ...any) (Re
 }
 
 func (s *sqlxDB) IDExec(query string, args ...any) (int64, error) {
-	r, err := s.db.Exec(query, args...)
-	if err != nil {
-		return 0, err
-	}
-	id, err := r.LastInsertId()
-	if err != nil {
-		return 0, err
-	}
-	return id, nil
+	return s.IDExecContext(context.Background(), query, args...)
 }
 
 func (s *sqlxDB) IDExecContext(ctx context.Context, query string, args ...any) (int64, error) {
-	r, err := s.db.ExecContext(ctx, query, args...)
+	r, err := s.exec(ctx, query, args)
 	if err != nil {
 		return 0, err
 	}
@@ -143,19 +203,11 @@ func (s *sqlxDB) IDExecContext(ctx context.Context, query string, args ...any) (
 }
 
 func (s *sqlxDB) AffectedExec(query string, args ...any) (int, error) {
-	r, err := s.db.Exec(query, args...)
-	if err != nil {
-		return 0, err
-	}
-	rowsAffected, err := r.RowsAffected()
-	if err != nil {
-		return 0, err
-	}
-	return int(rowsAffected), nil
+	return s.AffectedExecContext(context.Background(), query, args...)
 }
 
 func (s *sqlxDB) AffectedExecContext(ctx context.Context, query string, args ...any) (int, error) {
-	r, err := s.db.ExecContext(ctx, query, args...)
+	r, err := s.exec(ctx, query, args)
 	if err != nil {
 		return 0, err
 	}
@@ -166,22 +218,143 @@ func (s *sqlxDB) AffectedExecContext(ctx context.Context, query string, args ...
 	return int(rowsAffected), nil
 }
 
+// exec runs a statement with the guard, cache and query policies of the database applied.
+func (s *sqlxDB) exec(ctx context.Context, query string, args []any) (sql.Result, error) {
+	if err := checkGuard(ctx, s.guard, query); err != nil {
+		return nil, err
+	}
+	defer s.invalidate(query)
+	var r sql.Result
+	err := s.run(ctx, func(ctx context.Context) error {
+		var err error
+		r, err = s.db.ExecContext(ctx, query, args...)
+		return err
+	})
+	return r, err
+}
+
 func (s *sqlxDB) Query(query string, args ...any) (*sqlx.Rows, error) {
-	return s.db.Queryx(query, args...)
+	return s.QueryContext(context.Background(), query, args...)
+}
+
+// ExecReturning and its variants run through Get, Select or IDExec, which apply the guard.
+func (s *sqlxDB) ExecReturning(dest any, query string, args ...any) error {
+	return execReturning(context.Background(), s, dest, query, args)
+}
+
+func (s *sqlxDB) ExecReturningContext(ctx context.Context, dest any, query string, args ...any) error {
+	return execReturning(ctx, s, dest, query, args)
+}
+
+func (s *sqlxDB) NamedExecReturning(dest any, query string, arg any) error {
+	return namedExecReturning(context.Background(), s, dest, query, arg)
+}
+
+func (s *sqlxDB) NamedExecReturningContext(ctx context.Context, dest any, query string, arg any) error {
+	return namedExecReturning(ctx, s, dest, query, arg)
+}
+
+func (s *sqlxDB) NamedExecBatch(query string, slice any) (Result, error) {
+	return s.NamedExecBatchContext(context.Background(), query, slice)
+}
+
+func (s *sqlxDB) NamedExecBatchContext(ctx context.Context, query string, slice any) (Result, error) {
+	var res Result
+	err := s.run(ctx, func(ctx context.Context) error {
+		return s.Txc(ctx, func(tx Tx) error {
+			var err error
+			res, err = tx.NamedExecBatchContext(ctx, query, slice)
+			return err
+		})
+	})
+	return res, err
+}
+
+func (s *sqlxDB) QueryContext(ctx context.Context, query string, args ...any) (*sqlx.Rows, error) {
+	if err := checkGuard(ctx, s.guard, query); err != nil {
+		return nil, err
+	}
+	return s.reader(ctx).QueryxContext(ctx, query, args...)
 }
 
 func (s *sqlxDB) QueryRow(query string, args ...any) *sqlx.Row {
+	if err := checkGuard(context.Background(), s.guard, query); err != nil {
+		return errRow(err)
+	}
 	return s.db.QueryRowx(query, args...)
 }
 
+func (s *sqlxDB) QueryRowContext(ctx context.Context, query string, args ...any) (*sqlx.Row, error) {
+	if err := checkGuard(ctx, s.guard, query); err != nil {
+		return nil, queryError(query, err)
+	}
+	row := s.reader(ctx).QueryRowxContext(ctx, query, args...)
+	if err := row.Err(); err != nil {
+		return nil, queryError(query, err)
+	}
+	return row, nil
+}
+
+func (s *sqlxDB) GetRow(dest any, query string, args ...any) error {
+	return s.GetRowContext(context.Background(), dest, query, args...)
+}
+
+func (s *sqlxDB) GetRowContext(ctx context.Context, dest any, query string, args ...any) error {
+	if err := checkGuard(ctx, s.guard, query); err != nil {
+		return queryError(query, err)
+	}
+	return queryError(query, s.run(ctx, func(ctx context.Context) error {
+		return s.reader(ctx).GetContext(ctx, dest, query, args...)
+	}))
+}
+
+func (s *sqlxDB) MustExec(query string, args ...any) Result {
+	return Mustv(s.Exec(query, args...))
+}
+
+func (s *sqlxDB) MustQuery(query string, args ...any) *sqlx.Rows {
+	return Mustv(s.Query(query, args...))
+}
+
+// MustQueryRow panics with the query error instead of deferring it to Scan.
+func (s *sqlxDB) MustQueryRow(query string, args ...any) *sqlx.Row {
+	return Mustv(s.QueryRowContext(context.Background(), query, args...))
+}
+
+func (s *sqlxDB) MustGet(dest any, query string, args ...any) {
+	Must(s.Get(dest, query, args...))
+}
+
+func (s *sqlxDB) MustGetIn(dest any, query string, args ...any) {
+	Must(s.GetIn(dest, query, args...))
+}
+
+func (s *sqlxDB) MustSelect(dest any, query string, args ...any) {
+	Must(s.Select(dest, query, args...))
+}
+
+func (s *sqlxDB) MustSelectIn(dest any, query string, args ...any) {
+	Must(s.SelectIn(dest, query, args...))
+}
+
 func (s *sqlxDB) Prepare(query string) (*sqlx.Stmt, error) {
-	return s.db.Preparex(query)
+	return s.Preparex(query)
 }
 
 func (s *sqlxDB) Preparex(query string) (*sqlx.Stmt, error) {
+	if err := checkGuard(context.Background(), s.guard, query); err != nil {
+		return nil, err
+	}
 	return s.db.Preparex(query)
 }
 
+func (s *sqlxDB) PrepareNamed(query string) (*sqlx.NamedStmt, error) {
+	if err := checkGuard(context.Background(), s.guard, query); err != nil {
+		return nil, err
+	}
+	return s.db.PrepareNamed(query)
+}
+
 func (s *sqlxDB) Rebind(query string) string {
 	return s.db.Rebind(query)
 }
@@ -195,39 +368,56 @@ func (s *sqlxDB) BindNamed(query string, arg any) (string, []any, error) {
 }
 
 func (s *sqlxDB) Get(dest any, query string, args ...any) error {
-	return s.db.Get(dest, query, args...)
+	return s.GetContext(context.Background(), dest, query, args...)
 }
 
 func (s *sqlxDB) GetIn(dest any, query string, args ...any) error {
-	q, p, err := sqlx.In(query, args...)
-	if err != nil {
-		return err
-	}
-	return s.db.GetContext(context.Background(), dest, q, p...)
+	return s.GetInContext(context.Background(), dest, query, args...)
 }
 
 func (s *sqlxDB) GetInContext(ctx context.Context, dest any, query string, args ...any) error {
+	if err := checkGuard(ctx, s.guard, query); err != nil {
+		return err
+	}
 	q, p, err := sqlx.In(query, args...)
 	if err != nil {
 		return err
 	}
-	return s.db.GetContext(ctx, dest, q, p...)
+	return s.run(ctx, func(ctx context.Context) error {
+		return s.reader(ctx).GetContext(ctx, dest, q, p...)
+	})
 }
 
 func (s *sqlxDB) GetContext(ctx context.Context, dest any, query string, args ...any) error {
-	return s.db.GetContext(ctx, dest, query, args...)
+	if err := checkGuard(ctx, s.guard, query); err != nil {
+		return err
+	}
+	return s.cached(dest, query, args, func() error {
+		return s.run(ctx, func(ctx context.Context) error {
+			return s.reader(ctx).GetContext(ctx, dest, query, args...)
+		})
+	})
 }
 
 func (s *sqlxDB) Select(dest any, query string, args ...any) error {
-	return s.db.Select(dest, query, args...)
+	return s.SelectContext(context.Background(), dest, query, args...)
 }
 
 func (s *sqlxDB) SelectIn(dest any, query string, args ...any) error {
+	return s.SelectInContext(context.Background(), dest, query, args...)
+}
+
+func (s *sqlxDB) SelectInContext(ctx context.Context, dest any, query string, args ...any) error {
+	if err := checkGuard(ctx, s.guard, query); err != nil {
+		return err
+	}
 	q, p, err := sqlx.In(query, args...)
 	if err != nil {
 		return err
 	}
-	return s.db.SelectContext(context.Background(), dest, q, p...)
+	return s.run(ctx, func(ctx context.Context) error {
+		return s.reader(ctx).SelectContext(ctx, dest, q, p...)
+	})
 }
 
 func (s *sqlxDB) SelectInSeq(query string, args ...any) *RowsSeq {
@@ -235,7 +425,7 @@ func (s *sqlxDB) SelectInSeq(query string, args ...any) *RowsSeq {
 	if err != nil {
 		return &RowsSeq{err: err}
 	}
-	rows, err := s.db.Queryx(q, p...)
+	rows, err := s.Query(q, p...)
 	return &RowsSeq{
 		rows: rows,
 		err:  err,
@@ -243,41 +433,97 @@ func (s *sqlxDB) SelectInSeq(query string, args ...any) *RowsSeq {
 }
 
 func (s *sqlxDB) SelectSeq(query string, args ...any) *RowsSeq {
-	rows, err := s.db.Queryx(query, args...)
+	rows, err := s.Query(query, args...)
+	return &RowsSeq{
+		rows: rows,
+		err:  err,
+	}
+}
+
+func (s *sqlxDB) SelectInSeqContext(ctx context.Context, query string, args ...any) *RowsSeq {
+	q, p, err := sqlx.In(query, args...)
+	if err != nil {
+		return &RowsSeq{err: err}
+	}
+	return s.SelectSeqContext(ctx, q, p...)
+}
+
+func (s *sqlxDB) SelectSeqContext(ctx context.Context, query string, args ...any) *RowsSeq {
+	rows, err := s.QueryContext(ctx, query, args...)
 	return &RowsSeq{
 		rows: rows,
 		err:  err,
+		ctx:  ctx,
 	}
 }
 
 func (s *sqlxDB) SelectContext(ctx context.Context, dest any, query string, args ...any) error {
-	return s.db.SelectContext(ctx, dest, query, args...)
+	if err := checkGuard(ctx, s.guard, query); err != nil {
+		return err
+	}
+	return s.cached(dest, query, args, func() error {
+		return s.run(ctx, func(ctx context.Context) error {
+			return s.reader(ctx).SelectContext(ctx, dest, query, args...)
+		})
+	})
 }
 
-func (s *sqlxDB) NamedExec(query string, arg any) (sql.Result, error) {
-	return s.db.NamedExec(query, arg)
+func (s *sqlxDB) NamedExec(query string, arg any) (Result, error) {
+	if err := checkGuard(context.Background(), s.guard, query); err != nil {
+		return nil, err
+	}
+	defer s.invalidate(query)
+	var r sql.Result
+	err := s.run(context.Background(), func(ctx context.Context) error {
+		var err error
+		r, err = s.db.NamedExecContext(ctx, query, arg)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sqltResult{r}, nil
 }
 
 func (s *sqlxDB) NamedQuery(query string, arg any) (*sqlx.Rows, error) {
+	if err := checkGuard(context.Background(), s.guard, query); err != nil {
+		return nil, err
+	}
 	return s.db.NamedQuery(query, arg)
 }
 
+func (s *sqlxDB) Capabilities(ctx context.Context) (*Capabilities, error) {
+	if caps := s.state.caps.Load(); caps != nil {
+		return caps, nil
+	}
+	caps, err := DetectCapabilities(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	s.state.caps.Store(caps)
+	return caps, nil
+}
+
 func (s *sqlxDB) Close() error {
 	return s.db.Close()
 }
 
 func (s *sqlxDB) Tx(fn func(tx Tx) error) error {
-	return transaction(context.Background(), s.db, false, fn)
+	defer s.invalidateAll()
+	return transaction(context.Background(), s, false, fn)
 }
 
 func (s *sqlxDB) Txc(ctx context.Context, fn func(tx Tx) error) error {
-	return transaction(ctx, s.db, false, fn)
+	defer s.invalidateAll()
+	return transaction(ctx, s, false, fn)
 }
 
 func (s *sqlxDB) TxImm(fn func(tx Tx) error) error {
-	return transaction(context.Background(), s.db, true, fn)
+	defer s.invalidateAll()
+	return transaction(context.Background(), s, true, fn)
 }
 
 func (s *sqlxDB) TxcImm(ctx context.Context, fn func(tx Tx) error) error {
-	return transaction(ctx, s.db, true, fn)
+	defer s.invalidateAll()
+	return transaction(ctx, s, true, fn)
 }
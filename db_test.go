@@ -0,0 +1,595 @@
+package sqlt_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/james-darko/gort"
+	"github.com/james-darko/sqlt"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenMemory(t *testing.T) {
+	t.Parallel()
+	ctx := gort.Context()
+
+	a, err := sqlt.OpenMemory("shared-name")
+	require.NoError(t, err)
+	defer a.Close()
+	b, err := sqlt.OpenMemory("shared-name")
+	require.NoError(t, err)
+	defer b.Close()
+
+	// Holding a connection makes the pool create another one for the CREATE TABLE.
+	conn, err := a.SQLX().Connx(ctx)
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = a.ExecContext(ctx, "CREATE TABLE t (id INTEGER PRIMARY KEY)")
+	require.NoError(t, err)
+
+	var count int
+	require.NoError(t, conn.GetContext(ctx, &count, "SELECT COUNT(*) FROM sqlite_master WHERE name = 't'"))
+	assert.Equal(t, 1, count)
+
+	require.NoError(t, b.GetContext(ctx, &count, "SELECT COUNT(*) FROM sqlite_master WHERE name = 't'"))
+	assert.Equal(t, 0, count, "databases opened with the same name must be isolated")
+}
+
+func TestWrapStd(t *testing.T) {
+	t.Parallel()
+	ctx := gort.Context()
+	std, err := sql.Open("sqlite3", "file:"+filepath.Join(t.TempDir(), "std.db"))
+	require.NoError(t, err)
+	db := sqlt.WrapStd(std, "sqlite3")
+	defer db.Close()
+
+	assert.Same(t, std, db.Std())
+	assert.Equal(t, "sqlite3", db.DriverName())
+	_, err = std.ExecContext(ctx, "CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT); INSERT INTO users (name) VALUES ('ada')")
+	require.NoError(t, err)
+	var user struct{ Name string }
+	require.NoError(t, db.GetContext(ctx, &user, "SELECT name FROM users"))
+	assert.Equal(t, "ada", user.Name)
+}
+
+func TestTx_SQLXTx(t *testing.T) {
+	t.Parallel()
+	db := getTestDB(t)
+	defer db.Close()
+	ctx := gort.Context()
+
+	_, err := db.ExecContext(ctx, "CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)")
+	require.NoError(t, err)
+	err = db.Txc(ctx, func(tx sqlt.Tx) error {
+		stmt, err := tx.SQLXTx().PrepareNamed("INSERT INTO users (name) VALUES (:name)")
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+		for _, name := range []string{"ada", "grace"} {
+			if _, err := stmt.Exec(map[string]any{"name": name}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	var count int
+	require.NoError(t, db.GetContext(ctx, &count, "SELECT COUNT(*) FROM users"))
+	assert.Equal(t, 2, count)
+}
+
+func TestTx_Prepare(t *testing.T) {
+	t.Parallel()
+	db := getTestDB(t)
+	defer db.Close()
+	ctx := gort.Context()
+
+	_, err := db.ExecContext(ctx, "CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT)")
+	require.NoError(t, err)
+	err = db.Txc(ctx, func(tx sqlt.Tx) error {
+		stmt, err := tx.Preparex("INSERT INTO items (name) VALUES (?)")
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+		for i := range 100 {
+			if _, err := stmt.Exec(i); err != nil {
+				return err
+			}
+		}
+		named, err := tx.PrepareNamed("INSERT INTO items (name) VALUES (:name)")
+		if err != nil {
+			return err
+		}
+		defer named.Close()
+		_, err = named.Exec(map[string]any{"name": "last"})
+		return err
+	})
+	require.NoError(t, err)
+
+	var count int
+	require.NoError(t, db.GetContext(ctx, &count, "SELECT COUNT(*) FROM items"))
+	assert.Equal(t, 101, count)
+}
+
+func TestQueryRowContext_ReportsErrorsEagerly(t *testing.T) {
+	t.Parallel()
+	db := getTestDB(t)
+	defer db.Close()
+	ctx := gort.Context()
+
+	_, err := db.QueryRowContext(ctx, "SELECT missing FROM nowhere")
+	var queryErr *sqlt.QueryError
+	require.ErrorAs(t, err, &queryErr)
+	assert.Equal(t, "SELECT missing FROM nowhere", queryErr.Query)
+
+	row, err := db.QueryRowContext(ctx, "SELECT 1 WHERE 0")
+	require.NoError(t, err)
+	var n int
+	assert.ErrorIs(t, row.Scan(&n), sql.ErrNoRows)
+
+	err = db.GetRowContext(ctx, &n, "SELECT 1 WHERE 0")
+	require.ErrorAs(t, err, &queryErr)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	err = db.Txc(ctx, func(tx sqlt.Tx) error {
+		_, err := tx.QueryRowContext(ctx, "SELECT missing FROM nowhere")
+		return err
+	})
+	require.ErrorAs(t, err, &queryErr)
+
+	assert.Panics(t, func() { db.MustQueryRow("SELECT missing FROM nowhere") })
+	assert.Panics(t, func() { db.MustGetIn(&n, "SELECT 1 WHERE 1 IN (?)", []int{}) })
+}
+
+func TestHandle_SharedHelpers(t *testing.T) {
+	t.Parallel()
+	db := getTestDB(t)
+	defer db.Close()
+	ctx := gort.Context()
+
+	insert := func(s sqlt.Writer, name string) (int64, error) {
+		return s.IDExecContext(ctx, "INSERT INTO tags (name) VALUES (?)", name)
+	}
+	countIn := func(s sqlt.Reader, names []string) int {
+		var n int
+		s.MustGetIn(&n, "SELECT COUNT(*) FROM tags WHERE name IN (?)", names)
+		return n
+	}
+
+	db.MustExec("CREATE TABLE tags (id INTEGER PRIMARY KEY, name TEXT)")
+	_, err := insert(db, "a")
+	require.NoError(t, err)
+	err = db.Txc(ctx, func(tx sqlt.Tx) error {
+		if _, err := insert(tx, "b"); err != nil {
+			return err
+		}
+		assert.Equal(t, 2, countIn(tx, []string{"a", "b"}))
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, countIn(db, []string{"a", "b", "c"}))
+}
+
+func TestExecReturning(t *testing.T) {
+	t.Parallel()
+	db := getTestDB(t)
+	defer db.Close()
+	ctx := gort.Context()
+
+	db.MustExec("CREATE TABLE docs (id TEXT PRIMARY KEY, n INTEGER, created INTEGER DEFAULT 42)")
+	db.MustExec("CREATE TABLE counters (id INTEGER PRIMARY KEY, n INTEGER)")
+
+	var id string
+	require.NoError(t, db.ExecReturningContext(ctx, &id, "INSERT INTO docs (id, n) VALUES (?, ?) RETURNING id", "doc-1", 1))
+	assert.Equal(t, "doc-1", id)
+
+	var row struct {
+		ID      string `db:"id"`
+		Created int    `db:"created"`
+	}
+	require.NoError(t, db.ExecReturning(&row, "INSERT INTO docs (id, n) VALUES (?, ?) returning id, created", "doc-2", 2))
+	assert.Equal(t, "doc-2", row.ID)
+	assert.Equal(t, 42, row.Created)
+
+	var ids []string
+	require.NoError(t, db.ExecReturning(&ids, "UPDATE docs SET n = n + 1 RETURNING id"))
+	assert.ElementsMatch(t, []string{"doc-1", "doc-2"}, ids)
+
+	err := db.Txc(ctx, func(tx sqlt.Tx) error {
+		var rowid int64
+		if err := tx.ExecReturning(&rowid, "INSERT INTO counters (n) VALUES (?)", 7); err != nil {
+			return err
+		}
+		assert.Equal(t, int64(1), rowid)
+		return nil
+	})
+	require.NoError(t, err)
+
+	assert.Error(t, db.ExecReturning(&id, "INSERT INTO counters (n) VALUES (?)", 8), "rowid cannot be stored in a string")
+}
+
+func TestNamedExecReturning(t *testing.T) {
+	t.Parallel()
+	db := getTestDB(t)
+	defer db.Close()
+	ctx := gort.Context()
+
+	db.MustExec("CREATE TABLE posts (id INTEGER PRIMARY KEY, title TEXT NOT NULL, created_at INTEGER NOT NULL DEFAULT 1700000000)")
+
+	type post struct {
+		ID        int64  `db:"id"`
+		Title     string `db:"title"`
+		CreatedAt int64  `db:"created_at"`
+	}
+	p := &post{Title: "hello"}
+	require.NoError(t, db.NamedExecReturningContext(ctx, p, "INSERT INTO posts (title) VALUES (:title) RETURNING id, created_at", p))
+	assert.Equal(t, int64(1), p.ID)
+	assert.Equal(t, int64(1700000000), p.CreatedAt)
+	assert.Equal(t, "hello", p.Title)
+
+	err := db.Txc(ctx, func(tx sqlt.Tx) error {
+		var id int64
+		if err := tx.NamedExecReturning(&id, "INSERT INTO posts (title) VALUES (:title)", post{Title: "second"}); err != nil {
+			return err
+		}
+		assert.Equal(t, int64(2), id)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestNamedExecBatch(t *testing.T) {
+	t.Parallel()
+	db := getTestDB(t)
+	defer db.Close()
+	ctx := gort.Context()
+
+	db.MustExec("CREATE TABLE points (id INTEGER PRIMARY KEY, x INTEGER, y INTEGER)")
+	type point struct {
+		X int `db:"x"`
+		Y int `db:"y"`
+	}
+	// 1200 rows of two parameters need two statements under BatchParamLimit.
+	points := make([]point, 1200)
+	for i := range points {
+		points[i] = point{X: i, Y: -i}
+	}
+	res, err := db.NamedExecBatchContext(ctx, "INSERT INTO points (x, y) VALUES (:x, :y)", points)
+	require.NoError(t, err)
+	affected, err := res.RowsAffected()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1200), affected)
+	lastID, err := res.LastInsertId()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1200), lastID)
+
+	err = db.Txc(ctx, func(tx sqlt.Tx) error {
+		res, err := tx.NamedExecBatch("UPDATE points SET y = 0 WHERE x = :x", []map[string]any{{"x": 1}, {"x": 2}, {"x": -1}})
+		if err != nil {
+			return err
+		}
+		affected, err := res.RowsAffected()
+		assert.Equal(t, int64(2), affected)
+		return err
+	})
+	require.NoError(t, err)
+
+	_, err = db.NamedExecBatch("INSERT INTO points (id, x) VALUES (:id, :x)", []map[string]any{{"id": 5000, "x": 1}, {"id": 1, "x": 2}})
+	require.Error(t, err)
+	var count int
+	require.NoError(t, db.Get(&count, "SELECT COUNT(*) FROM points WHERE id = 5000"))
+	assert.Equal(t, 0, count, "a failed batch must be rolled back")
+}
+
+func TestCapabilities(t *testing.T) {
+	t.Parallel()
+	db := getTestDB(t)
+	defer db.Close()
+	ctx := gort.Context()
+
+	caps, err := db.Capabilities(ctx)
+	require.NoError(t, err)
+	assert.NotEmpty(t, caps.SQLiteVersion)
+	// The bundled SQLite of go-sqlite3 is recent enough for all ALTER TABLE forms.
+	assert.True(t, caps.Returning)
+	assert.True(t, caps.DropColumn)
+	assert.True(t, caps.RenameColumn)
+	assert.True(t, caps.StrictTables)
+	assert.True(t, caps.JSON)
+
+	cached, err := db.Capabilities(ctx)
+	require.NoError(t, err)
+	assert.Same(t, caps, cached)
+}
+
+func TestWatch(t *testing.T) {
+	t.Parallel()
+	// data_version is shared by the connections of an in-memory database, so use a file.
+	db, err := sqlt.Open("sqlite3", filepath.Join(t.TempDir(), "watch.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	ctx, cancel := context.WithCancel(gort.Context())
+	defer cancel()
+
+	_, err = db.ExecContext(ctx, "CREATE TABLE t (id INTEGER PRIMARY KEY)")
+	require.NoError(t, err)
+	events := db.Watch(ctx, []string{"t"})
+	// Wait for the watch connection to read the initial data_version.
+	time.Sleep(sqlt.WatchInterval / 2)
+	_, err = db.ExecContext(ctx, "INSERT INTO t (id) VALUES (1)")
+	require.NoError(t, err)
+
+	select {
+	case ev := <-events:
+		require.NoError(t, ev.Err)
+		assert.Equal(t, []string{"t"}, ev.Tables)
+	case <-time.After(10 * sqlt.WatchInterval):
+		t.Fatal("no change event")
+	}
+
+	cancel()
+	for range events {
+	}
+}
+
+func TestWatch_SingleConnection(t *testing.T) {
+	t.Parallel()
+	db, err := sqlt.Open("sqlite3", filepath.Join(t.TempDir(), "watch.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	db.SQLX().SetMaxOpenConns(1)
+
+	select {
+	case ev := <-db.Watch(gort.Context(), nil):
+		assert.ErrorContains(t, ev.Err, "MaxOpenConns")
+	case <-time.After(time.Second):
+		t.Fatal("Watch did not reject the single connection pool")
+	}
+	var n int
+	require.NoError(t, db.Get(&n, "SELECT 1"), "the only connection must stay available")
+}
+
+func TestWithGuard(t *testing.T) {
+	t.Parallel()
+	raw, err := sqlt.OpenMemory(t.Name())
+	require.NoError(t, err)
+	db := sqlt.Wrap(raw.SQLX(), sqlt.WithGuard())
+	defer db.Close()
+	ctx := gort.Context()
+
+	db.MustExec("CREATE TABLE t (id INTEGER PRIMARY KEY, v INTEGER)")
+	db.MustExec("INSERT INTO t (id, v) VALUES (1, 1), (2, 2)")
+
+	_, err = db.Exec("UPDATE t SET v = 0")
+	assert.ErrorIs(t, err, sqlt.ErrUnboundedWrite)
+	_, err = db.ExecContext(ctx, "DELETE FROM t")
+	assert.ErrorIs(t, err, sqlt.ErrUnboundedWrite)
+	err = db.Tx(func(tx sqlt.Tx) error {
+		_, err := tx.Exec("delete from t")
+		return err
+	})
+	assert.ErrorIs(t, err, sqlt.ErrUnboundedWrite)
+
+	_, err = db.Query("DELETE FROM t RETURNING id")
+	assert.ErrorIs(t, err, sqlt.ErrUnboundedWrite)
+	var ids []int
+	assert.ErrorIs(t, db.SelectContext(ctx, &ids, "UPDATE t SET v = 0 RETURNING id"), sqlt.ErrUnboundedWrite)
+	var id int
+	assert.ErrorIs(t, db.QueryRow("DELETE FROM t RETURNING id").Scan(&id), sqlt.ErrUnboundedWrite)
+	_, err = db.NamedQuery("DELETE FROM t RETURNING id", map[string]any{})
+	assert.ErrorIs(t, err, sqlt.ErrUnboundedWrite)
+	err = db.Tx(func(tx sqlt.Tx) error {
+		_, err := tx.QueryContext(ctx, "DELETE FROM t RETURNING id")
+		return err
+	})
+	assert.ErrorIs(t, err, sqlt.ErrUnboundedWrite)
+
+	rows := []struct{ V int }{{V: 0}}
+	_, err = db.NamedExecBatch("UPDATE t SET v = :v", rows)
+	assert.ErrorIs(t, err, sqlt.ErrUnboundedWrite)
+	err = db.Tx(func(tx sqlt.Tx) error {
+		_, err := tx.NamedExecBatch("DELETE FROM t", rows)
+		return err
+	})
+	assert.ErrorIs(t, err, sqlt.ErrUnboundedWrite)
+	var count int
+	require.NoError(t, db.Get(&count, "SELECT COUNT(*) FROM t WHERE v > 0"))
+	assert.Equal(t, 2, count, "rejected statements must not run")
+
+	_, err = db.NamedExecBatchContext(sqlt.AllowUnboundedWrite(ctx), "UPDATE t SET v = :v", rows)
+	require.NoError(t, err)
+	n, err := db.AffectedExec("UPDATE t SET v = 0 WHERE id = 1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	n, err = db.AffectedExecContext(sqlt.AllowUnboundedWrite(ctx), "DELETE FROM t")
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+}
+
+func TestRedactParams(t *testing.T) {
+	t.Parallel()
+	raw, err := sqlt.OpenMemory(t.Name())
+	require.NoError(t, err)
+	db := sqlt.Wrap(raw.SQLX(), sqlt.RedactParams(func(i int, name string, v any) any {
+		if i == 1 || name == "email" {
+			return "***"
+		}
+		return v
+	}))
+	defer db.Close()
+
+	args := []any{1, "secret", sql.Named("email", "a@example.com")}
+	assert.Equal(t, []any{1, "***", sql.Named("email", "***")}, sqlt.RedactedArgs(db, args...))
+	assert.Equal(t, []any{1, "secret", sql.Named("email", "a@example.com")}, args)
+	assert.Equal(t, args, sqlt.RedactedArgs(raw, args...))
+}
+
+func TestQueryPolicies(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "policies.db") + "?_busy_timeout=0"
+	locker, err := sqlt.Open("sqlite3", path)
+	require.NoError(t, err)
+	defer locker.Close()
+	db, err := sqlt.Open("sqlite3", path, sqlt.WithRetry(50, 10*time.Millisecond), sqlt.WithQueryTimeout(time.Millisecond))
+	require.NoError(t, err)
+	defer db.Close()
+	ctx := gort.Context()
+
+	slow := "WITH RECURSIVE c(n) AS (SELECT 1 UNION ALL SELECT n + 1 FROM c WHERE n < 100000000) SELECT COUNT(*) FROM c"
+	var count int
+	assert.Error(t, db.GetContext(ctx, &count, slow))
+
+	ctx = sqlt.WithTimeout(ctx, 0)
+	_, err = db.ExecContext(ctx, "CREATE TABLE t (id INTEGER PRIMARY KEY)")
+	require.NoError(t, err)
+
+	conn, err := locker.SQLX().Connx(ctx)
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.ExecContext(ctx, "BEGIN IMMEDIATE")
+	require.NoError(t, err)
+
+	_, err = db.ExecContext(sqlt.WithNoRetry(ctx), "INSERT INTO t (id) VALUES (1)")
+	assert.ErrorContains(t, err, "database is locked")
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		conn.ExecContext(ctx, "COMMIT")
+	}()
+	_, err = db.ExecContext(ctx, "INSERT INTO t (id) VALUES (2)")
+	assert.NoError(t, err)
+}
+
+func TestQueryPolicies_Tx(t *testing.T) {
+	t.Parallel()
+	db, err := sqlt.Open("sqlite3", filepath.Join(t.TempDir(), "bounded.db"), sqlt.WithQueryTimeout(time.Millisecond))
+	require.NoError(t, err)
+	defer db.Close()
+	ctx := gort.Context()
+
+	slow := "WITH RECURSIVE c(n) AS (SELECT 1 UNION ALL SELECT n + 1 FROM c WHERE n < 100000000) SELECT COUNT(*) FROM c WHERE n IN (?)"
+	var count int
+	assert.Error(t, db.GetInContext(ctx, &count, slow, []int{1, 2}), "In queries are bounded")
+	err = db.Tx(func(tx sqlt.Tx) error {
+		return tx.GetInContext(ctx, &count, slow, []int{1, 2})
+	})
+	assert.Error(t, err, "statements in transactions are bounded by WithQueryTimeout")
+
+	plain, err := sqlt.Open("sqlite3", filepath.Join(t.TempDir(), "plain.db"))
+	require.NoError(t, err)
+	defer plain.Close()
+	plain.MustExec("CREATE TABLE t (id INTEGER PRIMARY KEY)")
+	err = plain.Tx(func(tx sqlt.Tx) error {
+		_, err := tx.ExecContext(sqlt.WithTimeout(ctx, 2*time.Millisecond), "INSERT INTO t (id) "+
+			"WITH RECURSIVE c(n) AS (SELECT 1 UNION ALL SELECT n + 1 FROM c WHERE n < 100000000) SELECT n FROM c WHERE n < 0")
+		return err
+	})
+	assert.Error(t, err, "WithTimeout applies to statements in transactions")
+}
+
+func TestLockError(t *testing.T) {
+	t.Parallel()
+	db, err := sqlt.Open("sqlite3", filepath.Join(t.TempDir(), "lock.db")+"?_busy_timeout=100")
+	require.NoError(t, err)
+	defer db.Close()
+	db.MustExec("CREATE TABLE t (id INTEGER PRIMARY KEY)")
+
+	var inner error
+	err = db.Tx(func(tx sqlt.Tx) error {
+		tx.MustExec("INSERT INTO t (id) VALUES (1)")
+		inner = db.Tx(func(tx sqlt.Tx) error {
+			_, err := tx.Exec("INSERT INTO t (id) VALUES (2)")
+			return err
+		})
+		return nil
+	})
+	require.NoError(t, err)
+
+	var lerr *sqlt.LockError
+	require.ErrorAs(t, inner, &lerr)
+	assert.True(t, lerr.WriteLockHeld)
+	assert.Equal(t, 100*time.Millisecond, lerr.BusyTimeout)
+	assert.Equal(t, "delete", lerr.JournalMode)
+	assert.GreaterOrEqual(t, lerr.Waited, 100*time.Millisecond)
+	assert.ErrorContains(t, inner, "database is locked")
+
+	err = db.Unsafe().Tx(func(tx sqlt.Tx) error {
+		tx.MustExec("INSERT INTO t (id) VALUES (3)")
+		inner = db.Tx(func(tx sqlt.Tx) error {
+			_, err := tx.Exec("INSERT INTO t (id) VALUES (4)")
+			return err
+		})
+		return nil
+	})
+	require.NoError(t, err)
+	require.ErrorAs(t, inner, &lerr)
+	assert.True(t, lerr.WriteLockHeld, "writes through Unsafe count for the database")
+}
+
+func TestUnsafeScan(t *testing.T) {
+	t.Parallel()
+	db, err := sqlt.OpenMemory(t.Name())
+	require.NoError(t, err)
+	defer db.Close()
+	ctx := gort.Context()
+
+	_, err = db.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, email TEXT)")
+	require.NoError(t, err)
+	_, err = db.Exec("INSERT INTO users (name, email) VALUES ('alice', 'alice@example.com')")
+	require.NoError(t, err)
+
+	type user struct {
+		ID   int    `db:"id"`
+		Name string `db:"name"`
+	}
+	var u user
+	assert.Error(t, db.GetContext(ctx, &u, "SELECT * FROM users"))
+	require.NoError(t, db.GetContext(sqlt.WithUnsafeScan(ctx), &u, "SELECT * FROM users"))
+	assert.Equal(t, "alice", u.Name)
+
+	var users []user
+	require.NoError(t, db.Unsafe().Select(&users, "SELECT * FROM users"))
+	assert.Len(t, users, 1)
+	err = db.Unsafe().Tx(func(tx sqlt.Tx) error {
+		return tx.Get(&u, "SELECT * FROM users")
+	})
+	assert.NoError(t, err)
+}
+
+func TestExistsCount(t *testing.T) {
+	t.Parallel()
+	db, err := sqlt.OpenMemory(t.Name())
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, active BOOLEAN)")
+	require.NoError(t, err)
+	_, err = db.Exec("INSERT INTO users (name, active) VALUES ('alice', 1), ('bob', 0), ('carol', 1)")
+	require.NoError(t, err)
+
+	exists, err := db.Exists("SELECT 1 FROM users WHERE name = ?", "bob")
+	require.NoError(t, err)
+	assert.True(t, exists)
+	exists, err = db.Exists("SELECT 1 FROM users WHERE name = ?;", "dave")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	count, err := db.Count("SELECT id FROM users WHERE active")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	err = db.Tx(func(tx sqlt.Tx) error {
+		count, err := tx.Count("SELECT id FROM users WHERE name = ?", "dave")
+		assert.Zero(t, count)
+		return err
+	})
+	assert.NoError(t, err)
+}
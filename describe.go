@@ -0,0 +1,163 @@
+package sqlt
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// DescribeOption configures DescribeSchema.
+type DescribeOption func(*describeConfig)
+
+type describeConfig struct {
+	rowCounts bool
+	sql       bool
+}
+
+// WithoutRowCounts leaves the row counts out of DescribeSchema, which scans every table to count them.
+func WithoutRowCounts() DescribeOption {
+	return func(cfg *describeConfig) {
+		cfg.rowCounts = false
+	}
+}
+
+// WithObjectSQL adds the CREATE statement of every object to the output of DescribeSchema.
+func WithObjectSQL() DescribeOption {
+	return func(cfg *describeConfig) {
+		cfg.sql = true
+	}
+}
+
+// DescribeSchema writes a human readable description of the database schema to w, for debug
+// endpoints and CLI output. Tables are listed with their row count, columns, foreign keys, indexes
+// and triggers, followed by the views. Objects are sorted by name.
+func DescribeSchema(ctx context.Context, db DB, w io.Writer, opts ...DescribeOption) error {
+	cfg := &describeConfig{rowCounts: true}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	schema, err := FetchDBSchema(ctx, db)
+	if err != nil {
+		return fmt.Errorf("could not fetch schema: %w", err)
+	}
+	var b strings.Builder
+	if len(schema.Tables) == 0 && len(schema.Views) == 0 {
+		b.WriteString("no tables found\n")
+	}
+	for _, table := range sortedByName(schema.Tables, func(t *TableDefinition) string { return t.Name }) {
+		fmt.Fprintf(&b, "table %s", table.Name)
+		if cfg.rowCounts {
+			var count int64
+			if err := db.GetContext(ctx, &count, "SELECT COUNT(*) FROM "+quoteIdent(table.Name)); err != nil {
+				return fmt.Errorf("could not count rows of %s: %w", table.Name, err)
+			}
+			fmt.Fprintf(&b, " (%d rows)", count)
+		}
+		var options []string
+		if table.WithoutRowid {
+			options = append(options, "WITHOUT ROWID")
+		}
+		if table.Strict {
+			options = append(options, "STRICT")
+		}
+		if len(options) > 0 {
+			fmt.Fprintf(&b, " %s", strings.Join(options, ", "))
+		}
+		b.WriteString("\n")
+		for _, col := range table.Columns {
+			fmt.Fprintf(&b, "  column %s\n", describeColumn(col))
+		}
+		if len(table.PrimaryKey) > 1 {
+			fmt.Fprintf(&b, "  primary key (%s)\n", strings.Join(table.PrimaryKey, ", "))
+		}
+		for _, fk := range table.ForeignKeys {
+			fmt.Fprintf(&b, "  foreign key (%s) references %s", strings.Join(fk.Columns, ", "), fk.ForeignTable)
+			if len(fk.ForeignColumns) > 0 {
+				fmt.Fprintf(&b, " (%s)", strings.Join(fk.ForeignColumns, ", "))
+			}
+			if fk.OnUpdate != "" {
+				fmt.Fprintf(&b, " on update %s", fk.OnUpdate)
+			}
+			if fk.OnDelete != "" {
+				fmt.Fprintf(&b, " on delete %s", fk.OnDelete)
+			}
+			b.WriteString("\n")
+		}
+		for _, index := range sortedByName(schema.Indexes, func(i *IndexDefinition) string { return i.Name }) {
+			if !strings.EqualFold(index.Table, table.Name) {
+				continue
+			}
+			fmt.Fprintf(&b, "  index %s (%s)", index.Name, strings.Join(index.Columns, ", "))
+			if index.Unique {
+				b.WriteString(" unique")
+			}
+			if index.Where != "" {
+				fmt.Fprintf(&b, " where %s", index.Where)
+			}
+			b.WriteString("\n")
+			describeSQL(&b, cfg, index.SQL)
+		}
+		for _, trigger := range sortedByName(schema.Triggers, func(t *TriggerDefinition) string { return t.Name }) {
+			if strings.EqualFold(trigger.Table, table.Name) {
+				fmt.Fprintf(&b, "  trigger %s\n", trigger.Name)
+				describeSQL(&b, cfg, trigger.SQL)
+			}
+		}
+		describeSQL(&b, cfg, table.SQL)
+	}
+	for _, view := range sortedByName(schema.Views, func(v *ViewDefinition) string { return v.Name }) {
+		fmt.Fprintf(&b, "view %s\n", view.Name)
+		describeSQL(&b, cfg, view.SQL)
+	}
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+func describeColumn(col *ColumnDefinition) string {
+	parts := []string{col.Name}
+	if col.Type != "" {
+		parts = append(parts, col.Type)
+	}
+	if col.PrimaryKey {
+		parts = append(parts, "PRIMARY KEY")
+	}
+	if col.NotNull {
+		parts = append(parts, "NOT NULL")
+	}
+	if col.Unique {
+		parts = append(parts, "UNIQUE")
+	}
+	if col.DefaultValue != nil {
+		parts = append(parts, "DEFAULT "+*col.DefaultValue)
+	}
+	if col.Collation != "" {
+		parts = append(parts, "COLLATE "+col.Collation)
+	}
+	switch col.Hidden {
+	case 2:
+		parts = append(parts, "GENERATED VIRTUAL")
+	case 3:
+		parts = append(parts, "GENERATED STORED")
+	}
+	return strings.Join(parts, " ")
+}
+
+func describeSQL(b *strings.Builder, cfg *describeConfig, sql string) {
+	if cfg.sql && sql != "" {
+		fmt.Fprintf(b, "    %s\n", strings.ReplaceAll(sql, "\n", "\n    "))
+	}
+}
+
+// sortedByName returns the values of m sorted by the name returned by name.
+func sortedByName[T any](m map[string]T, name func(T) string) []T {
+	values := make([]T, 0, len(m))
+	for _, v := range m {
+		values = append(values, v)
+	}
+	slices.SortFunc(values, func(a, b T) int {
+		return strings.Compare(name(a), name(b))
+	})
+	return values
+}
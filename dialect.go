@@ -0,0 +1,545 @@
+package sqlt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// SchemaIntrospector reads the schema of a database of one engine.
+type SchemaIntrospector interface {
+	// FetchSchema returns the tables and indexes of the database. Indexes backing PRIMARY KEY and
+	// UNIQUE constraints are reported as the PrimaryKey and UniqueConstraints of their table.
+	FetchSchema(ctx context.Context, db Reader) (*SchemaDefinition, error)
+	// NormalizeType returns a declared column type as FetchSchema reports it, e.g. "integer" for SERIAL
+	// on PostgreSQL, so the types of a schema file can be compared with the database.
+	NormalizeType(decl string) string
+	// NormalizeExpr returns a default value or index expression as FetchSchema would report it.
+	// It returns "" for the defaults implied by a column type, such as the sequence of a SERIAL column.
+	NormalizeExpr(expr string) string
+}
+
+// DDLGenerator renders the statements creating and changing the objects of a schema for one engine.
+type DDLGenerator interface {
+	CreateTable(table *TableDefinition) string
+	DropTable(table *TableDefinition) string
+	AddColumn(table string, col *ColumnDefinition) string
+	DropColumn(table, column string) string
+	CreateIndex(index *IndexDefinition) string
+	DropIndex(index *IndexDefinition) string
+}
+
+// ErrUnsupportedDriver is returned by AutoMigrate for databases it cannot migrate, such as MySQL,
+// whose dialect only supports Verify.
+var ErrUnsupportedDriver = errors.New("unsupported driver")
+
+// Dialect adapts AutoMigrate and Verify to a database engine. The SQLite dialect uses the migration
+// engine built for SQLite, which rebuilds tables and manages views and triggers. Other dialects compare
+// the tables and indexes read by FetchSchema with the schema and apply the differences with the
+// statements of the DDLGenerator: tables and indexes are created and dropped, and columns added and
+// dropped as WithColumnAdditions, WithAllowColumnDrops and WithAllowTableDeletes allow. Other table
+// changes are reported as an ErrSchemaConflicts. Views, triggers, foreign keys and CHECK constraints
+// are created with their table but not compared. As with SQLite, the database is compared with the
+// schema once migrated, see WithVerifyAfter, but WithMigrationLock and WithHistory, whose tables are
+// written in SQLite SQL, make AutoMigrate return an error.
+//
+// The schema file is parsed as SQLite SQL, so it must use the subset of the engine's DDL the SQLite
+// grammar accepts, e.g. DEFAULT (now()) rather than DEFAULT now().
+type Dialect interface {
+	// Name returns the name of the engine, e.g. "sqlite" or "postgres".
+	Name() string
+	SchemaIntrospector
+	DDLGenerator
+}
+
+// DialectFor returns the dialect of the database/sql driver registered as driverName: Postgres for
+// "postgres", "pgx" and "pgx/v5", MySQL for "mysql", which only supports Verify, and SQLite for every
+// other driver.
+func DialectFor(driverName string) Dialect {
+	switch driverName {
+	case "postgres", "postgresql", "pgx", "pgx/v5":
+		return Postgres
+	case "mysql":
+		return MySQL
+	default:
+		return SQLite
+	}
+}
+
+// WithDialect makes AutoMigrate and Verify manage the database with d instead of the dialect
+// DialectFor returns for its driver name, for drivers registered under other names.
+func WithDialect(d Dialect) MigrateOption {
+	return func(cfg *migrateConfig) {
+		cfg.dialect = d
+	}
+}
+
+// dialectOf returns the dialect managing db, or nil if it is managed by the SQLite migration engine.
+func (cfg *migrateConfig) dialectOf(db DB) Dialect {
+	d := cfg.dialect
+	if d == nil {
+		d = DialectFor(db.DriverName())
+	}
+	if _, ok := d.(sqliteDialect); ok {
+		return nil
+	}
+	return d
+}
+
+// SQLite is the dialect of SQLite databases.
+var SQLite Dialect = sqliteDialect{}
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) Name() string { return "sqlite" }
+
+func (sqliteDialect) FetchSchema(ctx context.Context, db Reader) (*SchemaDefinition, error) {
+	return fetchDBSchema(ctx, db, nil)
+}
+
+func (sqliteDialect) NormalizeType(decl string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(decl), " "))
+}
+
+func (sqliteDialect) NormalizeExpr(expr string) string {
+	return strings.Join(strings.Fields(expr), " ")
+}
+
+func (sqliteDialect) CreateTable(table *TableDefinition) string { return table.SQL }
+
+func (sqliteDialect) DropTable(table *TableDefinition) string {
+	return "DROP TABLE " + quoteIdent(table.Name)
+}
+
+func (sqliteDialect) AddColumn(table string, col *ColumnDefinition) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", quoteIdent(table), columnSQL(col, quoteIdent))
+}
+
+func (sqliteDialect) DropColumn(table, column string) string {
+	return fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", quoteIdent(table), quoteIdent(column))
+}
+
+func (sqliteDialect) CreateIndex(index *IndexDefinition) string { return index.SQL }
+
+func (sqliteDialect) DropIndex(index *IndexDefinition) string {
+	return "DROP INDEX " + quoteIdent(index.Name)
+}
+
+// columnSQL renders the definition of col in CREATE TABLE and ADD COLUMN, quoting its name with quote.
+func columnSQL(col *ColumnDefinition, quote func(string) string) string {
+	var b strings.Builder
+	b.WriteString(quote(col.Name))
+	if col.Type != "" {
+		b.WriteString(" " + col.Type)
+	}
+	if col.NotNull {
+		b.WriteString(" NOT NULL")
+	}
+	if col.DefaultValue != nil {
+		b.WriteString(" DEFAULT " + *col.DefaultValue)
+	}
+	if col.Collation != "" {
+		b.WriteString(" COLLATE " + quoteIdent(col.Collation))
+	}
+	if col.Unique {
+		b.WriteString(" UNIQUE")
+	}
+	return b.String()
+}
+
+// createTableSQL renders CREATE TABLE for table, quoting names with ident and rendering CHECK
+// expressions with expr. Column UNIQUE constraints are declared on the table.
+func createTableSQL(table *TableDefinition, ident, expr func(string) string) string {
+	idents := func(names []string) string {
+		quoted := make([]string, len(names))
+		for i, name := range names {
+			quoted[i] = ident(name)
+		}
+		return strings.Join(quoted, ", ")
+	}
+	var defs []string
+	for _, col := range table.Columns {
+		c := *col
+		c.Unique = false
+		defs = append(defs, "\t"+columnSQL(&c, ident))
+	}
+	if len(table.PrimaryKey) > 0 {
+		defs = append(defs, fmt.Sprintf("\tPRIMARY KEY (%s)", idents(table.PrimaryKey)))
+	}
+	for _, col := range table.Columns {
+		if col.Unique {
+			defs = append(defs, fmt.Sprintf("\tUNIQUE (%s)", ident(col.Name)))
+		}
+	}
+	for _, columns := range table.UniqueConstraints {
+		defs = append(defs, fmt.Sprintf("\tUNIQUE (%s)", idents(columns)))
+	}
+	for _, fk := range table.ForeignKeys {
+		def := fmt.Sprintf("\tFOREIGN KEY (%s) REFERENCES %s", idents(fk.Columns), ident(fk.ForeignTable))
+		if len(fk.ForeignColumns) > 0 {
+			def += fmt.Sprintf(" (%s)", idents(fk.ForeignColumns))
+		}
+		if fk.OnUpdate != "" {
+			def += " ON UPDATE " + fk.OnUpdate
+		}
+		if fk.OnDelete != "" {
+			def += " ON DELETE " + fk.OnDelete
+		}
+		defs = append(defs, def)
+	}
+	for _, check := range table.Checks {
+		defs = append(defs, fmt.Sprintf("\tCHECK (%s)", expr(check)))
+	}
+	return fmt.Sprintf("CREATE TABLE %s (\n%s\n)", ident(table.Name), strings.Join(defs, ",\n"))
+}
+
+// compareTables returns the columns of desired missing from current, the columns of current missing from
+// desired, and the other differences between the tables, which adding and dropping columns cannot fix.
+func compareTables(d SchemaIntrospector, current, desired *TableDefinition) (added, removed []*ColumnDefinition, mismatches []string) {
+	for _, col := range desired.Columns {
+		cur := current.Column(col.Name)
+		if cur == nil {
+			added = append(added, col)
+			continue
+		}
+		if want, got := d.NormalizeType(col.Type), d.NormalizeType(cur.Type); want != got {
+			mismatches = append(mismatches, fmt.Sprintf("column %s type %s, schema %s", col.Name, got, want))
+		}
+		if want := col.NotNull || col.PrimaryKey; want != cur.NotNull {
+			mismatches = append(mismatches, fmt.Sprintf("column %s nullability (DB: not null %t, Schema: not null %t)", col.Name, cur.NotNull, want))
+		}
+		if want, got := normalizedDefault(d, col), normalizedDefault(d, cur); want != got {
+			mismatches = append(mismatches, fmt.Sprintf("column %s default %q, schema %q", col.Name, got, want))
+		}
+	}
+	for _, col := range current.Columns {
+		if desired.Column(col.Name) == nil {
+			removed = append(removed, col)
+		}
+	}
+	if want, got := foldedKeys(desired.PrimaryKey), foldedKeys(current.PrimaryKey); want != got {
+		mismatches = append(mismatches, fmt.Sprintf("primary key (%s), schema (%s)", got, want))
+	}
+	if want, got := uniqueKeys(desired), uniqueKeys(current); !slices.Equal(want, got) {
+		mismatches = append(mismatches, fmt.Sprintf("unique constraints %v, schema %v", got, want))
+	}
+	return added, removed, mismatches
+}
+
+func normalizedDefault(d SchemaIntrospector, col *ColumnDefinition) string {
+	if col.DefaultValue == nil {
+		return ""
+	}
+	return d.NormalizeExpr(*col.DefaultValue)
+}
+
+// foldedKeys returns the folded column names joined with commas.
+func foldedKeys(columns []string) string {
+	folded := make([]string, len(columns))
+	for i, col := range columns {
+		folded[i] = foldIdent(col)
+	}
+	return strings.Join(folded, ",")
+}
+
+// uniqueKeys returns the sorted unique constraints of table, declared on a column or on the table.
+func uniqueKeys(table *TableDefinition) []string {
+	var keys []string
+	for _, col := range table.Columns {
+		if col.Unique {
+			keys = append(keys, foldIdent(col.Name))
+		}
+	}
+	for _, columns := range table.UniqueConstraints {
+		keys = append(keys, foldedKeys(columns))
+	}
+	slices.Sort(keys)
+	return slices.Compact(keys)
+}
+
+// indexesMatch reports whether the indexes have the same table, keys, uniqueness and condition
+// once their expressions are normalized by d.
+func indexesMatch(d SchemaIntrospector, current, desired *IndexDefinition) bool {
+	normalize := func(i *IndexDefinition) *IndexDefinition {
+		n := &IndexDefinition{Table: i.Table, Unique: i.Unique, Where: d.NormalizeExpr(i.Where)}
+		for k, col := range i.Columns {
+			key := i.key(k)
+			key.Column = d.NormalizeExpr(col)
+			n.Columns = append(n.Columns, key.Column)
+			n.Keys = append(n.Keys, key)
+		}
+		return n
+	}
+	return normalize(current).Equal(normalize(desired))
+}
+
+// dialectChanges returns the differences from the tables and indexes of current to those of desired.
+func dialectChanges(d SchemaIntrospector, current, desired *SchemaDefinition) []SchemaChange {
+	var changes []SchemaChange
+	for _, table := range sortedByName(desired.Tables, func(t *TableDefinition) string { return t.Name }) {
+		cur := current.Table(table.Name)
+		if cur == nil {
+			changes = append(changes, SchemaChange{ObjectName: table.Name, ObjectType: "TABLE", Kind: "added"})
+			continue
+		}
+		added, removed, details := compareTables(d, cur, table)
+		for _, col := range added {
+			details = append(details, "missing column "+col.Name)
+		}
+		for _, col := range removed {
+			details = append(details, "extra column "+col.Name)
+		}
+		if len(details) > 0 {
+			changes = append(changes, SchemaChange{ObjectName: table.Name, ObjectType: "TABLE", Kind: "changed", Details: strings.Join(details, ", ")})
+		}
+	}
+	for _, table := range sortedByName(current.Tables, func(t *TableDefinition) string { return t.Name }) {
+		if desired.Table(table.Name) == nil {
+			changes = append(changes, SchemaChange{ObjectName: table.Name, ObjectType: "TABLE", Kind: "removed"})
+		}
+	}
+	for _, index := range sortedByName(desired.Indexes, func(i *IndexDefinition) string { return i.Name }) {
+		cur := current.Index(index.Name)
+		switch {
+		case cur == nil:
+			changes = append(changes, SchemaChange{ObjectName: index.Name, ObjectType: "INDEX", Kind: "added"})
+		case !indexesMatch(d, cur, index):
+			changes = append(changes, SchemaChange{ObjectName: index.Name, ObjectType: "INDEX", Kind: "changed", Details: cur.SQL})
+		}
+	}
+	for _, index := range sortedByName(current.Indexes, func(i *IndexDefinition) string { return i.Name }) {
+		if desired.Index(index.Name) == nil {
+			changes = append(changes, SchemaChange{ObjectName: index.Name, ObjectType: "INDEX", Kind: "removed"})
+		}
+	}
+	return changes
+}
+
+// dialectPlan returns the steps migrating the tables and indexes of current to those of desired with d.
+func dialectPlan(d Dialect, current, desired *SchemaDefinition, cfg *migrateConfig) (*MigrationPlan, error) {
+	plan := &MigrationPlan{}
+	step := func(action PlanAction, objectType, name string, stmts ...string) {
+		plan.Steps = append(plan.Steps, PlanStep{Action: action, ObjectType: objectType, ObjectName: name, Statements: stmts})
+	}
+	var conflicts []*SchemaConflictError
+	var deletions []string
+	var alters []PlanStep
+	for _, table := range sortedByName(desired.Tables, func(t *TableDefinition) string { return t.Name }) {
+		cur := current.Table(table.Name)
+		if cur == nil {
+			continue
+		}
+		added, removed, mismatches := compareTables(d, cur, table)
+		for _, col := range added {
+			switch {
+			case !cfg.addColumns:
+				mismatches = append(mismatches, "missing column "+col.Name)
+			case requiresValue(col) || col.PrimaryKey:
+				mismatches = append(mismatches, fmt.Sprintf("column %s cannot be added: it requires a value", col.Name))
+			}
+		}
+		if len(removed) > 0 && !cfg.columnDrops {
+			for _, col := range removed {
+				mismatches = append(mismatches, "extra column "+col.Name)
+			}
+		}
+		if len(mismatches) > 0 {
+			conflicts = append(conflicts, &SchemaConflictError{
+				ObjectName:      table.Name,
+				ObjectType:      "TABLE",
+				ExpectedSQL:     table.SQL,
+				ActualSQL:       cur.SQL,
+				ConflictDetails: strings.Join(mismatches, ", "),
+			})
+			continue
+		}
+		var stmts []string
+		for _, col := range added {
+			stmts = append(stmts, d.AddColumn(table.Name, col))
+		}
+		for _, col := range removed {
+			stmts = append(stmts, d.DropColumn(table.Name, col.Name))
+		}
+		if len(stmts) > 0 {
+			alters = append(alters, PlanStep{Action: PlanAlter, ObjectType: "TABLE", ObjectName: table.Name, Statements: stmts})
+		}
+	}
+	for _, table := range sortedByName(current.Tables, func(t *TableDefinition) string { return t.Name }) {
+		if desired.Table(table.Name) == nil {
+			deletions = append(deletions, table.Name)
+		}
+	}
+	if len(conflicts) > 0 {
+		return nil, ErrSchemaConflicts{Conflicts: conflicts}
+	}
+	if len(deletions) > 0 && !cfg.tableDeletes {
+		return nil, ErrTableDeletionNotAllowed{Tables: deletions}
+	}
+
+	var createIndexes []*IndexDefinition
+	for _, index := range sortedByName(current.Indexes, func(i *IndexDefinition) string { return i.Name }) {
+		want := desired.Index(index.Name)
+		if want != nil && indexesMatch(d, index, want) {
+			continue
+		}
+		if want != nil {
+			createIndexes = append(createIndexes, want)
+		}
+		if desired.Table(index.Table) != nil {
+			step(PlanDrop, "INDEX", index.Name, d.DropIndex(index))
+		}
+	}
+	for _, name := range deletions {
+		step(PlanDrop, "TABLE", name, d.DropTable(current.Table(name)))
+	}
+	for _, table := range creationOrder(current, desired) {
+		step(PlanCreate, "TABLE", table.Name, d.CreateTable(table))
+	}
+	plan.Steps = append(plan.Steps, alters...)
+	for _, index := range sortedByName(desired.Indexes, func(i *IndexDefinition) string { return i.Name }) {
+		if current.Index(index.Name) == nil {
+			createIndexes = append(createIndexes, index)
+		}
+	}
+	for _, index := range createIndexes {
+		step(PlanCreate, "INDEX", index.Name, d.CreateIndex(index))
+	}
+	return plan, nil
+}
+
+// creationOrder returns the tables of desired missing from current, each after the tables its foreign keys reference.
+func creationOrder(current, desired *SchemaDefinition) []*TableDefinition {
+	var missing []*TableDefinition
+	for _, table := range sortedByName(desired.Tables, func(t *TableDefinition) string { return t.Name }) {
+		if current.Table(table.Name) == nil {
+			missing = append(missing, table)
+		}
+	}
+	created := make(map[string]bool, len(missing))
+	ordered := make([]*TableDefinition, 0, len(missing))
+	for len(missing) > 0 {
+		progress := false
+		for i := 0; i < len(missing); i++ {
+			table := missing[i]
+			ready := true
+			for _, fk := range table.ForeignKeys {
+				ref := foldIdent(fk.ForeignTable)
+				if ref != foldIdent(table.Name) && !created[ref] && current.Table(fk.ForeignTable) == nil && desired.Table(fk.ForeignTable) != nil {
+					ready = false
+				}
+			}
+			if ready {
+				ordered = append(ordered, table)
+				created[foldIdent(table.Name)] = true
+				missing = slices.Delete(missing, i, i+1)
+				i--
+				progress = true
+			}
+		}
+		if !progress {
+			// A foreign key cycle: create the rest in name order and let the database report it.
+			ordered = append(ordered, missing...)
+			break
+		}
+	}
+	return ordered
+}
+
+// desiredDefinition returns the schema AutoMigrate and Verify migrate to: the statements loaded by
+// AutoMigrateFromEnv, or else those parsed from schema.
+func (cfg *migrateConfig) desiredDefinition(schema io.Reader) (*SchemaDefinition, error) {
+	stmts := cfg.schemaStmts
+	if stmts == nil {
+		var err error
+		if stmts, err = parseSchemaStatements(schema); err != nil {
+			return nil, err
+		}
+	}
+	return newSchemaDefinition(stmts, nil)
+}
+
+// autoMigrateDialect is AutoMigrateWith for databases managed by d, applying the plan in a transaction.
+func autoMigrateDialect(ctx context.Context, db DB, d Dialect, schema io.Reader, cfg *migrateConfig) error {
+	if d.Name() == MySQL.Name() {
+		return fmt.Errorf("AutoMigrate: %w: %s databases can only be checked with Verify", ErrUnsupportedDriver, d.Name())
+	}
+	if cfg.lockName != "" || cfg.history {
+		return fmt.Errorf("AutoMigrate: WithMigrationLock and WithHistory are not supported for %s databases", d.Name())
+	}
+	desired, err := cfg.desiredDefinition(schema)
+	if err != nil {
+		return err
+	}
+	current, err := d.FetchSchema(ctx, db)
+	if err != nil {
+		return fmt.Errorf("AutoMigrate: %w", err)
+	}
+	plan, err := dialectPlan(d, current, desired, cfg)
+	if err != nil {
+		return err
+	}
+	if cfg.dryRun != nil {
+		*cfg.dryRun = *plan
+		return nil
+	}
+	stmts := plan.Statements()
+	if len(stmts) == 0 {
+		return nil
+	}
+	err = db.Txc(ctx, func(tx Tx) error {
+		for _, stmt := range stmts {
+			if _, err := tx.ExecContext(ctx, stmt); err != nil {
+				return queryError(stmt, err)
+			}
+		}
+		if cfg.verifyAfter {
+			return checkDialectPostMigration(ctx, tx, d, desired, stmts)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("AutoMigrate: %w", err)
+	}
+	if err := cfg.writeSQLLog(stmts); err != nil {
+		return fmt.Errorf("AutoMigrate: %w", err)
+	}
+	if !cfg.verifyAfter {
+		if err := checkDialectPostMigration(ctx, db, d, desired, stmts); err != nil {
+			return fmt.Errorf("AutoMigrate: %w", err)
+		}
+	}
+	return nil
+}
+
+// checkDialectPostMigration returns an ErrPostMigrationDrift if the tables and indexes of db still
+// differ from desired once the executed statements are applied.
+func checkDialectPostMigration(ctx context.Context, db Reader, d SchemaIntrospector, desired *SchemaDefinition, executed []string) error {
+	current, err := d.FetchSchema(ctx, db)
+	if err != nil {
+		return fmt.Errorf("could not fetch database schema: %w", err)
+	}
+	if changes := dialectChanges(d, current, desired); len(changes) > 0 {
+		return ErrPostMigrationDrift{Changes: changes, Executed: executed}
+	}
+	return nil
+}
+
+// verifyDialect is Verify for databases managed by d.
+func verifyDialect(ctx context.Context, db Reader, d SchemaIntrospector, desired *SchemaDefinition) error {
+	current, err := d.FetchSchema(ctx, db)
+	if err != nil {
+		return fmt.Errorf("could not fetch database schema: %w", err)
+	}
+	changes := dialectChanges(d, current, desired)
+	if len(changes) == 0 {
+		return nil
+	}
+	details := make([]string, len(changes))
+	for i, c := range changes {
+		details[i] = c.String()
+	}
+	return fmt.Errorf("schema mismatch: %s", strings.Join(details, "; "))
+}
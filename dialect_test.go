@@ -0,0 +1,152 @@
+package sqlt_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/james-darko/gort"
+	"github.com/james-darko/sqlt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fetchedDialect is Postgres reporting a fixed schema, standing in for a PostgreSQL server.
+type fetchedDialect struct {
+	sqlt.Dialect
+	schema *sqlt.SchemaDefinition
+}
+
+func (d fetchedDialect) FetchSchema(context.Context, sqlt.Reader) (*sqlt.SchemaDefinition, error) {
+	return d.schema, nil
+}
+
+func ptr(s string) *string { return &s }
+
+// postgresUsers is the users table as FetchSchema reads it from PostgreSQL.
+func postgresUsers() *sqlt.SchemaDefinition {
+	users := &sqlt.TableDefinition{
+		Name: "users",
+		Columns: []*sqlt.ColumnDefinition{
+			{Name: "id", Type: "integer", NotNull: true, PrimaryKey: true, DefaultValue: ptr("nextval('users_id_seq'::regclass)")},
+			{Name: "name", Type: "character varying(255)", NotNull: true, DefaultValue: ptr("'x'::character varying")},
+			{Name: "created", Type: "timestamp with time zone", DefaultValue: ptr("now()")},
+			{Name: "email", Type: "text"},
+		},
+		PrimaryKey:        []string{"id"},
+		UniqueConstraints: [][]string{{"email"}},
+	}
+	return &sqlt.SchemaDefinition{
+		Tables: map[string]*sqlt.TableDefinition{"users": users},
+		Indexes: map[string]*sqlt.IndexDefinition{"users_lower_name": {
+			Name: "users_lower_name", Table: "users", Columns: []string{"lower((name)::text)"},
+			Keys: []sqlt.IndexKey{{Column: "lower((name)::text)"}}, Where: "(created IS NOT NULL)",
+		}},
+	}
+}
+
+const postgresUsersSchema = `
+CREATE TABLE Users (id SERIAL PRIMARY KEY, name VARCHAR(255) NOT NULL DEFAULT 'x', created TIMESTAMPTZ DEFAULT (now()), email TEXT UNIQUE);
+CREATE INDEX users_lower_name ON users (lower(name)) WHERE created IS NOT NULL;`
+
+func TestDialect_Postgres(t *testing.T) {
+	t.Parallel()
+	ctx := gort.Context()
+	db := getTestDB(t)
+	defer db.Close()
+	dialect := sqlt.WithDialect(fetchedDialect{Dialect: sqlt.Postgres, schema: postgresUsers()})
+
+	assert.Equal(t, sqlt.Postgres, sqlt.DialectFor("pgx"))
+	assert.Equal(t, sqlt.SQLite, sqlt.DialectFor("sqlite3"))
+
+	require.NoError(t, sqlt.VerifyString(ctx, db, postgresUsersSchema, dialect))
+	err := sqlt.VerifyString(ctx, db, strings.Replace(postgresUsersSchema, "VARCHAR(255)", "VARCHAR(100)", 1), dialect)
+	assert.ErrorContains(t, err, "changed table Users: column name type character varying(255), schema character varying(100)")
+
+	schema := postgresUsersSchema + `
+CREATE TABLE posts (id BIGSERIAL PRIMARY KEY, user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE, title TEXT CHECK (title != ''));
+CREATE INDEX posts_user ON posts (user_id, id DESC);`
+	schema = strings.Replace(schema, "email TEXT UNIQUE", "email TEXT UNIQUE, bio TEXT", 1)
+	var plan sqlt.MigrationPlan
+	require.NoError(t, sqlt.AutoMigrateWith(ctx, db, strings.NewReader(schema), dialect, sqlt.WithColumnAdditions(true), sqlt.WithDryRun(&plan)))
+	assert.Equal(t, []string{
+		"CREATE TABLE posts (\n\tid BIGSERIAL,\n\tuser_id INTEGER NOT NULL,\n\ttitle TEXT,\n\tPRIMARY KEY (id),\n" +
+			"\tFOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,\n\tCHECK (title != '')\n)",
+		"ALTER TABLE users ADD COLUMN bio TEXT",
+		"CREATE INDEX posts_user ON posts (user_id, id DESC)",
+	}, plan.Statements())
+
+	err = sqlt.AutoMigrateWith(ctx, db, strings.NewReader(`CREATE TABLE users (id SERIAL PRIMARY KEY, name TEXT)`), dialect)
+	var conflicts sqlt.ErrSchemaConflicts
+	require.True(t, errors.As(err, &conflicts), "%v", err)
+	assert.Contains(t, conflicts.Conflicts[0].ConflictDetails, "column name type character varying(255), schema text")
+	assert.Contains(t, conflicts.Conflicts[0].ConflictDetails, "extra column created")
+}
+
+func TestDialect_PostMigrationCheck(t *testing.T) {
+	t.Parallel()
+	ctx := gort.Context()
+	db := getTestDB(t)
+	defer db.Close()
+	require.NoError(t, sqlt.ExecString(ctx, db, `CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, created TEXT, email TEXT)`))
+	// The fixed schema is read again after migrating, so the new index looks like it was never created.
+	dialect := sqlt.WithDialect(fetchedDialect{Dialect: sqlt.Postgres, schema: postgresUsers()})
+	schema := postgresUsersSchema + "CREATE INDEX users_email ON users (email);"
+
+	err := sqlt.AutoMigrateWith(ctx, db, strings.NewReader(schema), dialect)
+	var drift sqlt.ErrPostMigrationDrift
+	require.ErrorAs(t, err, &drift)
+	assert.Equal(t, []string{"CREATE INDEX users_email ON users (email)"}, drift.Executed)
+	assert.Equal(t, "users_email", drift.Changes[0].ObjectName)
+
+	err = sqlt.AutoMigrateWith(ctx, db, strings.NewReader(schema), dialect, sqlt.WithHistory("v1"))
+	assert.ErrorContains(t, err, "WithMigrationLock and WithHistory are not supported for postgres databases")
+}
+
+// mysqlUsers is the users table as FetchSchema reads it from MySQL.
+func mysqlUsers() *sqlt.SchemaDefinition {
+	users := &sqlt.TableDefinition{
+		Name: "users",
+		Columns: []*sqlt.ColumnDefinition{
+			{Name: "id", Type: "bigint unsigned", NotNull: true, PrimaryKey: true},
+			{Name: "name", Type: "varchar(255)", NotNull: true, DefaultValue: ptr("'x'")},
+			{Name: "active", Type: "tinyint(1)", NotNull: true, DefaultValue: ptr("1")},
+			{Name: "created", Type: "timestamp", DefaultValue: ptr("CURRENT_TIMESTAMP")},
+			{Name: "price", Type: "decimal(10,2)"},
+		},
+		PrimaryKey:        []string{"id"},
+		UniqueConstraints: [][]string{{"name"}},
+	}
+	return &sqlt.SchemaDefinition{
+		Tables: map[string]*sqlt.TableDefinition{"users": users},
+		Indexes: map[string]*sqlt.IndexDefinition{"users_created": {
+			Name: "users_created", Table: "users", Columns: []string{"created"},
+			Keys: []sqlt.IndexKey{{Column: "created", Desc: true}},
+		}},
+	}
+}
+
+func TestDialect_MySQL(t *testing.T) {
+	t.Parallel()
+	ctx := gort.Context()
+	db := getTestDB(t)
+	defer db.Close()
+	dialect := sqlt.WithDialect(fetchedDialect{Dialect: sqlt.MySQL, schema: mysqlUsers()})
+
+	assert.Equal(t, sqlt.MySQL, sqlt.DialectFor("mysql"))
+
+	schema := `
+CREATE TABLE users (id BIGINT UNSIGNED PRIMARY KEY, name VARCHAR(255) NOT NULL UNIQUE DEFAULT 'x',
+	active BOOLEAN NOT NULL DEFAULT 1, created TIMESTAMP DEFAULT CURRENT_TIMESTAMP, price NUMERIC(10, 2));
+CREATE INDEX users_created ON users (created DESC);`
+	require.NoError(t, sqlt.VerifyString(ctx, db, schema, dialect))
+
+	err := sqlt.VerifyString(ctx, db, strings.Replace(schema, "created DESC", "created", 1), dialect)
+	assert.ErrorContains(t, err, "changed index users_created")
+	err = sqlt.VerifyString(ctx, db, strings.Replace(schema, "NOT NULL UNIQUE", "NOT NULL", 1), dialect)
+	assert.ErrorContains(t, err, "unique constraints [name], schema []")
+
+	err = sqlt.AutoMigrateWith(ctx, db, strings.NewReader(schema), dialect)
+	assert.ErrorIs(t, err, sqlt.ErrUnsupportedDriver)
+}
@@ -0,0 +1,181 @@
+package sqlt
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	rsql "github.com/rqlite/sql"
+)
+
+// SchemaChange describes how an object differs between two schemas.
+type SchemaChange struct {
+	ObjectName string
+	ObjectType string
+	// Kind is "added", "removed" or "changed".
+	Kind    string
+	Details string
+}
+
+func (c SchemaChange) String() string {
+	if c.Details == "" {
+		return fmt.Sprintf("%s %s %s", c.Kind, strings.ToLower(c.ObjectType), c.ObjectName)
+	}
+	return fmt.Sprintf("%s %s %s: %s", c.Kind, strings.ToLower(c.ObjectType), c.ObjectName, c.Details)
+}
+
+// DriftReport separates the changes made to a database outside sqlt from the changes intended by a new schema.
+type DriftReport struct {
+	// Drift lists the differences between the baseline schema and the database.
+	Drift []SchemaChange
+	// Intended lists the differences between the baseline schema and the desired schema.
+	Intended []SchemaChange
+}
+
+// DetectDrift compares the database and the desired schema against the baseline schema,
+// which is the schema the database was last migrated to.
+func DetectDrift(ctx context.Context, db DB, baseline, desired io.Reader) (*DriftReport, error) {
+	baselineStmts, err := parseSchemaStatements(baseline)
+	if err != nil {
+		return nil, fmt.Errorf("baseline schema: %w", err)
+	}
+	desiredStmts, err := parseSchemaStatements(desired)
+	if err != nil {
+		return nil, fmt.Errorf("desired schema: %w", err)
+	}
+	dbStmts, err := dbStatements(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	drift, err := diffSchemas(baselineStmts, dbStmts, StrictnessStructural)
+	if err != nil {
+		return nil, err
+	}
+	intended, err := diffSchemas(baselineStmts, desiredStmts, StrictnessStructural)
+	if err != nil {
+		return nil, err
+	}
+	return &DriftReport{Drift: drift, Intended: intended}, nil
+}
+
+// WithBaseline makes AutoMigrate refuse to overwrite changes made to the database outside sqlt.
+// baseline is the schema the database was last migrated to. If an object in the database differs
+// from both the baseline and the new schema, AutoMigrate returns a *DriftError.
+func WithBaseline(baseline io.Reader) MigrateOption {
+	return func(cfg *migrateConfig) {
+		cfg.baseline = baseline
+	}
+}
+
+// checkDrift returns a *DriftError if the migration would overwrite objects that drifted from the baseline.
+// Drifted objects already matching the desired schema are not overwritten and are ignored.
+func (cfg *migrateConfig) checkDrift(dbStmts, schemaStmts []rsql.Statement) error {
+	baselineStmts, err := parseSchemaStatements(cfg.baseline)
+	if err != nil {
+		return fmt.Errorf("baseline schema: %w", err)
+	}
+	drift, err := diffSchemas(baselineStmts, dbStmts, cfg.strictness)
+	if err != nil {
+		return err
+	}
+	if len(drift) == 0 {
+		return nil
+	}
+	pending, err := diffSchemas(dbStmts, schemaStmts, cfg.strictness)
+	if err != nil {
+		return err
+	}
+	overwritten := make(map[string]bool, len(pending))
+	for _, c := range pending {
+		overwritten[foldIdent(c.ObjectName)] = true
+	}
+	var conflicts []SchemaChange
+	for _, c := range drift {
+		if overwritten[foldIdent(c.ObjectName)] {
+			conflicts = append(conflicts, c)
+		}
+	}
+	if len(conflicts) > 0 {
+		return &DriftError{Changes: conflicts}
+	}
+	return nil
+}
+
+// diffSchemas returns the changes turning the objects in from into the objects in to.
+func diffSchemas(from, to []rsql.Statement, strictness Strictness) ([]SchemaChange, error) {
+	fromObjects := make(map[string]rsql.Statement, len(from))
+	for _, stmt := range from {
+		name, err := getStatementName(stmt)
+		if err != nil {
+			return nil, err
+		}
+		fromObjects[foldIdent(name)] = stmt
+	}
+	var changes []SchemaChange
+	seen := make(map[string]bool, len(to))
+	for _, toStmt := range to {
+		name, err := getStatementName(toStmt)
+		if err != nil {
+			return nil, err
+		}
+		seen[foldIdent(name)] = true
+		fromStmt, ok := fromObjects[foldIdent(name)]
+		if !ok {
+			changes = append(changes, SchemaChange{ObjectName: name, ObjectType: getObjectType(toStmt), Kind: "added"})
+			continue
+		}
+		matchType, desc, err := compareStatements(fromStmt, toStmt, strictness)
+		if err != nil {
+			return nil, fmt.Errorf("error comparing object '%s': %w", name, err)
+		}
+		if matchType != statementMatchExact {
+			changes = append(changes, SchemaChange{ObjectName: name, ObjectType: getObjectType(toStmt), Kind: "changed", Details: desc})
+		}
+	}
+	for _, fromStmt := range from {
+		name, _ := getStatementName(fromStmt)
+		if !seen[foldIdent(name)] {
+			changes = append(changes, SchemaChange{ObjectName: name, ObjectType: getObjectType(fromStmt), Kind: "removed"})
+		}
+	}
+	return changes, nil
+}
+
+// checkPostMigration returns an ErrPostMigrationDrift if the migrated database differs from the schema.
+// Tables kept because table deletes are not allowed or because their conflict was skipped with
+// WithOnConflict are expected to differ, and so are the deferred indexes not built yet.
+// Objects not named in AutoMigrateObjects are not compared. When no statement was executed, the
+// schema AutoMigrate read before migrating is reused instead of reading it again.
+func (cfg *migrateConfig) checkPostMigration(ctx context.Context, db Reader, allowTableDeletes bool, executed []string, deferred []deferredIndex) error {
+	dbStmts := cfg.dbStmts
+	if len(executed) > 0 || dbStmts == nil {
+		var err error
+		if dbStmts, err = schemaStatements(ctx, db, cfg.targetSchema); err != nil {
+			return fmt.Errorf("AutoMigrate: %w", err)
+		}
+	}
+	schemaStmts := cfg.schemaStmts
+	if cfg.objects != nil {
+		dbStmts, schemaStmts = cfg.filterObjects(dbStmts), cfg.filterObjects(schemaStmts)
+	}
+	changes, err := diffSchemas(dbStmts, schemaStmts, cfg.strictness)
+	if err != nil {
+		return fmt.Errorf("AutoMigrate: %w", err)
+	}
+	var drift []SchemaChange
+	for _, c := range changes {
+		if c.ObjectType == "TABLE" && (c.Kind == "removed" && !allowTableDeletes || slices.Contains(cfg.skippedTables, foldIdent(c.ObjectName))) {
+			continue
+		}
+		if c.ObjectType == "INDEX" && c.Kind == "added" && slices.ContainsFunc(deferred, func(d deferredIndex) bool { return strings.EqualFold(d.name, c.ObjectName) }) {
+			continue
+		}
+		drift = append(drift, c)
+	}
+	if len(drift) > 0 {
+		return ErrPostMigrationDrift{Changes: drift, Executed: executed}
+	}
+	return nil
+}
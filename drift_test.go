@@ -0,0 +1,132 @@
+package sqlt_test
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"testing"
+
+	"github.com/james-darko/gort"
+	"github.com/james-darko/sqlt"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const driftBaseline = `
+CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT);
+CREATE INDEX idx_users_name ON users (name);`
+
+func TestDetectDrift(t *testing.T) {
+	t.Parallel()
+	db := getTestDB(t)
+	defer db.Close()
+	ctx := gort.Context()
+
+	require.NoError(t, sqlt.ExecString(ctx, db, driftBaseline))
+	_, err := db.Exec("DROP INDEX idx_users_name; CREATE INDEX idx_users_name ON users (name, id);")
+	require.NoError(t, err)
+
+	desired := driftBaseline + `
+CREATE TABLE posts (id INTEGER PRIMARY KEY);`
+	report, err := sqlt.DetectDrift(ctx, db, strings.NewReader(driftBaseline), strings.NewReader(desired))
+	require.NoError(t, err)
+
+	require.Len(t, report.Drift, 1)
+	assert.Equal(t, "idx_users_name", report.Drift[0].ObjectName)
+	assert.Equal(t, "changed", report.Drift[0].Kind)
+	require.Len(t, report.Intended, 1)
+	assert.Equal(t, "posts", report.Intended[0].ObjectName)
+	assert.Equal(t, "added", report.Intended[0].Kind)
+}
+
+func TestAutoMigrate_WithBaselineRefusesToOverwriteDrift(t *testing.T) {
+	t.Parallel()
+	db := getTestDB(t)
+	defer db.Close()
+	ctx := gort.Context()
+
+	require.NoError(t, sqlt.ExecString(ctx, db, driftBaseline))
+	_, err := db.Exec("CREATE INDEX idx_out_of_band ON users (id, name)")
+	require.NoError(t, err)
+
+	err = sqlt.AutoMigrate(ctx, db, strings.NewReader(driftBaseline), true, sqlt.WithBaseline(strings.NewReader(driftBaseline)))
+	var driftErr *sqlt.DriftError
+	require.True(t, errors.As(err, &driftErr), "expected DriftError, got %v", err)
+	require.Len(t, driftErr.Changes, 1)
+	assert.Equal(t, "idx_out_of_band", driftErr.Changes[0].ObjectName)
+	assert.Equal(t, "added", driftErr.Changes[0].Kind)
+
+	// Adopting the out-of-band index in the desired schema is not an overwrite.
+	desired := driftBaseline + `
+CREATE INDEX idx_out_of_band ON users (id, name);`
+	err = sqlt.AutoMigrate(ctx, db, strings.NewReader(desired), true, sqlt.WithBaseline(strings.NewReader(driftBaseline)))
+	assert.NoError(t, err)
+}
+
+// randomSchema returns a schema of up to three tables built from a fixed pool of columns,
+// with indexes and views on some of them.
+func randomSchema(r *rand.Rand) string {
+	columns := []string{"a TEXT", "b INTEGER NOT NULL DEFAULT 0", "c REAL", "d TEXT COLLATE NOCASE", "e BLOB"}
+	var schema strings.Builder
+	for i := range 3 {
+		if r.IntN(4) == 0 {
+			continue
+		}
+		table := fmt.Sprintf("t%d", i)
+		var cols []string
+		for _, col := range columns {
+			if r.IntN(2) == 0 {
+				cols = append(cols, col)
+			}
+		}
+		fmt.Fprintf(&schema, "CREATE TABLE %s (id INTEGER PRIMARY KEY%s);\n", table, strings.Join(append([]string{""}, cols...), ", "))
+		if len(cols) == 0 {
+			continue
+		}
+		col := strings.Fields(cols[r.IntN(len(cols))])[0]
+		if r.IntN(2) == 0 {
+			fmt.Fprintf(&schema, "CREATE INDEX idx_%s_%s ON %s (%s);\n", table, col, table, col)
+		}
+		if r.IntN(3) == 0 {
+			fmt.Fprintf(&schema, "CREATE VIEW v_%s AS SELECT id, %s FROM %s;\n", table, col, table)
+		}
+	}
+	return schema.String()
+}
+
+func TestAutoMigrate_ThenVerifyPasses(t *testing.T) {
+	t.Parallel()
+	db := getTestDB(t)
+	defer db.Close()
+	ctx := gort.Context()
+
+	r := rand.New(rand.NewPCG(1, 2))
+	for i := range 50 {
+		schema := randomSchema(r)
+		err := sqlt.AutoMigrate(ctx, db, strings.NewReader(schema), true)
+		var drift sqlt.ErrPostMigrationDrift
+		require.False(t, errors.As(err, &drift), "step %d: %v\n%s", i, err, schema)
+		var conflicts sqlt.ErrSchemaConflicts
+		if errors.As(err, &conflicts) {
+			continue
+		}
+		require.NoError(t, err, "step %d:\n%s", i, schema)
+		require.NoError(t, sqlt.VerifyString(ctx, db, schema), "step %d:\n%s", i, schema)
+	}
+}
+
+func TestAutoMigrate_VerifyAfter(t *testing.T) {
+	t.Parallel()
+	db := getTestDB(t)
+	defer db.Close()
+	ctx := gort.Context()
+
+	schema := `CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT);
+CREATE INDEX idx_users_name ON users (name);
+CREATE VIEW user_names AS SELECT name FROM users;`
+	err := sqlt.AutoMigrate(ctx, db, strings.NewReader(schema), true, sqlt.WithVerifyAfter(), sqlt.WithDeferredIndexes())
+	require.NoError(t, err)
+	assert.NoError(t, sqlt.VerifyString(ctx, db, schema))
+}
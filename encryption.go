@@ -0,0 +1,97 @@
+package sqlt
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// OpenEncrypted opens a database encrypted with SQLCipher or SEE, running PRAGMA key with key on
+// every new connection before it is used. The driver must be built with encryption support,
+// otherwise SQLite ignores the pragma and the database is opened unencrypted.
+// Use Rekey to change the key.
+func OpenEncrypted(driverName, dataSourceName, key string, opts ...OpenOption) (DB, error) {
+	probe, err := sql.Open(driverName, dataSourceName)
+	if err != nil {
+		return nil, err
+	}
+	drv := probe.Driver()
+	probe.Close()
+	kc := &keyConnector{driver: drv, dsn: dataSourceName}
+	if dc, ok := drv.(driver.DriverContext); ok {
+		if kc.base, err = dc.OpenConnector(dataSourceName); err != nil {
+			return nil, err
+		}
+	}
+	kc.key.Store(&key)
+	db := sqlx.NewDb(sql.OpenDB(kc), driverName)
+	if mapper := defaultMapper.Load(); mapper != nil {
+		db.MapperFunc(*mapper)
+	}
+	s := Wrap(db, opts...).(*sqlxDB)
+	s.keys = kc
+	return s, nil
+}
+
+// keyConnector opens connections with the driver and unlocks them with PRAGMA key.
+type keyConnector struct {
+	driver driver.Driver
+	dsn    string
+	// base is the driver's own connector, if it provides one.
+	base driver.Connector
+	key  atomic.Pointer[string]
+}
+
+func (kc *keyConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	var conn driver.Conn
+	var err error
+	if kc.base != nil {
+		conn, err = kc.base.Connect(ctx)
+	} else {
+		conn, err = kc.driver.Open(kc.dsn)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := execDriverConn(ctx, conn, "PRAGMA key = "+quoteString(*kc.key.Load())); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("could not set encryption key: %w", err)
+	}
+	return conn, nil
+}
+
+func (kc *keyConnector) Driver() driver.Driver {
+	return kc.driver
+}
+
+// execDriverConn executes a statement without arguments on a raw driver connection.
+func execDriverConn(ctx context.Context, conn driver.Conn, query string) error {
+	if execer, ok := conn.(driver.ExecerContext); ok {
+		_, err := execer.ExecContext(ctx, query, nil)
+		return err
+	}
+	stmt, err := conn.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	// Drivers without ExecerContext only have the deprecated Stmt.Exec.
+	_, err = stmt.Exec(nil)
+	return err
+}
+
+// Rekey re-encrypts the database with newKey using PRAGMA rekey. If db was opened with
+// OpenEncrypted, connections opened afterwards use the new key.
+func Rekey(ctx context.Context, db DB, newKey string) error {
+	if _, err := db.ExecContext(ctx, "PRAGMA rekey = "+quoteString(newKey)); err != nil {
+		return fmt.Errorf("could not change encryption key: %w", err)
+	}
+	if s, ok := db.(*sqlxDB); ok && s.keys != nil {
+		s.keys.key.Store(&newKey)
+	}
+	return nil
+}
@@ -1,6 +1,14 @@
 package sqlt
 
-import "fmt" // Added import for fmt
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	rsql "github.com/rqlite/sql"
+)
 
 type Error struct {
 	err error
@@ -27,6 +35,90 @@ func (e Error) As(target any) bool {
 	return false
 }
 
+// QueryError is returned by QueryRowContext and the GetRow helpers and names the failed query.
+// It unwraps to the driver error, so errors.Is(err, sql.ErrNoRows) still works.
+type QueryError struct {
+	Query string
+	Err   error
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("query %q: %v", e.Query, e.Err)
+}
+
+func (e *QueryError) Unwrap() error {
+	return e.Err
+}
+
+// queryError wraps a non-nil err in a *QueryError for query.
+func queryError(query string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &QueryError{Query: query, Err: err}
+}
+
+// LockError is returned by transactions failing because the database is busy or locked,
+// with diagnostics about the lock wait. It unwraps to the driver error.
+type LockError struct {
+	Err error
+	// Waited is how long the failing statement ran before it gave up.
+	Waited time.Duration
+	// BusyTimeout is the busy_timeout of the database connections.
+	BusyTimeout time.Duration
+	// JournalMode is the journal_mode of the database, e.g. "wal" or "delete".
+	JournalMode string
+	// WriteLockHeld is set if another transaction of this DB had written when the error occurred,
+	// so it likely holds the write lock.
+	WriteLockHeld bool
+}
+
+func (e *LockError) Error() string {
+	msg := fmt.Sprintf("%v after waiting %s (busy_timeout %s, journal_mode %s", e.Err, e.Waited, e.BusyTimeout, e.JournalMode)
+	if e.WriteLockHeld {
+		msg += ", write lock held by another transaction in this process"
+	}
+	return msg + ")"
+}
+
+func (e *LockError) Unwrap() error {
+	return e.Err
+}
+
+// ParseError is returned when SQL cannot be parsed. Line and Column are 1-based and locate the
+// offending token in the parsed input; they are zero if the parser reported no position.
+type ParseError struct {
+	Line   int
+	Column int
+	Err    error
+}
+
+func (e *ParseError) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("parse error: %v", e.Err)
+	}
+	var perr *rsql.Error
+	if errors.As(e.Err, &perr) {
+		return fmt.Sprintf("parse error at line %d, column %d: %s", e.Line, e.Column, perr.Msg)
+	}
+	return fmt.Sprintf("parse error at line %d, column %d: %v", e.Line, e.Column, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// parseError wraps a parser error in a *ParseError, taking the position from it if it has one.
+func parseError(err error) error {
+	pe := &ParseError{Err: err}
+	var perr *rsql.Error
+	if errors.As(err, &perr) && perr.Pos.IsValid() {
+		pe.Line = perr.Pos.Line
+		pe.Column = perr.Pos.Column
+	}
+	return pe
+}
+
 // SchemaConflictError represents an error due to a schema conflict.
 type SchemaConflictError struct {
 	ObjectName      string
@@ -34,6 +126,27 @@ type SchemaConflictError struct {
 	ExpectedSQL     string
 	ActualSQL       string
 	ConflictDetails string
+	// TableOptions lists the trailing table options, such as STRICT, that differ.
+	TableOptions []TableOptionConflict
+}
+
+// TableOptionConflict describes a table option set in only one of the schema and the database.
+// Virtual table arguments such as FTS tokenizers are not supported by the parser and never reported.
+type TableOptionConflict struct {
+	Option   string // "WITHOUT ROWID" or "STRICT"
+	Expected bool   // set in the schema
+	Actual   bool   // set in the database
+}
+
+func (c TableOptionConflict) String() string {
+	return fmt.Sprintf("Table option %s mismatch (DB: %s, Schema: %s)", c.Option, optionState(c.Actual), optionState(c.Expected))
+}
+
+func optionState(set bool) string {
+	if set {
+		return "set"
+	}
+	return "not set"
 }
 
 // Error returns a formatted error message summarizing the schema conflict.
@@ -41,6 +154,55 @@ func (e *SchemaConflictError) Error() string {
 	return "schema conflict: " + e.ConflictDetails
 }
 
+// ErrSchemaConflicts is returned by AutoMigrate with every table it could not reconcile,
+// so all conflicts of a schema are reported in a single run.
+type ErrSchemaConflicts struct {
+	Conflicts []*SchemaConflictError
+}
+
+// Error returns a message listing the conflicting tables and their differences.
+func (e ErrSchemaConflicts) Error() string {
+	if len(e.Conflicts) == 1 {
+		return e.Conflicts[0].Error()
+	}
+	details := make([]string, len(e.Conflicts))
+	for i, c := range e.Conflicts {
+		details[i] = fmt.Sprintf("%s %s: %s", strings.ToLower(c.ObjectType), c.ObjectName, c.ConflictDetails)
+	}
+	return fmt.Sprintf("%d schema conflicts: %s", len(e.Conflicts), strings.Join(details, "; "))
+}
+
+func (e ErrSchemaConflicts) Unwrap() []error {
+	errs := make([]error, len(e.Conflicts))
+	for i, c := range e.Conflicts {
+		errs[i] = c
+	}
+	return errs
+}
+
+// ErrTenantMigrations is returned by MigrateAll when some of the databases could not be migrated.
+type ErrTenantMigrations struct {
+	Failed []TenantResult
+	Total  int
+}
+
+// Error returns a message listing the failed databases and their errors.
+func (e ErrTenantMigrations) Error() string {
+	details := make([]string, len(e.Failed))
+	for i, r := range e.Failed {
+		details[i] = fmt.Sprintf("database %d: %v", r.Index, r.Err)
+	}
+	return fmt.Sprintf("%d of %d databases failed to migrate: %s", len(e.Failed), e.Total, strings.Join(details, "; "))
+}
+
+func (e ErrTenantMigrations) Unwrap() []error {
+	errs := make([]error, len(e.Failed))
+	for i, r := range e.Failed {
+		errs[i] = r.Err
+	}
+	return errs
+}
+
 // ErrTableDeletionNotAllowed represents an error when table deletions are disallowed
 // but the schema migration would result in table deletions.
 type ErrTableDeletionNotAllowed struct {
@@ -52,3 +214,71 @@ func (e ErrTableDeletionNotAllowed) Error() string {
 	// Use fmt.Sprintf from the fmt package (ensure it's imported in the file if not already)
 	return fmt.Sprintf("table deletion not allowed, but the following tables would be deleted: %v", e.Tables)
 }
+
+// VersionedSchemaError is returned by VerifyAt and reports both of its checks.
+type VersionedSchemaError struct {
+	ExpectedVersion int
+	// ActualVersion is -1 if the version could not be read.
+	ActualVersion int
+	// VersionErr is set if the version does not match, or is ErrNoVersion if there is none.
+	VersionErr error
+	// SchemaErr is set if the database objects do not match the schema.
+	SchemaErr error
+}
+
+// Error returns a message listing the failed checks.
+func (e *VersionedSchemaError) Error() string {
+	var problems []string
+	if e.VersionErr != nil {
+		problems = append(problems, "version: "+e.VersionErr.Error())
+	}
+	if e.SchemaErr != nil {
+		problems = append(problems, "schema: "+e.SchemaErr.Error())
+	}
+	return "database does not match schema v" + strconv.Itoa(e.ExpectedVersion) + ": " + strings.Join(problems, "; ")
+}
+
+func (e *VersionedSchemaError) Unwrap() []error {
+	var errs []error
+	if e.VersionErr != nil {
+		errs = append(errs, e.VersionErr)
+	}
+	if e.SchemaErr != nil {
+		errs = append(errs, e.SchemaErr)
+	}
+	return errs
+}
+
+// ErrPostMigrationDrift is returned by AutoMigrate when the database still differs from the schema
+// after a migration that succeeded, meaning AutoMigrate did not make the changes the schema needs.
+// It should be reported as a bug together with the schema and the changes listed.
+type ErrPostMigrationDrift struct {
+	// Changes lists the differences from the database to the schema.
+	Changes []SchemaChange
+	// Executed lists the statements the migration executed.
+	Executed []string
+}
+
+// Error returns a message listing the objects differing from the schema.
+func (e ErrPostMigrationDrift) Error() string {
+	changes := make([]string, len(e.Changes))
+	for i, c := range e.Changes {
+		changes[i] = c.String()
+	}
+	return fmt.Sprintf("database differs from schema after migration (%d statements executed): %s", len(e.Executed), strings.Join(changes, "; "))
+}
+
+// DriftError is returned by AutoMigrate when it would overwrite changes made to the database outside sqlt.
+// See WithBaseline.
+type DriftError struct {
+	Changes []SchemaChange
+}
+
+// Error returns a formatted error message listing the drifted objects.
+func (e *DriftError) Error() string {
+	changes := make([]string, len(e.Changes))
+	for i, c := range e.Changes {
+		changes[i] = c.String()
+	}
+	return "database schema drifted from baseline: " + strings.Join(changes, "; ")
+}
@@ -0,0 +1,288 @@
+package sqlt
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	rsql "github.com/rqlite/sql"
+)
+
+// ExpandContractPlan holds the two phases of a zero-downtime schema change.
+//
+// Expand only adds to the schema, so the old and the new application version both work once it ran:
+// it creates tables, indexes, views and triggers, adds columns as nullable, and copies renamed columns
+// into their new name, keeping both names in sync with dual-write triggers.
+// Contract runs once no old application version is left: it drops the dual-write triggers and the
+// objects and columns the new schema no longer has, and rebuilds the tables whose columns gained
+// constraints ADD COLUMN cannot express. Rebuilds must run with foreign keys off, as for MigrateFunc.
+type ExpandContractPlan struct {
+	Expand   []string
+	Contract []string
+}
+
+// ExpandContractOption configures PlanExpandContract.
+type ExpandContractOption func(*expandContractConfig)
+
+type expandContractConfig struct {
+	// renames maps folded table names to the renames of their columns.
+	renames map[string][]columnRename
+}
+
+type columnRename struct {
+	from, to string
+}
+
+// WithRenamedColumn declares that column from of table is renamed to to in the new schema,
+// so it is copied and kept in sync instead of being dropped and added empty.
+func WithRenamedColumn(table, from, to string) ExpandContractOption {
+	return func(cfg *expandContractConfig) {
+		key := foldIdent(table)
+		cfg.renames[key] = append(cfg.renames[key], columnRename{from: from, to: to})
+	}
+}
+
+// PlanExpandContract splits the change from the old to the new schema into an expand and a contract phase.
+// Neither phase is executed; run the statements with Exec or ExecString.
+func PlanExpandContract(old, new io.Reader, opts ...ExpandContractOption) (*ExpandContractPlan, error) {
+	cfg := &expandContractConfig{renames: make(map[string][]columnRename)}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	oldSchema, err := ParseSchemaReader(old)
+	if err != nil {
+		return nil, fmt.Errorf("old schema: %w", err)
+	}
+	newSchema, err := ParseSchemaReader(new)
+	if err != nil {
+		return nil, fmt.Errorf("new schema: %w", err)
+	}
+	plan := &ExpandContractPlan{}
+	// recreated holds the folded names of the tables rebuilt in the contract phase, which drops their indexes and triggers.
+	recreated := make(map[string]bool)
+	var dualWriteTriggers []string
+
+	for _, newTable := range sortedByName(newSchema.Tables, func(t *TableDefinition) string { return t.Name }) {
+		oldTable := oldSchema.Table(newTable.Name)
+		if oldTable == nil {
+			plan.Expand = append(plan.Expand, newTable.SQL)
+			continue
+		}
+		renames := cfg.renames[foldIdent(newTable.Name)]
+		renamedTo := make(map[string]string)
+		for _, r := range renames {
+			if oldTable.Column(r.from) == nil || newTable.Column(r.to) == nil {
+				return nil, fmt.Errorf("rename of %s.%s to %s: column not found in the old or new schema", newTable.Name, r.from, r.to)
+			}
+			renamedTo[foldIdent(r.to)] = r.from
+		}
+		rebuild := tableConstraintsChanged(oldTable, newTable)
+		for _, oldCol := range oldTable.Columns {
+			newCol := newTable.Column(oldCol.Name)
+			if newCol == nil || describeColumn(oldCol) != describeColumn(newCol) {
+				rebuild = true
+			}
+		}
+		for _, newCol := range newTable.Columns {
+			if oldTable.Column(newCol.Name) != nil {
+				continue
+			}
+			if !addableColumn(newCol) {
+				rebuild = true
+			}
+			from, renamed := renamedTo[foldIdent(newCol.Name)]
+			// A default would hide the inserts of the old version from the dual-write triggers.
+			// The dropped column rebuilds the table anyway, which sets the default.
+			plan.Expand = append(plan.Expand, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", quoteIdent(newTable.Name), relaxedColumnSQL(newCol, !renamed)))
+			if renamed {
+				plan.Expand = append(plan.Expand, fmt.Sprintf("UPDATE %s SET %s = %s WHERE %s IS NULL",
+					quoteIdent(newTable.Name), quoteIdent(newCol.Name), quoteIdent(from), quoteIdent(newCol.Name)))
+				triggers := dualWriteColumnTriggers(oldTable, from, newCol.Name)
+				plan.Expand = append(plan.Expand, triggers...)
+				dualWriteTriggers = append(dualWriteTriggers, dualWriteTriggerNames(newTable.Name, from, newCol.Name)...)
+			}
+		}
+		if rebuild {
+			recreated[foldIdent(newTable.Name)] = true
+		}
+	}
+
+	for _, name := range dualWriteTriggers {
+		plan.Contract = append(plan.Contract, "DROP TRIGGER IF EXISTS "+quoteIdent(name))
+	}
+	// Renaming a rebuilt table fails while views and triggers refer to the dropped original,
+	// so a rebuild drops and recreates all of them.
+	rebuilding := len(recreated) > 0
+	for _, view := range sortedByName(oldSchema.Views, func(v *ViewDefinition) string { return v.Name }) {
+		if newView := newSchema.View(view.Name); rebuilding || newView == nil || newView.SQL != view.SQL {
+			plan.Contract = append(plan.Contract, "DROP VIEW IF EXISTS "+quoteIdent(view.Name))
+		}
+	}
+	for _, trigger := range sortedByName(oldSchema.Triggers, func(t *TriggerDefinition) string { return t.Name }) {
+		if newTrigger := newSchema.Trigger(trigger.Name); rebuilding || newTrigger == nil || newTrigger.SQL != trigger.SQL {
+			plan.Contract = append(plan.Contract, "DROP TRIGGER IF EXISTS "+quoteIdent(trigger.Name))
+		}
+	}
+	if rebuilding {
+		for _, view := range sortedByName(newSchema.Views, func(v *ViewDefinition) string { return v.Name }) {
+			if oldSchema.View(view.Name) == nil {
+				plan.Contract = append(plan.Contract, "DROP VIEW IF EXISTS "+quoteIdent(view.Name))
+			}
+		}
+		for _, trigger := range sortedByName(newSchema.Triggers, func(t *TriggerDefinition) string { return t.Name }) {
+			if oldSchema.Trigger(trigger.Name) == nil {
+				plan.Contract = append(plan.Contract, "DROP TRIGGER IF EXISTS "+quoteIdent(trigger.Name))
+			}
+		}
+	}
+	for _, index := range sortedByName(oldSchema.Indexes, func(i *IndexDefinition) string { return i.Name }) {
+		if newIndex := newSchema.Index(index.Name); newIndex == nil || !newIndex.Equal(index) {
+			plan.Contract = append(plan.Contract, "DROP INDEX IF EXISTS "+quoteIdent(index.Name))
+		}
+	}
+	for _, newTable := range sortedByName(newSchema.Tables, func(t *TableDefinition) string { return t.Name }) {
+		if recreated[foldIdent(newTable.Name)] {
+			stmts, err := rebuildTableSQL(newTable)
+			if err != nil {
+				return nil, err
+			}
+			plan.Contract = append(plan.Contract, stmts...)
+		}
+	}
+	for _, oldTable := range sortedByName(oldSchema.Tables, func(t *TableDefinition) string { return t.Name }) {
+		if newSchema.Table(oldTable.Name) == nil {
+			plan.Contract = append(plan.Contract, "DROP TABLE IF EXISTS "+quoteIdent(oldTable.Name))
+		}
+	}
+
+	// New objects are created in the expand phase, changed ones and the indexes of rebuilt tables
+	// once the contract phase changed their table. Views go last as they may read any table.
+	for _, index := range sortedByName(newSchema.Indexes, func(i *IndexDefinition) string { return i.Name }) {
+		oldIndex := oldSchema.Index(index.Name)
+		switch {
+		case recreated[foldIdent(index.Table)] || oldIndex != nil && !oldIndex.Equal(index):
+			plan.Contract = append(plan.Contract, index.SQL)
+		case oldIndex == nil:
+			plan.Expand = append(plan.Expand, index.SQL)
+		}
+	}
+	for _, trigger := range sortedByName(newSchema.Triggers, func(t *TriggerDefinition) string { return t.Name }) {
+		oldTrigger := oldSchema.Trigger(trigger.Name)
+		if oldTrigger == nil {
+			plan.Expand = append(plan.Expand, trigger.SQL)
+		}
+		if rebuilding || oldTrigger != nil && oldTrigger.SQL != trigger.SQL {
+			plan.Contract = append(plan.Contract, trigger.SQL)
+		}
+	}
+	for _, view := range sortedByName(newSchema.Views, func(v *ViewDefinition) string { return v.Name }) {
+		oldView := oldSchema.View(view.Name)
+		if oldView == nil {
+			plan.Expand = append(plan.Expand, view.SQL)
+		}
+		if rebuilding || oldView != nil && oldView.SQL != view.SQL {
+			plan.Contract = append(plan.Contract, view.SQL)
+		}
+	}
+	return plan, nil
+}
+
+// tableConstraintsChanged reports whether the table level definition of a table changed.
+func tableConstraintsChanged(old, new *TableDefinition) bool {
+	return !reflect.DeepEqual(old.PrimaryKey, new.PrimaryKey) ||
+		!reflect.DeepEqual(old.UniqueConstraints, new.UniqueConstraints) ||
+		!reflect.DeepEqual(old.ForeignKeys, new.ForeignKeys) ||
+		!reflect.DeepEqual(old.Checks, new.Checks) ||
+		old.WithoutRowid != new.WithoutRowid || old.Strict != new.Strict
+}
+
+// addableColumn reports whether ALTER TABLE ADD COLUMN can add col with its full definition.
+func addableColumn(col *ColumnDefinition) bool {
+	return !col.NotNull && !col.Unique && !col.PrimaryKey && col.Hidden == 0 && constantDefault(col)
+}
+
+// constantDefault reports whether col has no default or a default ADD COLUMN accepts.
+func constantDefault(col *ColumnDefinition) bool {
+	if col.DefaultValue == nil {
+		return true
+	}
+	def := strings.ToUpper(*col.DefaultValue)
+	return !strings.Contains(def, "(") && !strings.HasPrefix(def, "CURRENT_")
+}
+
+// relaxedColumnSQL returns the definition of col without the constraints ADD COLUMN rejects,
+// and without its default unless withDefault is set.
+func relaxedColumnSQL(col *ColumnDefinition, withDefault bool) string {
+	parts := []string{quoteIdent(col.Name)}
+	if col.Type != "" {
+		parts = append(parts, col.Type)
+	}
+	if withDefault && col.DefaultValue != nil && constantDefault(col) {
+		parts = append(parts, "DEFAULT "+*col.DefaultValue)
+	}
+	if col.Collation != "" {
+		parts = append(parts, "COLLATE "+col.Collation)
+	}
+	return strings.Join(parts, " ")
+}
+
+// rebuildTableSQL returns the statements replacing table by a copy built from its definition,
+// keeping the values of its columns.
+func rebuildTableSQL(table *TableDefinition) ([]string, error) {
+	stmt, err := rsql.NewParser(strings.NewReader(table.SQL)).ParseStatement()
+	if err != nil {
+		return nil, fmt.Errorf("could not parse table %s: %w", table.Name, err)
+	}
+	create, ok := stmt.(*rsql.CreateTableStatement)
+	if !ok {
+		return nil, fmt.Errorf("table %s was not parsed as a CREATE TABLE statement: %T", table.Name, stmt)
+	}
+	tempName := "_sqlt_new_" + table.Name
+	create.Name = &rsql.Ident{Name: tempName, Quoted: true}
+	var columns []string
+	for _, col := range table.Columns {
+		if col.Hidden < 2 {
+			columns = append(columns, quoteIdent(col.Name))
+		}
+	}
+	joined := strings.Join(columns, ", ")
+	return []string{
+		statementSQL(create),
+		fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s", quoteIdent(tempName), joined, joined, quoteIdent(table.Name)),
+		"DROP TABLE " + quoteIdent(table.Name),
+		fmt.Sprintf("ALTER TABLE %s RENAME TO %s", quoteIdent(tempName), quoteIdent(table.Name)),
+	}, nil
+}
+
+// dualWriteTriggerNames returns the names of the triggers created by dualWriteColumnTriggers.
+func dualWriteTriggerNames(table, from, to string) []string {
+	prefix := fmt.Sprintf("_sqlt_dual_%s_%s_%s_", table, from, to)
+	return []string{prefix + "insert_from", prefix + "insert_to", prefix + "update_from", prefix + "update_to"}
+}
+
+// dualWriteColumnTriggers returns triggers copying writes to column from of table into column to and back,
+// so applications using either name see the same values. Rows are matched by rowid, or by primary key
+// for WITHOUT ROWID tables. The WHEN conditions stop the copies from triggering each other.
+func dualWriteColumnTriggers(table *TableDefinition, from, to string) []string {
+	names := dualWriteTriggerNames(table.Name, from, to)
+	qTable, qFrom, qTo := quoteIdent(table.Name), quoteIdent(from), quoteIdent(to)
+	match := "rowid = NEW.rowid"
+	if table.WithoutRowid {
+		conds := make([]string, len(table.PrimaryKey))
+		for i, col := range table.PrimaryKey {
+			conds[i] = fmt.Sprintf("%s = NEW.%s", quoteIdent(col), quoteIdent(col))
+		}
+		match = strings.Join(conds, " AND ")
+	}
+	trigger := func(name, event, when, set, value string) string {
+		return fmt.Sprintf("CREATE TRIGGER %s AFTER %s ON %s FOR EACH ROW WHEN %s BEGIN UPDATE %s SET %s = NEW.%s WHERE %s; END",
+			quoteIdent(name), event, qTable, when, qTable, set, value, match)
+	}
+	return []string{
+		trigger(names[0], "INSERT", "NEW."+qTo+" IS NULL AND NEW."+qFrom+" IS NOT NULL", qTo, qFrom),
+		trigger(names[1], "INSERT", "NEW."+qFrom+" IS NULL AND NEW."+qTo+" IS NOT NULL", qFrom, qTo),
+		trigger(names[2], "UPDATE OF "+qFrom, "NEW."+qFrom+" IS NOT OLD."+qFrom+" AND NEW."+qTo+" IS NOT NEW."+qFrom, qTo, qFrom),
+		trigger(names[3], "UPDATE OF "+qTo, "NEW."+qTo+" IS NOT OLD."+qTo+" AND NEW."+qFrom+" IS NOT NEW."+qTo, qFrom, qTo),
+	}
+}
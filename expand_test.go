@@ -0,0 +1,102 @@
+package sqlt_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/james-darko/gort"
+	"github.com/james-darko/sqlt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanExpandContract(t *testing.T) {
+	t.Parallel()
+	db := getTestDB(t)
+	defer db.Close()
+	ctx := gort.Context()
+
+	old := `
+CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, email TEXT);
+CREATE INDEX idx_users_name ON users (name);
+CREATE VIEW user_names AS SELECT name FROM users;`
+	new := `
+CREATE TABLE users (id INTEGER PRIMARY KEY, full_name TEXT NOT NULL, age INTEGER);
+CREATE INDEX idx_users_full_name ON users (full_name);
+CREATE TABLE posts (id INTEGER PRIMARY KEY, user_id INTEGER NOT NULL REFERENCES users (id));
+CREATE VIEW user_names AS SELECT full_name FROM users;`
+	require.NoError(t, sqlt.ExecString(ctx, db, old))
+	db.MustExec("INSERT INTO users (id, name, email) VALUES (1, 'Ann', 'ann@example.com')")
+
+	plan, err := sqlt.PlanExpandContract(strings.NewReader(old), strings.NewReader(new), sqlt.WithRenamedColumn("users", "name", "full_name"))
+	require.NoError(t, err)
+
+	_, err = db.Exec(strings.Join(plan.Expand, ";\n"))
+	require.NoError(t, err, strings.Join(plan.Expand, ";\n"))
+	// The old version writes name, the new one full_name.
+	db.MustExec("INSERT INTO users (id, name) VALUES (2, 'Bob')")
+	db.MustExec("INSERT INTO users (id, full_name, age) VALUES (3, 'Cid', 30)")
+	db.MustExec("UPDATE users SET name = 'Anne' WHERE id = 1")
+	var rows []struct {
+		Name     *string `db:"name"`
+		FullName *string `db:"full_name"`
+	}
+	require.NoError(t, db.Select(&rows, "SELECT name, full_name FROM users ORDER BY id"))
+	require.Len(t, rows, 3)
+	for i, want := range []string{"Anne", "Bob", "Cid"} {
+		assert.Equal(t, want, *rows[i].Name)
+		assert.Equal(t, want, *rows[i].FullName)
+	}
+
+	db.MustExec("PRAGMA foreign_keys = OFF")
+	_, err = db.Exec(strings.Join(plan.Contract, ";\n"))
+	require.NoError(t, err, strings.Join(plan.Contract, ";\n"))
+	require.NoError(t, sqlt.VerifyString(ctx, db, new))
+	var names []string
+	require.NoError(t, db.Select(&names, "SELECT full_name FROM user_names ORDER BY full_name"))
+	assert.Equal(t, []string{"Anne", "Bob", "Cid"}, names)
+}
+
+func TestSyncTriggers(t *testing.T) {
+	t.Parallel()
+	db := getTestDB(t)
+	defer db.Close()
+	ctx := gort.Context()
+
+	schema := `
+CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, email TEXT);
+CREATE TABLE users_v2 (id INTEGER PRIMARY KEY, name TEXT NOT NULL, age INTEGER);`
+	require.NoError(t, sqlt.ExecString(ctx, db, schema))
+	def, err := sqlt.ParseSchemaReader(strings.NewReader(schema))
+	require.NoError(t, err)
+	triggers, err := sqlt.SyncTriggers(def.Table("users"), def.Table("users_v2"))
+	require.NoError(t, err)
+	_, err = db.Exec(strings.Join(triggers, ";\n"))
+	require.NoError(t, err)
+
+	db.MustExec("INSERT INTO users (id, name, email) VALUES (1, 'Ann', 'a@example.com'), (2, 'Bob', NULL)")
+	db.MustExec("UPDATE users_v2 SET age = 30 WHERE id = 1")
+	db.MustExec("UPDATE users SET name = 'Anne', id = 3 WHERE id = 1")
+	db.MustExec("DELETE FROM users WHERE id = 2")
+
+	var rows []struct {
+		ID   int
+		Name string
+		Age  *int
+	}
+	require.NoError(t, db.Select(&rows, "SELECT id, name, age FROM users_v2 ORDER BY id"))
+	require.Len(t, rows, 1)
+	assert.Equal(t, 3, rows[0].ID)
+	assert.Equal(t, "Anne", rows[0].Name)
+	require.NotNil(t, rows[0].Age)
+	assert.Equal(t, 30, *rows[0].Age)
+
+	_, err = sqlt.SyncTriggers(def.Table("users_v2"), def.Table("users"))
+	require.NoError(t, err)
+	def, err = sqlt.ParseSchemaReader(strings.NewReader(`
+CREATE TABLE a (id INTEGER PRIMARY KEY);
+CREATE TABLE b (id INTEGER PRIMARY KEY, required TEXT NOT NULL);`))
+	require.NoError(t, err)
+	_, err = sqlt.SyncTriggers(def.Table("a"), def.Table("b"))
+	assert.ErrorContains(t, err, "required")
+}
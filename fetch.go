@@ -0,0 +1,207 @@
+package sqlt
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	rsql "github.com/rqlite/sql"
+)
+
+// SchemaFetcher retrieves the schema stored at a location for AutoMigrateFromEnv, see WithSchemaFetcher.
+type SchemaFetcher interface {
+	FetchSchema(ctx context.Context, location string) ([]byte, error)
+}
+
+// SchemaFetcherFunc adapts a function to a SchemaFetcher.
+type SchemaFetcherFunc func(ctx context.Context, location string) ([]byte, error)
+
+// FetchSchema calls f.
+func (f SchemaFetcherFunc) FetchSchema(ctx context.Context, location string) ([]byte, error) {
+	return f(ctx, location)
+}
+
+// WithSchemaFetcher makes AutoMigrateFromEnv fetch schema locations of the form scheme://... with f,
+// e.g. s3:// locations with an S3 client. http and https locations are fetched with http.DefaultClient
+// unless a fetcher is set for them. Locations without a scheme are local file paths.
+func WithSchemaFetcher(scheme string, f SchemaFetcher) MigrateOption {
+	return func(cfg *migrateConfig) {
+		if cfg.fetchers == nil {
+			cfg.fetchers = make(map[string]SchemaFetcher)
+		}
+		cfg.fetchers[strings.ToLower(scheme)] = f
+	}
+}
+
+// fetchSchema returns the content of the schema at location.
+func (cfg *migrateConfig) fetchSchema(ctx context.Context, location string) ([]byte, error) {
+	scheme, path, ok := strings.Cut(location, "://")
+	if !ok {
+		content, err := os.ReadFile(location)
+		if err != nil {
+			return nil, fmt.Errorf("could not read schema file %s: %w", location, err)
+		}
+		return content, nil
+	}
+	scheme = strings.ToLower(scheme)
+	fetcher, ok := cfg.fetchers[scheme]
+	if !ok {
+		switch scheme {
+		case "http", "https":
+			fetcher = httpSchemaFetcher{client: http.DefaultClient}
+		case "file":
+			return cfg.fetchSchema(ctx, path)
+		default:
+			return nil, fmt.Errorf("no schema fetcher for %s, see WithSchemaFetcher", location)
+		}
+	}
+	content, err := fetcher.FetchSchema(ctx, location)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch schema %s: %w", location, err)
+	}
+	return content, nil
+}
+
+// fetchedSchemas caches the schemas fetched over HTTP by URL, so unchanged schemas are revalidated
+// with their ETag instead of being downloaded again.
+var fetchedSchemas sync.Map // map[string]*fetchedSchema
+
+type fetchedSchema struct {
+	etag    string
+	content []byte
+}
+
+type httpSchemaFetcher struct {
+	client *http.Client
+}
+
+func (f httpSchemaFetcher) FetchSchema(ctx context.Context, location string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return nil, err
+	}
+	var cached *fetchedSchema
+	if v, ok := fetchedSchemas.Load(location); ok {
+		cached = v.(*fetchedSchema)
+		req.Header.Set("If-None-Match", cached.etag)
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		return cached.content, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if etag := resp.Header.Get("ETag"); etag != "" {
+		fetchedSchemas.Store(location, &fetchedSchema{etag: etag, content: content})
+	} else {
+		fetchedSchemas.Delete(location)
+	}
+	return content, nil
+}
+
+// parsedSchemas caches the parsed statements of the last schema fetched from each location together
+// with the hash of its content, so a schema fetched again unchanged is not parsed again.
+var parsedSchemas sync.Map // map[string]*parsedSchema
+
+type parsedSchema struct {
+	hash  [sha256.Size]byte
+	stmts []rsql.Statement
+}
+
+// loadSchema returns the parsed statements of the schema at location, which is a local file path or
+// a location fetched with fetchSchema. If a schema public key is set, the schema's signature is checked
+// first, see WithSchemaPublicKey. The returned statements are shared and must not be modified.
+func (cfg *migrateConfig) loadSchema(ctx context.Context, location string) ([]rsql.Statement, error) {
+	key, err := cfg.schemaPublicKey()
+	if err != nil {
+		return nil, err
+	}
+	if key == nil && !strings.Contains(location, "://") {
+		return loadSchemaFile(location)
+	}
+	content, err := cfg.fetchSchema(ctx, location)
+	if err != nil {
+		return nil, err
+	}
+	if key != nil {
+		if err := cfg.verifySchemaSignature(ctx, key, location, content); err != nil {
+			return nil, err
+		}
+	}
+	hash := sha256.Sum256(content)
+	if v, ok := parsedSchemas.Load(location); ok && v.(*parsedSchema).hash == hash {
+		return v.(*parsedSchema).stmts, nil
+	}
+	stmts, err := parseSchemaStatements(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("schema %s: %w", location, err)
+	}
+	parsedSchemas.Store(location, &parsedSchema{hash: hash, stmts: stmts})
+	return stmts, nil
+}
+
+// ErrInvalidSchemaSignature is returned by AutoMigrateFromEnv when a schema does not match its signature.
+var ErrInvalidSchemaSignature = errors.New("invalid schema signature")
+
+// WithSchemaPublicKey makes AutoMigrateFromEnv refuse schemas not signed with the private key of key,
+// so whoever controls the schema location cannot inject statements. The ed25519 signature of the schema
+// is fetched from the schema location with .sig appended, either as the raw 64 bytes or base64 encoded.
+// Without the option, the base64 encoded key in the DATABASE_SCHEMA_PUBLIC_KEY environment variable is used, if set.
+func WithSchemaPublicKey(key ed25519.PublicKey) MigrateOption {
+	return func(cfg *migrateConfig) {
+		cfg.schemaKey = key
+	}
+}
+
+// schemaPublicKey returns the key schemas must be signed with, or nil if they need no signature.
+func (cfg *migrateConfig) schemaPublicKey() (ed25519.PublicKey, error) {
+	if cfg.schemaKey != nil {
+		return cfg.schemaKey, nil
+	}
+	encoded, ok := os.LookupEnv("DATABASE_SCHEMA_PUBLIC_KEY")
+	if !ok || encoded == "" {
+		return nil, nil
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("DATABASE_SCHEMA_PUBLIC_KEY is not a base64 encoded ed25519 public key")
+	}
+	return key, nil
+}
+
+// verifySchemaSignature checks content against the signature stored next to the schema at location.
+func (cfg *migrateConfig) verifySchemaSignature(ctx context.Context, key ed25519.PublicKey, location string, content []byte) error {
+	sig, err := cfg.fetchSchema(ctx, location+".sig")
+	if err != nil {
+		return fmt.Errorf("could not read schema signature: %w", err)
+	}
+	if len(sig) != ed25519.SignatureSize {
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig)))
+		if err != nil {
+			return fmt.Errorf("%w: signature of %s is neither raw nor base64 encoded", ErrInvalidSchemaSignature, location)
+		}
+		sig = decoded
+	}
+	if !ed25519.Verify(key, content, sig) {
+		return fmt.Errorf("%w: %s", ErrInvalidSchemaSignature, location)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package sqlt
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	rsql "github.com/rqlite/sql"
+)
+
+var fuzzSchemaSeeds = []string{
+	"CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL DEFAULT '', email TEXT UNIQUE COLLATE NOCASE);",
+	"CREATE TABLE t (a INT, b REAL CHECK (b > 0), PRIMARY KEY (a, b)) WITHOUT ROWID;",
+	"CREATE TABLE s (id INTEGER PRIMARY KEY AUTOINCREMENT, data BLOB) STRICT;",
+	"CREATE TABLE posts (id INTEGER PRIMARY KEY, user_id INTEGER REFERENCES users (id) ON DELETE CASCADE, CONSTRAINT u UNIQUE (user_id, id));",
+	"CREATE TABLE g (a INTEGER, b INTEGER GENERATED ALWAYS AS (a * 2) STORED);",
+	"CREATE INDEX idx ON users (name DESC, email COLLATE BINARY) WHERE name IS NOT NULL;",
+	"CREATE UNIQUE INDEX IF NOT EXISTS \"Quoted Idx\" ON \"Users\" (\"Name\");",
+	"CREATE VIEW v AS SELECT id, name FROM users WHERE id > 10;",
+	"CREATE TRIGGER trg AFTER INSERT ON users BEGIN UPDATE users SET name = upper(new.name) WHERE id = new.id; END;",
+}
+
+// FuzzParseSchema checks that rendering a parsed schema is stable: parsing the rendered SQL
+// and rendering it again gives the same SQL, and both parses compare as exact matches.
+func FuzzParseSchema(f *testing.F) {
+	for _, seed := range fuzzSchemaSeeds {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, schema string) {
+		stmts, err := parseSchemaStatements(strings.NewReader(schema))
+		if err != nil {
+			return
+		}
+		for _, stmt := range stmts {
+			checkRoundTrip(t, stmt)
+		}
+	})
+}
+
+// FuzzCompareStatements generates tables from their parts and checks that each compares
+// as an exact match with itself and with its rendered and parsed again form.
+func FuzzCompareStatements(f *testing.F) {
+	f.Add("users", "id", "INTEGER", "name", "TEXT", true, false, "''", false)
+	f.Add("t", "a", "INT", "b", "VARCHAR(10)", false, true, "", true)
+	f.Add("Quoted Table", "Col One", "", "x", "REAL", false, false, "1.5", false)
+	f.Fuzz(func(t *testing.T, table, col1, type1, col2, type2 string, notNull, unique bool, def string, withoutRowid bool) {
+		col := quoteIdent(col2) + " " + type2
+		if notNull {
+			col += " NOT NULL"
+		}
+		if unique {
+			col += " UNIQUE"
+		}
+		if def != "" {
+			col += " DEFAULT " + def
+		}
+		ddl := fmt.Sprintf("CREATE TABLE %s (%s %s PRIMARY KEY, %s)", quoteIdent(table), quoteIdent(col1), type1, col)
+		if withoutRowid {
+			ddl += " WITHOUT ROWID"
+		}
+		stmt, err := parseSchemaStatement(rsql.NewParser(strings.NewReader(ddl)))
+		if err != nil {
+			return
+		}
+		checkRoundTrip(t, stmt)
+	})
+}
+
+func checkRoundTrip(t *testing.T, stmt rsql.Statement) {
+	t.Helper()
+	if !isSchemaStatement(stmt) {
+		return
+	}
+	for _, strictness := range []Strictness{StrictnessExact, StrictnessStructural, StrictnessAffinity} {
+		if match, diff, err := compareStatements(stmt, stmt, strictness); err != nil || match != statementMatchExact {
+			t.Fatalf("statement does not match itself with strictness %s: %d %s %v\n%s", strictness, match, diff, err, statementSQL(stmt))
+		}
+	}
+	rendered := statementSQL(stmt)
+	reparsed, err := rsql.NewParser(strings.NewReader(rendered)).ParseStatement()
+	if err != nil {
+		t.Fatalf("rendered statement does not parse: %v\n%s", err, rendered)
+	}
+	if again := statementSQL(reparsed); again != rendered {
+		t.Fatalf("rendering is not stable:\n%s\n%s", rendered, again)
+	}
+	if match, diff, err := compareStatements(stmt, reparsed, StrictnessStructural); err != nil || match != statementMatchExact {
+		t.Fatalf("statement does not match its rendering: %d %s %v\n%s", match, diff, err, rendered)
+	}
+}
@@ -0,0 +1,123 @@
+package sqlt
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	rsql "github.com/rqlite/sql"
+)
+
+// scriptBuilder collects statements instead of executing them.
+type scriptBuilder struct {
+	buf strings.Builder
+}
+
+func (b *scriptBuilder) Exec(query string, args ...any) (Result, error) {
+	b.buf.WriteString(strings.TrimRight(strings.TrimSpace(query), ";"))
+	b.buf.WriteString(";\n")
+	return nil, nil
+}
+
+// GenerateMigrationScript returns the statements AutoMigrate would execute with opts to turn a database
+// matching the from schema into one matching the to schema, without using a database.
+// Each statement is terminated with a semicolon.
+//
+// The database is assumed to support every feature of the latest SQLite, such as ALTER TABLE DROP COLUMN,
+// and options acting on the database rather than on the statements, such as WithMigrationLock, WithHistory
+// or WithDialect, are ignored. As with AutoMigrate, tables are only dropped with WithAllowTableDeletes, and
+// table changes that cannot be applied return an ErrSchemaConflicts.
+func GenerateMigrationScript(from, to io.Reader, opts ...MigrateOption) (string, error) {
+	fromStmts, err := parseSchemaStatements(from)
+	if err != nil {
+		return "", fmt.Errorf("from schema: %w", err)
+	}
+	toStmts, err := parseSchemaStatements(to)
+	if err != nil {
+		return "", fmt.Errorf("to schema: %w", err)
+	}
+	return generateMigrationSQL(fromStmts, toStmts, opts)
+}
+
+// GenerateMigrationSQL is GenerateMigrationScript for schemas already parsed, e.g. the current one
+// read with FetchDBSchema and the desired one with ParseSchemaReader, so the script AutoMigrate would run
+// can be written to a file and reviewed before it is applied.
+func GenerateMigrationSQL(current, desired *SchemaDefinition, opts ...MigrateOption) (string, error) {
+	currentStmts, err := current.statements()
+	if err != nil {
+		return "", fmt.Errorf("current schema: %w", err)
+	}
+	desiredStmts, err := desired.statements()
+	if err != nil {
+		return "", fmt.Errorf("desired schema: %w", err)
+	}
+	return generateMigrationSQL(currentStmts, desiredStmts, opts)
+}
+
+func generateMigrationSQL(from, to []rsql.Statement, opts []MigrateOption) (string, error) {
+	cfg := newMigrateConfig(opts)
+	var b scriptBuilder
+	if err := reconcileSchema(&b, from, to, cfg.tableDeletes, cfg, latestCapabilities); err != nil {
+		return "", err
+	}
+	return b.buf.String(), nil
+}
+
+// statements returns the statements of the objects of s in the order SQL writes them, each parsed
+// from the SQL of its own definition, which is all a SchemaDefinition keeps of the parsed schema.
+func (s *SchemaDefinition) statements() ([]rsql.Statement, error) {
+	var stmts []rsql.Statement
+	add := func(name, sql string) error {
+		stmt, err := parseSchemaStatement(rsql.NewParser(strings.NewReader(sql)))
+		if err != nil {
+			return fmt.Errorf("could not parse SQL of %s (SQL: %s): %w", name, sql, err)
+		}
+		stmts = append(stmts, stmt)
+		return nil
+	}
+	for _, table := range sortedByName(s.Tables, func(t *TableDefinition) string { return t.Name }) {
+		if err := add(table.Name, table.SQL); err != nil {
+			return nil, err
+		}
+	}
+	for _, index := range sortedByName(s.Indexes, func(i *IndexDefinition) string { return i.Name }) {
+		if err := add(index.Name, index.SQL); err != nil {
+			return nil, err
+		}
+	}
+	for _, view := range sortedByName(s.Views, func(v *ViewDefinition) string { return v.Name }) {
+		if err := add(view.Name, view.SQL); err != nil {
+			return nil, err
+		}
+	}
+	for _, trigger := range sortedByName(s.Triggers, func(t *TriggerDefinition) string { return t.Name }) {
+		if err := add(trigger.Name, trigger.SQL); err != nil {
+			return nil, err
+		}
+	}
+	return stmts, nil
+}
+
+// GenerateMigrationScriptWithReverse is GenerateMigrationScript that also returns the script migrating back
+// from to to from with the same options. The reverse script restores the schema only; data in dropped
+// tables or columns is not restored.
+func GenerateMigrationScriptWithReverse(from, to io.Reader, opts ...MigrateOption) (forward, reverse string, err error) {
+	fromSQL, err := io.ReadAll(from)
+	if err != nil {
+		return "", "", fmt.Errorf("could not read from schema: %w", err)
+	}
+	toSQL, err := io.ReadAll(to)
+	if err != nil {
+		return "", "", fmt.Errorf("could not read to schema: %w", err)
+	}
+	forward, err = GenerateMigrationScript(bytes.NewReader(fromSQL), bytes.NewReader(toSQL), opts...)
+	if err != nil {
+		return "", "", err
+	}
+	reverse, err = GenerateMigrationScript(bytes.NewReader(toSQL), bytes.NewReader(fromSQL), opts...)
+	if err != nil {
+		return "", "", fmt.Errorf("reverse: %w", err)
+	}
+	return forward, reverse, nil
+}
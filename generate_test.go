@@ -0,0 +1,87 @@
+package sqlt_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/james-darko/gort"
+	"github.com/james-darko/sqlt"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateMigrationScript_ForwardAndReverse(t *testing.T) {
+	t.Parallel()
+	ctx := gort.Context()
+
+	fromSchema := `
+CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, email TEXT);
+CREATE INDEX idx_users_name ON users (name);
+CREATE TABLE legacy (id INTEGER PRIMARY KEY);`
+	toSchema := `
+CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT, name TEXT);
+CREATE INDEX idx_users_name ON users (name);
+CREATE TABLE posts (id INTEGER PRIMARY KEY, user_id INTEGER REFERENCES users (id));
+CREATE VIEW user_names AS SELECT name FROM users;`
+
+	_, err := sqlt.GenerateMigrationScript(strings.NewReader(fromSchema), strings.NewReader(toSchema))
+	var notAllowed sqlt.ErrTableDeletionNotAllowed
+	require.ErrorAs(t, err, &notAllowed, "tables are dropped only with WithAllowTableDeletes, as in AutoMigrate")
+
+	forward, reverse, err := sqlt.GenerateMigrationScriptWithReverse(strings.NewReader(fromSchema), strings.NewReader(toSchema), sqlt.WithAllowTableDeletes())
+	require.NoError(t, err)
+	assert.Contains(t, forward, `DROP TABLE IF EXISTS "legacy";`)
+	assert.Contains(t, reverse, `DROP VIEW IF EXISTS "user_names";`)
+
+	db := getTestDB(t)
+	defer db.Close()
+	require.NoError(t, sqlt.ExecString(ctx, db, fromSchema))
+	_, err = db.Exec("INSERT INTO users (name, email) VALUES ('ann', 'ann@example.com')")
+	require.NoError(t, err)
+
+	require.NoError(t, sqlt.ExecString(ctx, db, forward))
+	require.NoError(t, sqlt.VerifyString(ctx, db, toSchema))
+	var email string
+	require.NoError(t, db.Get(&email, "SELECT email FROM users WHERE name = 'ann'"))
+	assert.Equal(t, "ann@example.com", email)
+
+	require.NoError(t, sqlt.ExecString(ctx, db, reverse))
+	assert.NoError(t, sqlt.VerifyString(ctx, db, fromSchema))
+}
+
+func TestGenerateMigrationScript_Conflict(t *testing.T) {
+	t.Parallel()
+
+	_, err := sqlt.GenerateMigrationScript(
+		strings.NewReader(`CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT);`),
+		strings.NewReader(`CREATE TABLE users (id INTEGER PRIMARY KEY, name INTEGER);`))
+	var conflictErr *sqlt.SchemaConflictError
+	assert.True(t, errors.As(err, &conflictErr), "expected SchemaConflictError, got %v", err)
+}
+
+func TestGenerateMigrationSQL_Definitions(t *testing.T) {
+	t.Parallel()
+	ctx := gort.Context()
+	db := getTestDB(t)
+	defer db.Close()
+	require.NoError(t, sqlt.ExecString(ctx, db, `CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT);`))
+
+	current, err := sqlt.FetchDBSchema(ctx, db)
+	require.NoError(t, err)
+	desiredSQL := `CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, email TEXT);
+CREATE INDEX idx_users_email ON users (email);`
+	desired, err := sqlt.ParseSchemaReader(strings.NewReader(desiredSQL))
+	require.NoError(t, err)
+
+	_, err = sqlt.GenerateMigrationSQL(current, desired)
+	var conflict *sqlt.SchemaConflictError
+	require.ErrorAs(t, err, &conflict, "columns are added only with WithColumnAdditions, as in AutoMigrate")
+
+	script, err := sqlt.GenerateMigrationSQL(current, desired, sqlt.WithColumnAdditions(true))
+	require.NoError(t, err)
+	assert.Equal(t, "ALTER TABLE \"users\" ADD COLUMN \"email\" TEXT;\nCREATE INDEX \"idx_users_email\" ON \"users\" (\"email\");\n", script)
+	require.NoError(t, sqlt.ExecString(ctx, db, script))
+	assert.NoError(t, sqlt.VerifyString(ctx, db, desiredSQL))
+}
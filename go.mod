@@ -6,16 +6,18 @@ require github.com/jmoiron/sqlx v1.4.0 // direct
 
 require github.com/mattn/go-sqlite3 v1.14.28
 
-require github.com/rqlite/sql v0.0.0-20241111133259-a4122fabb196
+require (
+	github.com/james-darko/gort v0.0.0-20250525204534-336424590927
+	github.com/rqlite/sql v0.0.0-20241111133259-a4122fabb196
+	github.com/stretchr/testify v1.10.0
+	github.com/tursodatabase/libsql-client-go v0.0.0-20240902231107-85af5b9d094d
+)
 
 require (
 	github.com/antlr4-go/antlr/v4 v4.13.0 // indirect
 	github.com/coder/websocket v1.8.12 // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
-	github.com/james-darko/gort v0.0.0-20250525204534-336424590927 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	github.com/stretchr/testify v1.10.0 // indirect
-	github.com/tursodatabase/libsql-client-go v0.0.0-20240902231107-85af5b9d094d // indirect
 	golang.org/x/exp v0.0.0-20240325151524-a685a6edb6d8 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
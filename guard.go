@@ -0,0 +1,80 @@
+package sqlt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"unsafe"
+
+	"github.com/jmoiron/sqlx"
+	rsql "github.com/rqlite/sql"
+)
+
+// ErrUnboundedWrite is returned by databases opened with WithGuard for an UPDATE or DELETE
+// statement without a WHERE clause.
+var ErrUnboundedWrite = errors.New("UPDATE or DELETE without WHERE clause")
+
+// WithGuard rejects UPDATE and DELETE statements without a WHERE clause with ErrUnboundedWrite,
+// on the database and in its transactions, to prevent accidental mass updates in admin tooling.
+// Every method running or preparing a statement is guarded, including the query methods, which
+// would otherwise run DELETE ... RETURNING. Pass a context from AllowUnboundedWrite to a Context
+// method to run such a statement on purpose.
+// Statements the parser cannot handle are let through, as are those run through SQLX, SQLXTx or Std.
+func WithGuard() OpenOption {
+	return func(db *sqlxDB) {
+		db.guard = true
+	}
+}
+
+type allowUnboundedWriteKey struct{}
+
+// AllowUnboundedWrite returns a context letting the statements executed with it update or delete
+// every row of a table on a database opened with WithGuard.
+func AllowUnboundedWrite(ctx context.Context) context.Context {
+	return context.WithValue(ctx, allowUnboundedWriteKey{}, true)
+}
+
+// checkGuard returns ErrUnboundedWrite if guard is set and query updates or deletes without
+// a WHERE clause, unless ctx allows it.
+func checkGuard(ctx context.Context, guard bool, query string) (err error) {
+	if !guard || ctx.Value(allowUnboundedWriteKey{}) != nil {
+		return nil
+	}
+	upper := strings.ToUpper(query)
+	if !strings.Contains(upper, "UPDATE") && !strings.Contains(upper, "DELETE") {
+		return nil
+	}
+	defer func() {
+		if recover() != nil {
+			err = nil
+		}
+	}()
+	parser := rsql.NewParser(strings.NewReader(query))
+	for {
+		stmt, err := parser.ParseStatement()
+		if err != nil {
+			return nil
+		}
+		switch stmt := stmt.(type) {
+		case *rsql.UpdateStatement:
+			if stmt.WhereExpr == nil {
+				return fmt.Errorf("%w: %s", ErrUnboundedWrite, query)
+			}
+		case *rsql.DeleteStatement:
+			if stmt.WhereExpr == nil {
+				return fmt.Errorf("%w: %s", ErrUnboundedWrite, query)
+			}
+		}
+	}
+}
+
+// errRow returns a row whose Scan and Err return err, for QueryRow rejecting a statement before
+// running it. sqlx.Row keeps its error unexported, so it is set through reflection.
+func errRow(err error) *sqlx.Row {
+	row := &sqlx.Row{}
+	field := reflect.ValueOf(row).Elem().FieldByName("err")
+	reflect.NewAt(field.Type(), unsafe.Pointer(field.UnsafeAddr())).Elem().Set(reflect.ValueOf(err))
+	return row
+}
@@ -0,0 +1,112 @@
+package sqlt
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Reader is the set of query helpers that read from the database.
+type Reader interface {
+	Query(query string, args ...any) (*sqlx.Rows, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sqlx.Rows, error)
+	MustQuery(query string, args ...any) *sqlx.Rows
+	QueryRow(query string, args ...any) *sqlx.Row
+	// QueryRowContext runs the query and returns its error eagerly, as a *QueryError,
+	// instead of deferring it to Scan. sql.ErrNoRows is still only reported by Scan.
+	QueryRowContext(ctx context.Context, query string, args ...any) (*sqlx.Row, error)
+	MustQueryRow(query string, args ...any) *sqlx.Row
+	NamedQuery(query string, arg any) (*sqlx.Rows, error)
+
+	Get(dest any, query string, args ...any) error
+	GetContext(ctx context.Context, dest any, query string, args ...any) error
+	GetIn(dest any, query string, args ...any) error
+	GetInContext(ctx context.Context, dest any, query string, args ...any) error
+	// GetRow and GetRowContext are Get and GetContext with errors reported as *QueryError.
+	GetRow(dest any, query string, args ...any) error
+	GetRowContext(ctx context.Context, dest any, query string, args ...any) error
+	MustGet(dest any, query string, args ...any)
+	MustGetIn(dest any, query string, args ...any)
+
+	Select(dest any, query string, args ...any) error
+	SelectContext(ctx context.Context, dest any, query string, args ...any) error
+	SelectIn(dest any, query string, args ...any) error
+	SelectInContext(ctx context.Context, dest any, query string, args ...any) error
+	MustSelect(dest any, query string, args ...any)
+	MustSelectIn(dest any, query string, args ...any)
+	SelectSeq(query string, args ...any) *RowsSeq
+	SelectInSeq(query string, args ...any) *RowsSeq
+	// Exists reports whether query returns any row, e.g. Exists("SELECT 1 FROM users WHERE email = ?", email).
+	Exists(query string, args ...any) (bool, error)
+	ExistsContext(ctx context.Context, query string, args ...any) (bool, error)
+	// Count returns the number of rows query returns, e.g. Count("SELECT id FROM users WHERE active").
+	Count(query string, args ...any) (int64, error)
+	CountContext(ctx context.Context, query string, args ...any) (int64, error)
+	// SelectSeqContext and SelectInSeqContext check ctx between rows and close the cursor
+	// as soon as it is done, reporting ctx.Err() from Err.
+	SelectSeqContext(ctx context.Context, query string, args ...any) *RowsSeq
+	SelectInSeqContext(ctx context.Context, query string, args ...any) *RowsSeq
+}
+
+// Writer is the set of helpers that execute statements.
+type Writer interface {
+	Exec(query string, args ...any) (Result, error)
+	ExecContext(ctx context.Context, query string, args ...any) (Result, error)
+	MustExec(query string, args ...any) Result
+	IDExec(query string, args ...any) (int64, error)
+	IDExecContext(ctx context.Context, query string, args ...any) (int64, error)
+	AffectedExec(query string, args ...any) (int, error)
+	AffectedExecContext(ctx context.Context, query string, args ...any) (int, error)
+	NamedExec(query string, arg any) (Result, error)
+	// NamedExecBatch executes query for every element of slice within one transaction
+	// and returns the total rows affected and the last insert rowid.
+	// INSERT ... VALUES (...) queries are sent as multi-row inserts of at most BatchParamLimit parameters.
+	NamedExecBatch(query string, slice any) (Result, error)
+	NamedExecBatchContext(ctx context.Context, query string, slice any) (Result, error)
+	// ExecReturning executes a query with a RETURNING clause and scans the returned row into dest,
+	// or all returned rows if dest points to a slice. This supports text or UUID keys and
+	// multiple generated columns. Without a RETURNING clause the query is executed and
+	// its last insert rowid is stored in dest, which must then point to an integer.
+	ExecReturning(dest any, query string, args ...any) error
+	ExecReturningContext(ctx context.Context, dest any, query string, args ...any) error
+	// NamedExecReturning binds the named parameters of query from arg and runs it like ExecReturning.
+	// Passing the same struct pointer as dest and arg scans generated columns such as
+	// the id or created_at back into the inserted struct.
+	NamedExecReturning(dest any, query string, arg any) error
+	NamedExecReturningContext(ctx context.Context, dest any, query string, arg any) error
+}
+
+// Handle is implemented by both DB and Tx with identical signatures.
+// Code that should run both inside and outside a transaction accepts a Handle,
+// or just a Reader or Writer if it only needs one side.
+type Handle interface {
+	Reader
+	Writer
+	BindNamed(query string, arg any) (string, []any, error)
+	Prepare(query string) (*sqlx.Stmt, error)
+	Preparex(query string) (*sqlx.Stmt, error)
+	PrepareNamed(query string) (*sqlx.NamedStmt, error)
+	Rebind(query string) string
+	DriverName() string
+}
+
+// Sqler is the former name of Handle.
+//
+// Deprecated: Use Handle, or Reader or Writer.
+type Sqler = Handle
+
+// If err is not nil, it panics with the error wrapped in the sqlt.Error type.
+// Otherswise, it returns the value param
+func Mustv[T any](value T, err error) T {
+	if err != nil {
+		panic(Error{err})
+	}
+	return value
+}
+
+// If err is not nil, it panics with the error wrapped in the sqlt.Error type.
+func Must(err error) {
+	if err != nil {
+		panic(Error{err})
+	}
+}
@@ -8,10 +8,10 @@ func TestImplementations(t *testing.T) {
 	// just needs to compile
 	db := &sqlxDB{}
 	var _ DB = db
-	var _ Sqler = db
+	var _ Handle = db
 	tx := &txWrapper{}
 	var _ Tx = tx
-	var _ Sqler = tx
+	var _ Handle = tx
 	var r = &sqltResult{}
 	var _ Result = r
 }
@@ -0,0 +1,83 @@
+package sqlt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// WithHistory records every AutoMigrate run in the _sqlt_migrations_log table,
+// including failed runs whose changes were rolled back.
+//
+// Each row holds the start time, the given app version, the statements the run executed
+// as a JSON array, its duration and whether it succeeded along with the error if it did not.
+func WithHistory(appVersion string) MigrateOption {
+	return func(cfg *migrateConfig) {
+		cfg.history = true
+		cfg.appVersion = appVersion
+	}
+}
+
+// MigrationLogEntry is a row of the _sqlt_migrations_log table.
+type MigrationLogEntry struct {
+	ID         int64     `db:"id"`
+	StartedAt  time.Time `db:"started_at"`
+	AppVersion string    `db:"app_version"`
+	Plan       string    `db:"plan"`
+	DurationMS int64     `db:"duration_ms"`
+	Success    bool      `db:"success"`
+	Error      *string   `db:"error"`
+}
+
+// MigrationHistory returns the recorded AutoMigrate runs, most recent first.
+// See WithHistory for enabling the history.
+func MigrationHistory(ctx context.Context, db DB) ([]MigrationLogEntry, error) {
+	var entries []MigrationLogEntry
+	err := db.SelectContext(ctx, &entries, "SELECT id, started_at, app_version, plan, duration_ms, success, error FROM _sqlt_migrations_log ORDER BY id DESC")
+	if err != nil {
+		return nil, fmt.Errorf("could not read migration history: %w", err)
+	}
+	return entries, nil
+}
+
+// recordHistory writes a run to the history table if it is enabled.
+// The run has already finished when this is called, so failures are reported but not returned.
+func (cfg *migrateConfig) recordHistory(ctx context.Context, db DB, started time.Time, executed []string, runErr error) {
+	if !cfg.history {
+		return
+	}
+	// The run must be recorded even when the migration's context was cancelled.
+	ctx = context.WithoutCancel(ctx)
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS _sqlt_migrations_log (
+	id INTEGER PRIMARY KEY,
+	started_at TIMESTAMP NOT NULL,
+	app_version TEXT NOT NULL,
+	plan TEXT NOT NULL,
+	duration_ms INTEGER NOT NULL,
+	success BOOLEAN NOT NULL,
+	error TEXT
+)`)
+	if err != nil {
+		cfg.logf("could not create migration history table: %v", err)
+		return
+	}
+	if executed == nil {
+		executed = []string{}
+	}
+	plan, err := json.Marshal(executed)
+	if err != nil {
+		cfg.logf("could not encode migration plan: %v", err)
+		return
+	}
+	var errText *string
+	if runErr != nil {
+		s := runErr.Error()
+		errText = &s
+	}
+	_, err = db.ExecContext(ctx, "INSERT INTO _sqlt_migrations_log (started_at, app_version, plan, duration_ms, success, error) VALUES (?, ?, ?, ?, ?, ?)",
+		started.UTC(), cfg.appVersion, string(plan), time.Since(started).Milliseconds(), runErr == nil, errText)
+	if err != nil {
+		cfg.logf("could not record migration history: %v", err)
+	}
+}
@@ -0,0 +1,573 @@
+package sqlt
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// importedTable collects the definitions of a table converted from ORM models.
+type importedTable struct {
+	name        string
+	columns     []string
+	constraints []string
+	indexes     []*importedIndex
+	// foreignKeys identifies the foreign keys in constraints, see importGORMAssociation.
+	foreignKeys []string
+}
+
+type importedIndex struct {
+	name    string
+	unique  bool
+	columns []importedIndexColumn
+}
+
+type importedIndexColumn struct {
+	name     string
+	desc     bool
+	priority int
+}
+
+func (t *importedTable) index(name string) *importedIndex {
+	for _, index := range t.indexes {
+		if index.name == name {
+			return index
+		}
+	}
+	index := &importedIndex{name: name}
+	t.indexes = append(t.indexes, index)
+	return index
+}
+
+// importedSchema parses the statements creating tables into a SchemaDefinition.
+func importedSchema(tables []*importedTable) (*SchemaDefinition, error) {
+	var b strings.Builder
+	for _, t := range tables {
+		fmt.Fprintf(&b, "CREATE TABLE %s (\n  %s\n);\n", quoteIdent(t.name), strings.Join(append(t.columns, t.constraints...), ",\n  "))
+		for _, index := range t.indexes {
+			slices.SortStableFunc(index.columns, func(a, b importedIndexColumn) int { return cmp.Compare(a.priority, b.priority) })
+			columns := make([]string, len(index.columns))
+			for i, col := range index.columns {
+				columns[i] = quoteIdent(col.name)
+				if col.desc {
+					columns[i] += " DESC"
+				}
+			}
+			unique := ""
+			if index.unique {
+				unique = "UNIQUE "
+			}
+			fmt.Fprintf(&b, "CREATE %sINDEX %s ON %s (%s);\n", unique, quoteIdent(index.name), quoteIdent(t.name), strings.Join(columns, ", "))
+		}
+	}
+	return ParseSchemaReader(strings.NewReader(b.String()))
+}
+
+// snakeCase converts a Go identifier to snake case the way GORM and ent name columns and tables,
+// keeping initialisms together: UserID becomes user_id and HTTPStatus http_status.
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+var irregularPlurals = map[string]string{"person": "people", "child": "children", "man": "men", "woman": "women"}
+
+// pluralize returns the English plural of a snake case name, pluralizing its last word.
+func pluralize(name string) string {
+	prefix, word := "", name
+	if i := strings.LastIndexByte(name, '_'); i >= 0 {
+		prefix, word = name[:i+1], name[i+1:]
+	}
+	if plural, ok := irregularPlurals[word]; ok {
+		return prefix + plural
+	}
+	switch {
+	case strings.HasSuffix(word, "y") && len(word) > 1 && !strings.ContainsRune("aeiou", rune(word[len(word)-2])):
+		return prefix + word[:len(word)-1] + "ies"
+	case strings.HasSuffix(word, "s"), strings.HasSuffix(word, "x"), strings.HasSuffix(word, "z"),
+		strings.HasSuffix(word, "ch"), strings.HasSuffix(word, "sh"):
+		return prefix + word + "es"
+	}
+	return prefix + word + "s"
+}
+
+// ImportGORM converts GORM models, as passed to GORM's AutoMigrate, into a SchemaDefinition of the tables
+// GORM creates for them on SQLite, so their schema can be verified and migrated with sqlt. See SchemaDefinition.SQL.
+//
+// Tables are named by the model's TableName method or its pluralized snake case name, and columns by the
+// column tag setting or the field's snake case name. The primaryKey, column, type, not null,
+// default, unique, check, index, uniqueIndex, embedded and embeddedPrefix tag settings are supported, and
+// a field named ID is the primary key unless another field is tagged primaryKey. Belongs-to, has-one and
+// has-many associations following GORM's naming conventions, or naming their foreignKey, become foreign keys,
+// with their OnUpdate and OnDelete constraint settings. Many-to-many join tables are not imported.
+func ImportGORM(models ...any) (*SchemaDefinition, error) {
+	byType := make(map[reflect.Type]*importedTable, len(models))
+	tables := make([]*importedTable, 0, len(models))
+	types := make([]reflect.Type, 0, len(models))
+	for _, model := range models {
+		typ := reflect.TypeOf(model)
+		for typ != nil && typ.Kind() == reflect.Pointer {
+			typ = typ.Elem()
+		}
+		if typ == nil || typ.Kind() != reflect.Struct {
+			return nil, fmt.Errorf("ImportGORM: model %T is not a struct", model)
+		}
+		t := &importedTable{name: gormTableName(typ)}
+		byType[typ] = t
+		tables = append(tables, t)
+		types = append(types, typ)
+	}
+	for i, typ := range types {
+		if err := importGORMModel(tables[i], typ, byType); err != nil {
+			return nil, fmt.Errorf("ImportGORM: model %s: %w", typ.Name(), err)
+		}
+	}
+	s, err := importedSchema(tables)
+	if err != nil {
+		return nil, fmt.Errorf("ImportGORM: %w", err)
+	}
+	return s, nil
+}
+
+// gormTableName returns the name of the table of the model typ.
+func gormTableName(typ reflect.Type) string {
+	type tabler interface{ TableName() string }
+	if t, ok := reflect.New(typ).Interface().(tabler); ok {
+		return t.TableName()
+	}
+	return pluralize(snakeCase(typ.Name()))
+}
+
+// gormField is a field of a model with its parsed tag settings, keyed by upper-cased name.
+type gormField struct {
+	reflect.StructField
+	column   string
+	settings map[string]string
+}
+
+// parseGORMTag parses the settings of a gorm struct tag, such as `gorm:"column:name;not null;index:idx_name,unique"`.
+func parseGORMTag(tag string) map[string]string {
+	settings := make(map[string]string)
+	for _, part := range strings.Split(tag, ";") {
+		name, value, _ := strings.Cut(part, ":")
+		name = strings.ToUpper(strings.TrimSpace(name))
+		if name != "" {
+			settings[strings.ReplaceAll(name, "_", "")] = strings.TrimSpace(value)
+		}
+	}
+	return settings
+}
+
+// gormFields returns the fields of the model typ, with those of embedded structs flattened.
+func gormFields(typ reflect.Type, prefix string) []gormField {
+	var fields []gormField
+	for i := range typ.NumField() {
+		f := typ.Field(i)
+		tag := f.Tag.Get("gorm")
+		if tag == "-" || (!f.IsExported() && !f.Anonymous) {
+			continue
+		}
+		settings := parseGORMTag(tag)
+		if _, ok := settings["-"]; ok {
+			continue
+		}
+		ft := f.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		_, embedded := settings["EMBEDDED"]
+		if (f.Anonymous || embedded) && ft.Kind() == reflect.Struct && gormColumnType(ft) == "" {
+			fields = append(fields, gormFields(ft, prefix+settings["EMBEDDEDPREFIX"])...)
+			continue
+		}
+		column := settings["COLUMN"]
+		if column == "" {
+			column = prefix + snakeCase(f.Name)
+		}
+		fields = append(fields, gormField{StructField: f, column: column, settings: settings})
+	}
+	return fields
+}
+
+// gormColumnType returns the SQLite column type GORM uses for Go type typ, or "" if typ is an association.
+func gormColumnType(typ reflect.Type) string {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if typ == reflect.TypeFor[time.Time]() {
+		return "datetime"
+	}
+	switch typ.Kind() {
+	case reflect.Bool:
+		return "numeric"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "real"
+	case reflect.String:
+		return "text"
+	case reflect.Slice:
+		if typ.Elem().Kind() == reflect.Uint8 {
+			return "blob"
+		}
+	case reflect.Struct:
+		// Nullable wrappers such as sql.NullString and gorm.DeletedAt hold the value and a Valid flag.
+		if valid, ok := typ.FieldByName("Valid"); ok && valid.Type.Kind() == reflect.Bool && typ.NumField() == 2 {
+			return gormColumnType(typ.Field(0).Type)
+		}
+	}
+	return ""
+}
+
+func importGORMModel(t *importedTable, typ reflect.Type, byType map[reflect.Type]*importedTable) error {
+	fields := gormFields(typ, "")
+	hasPrimaryKey := slices.ContainsFunc(fields, func(f gormField) bool {
+		_, ok := f.settings["PRIMARYKEY"]
+		return ok
+	})
+	var primaryKey []string
+	for _, f := range fields {
+		colType := f.settings["TYPE"]
+		if colType == "" {
+			colType = gormColumnType(f.Type)
+		}
+		if colType == "" {
+			if err := importGORMAssociation(t, typ, f, byType); err != nil {
+				return err
+			}
+			continue
+		}
+		col := quoteIdent(f.column) + " " + colType
+		_, primary := f.settings["PRIMARYKEY"]
+		if primary || (!hasPrimaryKey && f.Name == "ID") {
+			primaryKey = append(primaryKey, quoteIdent(f.column))
+		}
+		if _, ok := f.settings["NOT NULL"]; ok {
+			col += " NOT NULL"
+		}
+		if _, ok := f.settings["UNIQUE"]; ok {
+			col += " UNIQUE"
+		}
+		if def, ok := f.settings["DEFAULT"]; ok {
+			if colType == "text" && !strings.HasPrefix(def, "'") && !strings.HasPrefix(def, "(") {
+				def = quoteString(def)
+			}
+			col += " DEFAULT " + def
+		}
+		t.columns = append(t.columns, col)
+		if check, ok := f.settings["CHECK"]; ok {
+			if name, expr, named := strings.Cut(check, ","); named && !strings.ContainsAny(name, " <>=!(") {
+				t.constraints = append(t.constraints, fmt.Sprintf("CONSTRAINT %s CHECK (%s)", quoteIdent(name), expr))
+			} else {
+				t.constraints = append(t.constraints, fmt.Sprintf("CHECK (%s)", check))
+			}
+		}
+		for _, setting := range []string{"INDEX", "UNIQUEINDEX"} {
+			value, ok := f.settings[setting]
+			if !ok {
+				continue
+			}
+			opts := strings.Split(value, ",")
+			name := strings.TrimSpace(opts[0])
+			if name == "" {
+				name = "idx_" + t.name + "_" + f.column
+			}
+			index := t.index(name)
+			index.unique = index.unique || setting == "UNIQUEINDEX"
+			col := importedIndexColumn{name: f.column, priority: 10}
+			for _, opt := range opts[1:] {
+				key, val, _ := strings.Cut(strings.TrimSpace(opt), ":")
+				switch strings.ToLower(key) {
+				case "unique":
+					index.unique = true
+				case "sort":
+					col.desc = strings.EqualFold(val, "desc")
+				case "priority":
+					fmt.Sscan(val, &col.priority)
+				}
+			}
+			index.columns = append(index.columns, col)
+		}
+	}
+	if len(primaryKey) > 0 {
+		t.constraints = append([]string{fmt.Sprintf("PRIMARY KEY (%s)", strings.Join(primaryKey, ", "))}, t.constraints...)
+	}
+	return nil
+}
+
+// importGORMAssociation adds the foreign key of a belongs-to or has-many association field of the model typ.
+// Fields referring to types that are not imported models are ignored.
+func importGORMAssociation(t *importedTable, typ reflect.Type, f gormField, byType map[reflect.Type]*importedTable) error {
+	target := f.Type
+	hasMany := false
+	for target.Kind() == reflect.Pointer || target.Kind() == reflect.Slice {
+		hasMany = hasMany || target.Kind() == reflect.Slice
+		target = target.Elem()
+	}
+	targetTable, ok := byType[target]
+	if !ok {
+		return nil
+	}
+	if _, ok := f.settings["MANY2MANY"]; ok {
+		return nil
+	}
+	onUpdate, onDelete := "", ""
+	for _, opt := range strings.Split(f.settings["CONSTRAINT"], ",") {
+		key, val, _ := strings.Cut(opt, ":")
+		switch strings.ToUpper(strings.TrimSpace(key)) {
+		case "ONUPDATE":
+			onUpdate = " ON UPDATE " + strings.TrimSpace(val)
+		case "ONDELETE":
+			onDelete = " ON DELETE " + strings.TrimSpace(val)
+		}
+	}
+	// A has-many or has-one association keeps the foreign key in the target, a belongs-to association in typ.
+	owner, owned, ownerTable, ownedTable := typ, target, t, targetTable
+	foreignKey := f.settings["FOREIGNKEY"]
+	if foreignKey == "" {
+		foreignKey = typ.Name() + "ID"
+		if !hasMany && gormColumnOf(typ, f.Name+"ID") != "" {
+			foreignKey = f.Name + "ID"
+		}
+	}
+	if !hasMany && gormColumnOf(target, foreignKey) == "" {
+		owner, owned, ownerTable, ownedTable = target, typ, targetTable, t
+	}
+	fkColumn := gormColumnOf(owned, foreignKey)
+	if fkColumn == "" {
+		return fmt.Errorf("association %s: no foreign key field %s in %s", f.Name, foreignKey, owned.Name())
+	}
+	references := f.settings["REFERENCES"]
+	if references == "" {
+		references = "ID"
+	}
+	refColumn := gormColumnOf(owner, references)
+	if refColumn == "" {
+		return fmt.Errorf("association %s: no referenced field %s in %s", f.Name, references, owner.Name())
+	}
+	// Both sides of an association may declare it; the foreign key is created once.
+	key := fkColumn + " " + ownerTable.name + " " + refColumn
+	if slices.Contains(ownedTable.foreignKeys, key) {
+		return nil
+	}
+	ownedTable.foreignKeys = append(ownedTable.foreignKeys, key)
+	ownedTable.constraints = append(ownedTable.constraints, fmt.Sprintf("CONSTRAINT %s FOREIGN KEY (%s) REFERENCES %s (%s)%s%s",
+		quoteIdent("fk_"+t.name+"_"+snakeCase(f.Name)), quoteIdent(fkColumn), quoteIdent(ownerTable.name), quoteIdent(refColumn), onUpdate, onDelete))
+	return nil
+}
+
+// gormColumnOf returns the column of the field name of the model typ, or "".
+func gormColumnOf(typ reflect.Type, name string) string {
+	for _, f := range gormFields(typ, "") {
+		if f.Name == name {
+			return f.column
+		}
+	}
+	return ""
+}
+
+// entSnapshot is the schema snapshot written by ent's schema/snapshot feature.
+type entSnapshot struct {
+	Schemas []*entSchema `json:"Schemas"`
+}
+
+type entSchema struct {
+	Name   string `json:"name"`
+	Config struct {
+		Table string `json:"Table"`
+	} `json:"config"`
+	Fields  []*entField `json:"fields"`
+	Edges   []*entEdge  `json:"edges"`
+	Indexes []*entIndex `json:"indexes"`
+}
+
+type entField struct {
+	Name string `json:"name"`
+	Info struct {
+		Type int `json:"Type"`
+	} `json:"type"`
+	Unique       bool              `json:"unique"`
+	Optional     bool              `json:"optional"`
+	Default      bool              `json:"default"`
+	DefaultValue any               `json:"default_value"`
+	StorageKey   string            `json:"storage_key"`
+	SchemaType   map[string]string `json:"schema_type"`
+}
+
+type entEdge struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Field   string `json:"field"`
+	RefName string `json:"ref_name"`
+	Inverse bool   `json:"inverse"`
+}
+
+type entIndex struct {
+	Unique     bool     `json:"unique"`
+	Fields     []string `json:"fields"`
+	Edges      []string `json:"edges"`
+	StorageKey string   `json:"storage_key"`
+}
+
+// entColumnTypes are the SQLite column types of ent's field types, indexed by their field.Type value.
+var entColumnTypes = []string{"", "bool", "datetime", "json", "uuid", "blob", "text", "text", "text",
+	"integer", "integer", "integer", "integer", "integer", "integer", "integer", "integer", "integer", "integer", "real", "real"}
+
+// ImportEnt converts an ent schema snapshot, the JSON generated by ent's schema/snapshot feature, into a
+// SchemaDefinition of the tables ent creates for it on SQLite, so the schema can be verified and migrated
+// with sqlt. See SchemaDefinition.SQL. The snapshot's Schemas array may also be passed on its own.
+//
+// Every table gets an integer id primary key unless the schema declares an id field. Edges become foreign keys
+// when they are bound to a field with Field; edges without a field and many-to-many join tables are not imported.
+func ImportEnt(snapshot io.Reader) (*SchemaDefinition, error) {
+	content, err := io.ReadAll(snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("ImportEnt: %w", err)
+	}
+	var snap entSnapshot
+	if err := json.Unmarshal(content, &snap); err != nil {
+		if err := json.Unmarshal(content, &snap.Schemas); err != nil {
+			return nil, fmt.Errorf("ImportEnt: could not decode snapshot: %w", err)
+		}
+	}
+	byName := make(map[string]*entSchema, len(snap.Schemas))
+	for _, schema := range snap.Schemas {
+		byName[schema.Name] = schema
+	}
+	tables := make([]*importedTable, len(snap.Schemas))
+	for i, schema := range snap.Schemas {
+		if tables[i], err = importEntSchema(schema, byName); err != nil {
+			return nil, fmt.Errorf("ImportEnt: schema %s: %w", schema.Name, err)
+		}
+	}
+	s, err := importedSchema(tables)
+	if err != nil {
+		return nil, fmt.Errorf("ImportEnt: %w", err)
+	}
+	return s, nil
+}
+
+func entTableName(schema *entSchema) string {
+	if schema.Config.Table != "" {
+		return schema.Config.Table
+	}
+	return pluralize(snakeCase(schema.Name))
+}
+
+func (f *entField) column() string {
+	if f.StorageKey != "" {
+		return f.StorageKey
+	}
+	return f.Name
+}
+
+func importEntSchema(schema *entSchema, byName map[string]*entSchema) (*importedTable, error) {
+	t := &importedTable{name: entTableName(schema)}
+	fields := make(map[string]*entField, len(schema.Fields))
+	if !slices.ContainsFunc(schema.Fields, func(f *entField) bool { return f.Name == "id" }) {
+		t.columns = append(t.columns, quoteIdent("id")+" integer NOT NULL PRIMARY KEY AUTOINCREMENT")
+	}
+	for _, f := range schema.Fields {
+		fields[f.Name] = f
+		colType := f.SchemaType["sqlite3"]
+		if colType == "" && f.Info.Type > 0 && f.Info.Type < len(entColumnTypes) {
+			colType = entColumnTypes[f.Info.Type]
+		}
+		if colType == "" {
+			return nil, fmt.Errorf("field %s has unknown type %d", f.Name, f.Info.Type)
+		}
+		col := quoteIdent(f.column()) + " " + colType
+		if f.Name == "id" {
+			col += " NOT NULL PRIMARY KEY"
+			if colType == "integer" {
+				col += " AUTOINCREMENT"
+			}
+		} else {
+			if f.Unique {
+				col += " UNIQUE"
+			}
+			if !f.Optional {
+				col += " NOT NULL"
+			}
+		}
+		if f.Default && f.DefaultValue != nil {
+			switch v := f.DefaultValue.(type) {
+			case string:
+				col += " DEFAULT " + quoteString(v)
+			case bool, float64:
+				col += fmt.Sprintf(" DEFAULT %v", v)
+			}
+		}
+		t.columns = append(t.columns, col)
+	}
+	edgeColumns := make(map[string]string)
+	for _, edge := range schema.Edges {
+		if edge.Field == "" {
+			continue
+		}
+		f, ok := fields[edge.Field]
+		if !ok {
+			return nil, fmt.Errorf("edge %s: field %s not found", edge.Name, edge.Field)
+		}
+		target, ok := byName[edge.Type]
+		if !ok {
+			return nil, fmt.Errorf("edge %s: schema %s not found", edge.Name, edge.Type)
+		}
+		edgeColumns[edge.Name] = f.column()
+		name := edge.Name
+		if edge.Inverse && edge.RefName != "" {
+			name = edge.RefName
+		}
+		onDelete := "NO ACTION"
+		if f.Optional {
+			onDelete = "SET NULL"
+		}
+		t.constraints = append(t.constraints, fmt.Sprintf("CONSTRAINT %s FOREIGN KEY (%s) REFERENCES %s (%s) ON DELETE %s",
+			quoteIdent(t.name+"_"+entTableName(target)+"_"+name), quoteIdent(f.column()), quoteIdent(entTableName(target)), quoteIdent("id"), onDelete))
+	}
+	for _, idx := range schema.Indexes {
+		var columns []string
+		for _, name := range idx.Fields {
+			f, ok := fields[name]
+			if !ok {
+				return nil, fmt.Errorf("index field %s not found", name)
+			}
+			columns = append(columns, f.column())
+		}
+		for _, name := range idx.Edges {
+			col, ok := edgeColumns[name]
+			if !ok {
+				return nil, fmt.Errorf("index edge %s has no field", name)
+			}
+			columns = append(columns, col)
+		}
+		name := idx.StorageKey
+		if name == "" {
+			name = strings.ToLower(snakeCase(schema.Name) + "_" + strings.Join(columns, "_"))
+		}
+		index := t.index(name)
+		index.unique = idx.Unique
+		for _, col := range columns {
+			index.columns = append(index.columns, importedIndexColumn{name: col})
+		}
+	}
+	return t, nil
+}
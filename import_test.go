@@ -0,0 +1,155 @@
+package sqlt_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/james-darko/gort"
+	"github.com/james-darko/sqlt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// gormModel mirrors gorm.Model.
+type gormModel struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt struct {
+		Time  time.Time
+		Valid bool
+	} `gorm:"index"`
+}
+
+type Customer struct {
+	gormModel
+	Email     string    `gorm:"not null;uniqueIndex"`
+	FirstName string    `gorm:"index:idx_name,priority:2"`
+	LastName  string    `gorm:"index:idx_name,priority:1"`
+	Tier      string    `gorm:"default:free"`
+	Age       *int      `gorm:"check:age >= 0"`
+	Invoices  []Invoice `gorm:"constraint:OnDelete:CASCADE"`
+	Ignored   string    `gorm:"-"`
+}
+
+type Invoice struct {
+	ID         int64
+	CustomerID uint
+	Total      float64
+	Paid       bool
+	PDF        []byte `gorm:"column:pdf_blob"`
+}
+
+type ledgerEntry struct {
+	Key    string `gorm:"primaryKey"`
+	Amount int
+}
+
+func (ledgerEntry) TableName() string { return "ledger" }
+
+func TestImportGORM(t *testing.T) {
+	t.Parallel()
+	schema, err := sqlt.ImportGORM(&Customer{}, Invoice{}, ledgerEntry{})
+	require.NoError(t, err)
+
+	customers := schema.Table("customers")
+	require.NotNil(t, customers)
+	var columns []string
+	for _, col := range customers.Columns {
+		columns = append(columns, col.Name)
+	}
+	assert.Equal(t, []string{"id", "created_at", "updated_at", "deleted_at", "email", "first_name", "last_name", "tier", "age"}, columns)
+	assert.Equal(t, []string{"id"}, customers.PrimaryKey)
+	assert.Equal(t, "datetime", customers.Column("deleted_at").Type)
+	assert.True(t, customers.Column("email").NotNull)
+	require.NotNil(t, customers.Column("tier").DefaultValue)
+	assert.Equal(t, "'free'", *customers.Column("tier").DefaultValue)
+	assert.Len(t, customers.Checks, 1)
+
+	require.NotNil(t, schema.Index("idx_customers_email"))
+	assert.True(t, schema.Index("idx_customers_email").Unique)
+	require.NotNil(t, schema.Index("idx_name"))
+	assert.Equal(t, []string{"last_name", "first_name"}, schema.Index("idx_name").Columns)
+	require.NotNil(t, schema.Index("idx_customers_deleted_at"))
+
+	invoices := schema.Table("invoices")
+	require.NotNil(t, invoices)
+	assert.Equal(t, "pdf_blob", invoices.Columns[4].Name)
+	assert.Equal(t, "numeric", invoices.Column("paid").Type)
+	require.Len(t, invoices.ForeignKeys, 1)
+	assert.Equal(t, []string{"customer_id"}, invoices.ForeignKeys[0].Columns)
+	assert.Equal(t, "customers", invoices.ForeignKeys[0].ForeignTable)
+	assert.Equal(t, "CASCADE", invoices.ForeignKeys[0].OnDelete)
+
+	require.NotNil(t, schema.Table("ledger"))
+	assert.Equal(t, []string{"key"}, schema.Table("ledger").PrimaryKey)
+
+	db := getTestDB(t)
+	defer db.Close()
+	ctx := gort.Context()
+	require.NoError(t, sqlt.AutoMigrate(ctx, db, strings.NewReader(schema.SQL()), false))
+	require.NoError(t, sqlt.VerifyString(ctx, db, schema.SQL()))
+}
+
+const entSnapshot = `{
+  "Schema": "example/ent/schema",
+  "Package": "example/ent",
+  "Schemas": [
+    {
+      "name": "User",
+      "config": {"Table": ""},
+      "edges": [{"name": "cars", "type": "Car"}],
+      "fields": [
+        {"name": "name", "type": {"Type": 7}, "unique": true},
+        {"name": "age", "type": {"Type": 12}, "optional": true},
+        {"name": "active", "type": {"Type": 1}, "default": true, "default_value": true},
+        {"name": "role", "type": {"Type": 6}, "default": true, "default_value": "member"}
+      ],
+      "indexes": [{"fields": ["name", "age"]}]
+    },
+    {
+      "name": "Car",
+      "edges": [{"name": "owner", "type": "User", "field": "owner_id", "ref_name": "cars", "inverse": true, "unique": true}],
+      "fields": [
+        {"name": "model", "type": {"Type": 7}, "schema_type": {"sqlite3": "varchar(64)"}},
+        {"name": "owner_id", "type": {"Type": 12}, "optional": true}
+      ],
+      "indexes": [{"unique": true, "fields": ["model"], "edges": ["owner"], "storage_key": "car_model_owner"}]
+    }
+  ],
+  "Features": ["schema/snapshot"]
+}`
+
+func TestImportEnt(t *testing.T) {
+	t.Parallel()
+	schema, err := sqlt.ImportEnt(strings.NewReader(entSnapshot))
+	require.NoError(t, err)
+
+	users := schema.Table("users")
+	require.NotNil(t, users)
+	assert.Equal(t, "id", users.Columns[0].Name)
+	assert.Equal(t, []string{"id"}, users.PrimaryKey)
+	assert.True(t, users.Column("name").NotNull)
+	assert.True(t, users.Column("name").Unique)
+	assert.False(t, users.Column("age").NotNull)
+	assert.Equal(t, "'member'", *users.Column("role").DefaultValue)
+	require.NotNil(t, schema.Index("user_name_age"))
+
+	cars := schema.Table("cars")
+	require.NotNil(t, cars)
+	assert.Equal(t, "varchar(64)", strings.ToLower(cars.Column("model").Type))
+	require.Len(t, cars.ForeignKeys, 1)
+	assert.Equal(t, "users", cars.ForeignKeys[0].ForeignTable)
+	assert.Equal(t, "SET NULL", cars.ForeignKeys[0].OnDelete)
+	index := schema.Index("car_model_owner")
+	require.NotNil(t, index)
+	assert.True(t, index.Unique)
+	assert.Equal(t, []string{"model", "owner_id"}, index.Columns)
+
+	db := getTestDB(t)
+	defer db.Close()
+	ctx := gort.Context()
+	require.NoError(t, sqlt.AutoMigrate(ctx, db, strings.NewReader(schema.SQL()), false))
+	require.NoError(t, sqlt.VerifyString(ctx, db, schema.SQL()))
+}
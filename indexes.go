@@ -0,0 +1,100 @@
+package sqlt
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// IndexBuild reports the progress of the index builds AutoMigrate deferred, see WithDeferredIndexes.
+type IndexBuild struct {
+	Index string
+	// Done counts the deferred indexes built so far, including this one; Total counts all of them.
+	Done, Total int
+	Elapsed     time.Duration
+	Err         error
+}
+
+// WithDeferredIndexes makes AutoMigrate commit its other changes before building the named indexes,
+// each in its own transaction, so heavy index builds do not extend the lock held by the main migration.
+// Without names, every index AutoMigrate creates is deferred. The changes committed before a failing
+// index build stay applied; running AutoMigrate again builds the missing indexes.
+func WithDeferredIndexes(names ...string) MigrateOption {
+	return func(cfg *migrateConfig) {
+		cfg.deferIndexes = true
+		cfg.deferredIndexNames = names
+	}
+}
+
+// WithIndexProgress calls fn after each index build deferred by WithDeferredIndexes.
+func WithIndexProgress(fn func(IndexBuild)) MigrateOption {
+	return func(cfg *migrateConfig) {
+		cfg.indexProgress = fn
+	}
+}
+
+var createIndexName = regexp.MustCompile(`(?is)^\s*CREATE\s+(?:UNIQUE\s+)?INDEX\s+(?:IF\s+NOT\s+EXISTS\s+)?("(?:[^"]|"")+"|\S+)`)
+
+// deferringExecer collects the CREATE INDEX statements of deferred indexes instead of executing them.
+type deferringExecer struct {
+	execer
+	cfg      *migrateConfig
+	deferred *[]deferredIndex
+}
+
+type deferredIndex struct {
+	name, sql string
+}
+
+func (e deferringExecer) Exec(query string, args ...any) (Result, error) {
+	if m := createIndexName.FindStringSubmatch(query); m != nil && len(args) == 0 {
+		name := unquoteIdent(m[1])
+		if e.cfg.defersIndex(name) {
+			*e.deferred = append(*e.deferred, deferredIndex{name: name, sql: query})
+			return sqltResult{}, nil
+		}
+	}
+	return e.execer.Exec(query, args...)
+}
+
+// deferringExecer returns tx deferring the index builds selected by WithDeferredIndexes into deferred,
+// or tx itself if no index is deferred.
+func (cfg *migrateConfig) deferringExecer(tx execer, deferred *[]deferredIndex) execer {
+	if !cfg.deferIndexes {
+		return tx
+	}
+	return deferringExecer{execer: tx, cfg: cfg, deferred: deferred}
+}
+
+func (cfg *migrateConfig) defersIndex(name string) bool {
+	if len(cfg.deferredIndexNames) == 0 {
+		return true
+	}
+	for _, n := range cfg.deferredIndexNames {
+		if foldIdent(n) == foldIdent(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// buildDeferredIndexes creates the deferred indexes one transaction at a time, reporting each to
+// the WithIndexProgress callback, and records the executed statements.
+func (cfg *migrateConfig) buildDeferredIndexes(ctx context.Context, db DB, deferred []deferredIndex, executed *[]string) error {
+	for i, index := range deferred {
+		started := time.Now()
+		err := db.Txc(ctx, func(tx Tx) error {
+			_, err := qualifyingExecer(tx, cfg.targetSchema).Exec(index.sql)
+			return err
+		})
+		if cfg.indexProgress != nil {
+			cfg.indexProgress(IndexBuild{Index: index.name, Done: i + 1, Total: len(deferred), Elapsed: time.Since(started), Err: err})
+		}
+		if err != nil {
+			return fmt.Errorf("AutoMigrate: could not build deferred index %s: %w", index.name, err)
+		}
+		*executed = append(*executed, index.sql)
+	}
+	return nil
+}
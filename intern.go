@@ -0,0 +1,65 @@
+package sqlt
+
+import "unique"
+
+// intern replaces the identifiers and type names of s by canonical copies shared by every schema
+// in the process, so large schemas held for a long time, e.g. by drift watchers, store each
+// distinct name once. SQL texts are left alone, as they are rarely repeated.
+func (s *SchemaDefinition) intern() {
+	for _, table := range s.Tables {
+		table.Name = intern(table.Name)
+		for _, col := range table.Columns {
+			col.Name = intern(col.Name)
+			col.Type = intern(col.Type)
+			col.Collation = intern(col.Collation)
+		}
+		internAll(table.PrimaryKey)
+		for _, cols := range table.UniqueConstraints {
+			internAll(cols)
+		}
+		for _, fk := range table.ForeignKeys {
+			internAll(fk.Columns)
+			fk.ForeignTable = intern(fk.ForeignTable)
+			internAll(fk.ForeignColumns)
+			fk.OnUpdate = intern(fk.OnUpdate)
+			fk.OnDelete = intern(fk.OnDelete)
+		}
+		for _, index := range table.ImplicitIndexes {
+			index.intern()
+		}
+	}
+	for _, index := range s.Indexes {
+		index.intern()
+	}
+	for _, view := range s.Views {
+		view.Name = intern(view.Name)
+	}
+	for _, trigger := range s.Triggers {
+		trigger.Name = intern(trigger.Name)
+		trigger.Table = intern(trigger.Table)
+	}
+}
+
+func (i *IndexDefinition) intern() {
+	i.Name = intern(i.Name)
+	i.Table = intern(i.Table)
+	internAll(i.Columns)
+	for n := range i.Keys {
+		i.Keys[n].Column = intern(i.Keys[n].Column)
+		i.Keys[n].Collation = intern(i.Keys[n].Collation)
+	}
+	i.Origin = intern(i.Origin)
+}
+
+func intern(s string) string {
+	if s == "" {
+		return s
+	}
+	return unique.Make(s).Value()
+}
+
+func internAll(names []string) {
+	for i, name := range names {
+		names[i] = intern(name)
+	}
+}
@@ -0,0 +1,177 @@
+package sqlt
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	rsql "github.com/rqlite/sql"
+)
+
+// schemaSnapshotQuery reads sqlite_master together with the PRAGMA table_xinfo, index_list,
+// index_info and foreign_key_list of every table, encoded as JSON, in a single query.
+// This keeps FetchDBSchema at one round trip on remote databases however many tables there are.
+const schemaSnapshotQuery = `SELECT m.type, m.name, m.sql,
+	CASE WHEN m.type = 'table' AND m.sql NOT LIKE 'CREATE VIRTUAL%' THEN (
+		SELECT json_group_array(json_object('name', c.name, 'type', c.type, 'notnull', c."notnull", 'dflt_value', c.dflt_value, 'pk', c.pk, 'hidden', c.hidden))
+		FROM pragma_table_xinfo(m.name) c
+	) END AS columns_json,
+	CASE WHEN m.type = 'table' AND m.sql NOT LIKE 'CREATE VIRTUAL%' THEN (
+		SELECT json_group_array(json_object('name', il.name, 'unique', il."unique", 'origin', il.origin, 'columns', json((
+			SELECT json_group_array(ii.name) FROM (SELECT name FROM pragma_index_info(il.name) ORDER BY seqno) ii
+		))))
+		FROM pragma_index_list(m.name) il
+	) END AS indexes_json,
+	CASE WHEN m.type = 'table' AND m.sql NOT LIKE 'CREATE VIRTUAL%' THEN (
+		SELECT json_group_array(json_object('id', fk.id, 'table', fk."table", 'from', fk."from", 'to', fk."to", 'on_update', fk.on_update, 'on_delete', fk.on_delete))
+		FROM (SELECT * FROM pragma_foreign_key_list(m.name) ORDER BY id, seq) fk
+	) END AS foreign_keys_json
+FROM sqlite_master m WHERE m.sql IS NOT NULL`
+
+type snapshotRow struct {
+	masterRow
+	ColumnsJSON     sql.NullString `db:"columns_json"`
+	IndexesJSON     sql.NullString `db:"indexes_json"`
+	ForeignKeysJSON sql.NullString `db:"foreign_keys_json"`
+}
+
+type pragmaColumn struct {
+	Name    string  `json:"name"`
+	Type    string  `json:"type"`
+	NotNull int     `json:"notnull"`
+	Default *string `json:"dflt_value"`
+	PK      int     `json:"pk"`
+	Hidden  int     `json:"hidden"`
+}
+
+type pragmaIndex struct {
+	Name    string    `json:"name"`
+	Unique  int       `json:"unique"`
+	Origin  string    `json:"origin"`
+	Columns []*string `json:"columns"`
+}
+
+type pragmaForeignKey struct {
+	ID       int     `json:"id"`
+	Table    string  `json:"table"`
+	From     string  `json:"from"`
+	To       *string `json:"to"`
+	OnUpdate string  `json:"on_update"`
+	OnDelete string  `json:"on_delete"`
+}
+
+// tablePragmas is what SQLite reports about a table.
+type tablePragmas struct {
+	columns     []pragmaColumn
+	indexes     []pragmaIndex
+	foreignKeys []pragmaForeignKey
+}
+
+// schemaSnapshot returns the parsed objects of the database, skipping internal objects,
+// and the PRAGMA information of its tables keyed by table name.
+func schemaSnapshot(ctx context.Context, db Reader) ([]rsql.Statement, map[string]*tablePragmas, error) {
+	var rows []snapshotRow
+	if err := db.SelectContext(ctx, &rows, schemaSnapshotQuery); err != nil {
+		return nil, nil, fmt.Errorf("could not read schema: %w", err)
+	}
+	master := make([]masterRow, len(rows))
+	pragmas := make(map[string]*tablePragmas)
+	for i, row := range rows {
+		master[i] = row.masterRow
+		if !row.ColumnsJSON.Valid {
+			continue
+		}
+		p := &tablePragmas{}
+		for _, field := range []struct {
+			src  sql.NullString
+			dest any
+		}{{row.ColumnsJSON, &p.columns}, {row.IndexesJSON, &p.indexes}, {row.ForeignKeysJSON, &p.foreignKeys}} {
+			if err := json.Unmarshal([]byte(field.src.String), field.dest); err != nil {
+				return nil, nil, fmt.Errorf("could not decode PRAGMA results of table %s: %w", row.Name, err)
+			}
+		}
+		pragmas[row.Name] = p
+	}
+	var stmts []rsql.Statement
+	for _, row := range filterInternalObjects(master) {
+		stmt, err := rsql.NewParser(strings.NewReader(row.Sql)).ParseStatement()
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not parse SQL for DB object %s (SQL: %s): %w", row.Name, row.Sql, err)
+		}
+		stmts = append(stmts, stmt)
+	}
+	return stmts, pragmas, nil
+}
+
+// applyTablePragmas overwrites the parsed definition of table with what SQLite reports about it.
+func applyTablePragmas(table *TableDefinition, p *tablePragmas) {
+	var pkColumns []pragmaColumn
+	for _, pc := range p.columns {
+		col := table.Column(pc.Name)
+		if col == nil {
+			col = &ColumnDefinition{Name: pc.Name, Type: pc.Type}
+			table.Columns = append(table.Columns, col)
+		}
+		col.NotNull = pc.NotNull != 0
+		col.Hidden = pc.Hidden
+		// The catalog keeps the default as written, which is what SQLite evaluates.
+		col.DefaultValue = pc.Default
+		col.PrimaryKey = pc.PK > 0
+		if pc.PK > 0 {
+			pkColumns = append(pkColumns, pc)
+		}
+	}
+	slices.SortFunc(pkColumns, func(a, b pragmaColumn) int { return a.PK - b.PK })
+	table.PrimaryKey = nil
+	for _, pc := range pkColumns {
+		table.PrimaryKey = append(table.PrimaryKey, pc.Name)
+	}
+	// A single INTEGER PRIMARY KEY column of a rowid table aliases the rowid.
+	if len(pkColumns) == 1 && !table.WithoutRowid && strings.EqualFold(pkColumns[0].Type, "INTEGER") {
+		table.Column(pkColumns[0].Name).RowidAlias = true
+	}
+
+	table.ImplicitIndexes = nil
+	for _, pi := range p.indexes {
+		if pi.Origin == "c" {
+			continue
+		}
+		index := &IndexDefinition{Name: pi.Name, Table: table.Name, Unique: pi.Unique != 0, Origin: pi.Origin}
+		for _, name := range pi.Columns {
+			if name == nil {
+				// Expressions and the rowid have no column name.
+				index.Columns = append(index.Columns, "")
+				continue
+			}
+			index.Columns = append(index.Columns, *name)
+		}
+		table.ImplicitIndexes = append(table.ImplicitIndexes, index)
+	}
+	slices.SortFunc(table.ImplicitIndexes, func(a, b *IndexDefinition) int { return strings.Compare(a.Name, b.Name) })
+
+	table.ForeignKeys = nil
+	byID := make(map[int]*ForeignKeyDefinition)
+	for _, pf := range p.foreignKeys {
+		fk, ok := byID[pf.ID]
+		if !ok {
+			fk = &ForeignKeyDefinition{ForeignTable: pf.Table, OnUpdate: foreignKeyAction(pf.OnUpdate), OnDelete: foreignKeyAction(pf.OnDelete)}
+			byID[pf.ID] = fk
+			table.ForeignKeys = append(table.ForeignKeys, fk)
+		}
+		fk.Columns = append(fk.Columns, pf.From)
+		if pf.To != nil {
+			fk.ForeignColumns = append(fk.ForeignColumns, *pf.To)
+		}
+	}
+}
+
+// foreignKeyAction maps the catalog's default action to the empty string used for undeclared actions.
+func foreignKeyAction(action string) string {
+	if action == "NO ACTION" {
+		return ""
+	}
+	return action
+}
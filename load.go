@@ -6,45 +6,72 @@ import (
 	"io"
 	"os"
 	"strings"
+	"sync"
 	"sync/atomic"
 
 	"github.com/james-darko/gort"
 )
 
 func loadDB() (DB, error) {
-	driver := os.Getenv("DATABASE_DRIVER")
-	if driver == "" {
-		driver = "sqlite3"
-	}
-	url := os.Getenv("DATABASE_URL")
-	if url == "" {
-		return nil, fmt.Errorf("DATABASE_URL env var not found")
+	cfg, err := ConfigFromEnv()
+	if err != nil {
+		return nil, err
 	}
-	if strings.HasPrefix(url, "libsql:") {
-		driver = "libsql"
-		token := os.Getenv("DATABASE_TOKEN")
-		if token == "" {
-			return nil, fmt.Errorf("DATABASE_TOKEN env var not found")
-		}
-		url = url + "?authToken=" + token
+	var db DB
+	if cfg.Key != "" {
+		db, err = OpenEncrypted(cfg.Driver, cfg.dsn(), cfg.Key)
+	} else {
+		db, err = Open(cfg.Driver, cfg.dsn())
 	}
-	db, err := Open(driver, url)
 	if err != nil {
 		return nil, fmt.Errorf("problem opening database: %v", err)
 	}
 	return db, nil
 }
 
-// LoadDB returns a database handle from environment variables.
+// envSecret returns the value of the env var name, or else the content of the file named by
+// name+"_FILE" with surrounding whitespace trimmed, so secrets can be mounted as files by
+// Kubernetes or Docker. It returns "" if neither is set.
+// File contents are cached until ResetDB is called.
+func envSecret(name string) (string, error) {
+	if v := os.Getenv(name); v != "" {
+		return v, nil
+	}
+	path := os.Getenv(name + "_FILE")
+	if path == "" {
+		return "", nil
+	}
+	if v, ok := secretFiles.Load(path); ok {
+		return v.(string), nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("could not read %s_FILE: %w", name, err)
+	}
+	v := strings.TrimSpace(string(content))
+	secretFiles.Store(path, v)
+	return v, nil
+}
+
+// secretFiles caches the content of secret files by path.
+var secretFiles sync.Map
+
+// LoadDB returns a database handle from environment variables, validated by ConfigFromEnv.
 // The result is cached after the first call. See ResetDB for resetting the cache.
 //
 // Env vars:
 //
-// DATABASE_URL: required
+// DATABASE_URL: required, unless DATABASE_URL_FILE is set.
 //
 // DATABASE_DRIVER: optional. Defaults to "sqlite3". Switches to "libsql" if DATABASE_URL starts with "libsql".
 //
 // DATABASE_TOKEN: optional. If DATABASE_URL starts with "libsql", DATABASE_TOKEN will be appended accordingly for turso auth.
+//
+// DATABASE_KEY: optional. The SQLCipher or SEE key of an encrypted database, see OpenEncrypted.
+//
+// DATABASE_URL_FILE, DATABASE_TOKEN_FILE, DATABASE_KEY_FILE: optional. Files holding the value of the
+// variable without the suffix, used if it is not set, e.g. a mounted Kubernetes or Docker secret.
+// Surrounding whitespace is trimmed and the content is cached until ResetDB.
 func LoadDB() (DB, error) {
 	dbPtr := loadDBHandle.Load()
 	if dbPtr == nil {
@@ -58,6 +85,7 @@ func LoadDB() (DB, error) {
 // See LoadDB for primary database loading.
 //
 // If schema is nil, and DATABASE_SCHEMA env file var is set, it will be used for validation.
+// The parsed DATABASE_SCHEMA file is cached until the file changes, even across ResetDB calls.
 //
 // The result is cached after the first call. See ResetDB for resetting the cache.
 func FullLoadDB(ctx context.Context, schema io.Reader, versions MigrationMap) (DB, error) {
@@ -90,8 +118,9 @@ func fullLoadDB(ctx context.Context, schema io.Reader, versions MigrationMap) (D
 	return db, nil
 }
 
-// ResetDB resets the cached database LoadDB and derivatives use.
+// ResetDB resets the cached database LoadDB and derivatives use, and the cached secret files.
 func ResetDB() {
+	secretFiles.Clear()
 	fn := loadDB
 	loadDBHandle.Store(&fn)
 	fillFn := fullLoadDB
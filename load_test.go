@@ -1,7 +1,14 @@
 package sqlt_test
 
 import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -78,3 +85,191 @@ func TestLoadDB_Error_NoURL(t *testing.T) {
 		assert.Contains(t, err.Error(), "DATABASE_URL env var not found")
 	}
 }
+
+func TestLoadDB_KeyFile(t *testing.T) {
+	sqlt.ResetDB()
+	keyFile := filepath.Join(t.TempDir(), "key")
+	require.NoError(t, os.WriteFile(keyFile, []byte("s3cret\n"), 0o600))
+	t.Setenv("DATABASE_DRIVER", "sqlite3")
+	t.Setenv("DATABASE_URL", filepath.Join(t.TempDir(), "enc.db"))
+	t.Setenv("DATABASE_KEY_FILE", keyFile)
+
+	db, err := sqlt.LoadDB()
+	require.NoError(t, err)
+	defer db.Close()
+	ctx := gort.Context()
+
+	// go-sqlite3 is built without encryption, so the key pragmas are accepted and ignored.
+	_, err = db.ExecContext(ctx, "CREATE TABLE t (id INTEGER PRIMARY KEY)")
+	require.NoError(t, err)
+	require.NoError(t, sqlt.Rekey(ctx, db, "n3w"))
+	db.SQLX().SetMaxIdleConns(0)
+	var count int
+	require.NoError(t, db.GetContext(ctx, &count, "SELECT COUNT(*) FROM t"))
+}
+
+func TestLoadDB_KeyFileMissing(t *testing.T) {
+	sqlt.ResetDB()
+	t.Setenv("DATABASE_URL", ":memory:")
+	t.Setenv("DATABASE_KEY_FILE", filepath.Join(t.TempDir(), "missing"))
+
+	_, err := sqlt.LoadDB()
+	assert.ErrorContains(t, err, "DATABASE_KEY_FILE")
+}
+
+func TestLoadDB_URLFile(t *testing.T) {
+	sqlt.ResetDB()
+	dir := t.TempDir()
+	urlFile := filepath.Join(dir, "url")
+	require.NoError(t, os.WriteFile(urlFile, []byte("  "+filepath.Join(dir, "a.db")+"\n"), 0o600))
+	t.Setenv("DATABASE_URL", "")
+	t.Setenv("DATABASE_URL_FILE", urlFile)
+	ctx := gort.Context()
+
+	db, err := sqlt.LoadDB()
+	require.NoError(t, err)
+	_, err = db.ExecContext(ctx, "CREATE TABLE a (id INTEGER)")
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	// The file content is cached until ResetDB.
+	require.NoError(t, os.WriteFile(urlFile, []byte(filepath.Join(dir, "b.db")), 0o600))
+	db, err = sqlt.LoadDB()
+	require.NoError(t, err)
+	var count int
+	require.NoError(t, db.GetContext(ctx, &count, "SELECT COUNT(*) FROM sqlite_master WHERE name = 'a'"))
+	assert.Equal(t, 1, count)
+	require.NoError(t, db.Close())
+
+	sqlt.ResetDB()
+	db, err = sqlt.LoadDB()
+	require.NoError(t, err)
+	defer db.Close()
+	require.NoError(t, db.GetContext(ctx, &count, "SELECT COUNT(*) FROM sqlite_master WHERE name = 'a'"))
+	assert.Equal(t, 0, count)
+}
+
+func TestLoadDB_SchemaLocation(t *testing.T) {
+	for _, location := range []string{"https://example.com/schema.sql", "s3://bucket/schema.sql"} {
+		sqlt.ResetDB()
+		t.Setenv("DATABASE_URL", ":memory:")
+		t.Setenv("DATABASE_SCHEMA", location)
+
+		db, err := sqlt.LoadDB()
+		require.NoError(t, err, "remote schema locations are not read by LoadDB")
+		db.Close()
+	}
+
+	sqlt.ResetDB()
+	t.Setenv("DATABASE_SCHEMA", "file://"+filepath.Join(t.TempDir(), "missing.sql"))
+	_, err := sqlt.LoadDB()
+	assert.ErrorContains(t, err, "could not read DATABASE_SCHEMA")
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("DATABASE_URL", "libsql://db.example.com")
+	t.Setenv("DATABASE_TOKEN", "tok3n")
+	t.Setenv("DATABASE_TOKN", "typo")
+	t.Setenv("DATABASE_SCHEMA_PUBLIC_KEY", base64.StdEncoding.EncodeToString(make([]byte, ed25519.PublicKeySize)))
+
+	cfg, err := sqlt.ConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, "libsql", cfg.Driver)
+	assert.Equal(t, []string{"DATABASE_TOKN"}, cfg.Unknown)
+	summary := cfg.String()
+	assert.NotContains(t, summary, "tok3n")
+	assert.Contains(t, summary, "token=REDACTED")
+	assert.Contains(t, summary, "unknown=DATABASE_TOKN")
+}
+
+func TestConfigFromEnv_Invalid(t *testing.T) {
+	t.Setenv("DATABASE_DRIVER", "nosuchdriver")
+	t.Setenv("DATABASE_URL", "file:a.db?_auth_pass=hunter2")
+	t.Setenv("DATABASE_URL_FILE", filepath.Join(t.TempDir(), "url"))
+	t.Setenv("DATABASE_SCHEMA", filepath.Join(t.TempDir(), "missing.sql"))
+
+	cfg, err := sqlt.ConfigFromEnv()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "both DATABASE_URL and DATABASE_URL_FILE are set")
+	assert.Contains(t, err.Error(), `driver "nosuchdriver" is not registered`)
+	assert.Contains(t, err.Error(), "could not read DATABASE_SCHEMA")
+	assert.NotContains(t, cfg.String(), "hunter2")
+}
+
+func TestAutoMigrateFromEnv_Locations(t *testing.T) {
+	schema := "CREATE TABLE notes (id INTEGER PRIMARY KEY, body TEXT);"
+	var requests, notModified int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		io.WriteString(w, schema)
+	}))
+	defer srv.Close()
+	ctx := gort.Context()
+
+	t.Setenv("DATABASE_SCHEMA", srv.URL+"/schema.sql")
+	for range 2 {
+		db, err := sqlt.OpenMemory(t.Name())
+		require.NoError(t, err)
+		require.NoError(t, sqlt.AutoMigrateFromEnv(ctx, db, false))
+		require.NoError(t, sqlt.VerifyString(ctx, db, schema))
+		db.Close()
+	}
+	assert.Equal(t, 2, requests)
+	assert.Equal(t, 1, notModified, "the second fetch revalidates the cached schema")
+
+	var fetched []string
+	s3 := sqlt.SchemaFetcherFunc(func(ctx context.Context, location string) ([]byte, error) {
+		fetched = append(fetched, location)
+		return []byte(schema), nil
+	})
+	t.Setenv("DATABASE_SCHEMA", "s3://bucket/schema.sql")
+	db, err := sqlt.OpenMemory(t.Name())
+	require.NoError(t, err)
+	defer db.Close()
+	require.ErrorContains(t, sqlt.AutoMigrateFromEnv(ctx, db, false), "no schema fetcher")
+	require.NoError(t, sqlt.AutoMigrateFromEnv(ctx, db, false, sqlt.WithSchemaFetcher("s3", s3)))
+	assert.Equal(t, []string{"s3://bucket/schema.sql"}, fetched)
+	require.NoError(t, sqlt.VerifyString(ctx, db, schema))
+
+	path := filepath.Join(t.TempDir(), "schema.sql")
+	require.NoError(t, os.WriteFile(path, []byte(schema+"CREATE INDEX idx_notes_body ON notes (body);"), 0o644))
+	t.Setenv("DATABASE_SCHEMA", "file://"+path)
+	require.NoError(t, sqlt.AutoMigrateFromEnv(ctx, db, false))
+	var indexes int
+	require.NoError(t, db.Get(&indexes, "SELECT COUNT(*) FROM sqlite_master WHERE name = 'idx_notes_body'"))
+	assert.Equal(t, 1, indexes)
+}
+
+func TestAutoMigrateFromEnv_SignedSchema(t *testing.T) {
+	schema := []byte("CREATE TABLE notes (id INTEGER PRIMARY KEY, body TEXT);")
+	pub, priv, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "schema.sql")
+	require.NoError(t, os.WriteFile(path, schema, 0o644))
+	ctx := gort.Context()
+	db, err := sqlt.OpenMemory(t.Name())
+	require.NoError(t, err)
+	defer db.Close()
+
+	t.Setenv("DATABASE_SCHEMA", path)
+	t.Setenv("DATABASE_SCHEMA_PUBLIC_KEY", base64.StdEncoding.EncodeToString(pub))
+	require.ErrorContains(t, sqlt.AutoMigrateFromEnv(ctx, db, false), "signature")
+
+	require.NoError(t, os.WriteFile(path+".sig", []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(priv, []byte("tampered")))), 0o644))
+	require.ErrorIs(t, sqlt.AutoMigrateFromEnv(ctx, db, false), sqlt.ErrInvalidSchemaSignature)
+
+	require.NoError(t, os.WriteFile(path+".sig", ed25519.Sign(priv, schema), 0o644))
+	require.NoError(t, sqlt.AutoMigrateFromEnv(ctx, db, false))
+	require.NoError(t, sqlt.VerifyString(ctx, db, string(schema)))
+
+	otherPub, _, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	require.ErrorIs(t, sqlt.AutoMigrateFromEnv(ctx, db, false, sqlt.WithSchemaPublicKey(otherPub)), sqlt.ErrInvalidSchemaSignature)
+}
@@ -0,0 +1,109 @@
+package sqlt
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// lockPollInterval is how often a waiting migration retries claiming a held lock.
+const lockPollInterval = 250 * time.Millisecond
+
+// WithMigrationLock serializes AutoMigrate and Migrate runs across processes sharing a database.
+// Before migrating, the row name is claimed in the _sqlt_locks table and it is released afterwards.
+//
+// The claim is refreshed while the migration runs. A lock that has not been refreshed within ttl,
+// for example because its holder crashed, is considered stale and taken over.
+// Waiting for a held lock is bounded by the context passed to the migration.
+func WithMigrationLock(name string, ttl time.Duration) MigrateOption {
+	return func(cfg *migrateConfig) {
+		cfg.lockName = name
+		cfg.lockTTL = ttl
+	}
+}
+
+// withLock runs fn while holding the configured migration lock, or just runs fn if none is configured.
+func (cfg *migrateConfig) withLock(ctx context.Context, db DB, fn func() error) error {
+	if cfg.lockName == "" {
+		return fn()
+	}
+	release, err := acquireMigrationLock(ctx, db, cfg.lockName, cfg.lockTTL, cfg.logf)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return fn()
+}
+
+func acquireMigrationLock(ctx context.Context, db DB, name string, ttl time.Duration, logf func(string, ...any)) (func(), error) {
+	if ttl <= 0 {
+		return nil, fmt.Errorf("migration lock %q: ttl must be positive", name)
+	}
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS _sqlt_locks (
+	name TEXT PRIMARY KEY,
+	owner TEXT NOT NULL,
+	expires_at INTEGER NOT NULL
+)`)
+	if err != nil {
+		return nil, fmt.Errorf("could not create lock table: %w", err)
+	}
+	owner, err := lockOwner()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		now := time.Now()
+		claimed, err := db.AffectedExecContext(ctx, `INSERT INTO _sqlt_locks (name, owner, expires_at) VALUES (?, ?, ?)
+ON CONFLICT (name) DO UPDATE SET owner = excluded.owner, expires_at = excluded.expires_at
+WHERE _sqlt_locks.expires_at < ?`, name, owner, now.Add(ttl).UnixMilli(), now.UnixMilli())
+		if err != nil {
+			return nil, fmt.Errorf("could not claim migration lock %q: %w", name, err)
+		}
+		if claimed > 0 {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("could not acquire migration lock %q: %w", name, ctx.Err())
+		case <-time.After(lockPollInterval):
+		}
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(max(ttl/3, time.Millisecond))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				// A failed refresh is retried on the next tick; the claim stays valid until it expires.
+				_, _ = db.Exec("UPDATE _sqlt_locks SET expires_at = ? WHERE name = ? AND owner = ?",
+					time.Now().Add(ttl).UnixMilli(), name, owner)
+			}
+		}
+	}()
+
+	return func() {
+		close(stop)
+		<-done
+		// The lock must be released even when the migration's context was cancelled.
+		_, err := db.ExecContext(context.WithoutCancel(ctx), "DELETE FROM _sqlt_locks WHERE name = ? AND owner = ?", name, owner)
+		if err != nil {
+			logf("failed to release migration lock %q: %v", name, err)
+		}
+	}, nil
+}
+
+func lockOwner() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("could not generate lock owner: %w", err)
+	}
+	return hex.EncodeToString(b[:]), nil
+}
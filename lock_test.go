@@ -0,0 +1,62 @@
+package sqlt_test
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/james-darko/gort"
+	"github.com/james-darko/sqlt"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func openLockTestDB(t *testing.T) sqlt.DB {
+	path := filepath.Join(t.TempDir(), "lock.db")
+	db, err := sqlt.Open("sqlite3", "file:"+path+"?_journal_mode=WAL&_busy_timeout=5000")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestMigrationLock_SerializesRuns(t *testing.T) {
+	t.Parallel()
+	db := openLockTestDB(t)
+	ctx := gort.Context()
+
+	targetSchema := `CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT);`
+	var wg sync.WaitGroup
+	errs := make([]error, 3)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = sqlt.AutoMigrate(ctx, db, strings.NewReader(targetSchema), true, sqlt.WithMigrationLock("deploy", time.Minute))
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		assert.NoError(t, err)
+	}
+
+	var held int
+	require.NoError(t, db.Get(&held, "SELECT COUNT(*) FROM _sqlt_locks"))
+	assert.Zero(t, held, "lock should be released after migration")
+	assert.NoError(t, sqlt.Verify(ctx, db, strings.NewReader(targetSchema)), "lock table must not show up in the schema diff")
+}
+
+func TestMigrationLock_TakesOverStaleLock(t *testing.T) {
+	t.Parallel()
+	db := openLockTestDB(t)
+	ctx := gort.Context()
+
+	_, err := db.Exec(`CREATE TABLE _sqlt_locks (name TEXT PRIMARY KEY, owner TEXT NOT NULL, expires_at INTEGER NOT NULL);
+INSERT INTO _sqlt_locks (name, owner, expires_at) VALUES ('deploy', 'crashed', 0);`)
+	require.NoError(t, err)
+
+	err = sqlt.AutoMigrate(ctx, db, strings.NewReader(`CREATE TABLE items (id INTEGER PRIMARY KEY);`), true, sqlt.WithMigrationLock("deploy", time.Minute))
+	require.NoError(t, err)
+}
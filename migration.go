@@ -26,35 +26,50 @@ func quoteIdent(ident string) string {
 	return fmt.Sprintf("\"%s\"", escapedIdent)
 }
 
+// qualifiedIdent quotes table, prefixed with the quoted schema if it is not empty.
+func qualifiedIdent(schema, table string) string {
+	if schema == "" {
+		return quoteIdent(table)
+	}
+	return quoteIdent(schema) + "." + quoteIdent(table)
+}
+
 const (
 	statementMatchExact         = iota
 	statementMatchReorderNeeded // only for tables
 	statementMatchNoMatch
 )
 
-// PrintTables prints the names and SQL of all tables in the database.
+// PrintTables prints a description of the database schema to stdout.
+//
+// Deprecated: Use DescribeSchema, which writes to any io.Writer.
 func PrintTables(ctx context.Context, db DB) error {
-	var tables []struct {
-		Name string `db:"name"`
-		Sql  string `db:"sql"`
-	}
-	err := db.Select(&tables, "SELECT name, sql FROM sqlite_master WHERE type='table' AND name NOT LIKE 'sqlite_%'")
-	if err != nil {
-		return fmt.Errorf("could not get tables: %w", err)
-	}
-	if len(tables) == 0 {
-		fmt.Println("no tables found")
-		return nil
+	return DescribeSchema(ctx, db, os.Stdout, WithObjectSQL())
+}
+
+// compareStatements compares two SQL statements and returns the match type and a description of the differences.
+// With StrictnessExact, any difference in the normalized SQL is reported as statementMatchNoMatch.
+func compareStatements(dbStmt, schemaStmt rsql.Statement, strictness Strictness) (matchType int, diffDescription string, err error) {
+	return statementSQLs(nil).compare(dbStmt, schemaStmt, strictness)
+}
+
+// statementSQLs memoizes statementSQL for the statements compared in one run, as rendering a statement
+// allocates all of its SQL again on every call. A nil statementSQLs renders without memoizing.
+type statementSQLs map[rsql.Statement]string
+
+func (c statementSQLs) of(stmt rsql.Statement) string {
+	if sql, ok := c[stmt]; ok {
+		return sql
 	}
-	fmt.Println("tables:")
-	for _, table := range tables {
-		fmt.Printf("%s - %s\n", table.Name, table.Sql)
+	sql := statementSQL(stmt)
+	if c != nil {
+		c[stmt] = sql
 	}
-	return nil
+	return sql
 }
 
-// compareStatements compares two SQL statements and returns the match type and a description of the differences.
-func compareStatements(dbStmt, schemaStmt rsql.Statement) (matchType int, diffDescription string, err error) {
+// compare is compareStatements rendering the statements through c.
+func (c statementSQLs) compare(dbStmt, schemaStmt rsql.Statement, strictness Strictness) (matchType int, diffDescription string, err error) {
 	// Initial Check:
 	if dbStmt == nil && schemaStmt != nil {
 		return statementMatchNoMatch, "Database object is nil, schema object is not (new object)", nil
@@ -66,8 +81,8 @@ func compareStatements(dbStmt, schemaStmt rsql.Statement) (matchType int, diffDe
 		return statementMatchExact, "", nil // Both nil, considered exact match.
 	}
 
-	dbSQL := dbStmt.String()
-	schemaSQL := schemaStmt.String()
+	dbSQL := c.of(dbStmt)
+	schemaSQL := c.of(schemaStmt)
 
 	if dbSQL == schemaSQL {
 		return statementMatchExact, "", nil
@@ -78,12 +93,21 @@ func compareStatements(dbStmt, schemaStmt rsql.Statement) (matchType int, diffDe
 	schemaTableStmt, schemaIsTable := schemaStmt.(*rsql.CreateTableStatement)
 
 	if dbIsTable && schemaIsTable {
-		match, desc := compareTableStatements(dbTableStmt, schemaTableStmt)
+		match, desc := compareTableStatements(dbTableStmt, schemaTableStmt, strictness == StrictnessAffinity)
+		if strictness == StrictnessExact && match != statementMatchNoMatch {
+			return statementMatchNoMatch, fmt.Sprintf("Definition mismatch. DB: %s, Schema: %s", dbSQL, schemaSQL), nil
+		}
 		return match, desc, nil
 	} else if dbIsTable != schemaIsTable {
 		return statementMatchNoMatch, "Object type mismatch (e.g., DB is a table, Schema is an index/view for the same name)", nil
 	}
 
+	dbIndexStmt, dbIsIndex := dbStmt.(*rsql.CreateIndexStatement)
+	schemaIndexStmt, schemaIsIndex := schemaStmt.(*rsql.CreateIndexStatement)
+	if dbIsIndex && schemaIsIndex && strictness != StrictnessExact && newIndexDefinition(dbIndexStmt).Equal(newIndexDefinition(schemaIndexStmt)) {
+		return statementMatchExact, "", nil
+	}
+
 	// String Comparison for Other Types (Indexes, Views, Triggers):
 	return statementMatchNoMatch, fmt.Sprintf("Definition mismatch. DB: %s, Schema: %s", dbSQL, schemaSQL), nil
 }
@@ -96,6 +120,54 @@ func normalizeTypeName(typeName string) string {
 	return upper
 }
 
+// columnTypeName returns the declared type name of col, or the empty string if it has none.
+func columnTypeName(col *rsql.ColumnDefinition) string {
+	if col.Type == nil {
+		return ""
+	}
+	return col.Type.Name.Name
+}
+
+func columnTypesEqual(a, b string, typeAffinity bool) bool {
+	if typeAffinity {
+		return typeAffinityOf(a) == typeAffinityOf(b)
+	}
+	return normalizeTypeName(a) == normalizeTypeName(b)
+}
+
+// typeAffinityOf returns the affinity SQLite gives a column declared with typeName,
+// following the rules of section 3.1 of https://www.sqlite.org/datatype3.html.
+func typeAffinityOf(typeName string) string {
+	upper := strings.ToUpper(typeName)
+	switch {
+	case strings.Contains(upper, "INT"):
+		return "INTEGER"
+	case strings.Contains(upper, "CHAR"), strings.Contains(upper, "CLOB"), strings.Contains(upper, "TEXT"):
+		return "TEXT"
+	case upper == "", strings.Contains(upper, "BLOB"):
+		return "BLOB"
+	case strings.Contains(upper, "REAL"), strings.Contains(upper, "FLOA"), strings.Contains(upper, "DOUB"):
+		return "REAL"
+	default:
+		return "NUMERIC"
+	}
+}
+
+// constraintKey returns the identity constraints are compared by. A table-level UNIQUE constraint is
+// identified by the set of its columns, so neither its name nor the order of its columns matter.
+func constraintKey(c rsql.Constraint) string {
+	unique, ok := c.(*rsql.UniqueConstraint)
+	if !ok || len(unique.Columns) == 0 {
+		return c.String()
+	}
+	cols := make([]string, len(unique.Columns))
+	for i, col := range unique.Columns {
+		cols[i] = foldIdent(col.String())
+	}
+	slices.Sort(cols)
+	return "UNIQUE (" + strings.Join(cols, ", ") + ")"
+}
+
 func compareConstraints(dbCons, schemaCons []rsql.Constraint) (bool, string) {
 	if len(dbCons) != len(schemaCons) {
 		return false, fmt.Sprintf("constraint count mismatch (DB: %d, Schema: %d)", len(dbCons), len(schemaCons))
@@ -103,10 +175,10 @@ func compareConstraints(dbCons, schemaCons []rsql.Constraint) (bool, string) {
 	dbConsStr := make(map[string]int)
 	schemaConsStr := make(map[string]int)
 	for _, c := range dbCons {
-		dbConsStr[c.String()]++
+		dbConsStr[constraintKey(c)]++
 	}
 	for _, c := range schemaCons {
-		schemaConsStr[c.String()]++
+		schemaConsStr[constraintKey(c)]++
 	}
 	for s, count := range dbConsStr {
 		if schemaConsStr[s] != count {
@@ -121,25 +193,23 @@ func compareConstraints(dbCons, schemaCons []rsql.Constraint) (bool, string) {
 	return true, ""
 }
 
-func compareTableStatements(dbStmt, schemaStmt *rsql.CreateTableStatement) (int, string) {
+func compareTableStatements(dbStmt, schemaStmt *rsql.CreateTableStatement, typeAffinity bool) (int, string) {
 	var diffs []string
-	dbCols := make(map[string]*rsql.ColumnDefinition)
-	for _, col := range dbStmt.Columns {
-		dbCols[col.Name.Name] = col
-	}
 	schemaCols := make(map[string]*rsql.ColumnDefinition)
 	for _, col := range schemaStmt.Columns {
 		schemaCols[col.Name.Name] = col
 	}
 
-	for name, dbCol := range dbCols {
+	for _, dbCol := range dbStmt.Columns {
+		name := dbCol.Name.Name
 		schemaCol, ok := schemaCols[name]
 		if !ok {
 			diffs = append(diffs, fmt.Sprintf("Extra DB column: '%s'", name))
 			continue
 		}
-		if normalizeTypeName(dbCol.Type.Name.Name) != normalizeTypeName(schemaCol.Type.Name.Name) { // .Name added
-			diffs = append(diffs, fmt.Sprintf("Column '%s': type mismatch (DB: %s, Schema: %s)", name, dbCol.Type.Name.Name, schemaCol.Type.Name.Name)) // .Name added
+		dbType, schemaType := columnTypeName(dbCol), columnTypeName(schemaCol)
+		if !columnTypesEqual(dbType, schemaType, typeAffinity) {
+			diffs = append(diffs, fmt.Sprintf("Column '%s': type mismatch (DB: %s, Schema: %s)", name, dbType, schemaType))
 		}
 		dbInlineCons := getInlineConstraints(dbCol.Constraints)
 		schemaInlineCons := getInlineConstraints(schemaCol.Constraints)
@@ -149,8 +219,10 @@ func compareTableStatements(dbStmt, schemaStmt *rsql.CreateTableStatement) (int,
 		}
 		delete(schemaCols, name)
 	}
-	for name := range schemaCols {
-		diffs = append(diffs, fmt.Sprintf("Missing Schema column: '%s'", name))
+	for _, col := range schemaStmt.Columns {
+		if _, ok := schemaCols[col.Name.Name]; ok {
+			diffs = append(diffs, fmt.Sprintf("Missing Schema column: '%s'", col.Name.Name))
+		}
 	}
 
 	dbTableConsMatch, tableConsDiff := compareConstraints(getTableLevelConstraints(dbStmt.Constraints), getTableLevelConstraints(schemaStmt.Constraints))
@@ -158,6 +230,10 @@ func compareTableStatements(dbStmt, schemaStmt *rsql.CreateTableStatement) (int,
 		diffs = append(diffs, fmt.Sprintf("Table-level constraints mismatch: %s", tableConsDiff))
 	}
 
+	for _, c := range compareTableOptions(dbStmt, schemaStmt) {
+		diffs = append(diffs, c.String())
+	}
+
 	if len(diffs) > 0 {
 		return statementMatchNoMatch, strings.Join(diffs, "; ")
 	}
@@ -177,6 +253,38 @@ func compareTableStatements(dbStmt, schemaStmt *rsql.CreateTableStatement) (int,
 	return statementMatchExact, ""
 }
 
+// statementSQL returns the SQL of stmt. Unlike stmt.String(), it keeps the
+// trailing WITHOUT ROWID and STRICT options of tables.
+func statementSQL(stmt rsql.Statement) string {
+	table, ok := stmt.(*rsql.CreateTableStatement)
+	if !ok || table.Select != nil {
+		return stmt.String()
+	}
+	var options []string
+	if table.Without.IsValid() {
+		options = append(options, "WITHOUT ROWID")
+	}
+	if table.Strict.IsValid() {
+		options = append(options, "STRICT")
+	}
+	if len(options) == 0 {
+		return stmt.String()
+	}
+	return stmt.String() + " " + strings.Join(options, ", ")
+}
+
+// compareTableOptions returns the trailing table options set in only one of the statements.
+func compareTableOptions(dbStmt, schemaStmt *rsql.CreateTableStatement) []TableOptionConflict {
+	var conflicts []TableOptionConflict
+	if db, schema := dbStmt.Without.IsValid(), schemaStmt.Without.IsValid(); db != schema {
+		conflicts = append(conflicts, TableOptionConflict{Option: "WITHOUT ROWID", Expected: schema, Actual: db})
+	}
+	if db, schema := dbStmt.Strict.IsValid(), schemaStmt.Strict.IsValid(); db != schema {
+		conflicts = append(conflicts, TableOptionConflict{Option: "STRICT", Expected: schema, Actual: db})
+	}
+	return conflicts
+}
+
 func getInlineConstraints(constraints []rsql.Constraint) []rsql.Constraint {
 	var inline []rsql.Constraint
 	for _, c := range constraints {
@@ -196,22 +304,67 @@ func getTableLevelConstraints(constraints []rsql.Constraint) []rsql.Constraint {
 	return tableLevel
 }
 
-func masterRows(db DBReader) ([]masterRow, error) { // Changed DB to DBReader
+func masterRows(ctx context.Context, db Reader) ([]masterRow, error) {
+	return schemaMasterRows(ctx, db, "")
+}
+
+// schemaMasterRows returns the objects of the attached database schema, or of the main database if it is empty.
+func schemaMasterRows(ctx context.Context, db Reader, schema string) ([]masterRow, error) {
 	var rows []masterRow
-	err := db.Select(&rows, "SELECT name, sql FROM sqlite_master WHERE name NOT LIKE 'sqlite_%'")
+	err := db.SelectContext(ctx, &rows, "SELECT type, name, sql FROM "+qualifiedIdent(schema, "sqlite_master")+" WHERE sql IS NOT NULL")
 	if err != nil {
 		return nil, err
 	}
-	return rows, nil
+	return filterInternalObjects(rows), nil
 }
 
 type masterRow struct {
+	Type string `db:"type"`
 	Name string `db:"name"`
 	Sql  string `db:"sql"`
 }
 
-func VerifyString(ctx context.Context, db DB, sql string) error {
-	return Verify(ctx, db, strings.NewReader(sql))
+// filterInternalObjects removes the objects not managed by the schema: SQLite's own objects
+// such as sqlite_sequence and automatic indexes, the shadow tables of virtual tables and sqlt's _sqlt_ tables.
+func filterInternalObjects(rows []masterRow) []masterRow {
+	var virtualTables []string
+	for _, row := range rows {
+		if isVirtualTableSQL(row.Sql) {
+			virtualTables = append(virtualTables, strings.ToLower(row.Name))
+		}
+	}
+	filtered := rows[:0]
+	for _, row := range rows {
+		if !isInternalObject(row, virtualTables) {
+			filtered = append(filtered, row)
+		}
+	}
+	return filtered
+}
+
+func isInternalObject(row masterRow, virtualTables []string) bool {
+	name := strings.ToLower(row.Name)
+	if strings.HasPrefix(name, "sqlite_") || strings.HasPrefix(name, "_sqlt_") {
+		return true
+	}
+	if row.Type != "table" || isVirtualTableSQL(row.Sql) {
+		return false
+	}
+	for _, vtab := range virtualTables {
+		if strings.HasPrefix(name, vtab+"_") {
+			return true
+		}
+	}
+	return false
+}
+
+func isVirtualTableSQL(sql string) bool {
+	fields := strings.Fields(strings.ToUpper(sql))
+	return len(fields) >= 3 && fields[0] == "CREATE" && fields[1] == "VIRTUAL" && fields[2] == "TABLE"
+}
+
+func VerifyString(ctx context.Context, db DB, sql string, opts ...MigrateOption) error {
+	return Verify(ctx, db, strings.NewReader(sql), opts...)
 }
 
 func ExecSchemaFromEnv(ctx context.Context, db DB) error {
@@ -232,16 +385,60 @@ func VerifyFromEnv(ctx context.Context, db DB) error {
 	if !ok {
 		return fmt.Errorf("DATABASE_SCHEMA env var not found")
 	}
-	schema, err := os.Open(schemaFile)
+	schemaStmts, err := loadSchemaFile(schemaFile)
 	if err != nil {
-		return fmt.Errorf("could not open schema file %s: %w", schemaFile, err)
+		return err
 	}
-	defer schema.Close()
-	return Verify(ctx, db, schema)
+	return verifyStatements(ctx, db, schemaStmts, newMigrateConfig(nil))
+}
+
+// Verify checks that the objects in the database match the schema.
+// Only WithStrictness and WithTypeAffinity affect it, other options are ignored.
+func Verify(ctx context.Context, db DB, schema io.Reader, opts ...MigrateOption) error {
+	schemaStmts, err := parseSchemaStatements(schema)
+	if err != nil {
+		return err
+	}
+	return verifyStatements(ctx, db, schemaStmts, newMigrateConfig(opts))
 }
 
-func Verify(ctx context.Context, db DB, schema io.Reader) error {
-	dbMasterRows, err := masterRows(db)
+// VerifyAt checks both that the version table of the database holds expectedVersion and that its objects
+// match the schema, catching databases whose version is right but whose structure drifted.
+// Both checks always run; if either fails, a *VersionedSchemaError reports them together.
+// WithVersionTable, WithStrictness and WithTypeAffinity affect it, other options are ignored.
+func VerifyAt(ctx context.Context, db DB, schema io.Reader, expectedVersion int, opts ...MigrateOption) error {
+	cfg := newMigrateConfig(opts)
+	schemaStmts, err := parseSchemaStatements(schema)
+	if err != nil {
+		return err
+	}
+	verr := &VersionedSchemaError{ExpectedVersion: expectedVersion, ActualVersion: -1}
+	err = db.GetContext(ctx, &verr.ActualVersion, "SELECT version FROM "+cfg.versionTable+" LIMIT 1")
+	switch {
+	case errors.Is(err, sql.ErrNoRows) || err != nil && strings.Contains(err.Error(), "no such table"):
+		verr.VersionErr = ErrNoVersion
+	case err != nil:
+		verr.VersionErr = fmt.Errorf("could not read version: %w", err)
+	case verr.ActualVersion != expectedVersion:
+		verr.VersionErr = fmt.Errorf("database is at version v%d, expected v%d", verr.ActualVersion, expectedVersion)
+	}
+	verr.SchemaErr = verifyStatements(ctx, db, schemaStmts, cfg)
+	if verr.VersionErr != nil || verr.SchemaErr != nil {
+		return verr
+	}
+	return nil
+}
+
+// verifyStatements checks that the objects in the database match the parsed schema statements.
+func verifyStatements(ctx context.Context, db DB, schemaStmts []rsql.Statement, cfg *migrateConfig) error {
+	if d := cfg.dialectOf(db); d != nil {
+		desired, err := newSchemaDefinition(schemaStmts, nil)
+		if err != nil {
+			return err
+		}
+		return verifyDialect(ctx, db, d, desired)
+	}
+	dbMasterRows, err := masterRows(ctx, db)
 	if err != nil {
 		return fmt.Errorf("could not get master rows from DB: %w", err)
 	}
@@ -251,61 +448,68 @@ func Verify(ctx context.Context, db DB, schema io.Reader) error {
 		parser := rsql.NewParser(strings.NewReader(row.Sql))
 		stmt, err := parser.ParseStatement()
 		if err != nil {
-			if strings.Contains(err.Error(), "unexpected token IDENT") && strings.Contains(row.Sql, "sqlite_sequence") {
-				continue
-			}
 			return fmt.Errorf("could not parse SQL for DB object %s (SQL: %s): %w", row.Name, row.Sql, err)
 		}
-		dbObjectsMap[row.Name] = stmt
-		dbObjectNames[row.Name] = struct{}{}
+		dbObjectsMap[foldIdent(row.Name)] = stmt
+		dbObjectNames[foldIdent(row.Name)] = struct{}{}
 	}
 
-	schemaParser := rsql.NewParser(schema)
 	verifiedDbObjects := make(map[string]struct{})
-	for {
-		schemaStmt, err := schemaParser.ParseStatement()
-		if errors.Is(err, io.EOF) {
-			break
-		} else if err != nil {
-			return fmt.Errorf("could not parse statement from input schema: %w", err)
-		}
-		switch schemaStmt.(type) {
-		case *rsql.InsertStatement, *rsql.UpdateStatement, *rsql.DeleteStatement, *rsql.SelectStatement:
-			continue
-		}
+	sqls := make(statementSQLs)
+	for _, schemaStmt := range schemaStmts {
 		schemaObjectName, err := getStatementName(schemaStmt)
 		if err != nil {
 			return fmt.Errorf("could not extract name from schema statement %s: %w", schemaStmt.String(), err)
 		}
-		dbStmt, found := dbObjectsMap[schemaObjectName]
+		dbStmt, found := dbObjectsMap[foldIdent(schemaObjectName)]
 		if !found {
 			return fmt.Errorf("object '%s' from schema not found in database", schemaObjectName)
 		}
-		matchType, diffDescription, cmpErr := compareStatements(dbStmt, schemaStmt)
+		matchType, diffDescription, cmpErr := sqls.compare(dbStmt, schemaStmt, cfg.strictness)
 		if cmpErr != nil {
 			return fmt.Errorf("error comparing object '%s': %w. DB SQL: %s, Schema SQL: %s", schemaObjectName, cmpErr, dbStmt.String(), schemaStmt.String())
 		}
 		if matchType != statementMatchExact {
-			return fmt.Errorf("schema mismatch for object '%s': %s. DB SQL: \n%s\nSchema SQL: \n%s", schemaObjectName, diffDescription, dbStmt.String(), schemaStmt.String())
+			if conflictErr := tableOptionConflictError(dbStmt, schemaStmt, diffDescription); conflictErr != nil {
+				return fmt.Errorf("schema mismatch for object '%s': %w", schemaObjectName, conflictErr)
+			}
+			return fmt.Errorf("schema mismatch for object '%s': %s. DB SQL: \n%s\nSchema SQL: \n%s", schemaObjectName, diffDescription, sqls.of(dbStmt), sqls.of(schemaStmt))
 		}
-		verifiedDbObjects[schemaObjectName] = struct{}{}
+		verifiedDbObjects[foldIdent(schemaObjectName)] = struct{}{}
 	}
 
 	for dbObjName := range dbObjectNames {
 		if _, isVerified := verifiedDbObjects[dbObjName]; !isVerified {
-			if strings.HasPrefix(dbObjName, "sqlite_") {
-				continue
-			}
-			extraStmtString := ""
-			if extraStmt, ok := dbObjectsMap[dbObjName]; ok {
-				extraStmtString = extraStmt.String()
-			}
-			return fmt.Errorf("object '%s' found in database but not in schema. DB SQL: \n%s", dbObjName, extraStmtString)
+			extraStmt := dbObjectsMap[dbObjName]
+			extraName, _ := getStatementName(extraStmt)
+			return fmt.Errorf("object '%s' found in database but not in schema. DB SQL: \n%s", extraName, sqls.of(extraStmt))
 		}
 	}
 	return nil
 }
 
+// tableOptionConflictError returns a *SchemaConflictError naming the differing table options,
+// or nil if the statements are not tables or their options match.
+func tableOptionConflictError(dbStmt, schemaStmt rsql.Statement, diffDescription string) *SchemaConflictError {
+	dbTable, dbOk := dbStmt.(*rsql.CreateTableStatement)
+	schemaTable, schemaOk := schemaStmt.(*rsql.CreateTableStatement)
+	if !dbOk || !schemaOk {
+		return nil
+	}
+	options := compareTableOptions(dbTable, schemaTable)
+	if len(options) == 0 {
+		return nil
+	}
+	return &SchemaConflictError{
+		ObjectName:      schemaTable.Name.Name,
+		ObjectType:      "TABLE",
+		ExpectedSQL:     statementSQL(schemaStmt),
+		ActualSQL:       statementSQL(dbStmt),
+		ConflictDetails: diffDescription,
+		TableOptions:    options,
+	}
+}
+
 func getStatementName(stmt rsql.Statement) (string, error) {
 	switch s := stmt.(type) {
 	case *rsql.CreateTableStatement:
@@ -344,20 +548,36 @@ var ErrNoVersion = errors.New("no version found in database")
 // Applies the function in the versions map until a func is not found in the current version.
 // The version number denotes the version the function migrates from.
 //
-// Expects a table named `version` with a `version` column with current version number.
+// Expects a table named `version` with a `version` column with current version number,
+// see WithVersionTable to use another table.
 // Returns ErrNoVersion if the version table is not found or empty.
-func Migrate(ctx context.Context, db DB, versions map[int]func(context.Context, DB) error) error {
+//
+// See MigrateOption for the available options.
+func Migrate(ctx context.Context, db DB, versions map[int]func(context.Context, DB) error, opts ...MigrateOption) error {
+	cfg := newMigrateConfig(opts)
+	applied := 0
+	defer func() {
+		if applied > 0 {
+			cfg.checkpoint(ctx, db)
+		}
+	}()
+	return cfg.withLock(ctx, db, func() error {
+		return migrate(ctx, db, versions, cfg, &applied)
+	})
+}
+
+func migrate(ctx context.Context, db DB, versions map[int]func(context.Context, DB) error, cfg *migrateConfig, applied *int) error {
 	lastVersion := -1
 	for {
 		var version int
-		err := db.Get(&version, "SELECT version FROM version LIMIT 1")
+		err := db.GetContext(ctx, &version, "SELECT version FROM "+cfg.versionTable+" LIMIT 1")
 		if err != nil {
-			if errors.Is(err, sql.ErrNoRows) || strings.Contains(err.Error(), "no such table: version") {
+			if errors.Is(err, sql.ErrNoRows) || strings.Contains(err.Error(), "no such table") {
 				return ErrNoVersion
 			}
 			return err
 		}
-		fmt.Printf("current database schema version: v%d\n", version)
+		cfg.logf("current database schema version: v%d", version)
 		if version == lastVersion {
 			return nil
 		}
@@ -365,12 +585,64 @@ func Migrate(ctx context.Context, db DB, versions map[int]func(context.Context,
 		if !ok {
 			return nil
 		}
-		err = fn(ctx, db)
+		var executed []string
+		fnCtx := context.WithValue(context.WithValue(ctx, versionTableKey{}, cfg.versionTable), loggerKey{}, cfg.logf)
+		err = fn(fnCtx, &recordingDB{DB: db, executed: &executed, prepare: cfg.applyBusyTimeout})
 		if err != nil {
 			return fmt.Errorf("migration from version v%d failed: %w", version, err)
 		}
-		fmt.Printf("migration to database schema v%d complete\n", version+1)
+		*applied++
+		if err := cfg.writeSQLLog(executed); err != nil {
+			return fmt.Errorf("migration from version v%d: %w", version, err)
+		}
+		cfg.logf("migration to database schema v%d complete", version+1)
+	}
+}
+
+// versionTableKey passes the quoted version table of Migrate to the functions built by MigrateFunc.
+type versionTableKey struct{}
+
+// loggerKey passes the logf of Migrate, see WithLogger, to the functions built by Requires.
+type loggerKey struct{}
+
+// migrationLogf returns the logf of the running Migrate, or one printing to standard output.
+func migrationLogf(ctx context.Context) func(format string, args ...any) {
+	if logf, ok := ctx.Value(loggerKey{}).(func(format string, args ...any)); ok {
+		return logf
+	}
+	return (&migrateConfig{}).logf
+}
+
+// updateVersion sets the version in the version table of the running Migrate to expr.
+// The version table has a single row, so this is not a mass update even under WithGuard.
+func updateVersion(ctx context.Context, db Writer, expr string, args ...any) error {
+	versionTable, ok := ctx.Value(versionTableKey{}).(string)
+	if !ok {
+		versionTable = quoteIdent("version")
 	}
+	if _, err := db.ExecContext(AllowUnboundedWrite(ctx), "UPDATE "+versionTable+" SET version = "+expr, args...); err != nil {
+		return fmt.Errorf("could not update version: %w", err)
+	}
+	return nil
+}
+
+// checkForeignKeys returns an error listing the rows of the attached database schema, or of the main
+// database if it is empty, that violate a foreign key.
+func checkForeignKeys(ctx context.Context, db Reader, schema string) error {
+	pragma := "PRAGMA foreign_key_check"
+	if schema != "" {
+		pragma = "PRAGMA " + quoteIdent(schema) + ".foreign_key_check"
+	}
+	var mErrors []migrateTable
+	err := db.SelectContext(ctx, &mErrors, pragma)
+	if errors.Is(err, sql.ErrNoRows) {
+		//success
+	} else if err != nil {
+		return err
+	} else if len(mErrors) > 0 {
+		return fmt.Errorf("foreign_key migration errors: %v", mErrors)
+	}
+	return nil
 }
 
 type migrateTable struct {
@@ -500,17 +772,10 @@ func MigrateFunc(db DB, version int, migrateTables []string, fn func(tx Tx, rest
 			if err != nil {
 				return err
 			}
-			var mErrors []migrateTable
-			err = tx.Select(&mErrors, "PRAGMA foreign_key_check")
-			if errors.Is(err, sql.ErrNoRows) {
-				//success
-			} else if err != nil {
+			if err := checkForeignKeys(ctx, tx, ""); err != nil {
 				return err
-			} else if len(mErrors) > 0 {
-				return fmt.Errorf("foreign_key migration errors: %v", mErrors)
 			}
-			tx.MustExec(`UPDATE version SET version = version + 1`)
-			return nil
+			return updateVersion(ctx, tx, "version + 1")
 		})
 		if err != nil {
 			_, pErr := db.Exec("PRAGMA foreign_keys=ON")
@@ -529,8 +794,8 @@ func MigrateFunc(db DB, version int, migrateTables []string, fn func(tx Tx, rest
 }
 
 // ExecString executes the SQL from the provided string in a transaction.
-func ExecString(ctx context.Context, db DB, sql string) error {
-	return Exec(ctx, db, strings.NewReader(sql))
+func ExecString(ctx context.Context, db DB, sql string, opts ...MigrateOption) error {
+	return Exec(ctx, db, strings.NewReader(sql), opts...)
 }
 
 // ExecTxString executes the SQL from the provided string in a transaction.
@@ -539,10 +804,29 @@ func ExecTxString(tx Tx, sql string) error {
 }
 
 // Exec executes the SQL from the provided reader in a transaction.
-func Exec(ctx context.Context, db DB, reader io.Reader) error {
-	return db.Txc(ctx, func(tx Tx) error {
-		return ExecTx(tx, reader)
+//
+// Statements the parser cannot handle are split on semicolons instead, see WithRawExec.
+//
+// Only WithSQLLog, WithStatementBatching, WithRawExec and WithStrictParsing apply to Exec;
+// other MigrateOptions are ignored.
+func Exec(ctx context.Context, db DB, reader io.Reader, opts ...MigrateOption) error {
+	cfg := newMigrateConfig(opts)
+	var executed []string
+	err := db.Txc(ctx, func(tx Tx) error {
+		rtx := &recordingTx{Tx: tx, executed: &executed}
+		if !cfg.batchStatements {
+			return execStatements(rtx, reader, cfg)
+		}
+		var batch statementBatch
+		if err := execStatements(&batch, reader, cfg); err != nil {
+			return err
+		}
+		return rtx.execBatch(ctx, &batch)
 	})
+	if err != nil {
+		return err
+	}
+	return cfg.writeSQLLog(executed)
 }
 
 // func ExecTx(tx Tx, reader io.Reader) error {
@@ -606,31 +890,53 @@ func Exec(ctx context.Context, db DB, reader io.Reader) error {
 // }
 
 // ExecTx executes the SQL from the provided reader in a transaction.
-func ExecTx(tx Sqler, reader io.Reader) (err error) {
-	var last rsql.Statement
+func ExecTx(tx Handle, reader io.Reader) error {
+	return execStatements(tx, reader, newMigrateConfig(nil))
+}
+
+// execStatements executes the statements read from reader one by one.
+// The script is parsed before anything is executed. If the parser cannot handle it, or WithRawExec
+// is set, it is split with splitStatements instead, unless WithStrictParsing is set.
+func execStatements(tx execer, reader io.Reader, cfg *migrateConfig) error {
+	script, err := io.ReadAll(reader)
+	if err != nil {
+		return fmt.Errorf("could not read statements: %w", err)
+	}
+	var stmts []string
+	if !cfg.rawExec {
+		stmts, err = parseStatementsSQL(string(script))
+		if err != nil && cfg.strictParsing {
+			return err
+		}
+	}
+	if cfg.rawExec || err != nil {
+		stmts = splitStatements(string(script))
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return fmt.Errorf("error executing statement: %s\n%w", stmt, err)
+		}
+	}
+	return nil
+}
+
+// parseStatementsSQL parses every statement of script and returns their SQL.
+// All of script must be consumed; any error is a *ParseError.
+func parseStatementsSQL(script string) (stmts []string, err error) {
 	defer func() {
 		if r := recover(); r != nil {
-			if last != nil {
-				fmt.Printf("last successful statement: %s\n", last.String())
-			}
-			if e, ok := r.(error); ok {
-				err = e
-			} else {
-				err = fmt.Errorf("recovered rsql panic: %v", r)
-			}
+			err = parseError(fmt.Errorf("recovered rsql panic: %v", r))
 		}
 	}()
-	parser := rsql.NewParser(reader)
+	parser := rsql.NewParser(strings.NewReader(script))
 	for {
 		stmt, err := parser.ParseStatement()
-		if errors.Is(err, io.EOF) || stmt == nil {
-			break
+		if errors.Is(err, io.EOF) {
+			return stmts, nil
 		}
-		_, err = tx.Exec(stmt.String())
 		if err != nil {
-			return fmt.Errorf("error executing statement: %s\n%w", stmt.String(), err)
+			return nil, parseError(err)
 		}
-		last = stmt
+		stmts = append(stmts, statementSQL(stmt))
 	}
-	return nil
 }
@@ -0,0 +1,256 @@
+package sqlt
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	rsql "github.com/rqlite/sql"
+)
+
+func TestFilterInternalObjects(t *testing.T) {
+	rows := []masterRow{
+		{Type: "table", Name: "docs", Sql: "CREATE VIRTUAL TABLE docs USING fts5(body)"},
+		{Type: "table", Name: "docs_data", Sql: "CREATE TABLE 'docs_data'(id INTEGER PRIMARY KEY, block BLOB)"},
+		{Type: "table", Name: "docs_config", Sql: "CREATE TABLE 'docs_config'(k PRIMARY KEY, v) WITHOUT ROWID"},
+		{Type: "index", Name: "docs_idx_body", Sql: "CREATE INDEX docs_idx_body ON notes (body)"},
+		{Type: "table", Name: "sqlite_sequence", Sql: "CREATE TABLE sqlite_sequence(name,seq)"},
+		{Type: "table", Name: "_sqlt_locks", Sql: "CREATE TABLE _sqlt_locks (name TEXT PRIMARY KEY)"},
+		{Type: "table", Name: "notes", Sql: "CREATE TABLE notes (body TEXT)"},
+	}
+	var names []string
+	for _, row := range filterInternalObjects(rows) {
+		names = append(names, row.Name)
+	}
+	want := []string{"docs", "docs_idx_body", "notes"}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("filterInternalObjects kept %v, want %v", names, want)
+	}
+}
+
+func TestTypeAffinityOf(t *testing.T) {
+	for typeName, want := range map[string]string{
+		"INT":              "INTEGER",
+		"BIGINT":           "INTEGER",
+		"VARCHAR":          "TEXT",
+		"nchar":            "TEXT",
+		"CLOB":             "TEXT",
+		"BLOB":             "BLOB",
+		"":                 "BLOB",
+		"DOUBLE PRECISION": "REAL",
+		"FLOAT":            "REAL",
+		"DECIMAL":          "NUMERIC",
+		"BOOLEAN":          "NUMERIC",
+	} {
+		if got := typeAffinityOf(typeName); got != want {
+			t.Errorf("typeAffinityOf(%q) = %s, want %s", typeName, got, want)
+		}
+	}
+}
+
+func TestLoadSchemaFile_Cache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schema.sql")
+	if err := os.WriteFile(path, []byte("CREATE TABLE a (id INTEGER);"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	first, err := loadSchemaFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := loadSchemaFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if &first[0] != &second[0] {
+		t.Fatal("unchanged schema file was parsed again")
+	}
+
+	// Touching the file without changing its content keeps the parsed statements.
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	touched, err := loadSchemaFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if &first[0] != &touched[0] {
+		t.Fatal("touched schema file with the same content was parsed again")
+	}
+
+	if err := os.WriteFile(path, []byte("CREATE TABLE a (id INTEGER);\nCREATE TABLE b (id INTEGER);"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	changed, err := loadSchemaFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changed) != 2 {
+		t.Fatalf("changed schema file has %d statements, want 2", len(changed))
+	}
+}
+
+func TestParseSQLiteVersion(t *testing.T) {
+	for version, want := range map[string]int{"3.45.1": 3045001, "3.8": 3008000, "3.35.0": 3035000} {
+		got, err := parseSQLiteVersion(version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("parseSQLiteVersion(%q) = %d, want %d", version, got, want)
+		}
+	}
+	if _, err := parseSQLiteVersion("x.y"); err == nil {
+		t.Error("expected an error for an invalid version")
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	script := `
+-- leading comment;
+CREATE TABLE "a;b" (x TEXT DEFAULT 'it''s; fine', [y;z] INTEGER);
+/* block; comment */
+CREATE TEMP TRIGGER t AFTER INSERT ON "a;b" BEGIN
+	SELECT 1;
+	SELECT 'END;';
+END;
+BEGIN;
+COMMIT
+-- trailing comment`
+	want := []string{
+		"-- leading comment;\nCREATE TABLE \"a;b\" (x TEXT DEFAULT 'it''s; fine', [y;z] INTEGER)",
+		"/* block; comment */\nCREATE TEMP TRIGGER t AFTER INSERT ON \"a;b\" BEGIN\n\tSELECT 1;\n\tSELECT 'END;';\nEND",
+		"BEGIN",
+		"COMMIT",
+	}
+	got := splitStatements(script)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("splitStatements = %q, want %q", got, want)
+	}
+}
+
+func TestParseStatementsParallel(t *testing.T) {
+	var b strings.Builder
+	for i := range parallelParseThreshold * 2 {
+		fmt.Fprintf(&b, "-- table %d\nCREATE TABLE t%d (id INTEGER PRIMARY KEY, name TEXT DEFAULT 'a;b');\n", i, i)
+		fmt.Fprintf(&b, "CREATE TRIGGER tr%d AFTER INSERT ON t%d BEGIN UPDATE t%d SET name = 'x'; END;\n", i, i, i)
+		fmt.Fprintf(&b, "INSERT INTO t%d (name) VALUES ('seed');\n", i)
+	}
+	script := b.String()
+	pieces := splitStatements(script)
+	parallel, ok := parseStatementsParallel(pieces)
+	if !ok {
+		t.Fatal("parseStatementsParallel failed to parse the schema")
+	}
+	var sequential []rsql.Statement
+	parser := rsql.NewParser(strings.NewReader(script))
+	for {
+		stmt, err := parser.ParseStatement()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if isSchemaStatement(stmt) {
+			sequential = append(sequential, stmt)
+		}
+	}
+	if len(parallel) != len(sequential) {
+		t.Fatalf("parsed %d statements in parallel, want %d", len(parallel), len(sequential))
+	}
+	for i := range parallel {
+		if statementSQL(parallel[i]) != statementSQL(sequential[i]) {
+			t.Fatalf("statement %d is %s, want %s", i, statementSQL(parallel[i]), statementSQL(sequential[i]))
+		}
+	}
+
+	if _, ok := parseStatementsParallel(append(pieces, "CREATE TABLE broken (")); ok {
+		t.Fatal("parseStatementsParallel accepted an invalid statement")
+	}
+	_, err := parseSchemaStatements(strings.NewReader(script + "CREATE TABLE broken (\n"))
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) || parseErr.Line != strings.Count(script, "\n")+2 {
+		t.Fatalf("parseSchemaStatements returned %v, want a parse error on the last line", err)
+	}
+}
+
+// benchmarkSchemaStatements parses a schema of tables with ten columns, an index and a trigger each.
+func benchmarkSchemaStatements(b *testing.B, tables int) []rsql.Statement {
+	var sb strings.Builder
+	for i := range tables {
+		fmt.Fprintf(&sb, "CREATE TABLE t%d (id INTEGER PRIMARY KEY, a TEXT NOT NULL, b INTEGER DEFAULT 0, c REAL, d BLOB, e TEXT, f TEXT, g INTEGER, h TEXT, i TEXT, UNIQUE (a, b));\n", i)
+		fmt.Fprintf(&sb, "CREATE INDEX idx_t%d_a ON t%d (a, b DESC);\n", i, i)
+		fmt.Fprintf(&sb, "CREATE TRIGGER tr_t%d AFTER UPDATE ON t%d BEGIN UPDATE t%d SET c = 1 WHERE id = NEW.id; END;\n", i, i, i)
+	}
+	stmts, err := parseSchemaStatements(strings.NewReader(sb.String()))
+	if err != nil {
+		b.Fatal(err)
+	}
+	return stmts
+}
+
+func BenchmarkCompareStatements(b *testing.B) {
+	dbStmts, schemaStmts := benchmarkSchemaStatements(b, 1), benchmarkSchemaStatements(b, 1)
+	b.ReportAllocs()
+	for b.Loop() {
+		for i := range dbStmts {
+			if match, _, err := compareStatements(dbStmts[i], schemaStmts[i], StrictnessStructural); err != nil || match != statementMatchExact {
+				b.Fatalf("statement %d does not match: %v", i, err)
+			}
+		}
+	}
+}
+
+func BenchmarkReconcileSchema(b *testing.B) {
+	dbStmts, schemaStmts := benchmarkSchemaStatements(b, 200), benchmarkSchemaStatements(b, 200)
+	cfg := newMigrateConfig(nil)
+	b.ReportAllocs()
+	for b.Loop() {
+		var batch statementBatch
+		if err := reconcileSchema(&batch, dbStmts, schemaStmts, false, cfg, latestCapabilities); err != nil {
+			b.Fatal(err)
+		}
+		if len(batch.stmts) > 0 {
+			b.Fatalf("reconciling identical schemas executed %v", batch.stmts)
+		}
+	}
+}
+
+func TestPlanner_ClassifiesParsedStatements(t *testing.T) {
+	p := &planner{}
+	p.out = &p.batch
+	for _, stmt := range []string{
+		`CREATE UNIQUE INDEX "users_email" ON "users" ("email")`,
+		`CREATE VIRTUAL TABLE docs USING fts5(body)`,
+		`CREATE TEMP TRIGGER touch AFTER UPDATE ON users BEGIN SELECT 1; END`,
+		`ALTER TABLE "users" RENAME TO "users_temp_reorder_sqlt"`,
+		`CREATE TABLE "users" ("id" INTEGER PRIMARY KEY)`,
+		`DROP TABLE "users_temp_reorder_sqlt"`,
+		`DROP VIEW IF EXISTS "names"`,
+	} {
+		if _, err := p.Exec(stmt); err != nil {
+			t.Fatalf("Exec(%s): %v", stmt, err)
+		}
+	}
+	var got []string
+	for _, step := range p.plan.Steps {
+		got = append(got, fmt.Sprintf("%s %s %s (%d)", step.Action, step.ObjectType, step.ObjectName, len(step.Statements)))
+	}
+	want := []string{
+		"create INDEX users_email (1)",
+		"execute   (1)",
+		"execute   (1)",
+		"rebuild TABLE users (3)",
+		"drop VIEW names (1)",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got steps %q, want %q", got, want)
+	}
+}
@@ -1,9 +1,14 @@
 package sqlt_test
 
 import (
+	"context"
+	"errors"
 	"fmt" // Keep for TestMigration
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 	// "os" // No longer needed for t.Setenv
 
 	// "github.com/jmoiron/sqlx" // No longer needed here, getTestDB is in automigrate_test.go
@@ -134,3 +139,361 @@ func TestMigration(t *testing.T) {
 		t.Fatalf("Verify after migration failed: %v", err)
 	}
 }
+
+func TestMigrationSQLLog(t *testing.T) {
+	t.Parallel()
+	db := getTestDB(t)
+	defer db.Close()
+
+	ctx := gort.Context()
+
+	err := sqlt.ExecString(ctx, db, base)
+	if err != nil {
+		t.Fatalf("Failed to setup test db: %v", err)
+	}
+	versions := sqlt.MigrationMap{
+		1: func(ctx context.Context, db sqlt.DB) error {
+			return sqlt.ExecString(ctx, db, table3+"\nUPDATE version SET version = 2;")
+		},
+	}
+
+	var sqlLog strings.Builder
+	err = sqlt.Migrate(ctx, db, versions, sqlt.WithSQLLog(&sqlLog))
+	if err != nil {
+		t.Fatalf("Migration failed: %v", err)
+	}
+	for _, want := range []string{`CREATE TABLE "table_3"`, `INSERT INTO "table_3"`, `UPDATE "version" SET "version" = 2;` + "\n"} {
+		if !strings.Contains(sqlLog.String(), want) {
+			t.Fatalf("SQL log is missing %q:\n%s", want, sqlLog.String())
+		}
+	}
+}
+
+func TestMigrationSQLLog_RolledBackAndNamed(t *testing.T) {
+	t.Parallel()
+	db := getTestDB(t)
+	defer db.Close()
+
+	ctx := gort.Context()
+
+	db.MustExec("CREATE TABLE version (version INTEGER NOT NULL); INSERT INTO version VALUES (1); CREATE TABLE notes (body TEXT)")
+	versions := sqlt.MigrationMap{
+		1: func(ctx context.Context, db sqlt.DB) error {
+			// The optional step fails and is rolled back, the migration goes on without it.
+			_ = db.Txc(ctx, func(tx sqlt.Tx) error {
+				if _, err := tx.Exec("INSERT INTO notes (body) VALUES ('rolled back')"); err != nil {
+					return err
+				}
+				return errors.New("optional step failed")
+			})
+			if _, err := db.NamedExec("INSERT INTO notes (body) VALUES (:body)", map[string]any{"body": "kept"}); err != nil {
+				return err
+			}
+			_, err := db.Exec("UPDATE version SET version = 2")
+			return err
+		},
+	}
+
+	var sqlLog strings.Builder
+	if err := sqlt.Migrate(ctx, db, versions, sqlt.WithSQLLog(&sqlLog)); err != nil {
+		t.Fatalf("Migration failed: %v", err)
+	}
+	want := "INSERT INTO notes (body) VALUES (:body);\nUPDATE version SET version = 2;\n"
+	if sqlLog.String() != want {
+		t.Fatalf("SQL log is %q, want %q", sqlLog.String(), want)
+	}
+}
+
+func TestVerifyTableOptionMismatch(t *testing.T) {
+	t.Parallel()
+	db := getTestDB(t)
+	defer db.Close()
+
+	ctx := gort.Context()
+
+	err := sqlt.ExecString(ctx, db, `CREATE TABLE kv (k TEXT PRIMARY KEY, v TEXT) WITHOUT ROWID;`)
+	if err != nil {
+		t.Fatalf("Failed to setup test db: %v", err)
+	}
+
+	err = sqlt.VerifyString(ctx, db, `CREATE TABLE kv (k TEXT PRIMARY KEY, v TEXT) STRICT;`)
+	var conflictErr *sqlt.SchemaConflictError
+	if !errors.As(err, &conflictErr) {
+		t.Fatalf("Expected SchemaConflictError, got: %v", err)
+	}
+	want := []sqlt.TableOptionConflict{
+		{Option: "WITHOUT ROWID", Expected: false, Actual: true},
+		{Option: "STRICT", Expected: true, Actual: false},
+	}
+	if !reflect.DeepEqual(conflictErr.TableOptions, want) {
+		t.Fatalf("Unexpected table option conflicts: %+v", conflictErr.TableOptions)
+	}
+	if strings.Contains(err.Error(), "DB SQL") {
+		t.Fatalf("Error should name the options instead of dumping SQL: %v", err)
+	}
+}
+
+func TestVerifyIgnoresSqliteSequence(t *testing.T) {
+	t.Parallel()
+	db := getTestDB(t)
+	defer db.Close()
+
+	ctx := gort.Context()
+
+	schema := `CREATE TABLE events (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT UNIQUE);`
+	if err := sqlt.AutoMigrate(ctx, db, strings.NewReader(schema), false); err != nil {
+		t.Fatalf("AutoMigrate failed: %v", err)
+	}
+	if _, err := db.Exec("INSERT INTO events (name) VALUES ('created')"); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+	if err := sqlt.VerifyString(ctx, db, schema); err != nil {
+		t.Fatalf("Verify should ignore sqlite_sequence and autoindexes: %v", err)
+	}
+	if err := sqlt.AutoMigrate(ctx, db, strings.NewReader(schema), false); err != nil {
+		t.Fatalf("AutoMigrate should ignore sqlite_sequence and autoindexes: %v", err)
+	}
+}
+
+func TestVerifyMatchesNamesCaseInsensitively(t *testing.T) {
+	t.Parallel()
+	db := getTestDB(t)
+	defer db.Close()
+
+	ctx := gort.Context()
+
+	if err := sqlt.ExecString(ctx, db, `CREATE TABLE users (id INTEGER PRIMARY KEY);`); err != nil {
+		t.Fatalf("Failed to setup test db: %v", err)
+	}
+	if err := sqlt.VerifyString(ctx, db, `CREATE TABLE Users (id INTEGER PRIMARY KEY);`); err != nil {
+		t.Fatalf("Verify should match names like SQLite does: %v", err)
+	}
+}
+
+func TestVerify_Strictness(t *testing.T) {
+	t.Parallel()
+	db := getTestDB(t)
+	defer db.Close()
+	ctx := gort.Context()
+
+	if err := sqlt.ExecString(ctx, db, `CREATE TABLE notes (id INT PRIMARY KEY, title VARCHAR(80));`); err != nil {
+		t.Fatalf("Failed to setup test db: %v", err)
+	}
+
+	intSchema := `CREATE TABLE notes (id INTEGER PRIMARY KEY, title VARCHAR(80));`
+	if err := sqlt.VerifyString(ctx, db, intSchema); err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if err := sqlt.VerifyString(ctx, db, intSchema, sqlt.WithStrictness(sqlt.StrictnessExact)); err == nil {
+		t.Fatal("Expected exact Verify to fail on INT vs INTEGER, but it succeeded")
+	}
+
+	textSchema := `CREATE TABLE notes (id INT PRIMARY KEY, title TEXT);`
+	if err := sqlt.VerifyString(ctx, db, textSchema); err == nil {
+		t.Fatal("Expected Verify to fail on VARCHAR vs TEXT, but it succeeded")
+	}
+	if err := sqlt.VerifyString(ctx, db, textSchema, sqlt.WithStrictness(sqlt.StrictnessAffinity)); err != nil {
+		t.Fatalf("Verify with affinity failed: %v", err)
+	}
+}
+
+func TestExec_RawFallback(t *testing.T) {
+	t.Parallel()
+	db := getTestDB(t)
+	defer db.Close()
+	ctx := gort.Context()
+
+	// The parser cannot handle REFERENCES without a column list, so the script is split raw.
+	script := `
+CREATE TABLE teams (id INTEGER PRIMARY KEY);
+CREATE TABLE audit (msg TEXT); -- semicolons; in comments
+CREATE TABLE members (team_id INTEGER REFERENCES teams);
+CREATE TRIGGER members_audit AFTER INSERT ON members BEGIN
+	INSERT INTO audit (msg) VALUES ('joined; team');
+	INSERT INTO audit (msg) VALUES ('twice');
+END;`
+	if err := sqlt.ExecString(ctx, db, script); err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if err := sqlt.ExecString(ctx, db, `INSERT INTO teams (id) VALUES (1); INSERT INTO members (team_id) VALUES (1);`, sqlt.WithRawExec()); err != nil {
+		t.Fatalf("raw Exec failed: %v", err)
+	}
+	var msgs []string
+	if err := db.Select(&msgs, "SELECT msg FROM audit ORDER BY rowid"); err != nil {
+		t.Fatalf("Failed to read audit: %v", err)
+	}
+	if !reflect.DeepEqual(msgs, []string{"joined; team", "twice"}) {
+		t.Fatalf("trigger wrote %v", msgs)
+	}
+}
+
+func TestExec_StrictParsing(t *testing.T) {
+	t.Parallel()
+	db := getTestDB(t)
+	defer db.Close()
+	ctx := gort.Context()
+
+	script := "CREATE TABLE ok (id INTEGER);\nCREATE TABLE members (team_id INTEGER REFERENCES teams);"
+	err := sqlt.ExecString(ctx, db, script, sqlt.WithStrictParsing())
+	var parseErr *sqlt.ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("Expected a *ParseError, got %v", err)
+	}
+	if parseErr.Line != 2 || parseErr.Column == 0 {
+		t.Fatalf("Expected the error on line 2, got line %d, column %d", parseErr.Line, parseErr.Column)
+	}
+	var count int
+	if err := db.Get(&count, "SELECT COUNT(*) FROM sqlite_master WHERE name = 'ok'"); err != nil {
+		t.Fatalf("Failed to query sqlite_master: %v", err)
+	}
+	if count != 0 {
+		t.Fatal("No statement must run when the script cannot be parsed")
+	}
+}
+
+// openAttachedDB returns a database with a second database attached as aux, on a single
+// connection as ATTACH only applies to the connection executing it.
+func openAttachedDB(t *testing.T) sqlt.DB {
+	dir := t.TempDir()
+	db, err := sqlt.Open("sqlite3", filepath.Join(dir, "main.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SQLX().SetMaxOpenConns(1)
+	if _, err := db.Exec("ATTACH DATABASE ? AS aux", filepath.Join(dir, "aux.db")); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestMigrate_VersionTable(t *testing.T) {
+	t.Parallel()
+	db := openAttachedDB(t)
+	defer db.Close()
+	ctx := gort.Context()
+
+	if _, err := db.Exec("CREATE TABLE aux.schema_version (version INTEGER NOT NULL); INSERT INTO aux.schema_version VALUES (1)"); err != nil {
+		t.Fatal(err)
+	}
+	versions := sqlt.MigrationMap{
+		1: sqlt.MigrateFunc(db, 1, nil, func(tx sqlt.Tx, restore func() error) error {
+			return nil
+		}),
+	}
+	if err := sqlt.Migrate(ctx, db, versions); !errors.Is(err, sqlt.ErrNoVersion) {
+		t.Fatalf("expected ErrNoVersion without the option, got %v", err)
+	}
+	if err := sqlt.Migrate(ctx, db, versions, sqlt.WithVersionTable("aux", "schema_version")); err != nil {
+		t.Fatalf("Migration failed: %v", err)
+	}
+	var version int
+	if err := db.Get(&version, "SELECT version FROM aux.schema_version"); err != nil {
+		t.Fatal(err)
+	}
+	if version != 2 {
+		t.Fatalf("expected version 2, got %d", version)
+	}
+}
+
+func TestMigrate_BusyTimeout(t *testing.T) {
+	t.Parallel()
+	db := getTestDB(t)
+	defer db.Close()
+	ctx := gort.Context()
+
+	db.MustExec("CREATE TABLE version (version INTEGER NOT NULL); INSERT INTO version VALUES (1)")
+	var busyTimeout int
+	versions := sqlt.MigrationMap{
+		1: sqlt.MigrateFunc(db, 1, nil, func(tx sqlt.Tx, restore func() error) error {
+			return tx.Get(&busyTimeout, "PRAGMA busy_timeout")
+		}),
+	}
+	if err := sqlt.Migrate(ctx, db, versions, sqlt.WithBusyTimeout(1234*time.Millisecond)); err != nil {
+		t.Fatalf("Migration failed: %v", err)
+	}
+	if busyTimeout != 1234 {
+		t.Fatalf("expected busy_timeout 1234 in the migration transaction, got %d", busyTimeout)
+	}
+}
+
+func TestAutoMigrate_TargetSchema(t *testing.T) {
+	t.Parallel()
+	db := openAttachedDB(t)
+	defer db.Close()
+	ctx := gort.Context()
+
+	schema := `
+CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT NOT NULL);
+CREATE INDEX idx_items_name ON items (name);`
+	if err := sqlt.AutoMigrate(ctx, db, strings.NewReader(schema), false, sqlt.WithTargetSchema("aux")); err != nil {
+		t.Fatalf("AutoMigrate failed: %v", err)
+	}
+	db.MustExec("INSERT INTO aux.items (name) VALUES ('a')")
+	schema += `
+CREATE TABLE tags (id INTEGER PRIMARY KEY, item_id INTEGER NOT NULL REFERENCES items (id));`
+	if err := sqlt.AutoMigrate(ctx, db, strings.NewReader(schema), false, sqlt.WithTargetSchema("aux")); err != nil {
+		t.Fatalf("AutoMigrate failed: %v", err)
+	}
+
+	var names []string
+	if err := db.Select(&names, "SELECT name FROM aux.sqlite_master ORDER BY name"); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(names, []string{"idx_items_name", "items", "tags"}) {
+		t.Fatalf("unexpected aux objects: %v", names)
+	}
+	var mainCount int
+	if err := db.Get(&mainCount, "SELECT COUNT(*) FROM main.sqlite_master WHERE name LIKE '%items%'"); err != nil {
+		t.Fatal(err)
+	}
+	if mainCount != 0 {
+		t.Fatalf("expected no items objects in main, got %d", mainCount)
+	}
+}
+
+func TestVerifyAt(t *testing.T) {
+	t.Parallel()
+	db := getTestDB(t)
+	defer db.Close()
+	ctx := gort.Context()
+
+	if err := sqlt.ExecString(ctx, db, base); err != nil {
+		t.Fatalf("Failed to setup test db: %v", err)
+	}
+	if err := sqlt.VerifyAt(ctx, db, strings.NewReader(base), 1); err != nil {
+		t.Fatalf("VerifyAt failed: %v", err)
+	}
+
+	err := sqlt.VerifyAt(ctx, db, strings.NewReader(basePlus2), 2)
+	var verr *sqlt.VersionedSchemaError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected VersionedSchemaError, got %v", err)
+	}
+	if verr.ActualVersion != 1 || verr.VersionErr == nil || verr.SchemaErr == nil {
+		t.Fatalf("expected version and schema failures, got %v", err)
+	}
+
+	db.MustExec("DROP TABLE version")
+	err = sqlt.VerifyAt(ctx, db, strings.NewReader(table2WithRemovedColumn), 1)
+	if !errors.Is(err, sqlt.ErrNoVersion) {
+		t.Fatalf("expected ErrNoVersion, got %v", err)
+	}
+}
+
+func TestVerify_CanceledContext(t *testing.T) {
+	t.Parallel()
+	db := getTestDB(t)
+	defer db.Close()
+
+	if err := sqlt.ExecString(gort.Context(), db, base); err != nil {
+		t.Fatalf("Failed to setup test db: %v", err)
+	}
+	ctx, cancel := context.WithCancel(gort.Context())
+	cancel()
+	if err := sqlt.VerifyString(ctx, db, base); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled from Verify, got %v", err)
+	}
+	if _, err := sqlt.DetectDrift(ctx, db, strings.NewReader(base), strings.NewReader(base)); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled from DetectDrift, got %v", err)
+	}
+}
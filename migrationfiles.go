@@ -0,0 +1,93 @@
+package sqlt
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// migrationFileName matches golang-migrate files, NNNN_name.up.sql and NNNN_name.down.sql,
+// and Atlas files, NNNN_name.sql.
+var migrationFileName = regexp.MustCompile(`^(\d+)_(.*?)(\.up|\.down)?\.sql$`)
+
+// MigrationsFromFS reads a directory of migration files, in the golang-migrate format, NNNN_name.up.sql with
+// an optional NNNN_name.down.sql, or in the Atlas format, NNNN_name.sql, and returns them as migrations for Migrate.
+//
+// The number of each file is the version it migrates to, so up is keyed by the number of the previous file,
+// or 0 for the first, and down, holding the down migrations present, by the number of their file.
+// Versions need not be consecutive, so timestamped files work as well. A database migrated by golang-migrate
+// can continue with sqlt by passing WithVersionTable("", "schema_migrations") to Migrate.
+// Each file runs in a transaction together with its version update.
+func MigrationsFromFS(fsys fs.FS) (up, down MigrationMap, err error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not read migrations: %w", err)
+	}
+	type migrationFile struct {
+		version  int
+		up, down string
+	}
+	files := make(map[int]*migrationFile)
+	for _, entry := range entries {
+		m := migrationFileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || m == nil {
+			continue
+		}
+		version, err := strconv.Atoi(m[1])
+		if err != nil {
+			return nil, nil, fmt.Errorf("migration %s: invalid version: %w", entry.Name(), err)
+		}
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not read migration %s: %w", entry.Name(), err)
+		}
+		f, ok := files[version]
+		if !ok {
+			f = &migrationFile{version: version}
+			files[version] = f
+		}
+		sqlText := &f.up
+		if m[3] == ".down" {
+			sqlText = &f.down
+		}
+		if *sqlText != "" {
+			return nil, nil, fmt.Errorf("migration %s: version %d is defined twice", entry.Name(), version)
+		}
+		*sqlText = string(content)
+	}
+	versions := make([]int, 0, len(files))
+	for version := range files {
+		versions = append(versions, version)
+	}
+	slices.Sort(versions)
+	up, down = make(MigrationMap, len(versions)), make(MigrationMap)
+	from := 0
+	for _, version := range versions {
+		f := files[version]
+		if f.up == "" {
+			return nil, nil, fmt.Errorf("migration %d has no up migration", version)
+		}
+		up[from] = sqlMigration(f.up, version)
+		if f.down != "" {
+			down[version] = sqlMigration(f.down, from)
+		}
+		from = version
+	}
+	return up, down, nil
+}
+
+// sqlMigration returns a migration executing script and setting the version to version in one transaction.
+func sqlMigration(script string, version int) MigrationFunc {
+	return func(ctx context.Context, db DB) error {
+		return db.Txc(ctx, func(tx Tx) error {
+			if err := ExecTx(tx, strings.NewReader(script)); err != nil {
+				return err
+			}
+			return updateVersion(ctx, tx, "?", version)
+		})
+	}
+}
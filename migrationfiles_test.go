@@ -0,0 +1,128 @@
+package sqlt_test
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/james-darko/gort"
+	"github.com/james-darko/sqlt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrationsFromFS(t *testing.T) {
+	t.Parallel()
+	ctx := gort.Context()
+	fsys := fstest.MapFS{
+		"000001_create_users.up.sql":   {Data: []byte("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT);")},
+		"000001_create_users.down.sql": {Data: []byte("DROP TABLE users;")},
+		"000003_add_email.up.sql":      {Data: []byte("ALTER TABLE users ADD COLUMN email TEXT;\nCREATE INDEX idx_users_email ON users (email);")},
+		"20240101120000_posts.sql":     {Data: []byte("-- atlas:txmode none\nCREATE TABLE posts (id INTEGER PRIMARY KEY);")},
+		"atlas.sum":                    {Data: []byte("h1:...")},
+		"README.md":                    {Data: []byte("migrations")},
+	}
+	up, down, err := sqlt.MigrationsFromFS(fsys)
+	require.NoError(t, err)
+	assert.Len(t, up, 3)
+	assert.Contains(t, up, 0)
+	assert.Contains(t, up, 1)
+	assert.Contains(t, up, 3)
+	assert.Len(t, down, 1)
+	assert.Contains(t, down, 1)
+
+	db := getTestDB(t)
+	defer db.Close()
+	// golang-migrate's version table.
+	db.MustExec("CREATE TABLE schema_migrations (version uint64 NOT NULL PRIMARY KEY, dirty boolean NOT NULL); INSERT INTO schema_migrations VALUES (0, false)")
+	require.NoError(t, sqlt.Migrate(ctx, db, up, sqlt.WithVersionTable("", "schema_migrations")))
+	var version int
+	require.NoError(t, db.Get(&version, "SELECT version FROM schema_migrations"))
+	assert.Equal(t, 20240101120000, version)
+	assert.True(t, objectExists(t, db, "index", "idx_users_email"))
+	assert.True(t, objectExists(t, db, "table", "posts"))
+
+	other := getTestDB(t)
+	defer other.Close()
+	other.MustExec("CREATE TABLE version (version INTEGER NOT NULL); INSERT INTO version VALUES (0)")
+	require.NoError(t, up[0](ctx, other))
+	require.NoError(t, down[1](ctx, other))
+	require.NoError(t, other.Get(&version, "SELECT version FROM version"))
+	assert.Equal(t, 0, version)
+	assert.False(t, objectExists(t, other, "table", "users"))
+}
+
+func TestMigrationsFromFS_Invalid(t *testing.T) {
+	t.Parallel()
+	_, _, err := sqlt.MigrationsFromFS(fstest.MapFS{"0002_x.down.sql": {Data: []byte("SELECT 1;")}})
+	assert.ErrorContains(t, err, "migration 2 has no up migration")
+	_, _, err = sqlt.MigrationsFromFS(fstest.MapFS{"0002_x.up.sql": {Data: []byte("SELECT 1;")}, "2_y.sql": {Data: []byte("SELECT 1;")}})
+	assert.ErrorContains(t, err, "version 2 is defined twice")
+}
+
+func TestBaseline(t *testing.T) {
+	t.Parallel()
+	ctx := gort.Context()
+	db := getTestDB(t)
+	defer db.Close()
+	db.MustExec("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT); INSERT INTO users (name) VALUES ('ada')")
+
+	_, err := sqlt.ReadBaseline(ctx, db)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	require.NoError(t, sqlt.Baseline(ctx, db, 7))
+	record, err := sqlt.ReadBaseline(ctx, db)
+	require.NoError(t, err)
+	assert.Equal(t, 7, record.Version)
+	assert.Len(t, record.SchemaHash, 64)
+	assert.Contains(t, record.Schema, `CREATE TABLE "users"`)
+	assert.NotContains(t, record.Schema, "version")
+
+	var version, users int
+	require.NoError(t, db.Get(&version, "SELECT version FROM version"))
+	assert.Equal(t, 7, version)
+	require.NoError(t, db.Get(&users, "SELECT COUNT(*) FROM users"))
+	assert.Equal(t, 1, users, "nothing is executed")
+
+	assert.ErrorContains(t, sqlt.Baseline(ctx, db, 8), "database already has version 7")
+
+	up := sqlt.MigrationMap{7: func(ctx context.Context, db sqlt.DB) error {
+		_, err := db.ExecContext(ctx, "ALTER TABLE users ADD COLUMN email TEXT; UPDATE version SET version = 8")
+		return err
+	}}
+	require.NoError(t, sqlt.Migrate(ctx, db, up))
+	require.NoError(t, sqlt.VerifyAt(ctx, db, strings.NewReader("CREATE TABLE version (version INTEGER NOT NULL); CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, email TEXT);"), 8))
+}
+
+func TestRequires(t *testing.T) {
+	t.Parallel()
+	ctx := gort.Context()
+	db := getTestDB(t)
+	defer db.Close()
+	db.MustExec("CREATE TABLE version (version INTEGER NOT NULL); INSERT INTO version VALUES (0); CREATE TABLE log (entry TEXT)")
+	logEntry := func(entry string) sqlt.MigrationFunc {
+		return func(ctx context.Context, db sqlt.DB) error {
+			_, err := db.ExecContext(ctx, "INSERT INTO log VALUES (?); UPDATE version SET version = version + 1", entry)
+			return err
+		}
+	}
+	versions := sqlt.MigrationMap{
+		0: sqlt.Requires(sqlt.MigrationRequirement{MinSQLiteVersion: "3.0.0", Drivers: []string{"sqlite3"}}, logEntry("sqlite3")),
+		1: sqlt.Requires(sqlt.MigrationRequirement{Drivers: []string{"libsql"}, Skip: true}, logEntry("libsql")),
+		2: sqlt.Requires(sqlt.MigrationRequirement{Check: func(caps *sqlt.Capabilities) bool { return caps.JSON }}, logEntry("json")),
+		3: sqlt.Requires(sqlt.MigrationRequirement{MinSQLiteVersion: "99.0"}, logEntry("future")),
+	}
+	err := sqlt.Migrate(ctx, db, versions)
+	var reqErr *sqlt.RequirementError
+	require.ErrorAs(t, err, &reqErr)
+	assert.Contains(t, reqErr.Reason, "is older than 99.0")
+
+	var entries []string
+	require.NoError(t, db.Select(&entries, "SELECT entry FROM log"))
+	assert.Equal(t, []string{"sqlite3", "json"}, entries)
+	var version int
+	require.NoError(t, db.Get(&version, "SELECT version FROM version"))
+	assert.Equal(t, 3, version)
+}
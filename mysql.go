@@ -0,0 +1,332 @@
+package sqlt
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// MySQL is the dialect of MySQL and MariaDB databases, see Dialect. It reads the tables and indexes of
+// the current database from information_schema, so Verify can check a MySQL database against a schema file.
+//
+// MySQL does not record whether a unique index was declared as a UNIQUE constraint: unique indexes named
+// after their first column, as MySQL names unnamed UNIQUE constraints, are reported as constraints and the
+// others as indexes. The indexes MySQL creates for foreign keys are not reported. Expression indexes are
+// not supported. AutoMigrate returns ErrUnsupportedDriver for MySQL databases: MySQL commits each DDL
+// statement, so a failed migration would leave the statements before the failing one applied.
+var MySQL Dialect = mysqlDialect{}
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) Name() string { return "mysql" }
+
+// mysqlColumnsQuery lists the columns of the base tables of the current database.
+const mysqlColumnsQuery = `SELECT c.TABLE_NAME AS table_name, c.COLUMN_NAME AS column_name, c.COLUMN_TYPE AS column_type,
+	c.IS_NULLABLE = 'NO' AS not_null, c.COLUMN_DEFAULT AS column_default, c.EXTRA AS extra
+FROM information_schema.COLUMNS c
+JOIN information_schema.TABLES t ON t.TABLE_SCHEMA = c.TABLE_SCHEMA AND t.TABLE_NAME = c.TABLE_NAME
+WHERE c.TABLE_SCHEMA = DATABASE() AND t.TABLE_TYPE = 'BASE TABLE'
+ORDER BY c.TABLE_NAME, c.ORDINAL_POSITION`
+
+// mysqlIndexesQuery lists the key columns of the indexes of the current database.
+const mysqlIndexesQuery = `SELECT TABLE_NAME AS table_name, INDEX_NAME AS index_name, NON_UNIQUE = 0 AS is_unique,
+	COALESCE(COLUMN_NAME, '') AS column_name, COALESCE(COLLATION, 'A') = 'D' AS is_desc
+FROM information_schema.STATISTICS
+WHERE TABLE_SCHEMA = DATABASE()
+ORDER BY TABLE_NAME, INDEX_NAME, SEQ_IN_INDEX`
+
+// mysqlForeignKeysQuery lists the columns of the foreign keys of the current database.
+const mysqlForeignKeysQuery = `SELECT k.TABLE_NAME AS table_name, k.CONSTRAINT_NAME AS constraint_name, k.COLUMN_NAME AS column_name,
+	k.REFERENCED_TABLE_NAME AS referenced_table, k.REFERENCED_COLUMN_NAME AS referenced_column,
+	r.UPDATE_RULE AS update_rule, r.DELETE_RULE AS delete_rule
+FROM information_schema.KEY_COLUMN_USAGE k
+JOIN information_schema.REFERENTIAL_CONSTRAINTS r ON r.CONSTRAINT_SCHEMA = k.CONSTRAINT_SCHEMA AND r.CONSTRAINT_NAME = k.CONSTRAINT_NAME
+WHERE k.TABLE_SCHEMA = DATABASE() AND k.REFERENCED_TABLE_NAME IS NOT NULL
+ORDER BY k.TABLE_NAME, k.CONSTRAINT_NAME, k.ORDINAL_POSITION`
+
+type mysqlColumn struct {
+	TableName     string  `db:"table_name"`
+	ColumnName    string  `db:"column_name"`
+	ColumnType    string  `db:"column_type"`
+	NotNull       bool    `db:"not_null"`
+	ColumnDefault *string `db:"column_default"`
+	Extra         string  `db:"extra"`
+}
+
+type mysqlIndexColumn struct {
+	TableName  string `db:"table_name"`
+	IndexName  string `db:"index_name"`
+	IsUnique   bool   `db:"is_unique"`
+	ColumnName string `db:"column_name"`
+	IsDesc     bool   `db:"is_desc"`
+}
+
+type mysqlForeignKeyColumn struct {
+	TableName        string `db:"table_name"`
+	ConstraintName   string `db:"constraint_name"`
+	ColumnName       string `db:"column_name"`
+	ReferencedTable  string `db:"referenced_table"`
+	ReferencedColumn string `db:"referenced_column"`
+	UpdateRule       string `db:"update_rule"`
+	DeleteRule       string `db:"delete_rule"`
+}
+
+func (d mysqlDialect) FetchSchema(ctx context.Context, db Reader) (*SchemaDefinition, error) {
+	var columns []mysqlColumn
+	if err := db.SelectContext(ctx, &columns, mysqlColumnsQuery); err != nil {
+		return nil, fmt.Errorf("could not read columns: %w", err)
+	}
+	var indexColumns []mysqlIndexColumn
+	if err := db.SelectContext(ctx, &indexColumns, mysqlIndexesQuery); err != nil {
+		return nil, fmt.Errorf("could not read indexes: %w", err)
+	}
+	var fkColumns []mysqlForeignKeyColumn
+	if err := db.SelectContext(ctx, &fkColumns, mysqlForeignKeysQuery); err != nil {
+		return nil, fmt.Errorf("could not read foreign keys: %w", err)
+	}
+	s := &SchemaDefinition{
+		Tables:   make(map[string]*TableDefinition),
+		Indexes:  make(map[string]*IndexDefinition),
+		Views:    make(map[string]*ViewDefinition),
+		Triggers: make(map[string]*TriggerDefinition),
+	}
+	for _, c := range columns {
+		table := s.Table(c.TableName)
+		if table == nil {
+			table = &TableDefinition{Name: c.TableName}
+			s.Tables[s.key(c.TableName)] = table
+		}
+		table.Columns = append(table.Columns, &ColumnDefinition{
+			Name:         c.ColumnName,
+			Type:         c.ColumnType,
+			NotNull:      c.NotNull,
+			DefaultValue: mysqlDefault(c.ColumnDefault, c.Extra),
+		})
+	}
+
+	// fkNames maps each table to the names MySQL may give the indexes it creates for its foreign keys.
+	fkNames := make(map[string][]string)
+	for i, c := range fkColumns {
+		table := s.Table(c.TableName)
+		if table == nil {
+			continue
+		}
+		if i == 0 || fkColumns[i-1].TableName != c.TableName || fkColumns[i-1].ConstraintName != c.ConstraintName {
+			table.ForeignKeys = append(table.ForeignKeys, &ForeignKeyDefinition{
+				ForeignTable: c.ReferencedTable,
+				OnUpdate:     mysqlRule(c.UpdateRule),
+				OnDelete:     mysqlRule(c.DeleteRule),
+			})
+			fkNames[s.key(table.Name)] = append(fkNames[s.key(table.Name)], c.ConstraintName, c.ColumnName)
+		}
+		fk := table.ForeignKeys[len(table.ForeignKeys)-1]
+		fk.Columns = append(fk.Columns, c.ColumnName)
+		fk.ForeignColumns = append(fk.ForeignColumns, c.ReferencedColumn)
+	}
+
+	var indexes []*IndexDefinition
+	for i, c := range indexColumns {
+		if i == 0 || indexColumns[i-1].TableName != c.TableName || indexColumns[i-1].IndexName != c.IndexName {
+			indexes = append(indexes, &IndexDefinition{Name: c.IndexName, Table: c.TableName, Unique: c.IsUnique, Origin: "c"})
+		}
+		index := indexes[len(indexes)-1]
+		index.Columns = append(index.Columns, c.ColumnName)
+		index.Keys = append(index.Keys, IndexKey{Column: c.ColumnName, Desc: c.IsDesc})
+	}
+	for _, index := range indexes {
+		table := s.Table(index.Table)
+		if table == nil {
+			continue
+		}
+		index.SQL = d.CreateIndex(index)
+		switch {
+		case index.Name == "PRIMARY":
+			index.Origin = "pk"
+			table.PrimaryKey = index.Columns
+			for _, name := range index.Columns {
+				if col := table.Column(name); col != nil {
+					col.PrimaryKey = true
+				}
+			}
+		case index.Unique && mysqlConstraintIndexName(index.Name, index.Columns[0]):
+			index.Origin = "u"
+			table.UniqueConstraints = append(table.UniqueConstraints, index.Columns)
+		case !index.Unique && slices.Contains(fkNames[s.key(table.Name)], index.Name):
+			continue
+		default:
+			s.Indexes[s.key(index.Name)] = index
+			continue
+		}
+		table.ImplicitIndexes = append(table.ImplicitIndexes, index)
+	}
+	for _, table := range s.Tables {
+		table.SQL = d.CreateTable(table)
+	}
+	s.intern()
+	return s, nil
+}
+
+// mysqlConstraintIndexName reports whether name is one MySQL gives the index of an unnamed UNIQUE constraint
+// whose first column is column: the column name, with a numeric suffix if that name is taken.
+func mysqlConstraintIndexName(name, column string) bool {
+	if strings.EqualFold(name, column) {
+		return true
+	}
+	prefix, suffix, ok := cutLast(name, "_")
+	if !ok || !strings.EqualFold(prefix, column) {
+		return false
+	}
+	_, err := strconv.Atoi(suffix)
+	return err == nil
+}
+
+// cutLast slices s around the last instance of sep.
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
+
+// mysqlRule returns a referential action of information_schema as the ON UPDATE and ON DELETE
+// clauses of a schema would, leaving out the defaults.
+func mysqlRule(rule string) string {
+	switch rule {
+	case "NO ACTION", "RESTRICT":
+		return ""
+	default:
+		return rule
+	}
+}
+
+// mysqlDefault returns the default of a column as SQL. MySQL reports literal defaults unquoted and
+// flags expression defaults as DEFAULT_GENERATED, while MariaDB quotes literals and reports no default
+// as NULL.
+func mysqlDefault(def *string, extra string) *string {
+	if def == nil || *def == "NULL" {
+		return nil
+	}
+	value := *def
+	literal := !strings.Contains(extra, "DEFAULT_GENERATED") && !strings.HasPrefix(value, "'") &&
+		!mysqlNumber.MatchString(value) && !strings.HasPrefix(strings.ToUpper(value), "CURRENT_TIMESTAMP")
+	if literal {
+		value = "'" + strings.ReplaceAll(value, "'", "''") + "'"
+	}
+	return &value
+}
+
+var mysqlNumber = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+
+// mysqlTypeAliases maps the names of MySQL types to the names information_schema reports.
+var mysqlTypeAliases = map[string]string{
+	"integer":           "int",
+	"bool":              "tinyint",
+	"boolean":           "tinyint",
+	"dec":               "decimal",
+	"numeric":           "decimal",
+	"fixed":             "decimal",
+	"real":              "double",
+	"double precision":  "double",
+	"character":         "char",
+	"character varying": "varchar",
+}
+
+// mysqlTypePattern splits a type into its name, modifiers and attributes such as unsigned.
+var mysqlTypePattern = regexp.MustCompile(`^([a-z ]+?)\s*(\(.*\))?\s*((?:unsigned|zerofill| )*)$`)
+
+// mysqlIntegerTypes lists the integer types, whose display width information_schema no longer reports.
+var mysqlIntegerTypes = []string{"tinyint", "smallint", "mediumint", "int", "bigint"}
+
+func (mysqlDialect) NormalizeType(decl string) string {
+	decl = strings.ToLower(strings.Join(strings.Fields(decl), " "))
+	m := mysqlTypePattern.FindStringSubmatch(decl)
+	if m == nil {
+		return decl
+	}
+	name, modifiers, attrs := m[1], strings.ReplaceAll(m[2], " ", ""), strings.TrimSpace(m[3])
+	if alias, ok := mysqlTypeAliases[name]; ok {
+		name = alias
+	}
+	switch {
+	case slices.Contains(mysqlIntegerTypes, name):
+		modifiers = ""
+	case name == "char" && modifiers == "":
+		modifiers = "(1)"
+	case name == "decimal" && modifiers == "":
+		modifiers = "(10,0)"
+	case name == "decimal" && !strings.Contains(modifiers, ","):
+		modifiers = strings.TrimSuffix(modifiers, ")") + ",0)"
+	}
+	if attrs != "" {
+		return name + modifiers + " " + attrs
+	}
+	return name + modifiers
+}
+
+func (mysqlDialect) NormalizeExpr(expr string) string {
+	parts := strings.Split(expr, "'")
+	for i := 0; i < len(parts); i += 2 {
+		part := strings.ToLower(whitespace.ReplaceAllString(parts[i], " "))
+		part = strings.NewReplacer("`", "", `"`, "").Replace(part)
+		part = strings.NewReplacer("current_timestamp()", "current_timestamp", "now()", "current_timestamp").Replace(part)
+		parts[i] = part
+	}
+	return trimOuterParens(strings.TrimSpace(strings.Join(parts, "'")))
+}
+
+// mysqlIdent quotes name with backticks.
+func mysqlIdent(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+// mysqlExpr quotes the identifiers of expr, rendered by the SQLite parser in double quotes, with backticks.
+func mysqlExpr(expr string) string {
+	parts := strings.Split(expr, "'")
+	for i := 0; i < len(parts); i += 2 {
+		parts[i] = strings.ReplaceAll(parts[i], `"`, "`")
+	}
+	return strings.Join(parts, "'")
+}
+
+func (mysqlDialect) CreateTable(table *TableDefinition) string {
+	return createTableSQL(table, mysqlIdent, mysqlExpr)
+}
+
+func (mysqlDialect) DropTable(table *TableDefinition) string {
+	return "DROP TABLE " + mysqlIdent(table.Name)
+}
+
+func (mysqlDialect) AddColumn(table string, col *ColumnDefinition) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", mysqlIdent(table), columnSQL(col, mysqlIdent))
+}
+
+func (mysqlDialect) DropColumn(table, column string) string {
+	return fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", mysqlIdent(table), mysqlIdent(column))
+}
+
+func (mysqlDialect) CreateIndex(index *IndexDefinition) string {
+	keys := make([]string, len(index.Columns))
+	for i, col := range index.Columns {
+		keys[i] = mysqlIdent(col)
+		if index.key(i).Desc {
+			keys[i] += " DESC"
+		}
+	}
+	sql := "CREATE INDEX "
+	if index.Unique {
+		sql = "CREATE UNIQUE INDEX "
+	}
+	sql += fmt.Sprintf("%s ON %s (%s)", mysqlIdent(index.Name), mysqlIdent(index.Table), strings.Join(keys, ", "))
+	if index.Where != "" {
+		// MySQL has no partial indexes; the condition is kept so the statement fails instead of indexing every row.
+		sql += " WHERE " + mysqlExpr(index.Where)
+	}
+	return sql
+}
+
+func (mysqlDialect) DropIndex(index *IndexDefinition) string {
+	return fmt.Sprintf("DROP INDEX %s ON %s", mysqlIdent(index.Name), mysqlIdent(index.Table))
+}
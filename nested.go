@@ -0,0 +1,128 @@
+package sqlt
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/jmoiron/sqlx/reflectx"
+)
+
+// SelectNested runs a JOIN query and assembles its rows into dest, a pointer to a slice of parent structs,
+// each with a slice field named childField by the mapper, e.g. "orders" for Orders []Order `db:"orders"`.
+// Columns named childField.column, see AliasedColumns, are scanned into the elements of that slice,
+// the other columns into the parent. Rows with the same parent columns are assembled into one parent,
+// in the order the query returns them, so a single query replaces a query per parent.
+// Rows whose child columns are all NULL, as returned by a LEFT JOIN for parents without children,
+// add no child.
+func SelectNested(ctx context.Context, db Reader, dest any, childField string, query string, args ...any) error {
+	slice := reflect.ValueOf(dest)
+	if slice.Kind() != reflect.Pointer || slice.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("SelectNested: dest must be a pointer to a slice, got %T", dest)
+	}
+	slice = slice.Elem()
+	parentType := reflectx.Deref(slice.Type().Elem())
+	if parentType.Kind() != reflect.Struct {
+		return fmt.Errorf("SelectNested: dest elements must be structs, got %s", slice.Type().Elem())
+	}
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	children := rows.Mapper.TypeMap(parentType).GetByPath(childField)
+	if children == nil || children.Field.Type.Kind() != reflect.Slice {
+		return fmt.Errorf("SelectNested: %s has no slice field %s", parentType, childField)
+	}
+	childType := reflectx.Deref(children.Field.Type.Elem())
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	var parentColumns, childColumns []string
+	var parentIndexes, childIndexes []int
+	for i, column := range columns {
+		if name, ok := strings.CutPrefix(column, childField+"."); ok {
+			childColumns, childIndexes = append(childColumns, name), append(childIndexes, i)
+		} else {
+			parentColumns, parentIndexes = append(parentColumns, column), append(parentIndexes, i)
+		}
+	}
+	parentFields := rows.Mapper.TraversalsByName(parentType, parentColumns)
+	childFields := rows.Mapper.TraversalsByName(childType, childColumns)
+	for i, traversal := range parentFields {
+		if len(traversal) == 0 {
+			return fmt.Errorf("SelectNested: missing destination name %s in %s", parentColumns[i], parentType)
+		}
+	}
+	for i, traversal := range childFields {
+		if len(traversal) == 0 {
+			return fmt.Errorf("SelectNested: missing destination name %s in %s", childColumns[i], childType)
+		}
+	}
+
+	// parents holds the index in slice of the parent of each key.
+	parents := make(map[string]int)
+	values := make([]any, len(columns))
+	for rows.Next() {
+		parent := reflect.New(parentType).Elem()
+		for i, traversal := range parentFields {
+			values[parentIndexes[i]] = reflectx.FieldByIndexes(parent, traversal).Addr().Interface()
+		}
+		// Child columns are scanned through pointers, which are left nil by NULL values.
+		childValues := make([]reflect.Value, len(childFields))
+		for i, traversal := range childFields {
+			fieldType := reflectx.FieldByIndexes(reflect.New(childType).Elem(), traversal).Type()
+			childValues[i] = reflect.New(reflect.PointerTo(fieldType))
+			values[childIndexes[i]] = childValues[i].Interface()
+		}
+		if err := rows.Scan(values...); err != nil {
+			return err
+		}
+
+		key := parentKey(parent, parentFields)
+		index, ok := parents[key]
+		if !ok {
+			index = slice.Len()
+			parents[key] = index
+			if slice.Type().Elem().Kind() == reflect.Pointer {
+				slice.Set(reflect.Append(slice, parent.Addr()))
+			} else {
+				slice.Set(reflect.Append(slice, parent))
+			}
+		}
+		child := reflect.New(childType).Elem()
+		found := false
+		for i, traversal := range childFields {
+			if ptr := childValues[i].Elem(); !ptr.IsNil() {
+				reflectx.FieldByIndexes(child, traversal).Set(ptr.Elem())
+				found = true
+			}
+		}
+		if !found {
+			continue
+		}
+		field := reflectx.FieldByIndexes(reflect.Indirect(slice.Index(index)), children.Index)
+		if children.Field.Type.Elem().Kind() == reflect.Pointer {
+			field.Set(reflect.Append(field, child.Addr()))
+		} else {
+			field.Set(reflect.Append(field, child))
+		}
+	}
+	return rows.Err()
+}
+
+// parentKey returns a string identifying the values of the fields of parent scanned from the parent columns.
+func parentKey(parent reflect.Value, fields [][]int) string {
+	var key strings.Builder
+	for _, traversal := range fields {
+		v := reflect.Indirect(reflectx.FieldByIndexes(parent, traversal))
+		if v.IsValid() {
+			fmt.Fprintf(&key, "%#v", v.Interface())
+		}
+		key.WriteByte(0)
+	}
+	return key.String()
+}
@@ -0,0 +1,44 @@
+package sqlt_test
+
+import (
+	"testing"
+
+	"github.com/james-darko/gort"
+	"github.com/james-darko/sqlt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelectNested(t *testing.T) {
+	t.Parallel()
+	db, err := sqlt.OpenMemory(t.Name())
+	require.NoError(t, err)
+	defer db.Close()
+	ctx := gort.Context()
+
+	_, err = db.Exec(`CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT);
+CREATE TABLE orders (id INTEGER PRIMARY KEY, user_id INTEGER, total INTEGER);
+INSERT INTO users (id, name) VALUES (1, 'alice'), (2, 'bob'), (3, 'carol');
+INSERT INTO orders (id, user_id, total) VALUES (10, 1, 5), (11, 1, 7), (12, 3, 9);`)
+	require.NoError(t, err)
+
+	type user struct {
+		ID     int          `db:"id"`
+		Name   string       `db:"name"`
+		Orders []*joinOrder `db:"orders"`
+	}
+	var users []user
+	query := "SELECT u.id, u.name, " + sqlt.AliasedColumns("o", "orders", joinOrder{}) +
+		" FROM users u LEFT JOIN orders o ON o.user_id = u.id ORDER BY u.id, o.id"
+	require.NoError(t, sqlt.SelectNested(ctx, db, &users, "orders", query))
+	require.Len(t, users, 3)
+	assert.Equal(t, "alice", users[0].Name)
+	require.Len(t, users[0].Orders, 2)
+	assert.Equal(t, joinOrder{ID: 11, Total: 7}, *users[0].Orders[1])
+	assert.Empty(t, users[1].Orders)
+	require.Len(t, users[2].Orders, 1)
+	assert.Equal(t, 12, users[2].Orders[0].ID)
+
+	err = sqlt.SelectNested(ctx, db, &users, "items", query)
+	assert.ErrorContains(t, err, "no slice field items")
+}
@@ -0,0 +1,400 @@
+package sqlt
+
+import (
+	"context"
+	"crypto/ed25519"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	rsql "github.com/rqlite/sql"
+)
+
+// DefaultCheckpointThreshold is the number of WAL frames left behind by a migration
+// above which a truncating checkpoint is run. It matches SQLite's default wal_autocheckpoint.
+const DefaultCheckpointThreshold = 1000
+
+// MigrateOption configures AutoMigrate and Migrate.
+type MigrateOption func(*migrateConfig)
+
+type migrateConfig struct {
+	busyTimeout         time.Duration
+	statementTimeout    time.Duration
+	logger              func(format string, args ...any)
+	checkpointThreshold int
+	lockName            string
+	lockTTL             time.Duration
+	history             bool
+	sqlLog              io.Writer
+	baseline            io.Reader
+	appVersion          string
+	strictness          Strictness
+	batchStatements     bool
+	rawExec             bool
+	strictParsing       bool
+	versionTable        string
+	targetSchema        string
+	deferIndexes        bool
+	deferredIndexNames  []string
+	indexProgress       func(IndexBuild)
+	analyze             bool
+	optimize            bool
+	backfill            map[string]string
+	rebuildSelects      map[string]string
+	objects             map[string]string
+	onConflict          func(SchemaConflictError) Resolution
+	fetchers            map[string]SchemaFetcher
+	schemaKey           ed25519.PublicKey
+	schemaStmts         []rsql.Statement
+	dbStmts             []rsql.Statement
+	parallelism         int
+	schemaVersion       *int
+	skippedTables       []string
+	verifyAfter         bool
+	addColumns          bool
+	renames             map[string]map[string]string
+	tableDeletes        bool
+	columnDrops         bool
+	dryRun              *MigrationPlan
+	dialect             Dialect
+}
+
+func newMigrateConfig(opts []MigrateOption) *migrateConfig {
+	cfg := &migrateConfig{
+		checkpointThreshold: DefaultCheckpointThreshold,
+		versionTable:        quoteIdent("version"),
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// WithBusyTimeout sets PRAGMA busy_timeout on the migration connection, so a migration
+// waits up to d for a concurrent writer instead of failing with "database is locked".
+// With Migrate, it is set at the start of every transaction a migration function starts.
+func WithBusyTimeout(d time.Duration) MigrateOption {
+	return func(cfg *migrateConfig) {
+		cfg.busyTimeout = d
+	}
+}
+
+// WithStatementTimeout bounds each statement AutoMigrate executes to d, interrupting it once
+// its context deadline passes, so a rebuild of a large table fails instead of holding the
+// write lock indefinitely. With WithStatementBatching, the batch is bounded as a whole.
+func WithStatementTimeout(d time.Duration) MigrateOption {
+	return func(cfg *migrateConfig) {
+		cfg.statementTimeout = d
+	}
+}
+
+// WithLogger sends the progress messages of Migrate, including the migrations Requires skips, and the
+// failures a migration only reports, such as a failed ANALYZE after the changes were committed, to logf
+// instead of standard output.
+// log.Printf and testing.T.Logf can be passed as logf.
+func WithLogger(logf func(format string, args ...any)) MigrateOption {
+	return func(cfg *migrateConfig) {
+		cfg.logger = logf
+	}
+}
+
+// WithCheckpointThreshold sets the number of WAL frames a migration may leave behind
+// before PRAGMA wal_checkpoint(TRUNCATE) is run once it completes.
+// A negative value disables the checkpoint. Defaults to DefaultCheckpointThreshold.
+func WithCheckpointThreshold(frames int) MigrateOption {
+	return func(cfg *migrateConfig) {
+		cfg.checkpointThreshold = frames
+	}
+}
+
+// Strictness controls how closely a database object must match its schema definition.
+type Strictness int
+
+const (
+	// StrictnessStructural ignores formatting, the order of constraints and the INT/INTEGER spelling,
+	// but requires the same declared column types. It is the default.
+	StrictnessStructural Strictness = iota
+	// StrictnessExact requires the SQL of both definitions to be identical once normalized by the parser.
+	StrictnessExact
+	// StrictnessAffinity is like StrictnessStructural, but compares column types by their SQLite affinity.
+	StrictnessAffinity
+)
+
+// String returns the name of the strictness level.
+func (s Strictness) String() string {
+	switch s {
+	case StrictnessStructural:
+		return "structural"
+	case StrictnessExact:
+		return "exact"
+	case StrictnessAffinity:
+		return "affinity"
+	default:
+		return fmt.Sprintf("Strictness(%d)", int(s))
+	}
+}
+
+// WithStrictness sets how closely database objects must match the schema. Defaults to StrictnessStructural.
+func WithStrictness(s Strictness) MigrateOption {
+	return func(cfg *migrateConfig) {
+		cfg.strictness = s
+	}
+}
+
+// WithTypeAffinity compares column types by their SQLite affinity instead of their name,
+// so VARCHAR(255), TEXT and CLOB are considered the same type. This avoids rebuilding
+// tables whose schema was written for another database engine.
+// It is the same as WithStrictness(StrictnessAffinity).
+func WithTypeAffinity() MigrateOption {
+	return WithStrictness(StrictnessAffinity)
+}
+
+// WithStatementBatching makes Exec and AutoMigrate send all their statements to the database
+// in a single multi-statement Exec instead of one call per statement, which saves a network
+// round trip per statement with the libsql driver.
+// Batched statements are still executed in one transaction.
+func WithStatementBatching() MigrateOption {
+	return func(cfg *migrateConfig) {
+		cfg.batchStatements = true
+	}
+}
+
+// WithAllowTableDeletes makes AutoMigrateWith drop the tables missing from the schema.
+// Columns missing from their table are still reported as a *SchemaConflictError, see WithAllowColumnDrops.
+func WithAllowTableDeletes() MigrateOption {
+	return func(cfg *migrateConfig) {
+		cfg.tableDeletes = true
+	}
+}
+
+// WithAllowColumnDrops makes AutoMigrateWith drop the columns missing from their table in the schema
+// when the database supports ALTER TABLE DROP COLUMN (SQLite 3.35), which deletes their data.
+// Tables missing from the schema are only dropped with WithAllowTableDeletes.
+func WithAllowColumnDrops() MigrateOption {
+	return func(cfg *migrateConfig) {
+		cfg.columnDrops = true
+	}
+}
+
+// WithDryRun makes AutoMigrateWith store the changes it would make in plan instead of making them,
+// see AutoMigratePlan.
+func WithDryRun(plan *MigrationPlan) MigrateOption {
+	return func(cfg *migrateConfig) {
+		cfg.dryRun = plan
+	}
+}
+
+// WithColumnAdditions sets whether AutoMigrate adds the nullable or defaulted columns a schema appends
+// to a table with ALTER TABLE ADD COLUMN. It defaults to disabled, which reports such tables as a
+// *SchemaConflictError unless WithBackfill or WithRebuildSelect applies to them.
+func WithColumnAdditions(enabled bool) MigrateOption {
+	return func(cfg *migrateConfig) {
+		cfg.addColumns = enabled
+	}
+}
+
+// WithRawExec makes Exec split its script into statements at semicolons without parsing it.
+// Semicolons in string literals, quoted identifiers, comments and CREATE TRIGGER bodies are skipped.
+// Exec already falls back to this when the parser cannot handle the script; the option skips the parser,
+// which also executes every statement exactly as written instead of in its normalized form.
+func WithRawExec() MigrateOption {
+	return func(cfg *migrateConfig) {
+		cfg.rawExec = true
+	}
+}
+
+// WithStrictParsing makes Exec fail with a *ParseError when any part of its script cannot be parsed,
+// instead of falling back to splitting it without parsing.
+func WithStrictParsing() MigrateOption {
+	return func(cfg *migrateConfig) {
+		cfg.strictParsing = true
+	}
+}
+
+// WithVersionTable sets the table Migrate reads and increments the version number in.
+// schema names the attached database holding it, or is empty for the main database.
+// Defaults to the table version of the main database.
+func WithVersionTable(schema, table string) MigrateOption {
+	return func(cfg *migrateConfig) {
+		cfg.versionTable = qualifiedIdent(schema, table)
+	}
+}
+
+// WithTargetSchema makes AutoMigrate reconcile the objects of the attached database schema
+// instead of the main database: it reads that database's sqlite_master and qualifies the
+// objects it creates, alters and drops with schema. Unqualified table names in the schema's
+// triggers and views resolve as usual in SQLite, so they must not exist in the main database.
+func WithTargetSchema(schema string) MigrateOption {
+	return func(cfg *migrateConfig) {
+		cfg.targetSchema = schema
+	}
+}
+
+// WithSQLLog writes every statement a migration applied to w, each terminated with a semicolon,
+// producing a replayable script of the changes. Statements are written once the transaction
+// or migration version that executed them succeeds. Bind arguments are not included.
+func WithSQLLog(w io.Writer) MigrateOption {
+	return func(cfg *migrateConfig) {
+		cfg.sqlLog = w
+	}
+}
+
+// writeSQLLog writes the applied statements to the configured SQL log, if any.
+func (cfg *migrateConfig) writeSQLLog(statements []string) error {
+	if cfg.sqlLog == nil {
+		return nil
+	}
+	for _, stmt := range statements {
+		stmt = strings.TrimRight(strings.TrimSpace(stmt), ";")
+		if _, err := io.WriteString(cfg.sqlLog, stmt+";\n"); err != nil {
+			return fmt.Errorf("could not write SQL log: %w", err)
+		}
+	}
+	return nil
+}
+
+// logf reports a message of a migration, see WithLogger.
+func (cfg *migrateConfig) logf(format string, args ...any) {
+	if cfg.logger != nil {
+		cfg.logger(format, args...)
+		return
+	}
+	fmt.Printf(format+"\n", args...)
+}
+
+// statementContext returns the context of a statement AutoMigrate executes, see WithStatementTimeout.
+func (cfg *migrateConfig) statementContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if cfg.statementTimeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, cfg.statementTimeout)
+}
+
+// timeoutExecer executes statements on tx, each with the context of cfg.statementContext.
+type timeoutExecer struct {
+	tx  Tx
+	ctx context.Context
+	cfg *migrateConfig
+}
+
+func (e timeoutExecer) Exec(query string, args ...any) (Result, error) {
+	ctx, cancel := e.cfg.statementContext(e.ctx)
+	defer cancel()
+	return e.tx.ExecContext(ctx, query, args...)
+}
+
+// timeoutExecer returns tx bounding each statement with WithStatementTimeout, if set.
+func (cfg *migrateConfig) timeoutExecer(ctx context.Context, tx Tx) execer {
+	if cfg.statementTimeout <= 0 {
+		return tx
+	}
+	return timeoutExecer{tx: tx, ctx: ctx, cfg: cfg}
+}
+
+// applyBusyTimeout sets the busy timeout on the connection used by db, which must be a
+// transaction or a pinned connection for the setting to reach the statements of the migration.
+func (cfg *migrateConfig) applyBusyTimeout(db Handle) error {
+	if cfg.busyTimeout <= 0 {
+		return nil
+	}
+	if _, err := db.Exec(fmt.Sprintf("PRAGMA busy_timeout = %d", cfg.busyTimeout.Milliseconds())); err != nil {
+		return fmt.Errorf("could not set busy_timeout: %w", err)
+	}
+	return nil
+}
+
+// WithVerifyAfter makes AutoMigrate compare the database with the schema inside the migration
+// transaction, rolling the migration back with an ErrPostMigrationDrift if they still differ,
+// instead of comparing them once the changes are committed.
+func WithVerifyAfter() MigrateOption {
+	return func(cfg *migrateConfig) {
+		cfg.verifyAfter = true
+	}
+}
+
+// WithAnalyze makes AutoMigrate run ANALYZE on the tables it created or rebuilt, and on the tables
+// it added indexes to, once it committed, so the query planner has fresh statistics right away.
+func WithAnalyze() MigrateOption {
+	return func(cfg *migrateConfig) {
+		cfg.analyze = true
+	}
+}
+
+// WithOptimize makes AutoMigrate run PRAGMA optimize once it committed changes, which lets SQLite
+// decide which tables need new statistics. It is cheaper than WithAnalyze on large databases.
+func WithOptimize() MigrateOption {
+	return func(cfg *migrateConfig) {
+		cfg.optimize = true
+	}
+}
+
+// analyzedTable matches the statements that create, rebuild or index a table, capturing the table name.
+var analyzedTable = regexp.MustCompile(`(?is)^\s*(?:CREATE\s+TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?|CREATE\s+(?:UNIQUE\s+)?INDEX\s+.*?\s+ON\s+|INSERT\s+INTO\s+)("(?:[^"]|"")+"|[^\s(]+)`)
+
+// analyzeTables refreshes the query planner statistics after a migration executed the given statements,
+// see WithAnalyze and WithOptimize. The migration has already been committed when this runs,
+// so failures are reported but not returned.
+func (cfg *migrateConfig) analyzeTables(ctx context.Context, db DB, executed []string) {
+	if len(executed) == 0 {
+		return
+	}
+	if cfg.analyze {
+		rows, err := schemaMasterRows(ctx, db, cfg.targetSchema)
+		if err != nil {
+			cfg.logf("could not list tables to analyze after migration: %v", err)
+			return
+		}
+		tables := make(map[string]string)
+		for _, row := range rows {
+			if row.Type == "table" && !isVirtualTableSQL(row.Sql) {
+				tables[foldIdent(row.Name)] = row.Name
+			}
+		}
+		analyzed := make(map[string]bool)
+		for _, stmt := range executed {
+			m := analyzedTable.FindStringSubmatch(stmt)
+			if m == nil {
+				continue
+			}
+			name, ok := tables[foldIdent(unquoteIdent(m[1]))]
+			if !ok || analyzed[name] {
+				continue
+			}
+			analyzed[name] = true
+			if _, err := db.ExecContext(ctx, "ANALYZE "+qualifiedIdent(cfg.targetSchema, name)); err != nil {
+				cfg.logf("could not analyze %s after migration: %v", name, err)
+			}
+		}
+	}
+	if cfg.optimize {
+		if _, err := db.ExecContext(ctx, "PRAGMA optimize"); err != nil {
+			cfg.logf("could not optimize after migration: %v", err)
+		}
+	}
+}
+
+// checkpoint truncates the WAL if the migration left more than the configured number of frames in it.
+// Databases not in WAL mode are left untouched. The migration has already been committed when this
+// runs, so failures are reported but not returned.
+func (cfg *migrateConfig) checkpoint(ctx context.Context, db DB) {
+	if cfg.checkpointThreshold < 0 {
+		return
+	}
+	var res struct {
+		Busy         int `db:"busy"`
+		Log          int `db:"log"`
+		Checkpointed int `db:"checkpointed"`
+	}
+	if err := db.GetContext(ctx, &res, "PRAGMA wal_checkpoint(PASSIVE)"); err != nil {
+		cfg.logf("could not inspect WAL after migration: %v", err)
+		return
+	}
+	if res.Log < 0 || res.Log < cfg.checkpointThreshold {
+		return
+	}
+	if _, err := db.ExecContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+		cfg.logf("could not checkpoint WAL after migration: %v", err)
+	}
+}
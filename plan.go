@@ -0,0 +1,163 @@
+package sqlt
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	rsql "github.com/rqlite/sql"
+)
+
+// PlanAction is the kind of change a PlanStep makes to a database object.
+type PlanAction string
+
+const (
+	PlanCreate  PlanAction = "create"
+	PlanDrop    PlanAction = "drop"
+	PlanAlter   PlanAction = "alter"
+	PlanRebuild PlanAction = "rebuild"
+	// PlanExecute is a statement that is not recognized as changing a single object.
+	PlanExecute PlanAction = "execute"
+)
+
+// PlanStep is a change AutoMigrate would make to one database object, with the statements making it.
+// A table rebuild creates a new table, copies the rows over and replaces the old table in several statements.
+type PlanStep struct {
+	Action     PlanAction `json:"action"`
+	ObjectType string     `json:"object_type"`
+	ObjectName string     `json:"object_name"`
+	Statements []string   `json:"statements"`
+}
+
+// MigrationPlan lists the changes AutoMigrate would make to a database, in execution order.
+type MigrationPlan struct {
+	Steps []PlanStep `json:"steps"`
+}
+
+// Statements returns the statements of all steps in execution order.
+func (p *MigrationPlan) Statements() []string {
+	var stmts []string
+	for _, step := range p.Steps {
+		stmts = append(stmts, step.Statements...)
+	}
+	return stmts
+}
+
+// String returns the plan as a script, each statement terminated with a semicolon.
+func (p *MigrationPlan) String() string {
+	var b strings.Builder
+	for _, stmt := range p.Statements() {
+		b.WriteString(stmt)
+		b.WriteString(";\n")
+	}
+	return b.String()
+}
+
+// AutoMigratePlan returns the changes AutoMigrate would make to db for schema with the same arguments,
+// without changing the database. The options selecting and comparing objects apply as in AutoMigrate,
+// including the WithBaseline drift check and WithDialect; deferred index builds are the last steps
+// of the plan, as AutoMigrate runs them after its other changes.
+// Conflicts, drift and disallowed table deletes are returned as errors, as AutoMigrate would.
+func AutoMigratePlan(ctx context.Context, db DB, schema io.Reader, allowTableDeletes bool, opts ...MigrateOption) (*MigrationPlan, error) {
+	cfg := newMigrateConfig(opts)
+	if d := cfg.dialectOf(db); d != nil {
+		plan := &MigrationPlan{}
+		cfg.tableDeletes = cfg.tableDeletes || allowTableDeletes
+		cfg.dryRun = plan
+		if err := autoMigrateDialect(ctx, db, d, schema, cfg); err != nil {
+			return nil, err
+		}
+		return plan, nil
+	}
+	caps, err := db.Capabilities(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("AutoMigratePlan: %w", err)
+	}
+	dbStmts, schemaStmts, err := cfg.migrationStatements(ctx, db, schema)
+	if err != nil {
+		return nil, fmt.Errorf("AutoMigratePlan: %w", err)
+	}
+	p := &planner{}
+	p.out = qualifyingExecer(&p.batch, cfg.targetSchema)
+	var deferred []deferredIndex
+	if err := reconcileSchema(cfg.deferringExecer(p, &deferred), dbStmts, schemaStmts, allowTableDeletes || cfg.tableDeletes, cfg, caps); err != nil {
+		return nil, err
+	}
+	for _, index := range deferred {
+		if _, err := p.Exec(index.sql); err != nil {
+			return nil, err
+		}
+	}
+	return &p.plan, nil
+}
+
+// planner groups the statements reconcileSchema executes into the steps of a MigrationPlan.
+// The statements of a table rebuild, see rebuildTable and rebuildTableSelect, form one step
+// that ends with the statement removing the temporary table name.
+type planner struct {
+	plan  MigrationPlan
+	batch statementBatch
+	out   execer
+	// rebuildEnd is the folded name of the temporary table ending the open rebuild step, if any.
+	rebuildEnd string
+}
+
+func (p *planner) Exec(query string, args ...any) (Result, error) {
+	if _, err := p.out.Exec(query, args...); err != nil {
+		return nil, err
+	}
+	stmt := p.batch.stmts[len(p.batch.stmts)-1]
+	// Statements the parser rejects are left unclassified.
+	parsed, _ := parseSchemaStatement(rsql.NewParser(strings.NewReader(query)))
+	if p.rebuildEnd != "" {
+		step := &p.plan.Steps[len(p.plan.Steps)-1]
+		step.Statements = append(step.Statements, stmt)
+		switch s := parsed.(type) {
+		case *rsql.DropTableStatement:
+			if foldIdent(rsql.IdentName(s.Name)) == p.rebuildEnd {
+				p.rebuildEnd = ""
+			}
+		case *rsql.AlterTableStatement:
+			if foldIdent(rsql.IdentName(s.Name)) == p.rebuildEnd {
+				p.rebuildEnd = ""
+			}
+		}
+		return &batchResult{}, nil
+	}
+	p.plan.Steps = append(p.plan.Steps, p.step(parsed, stmt))
+	return &batchResult{}, nil
+}
+
+// step returns the step starting with stmt, parsed as parsed. Statements that are not recognized,
+// including those the parser does not support such as CREATE VIRTUAL TABLE, form a PlanExecute step.
+func (p *planner) step(parsed rsql.Statement, stmt string) PlanStep {
+	step := PlanStep{Action: PlanExecute, Statements: []string{stmt}}
+	switch s := parsed.(type) {
+	case *rsql.CreateTableStatement:
+		step.Action, step.ObjectType, step.ObjectName = PlanCreate, "TABLE", rsql.IdentName(s.Name)
+		if table, ok := strings.CutPrefix(step.ObjectName, "_sqlt_new_"); ok {
+			step.Action, step.ObjectName, p.rebuildEnd = PlanRebuild, table, foldIdent(step.ObjectName)
+		}
+	case *rsql.CreateIndexStatement:
+		step.Action, step.ObjectType, step.ObjectName = PlanCreate, "INDEX", rsql.IdentName(s.Name)
+	case *rsql.CreateViewStatement:
+		step.Action, step.ObjectType, step.ObjectName = PlanCreate, "VIEW", rsql.IdentName(s.Name)
+	case *rsql.CreateTriggerStatement:
+		step.Action, step.ObjectType, step.ObjectName = PlanCreate, "TRIGGER", rsql.IdentName(s.Name)
+	case *rsql.DropTableStatement:
+		step.Action, step.ObjectType, step.ObjectName = PlanDrop, "TABLE", rsql.IdentName(s.Name)
+	case *rsql.DropIndexStatement:
+		step.Action, step.ObjectType, step.ObjectName = PlanDrop, "INDEX", rsql.IdentName(s.Name)
+	case *rsql.DropViewStatement:
+		step.Action, step.ObjectType, step.ObjectName = PlanDrop, "VIEW", rsql.IdentName(s.Name)
+	case *rsql.DropTriggerStatement:
+		step.Action, step.ObjectType, step.ObjectName = PlanDrop, "TRIGGER", rsql.IdentName(s.Name)
+	case *rsql.AlterTableStatement:
+		step.Action, step.ObjectType, step.ObjectName = PlanAlter, "TABLE", rsql.IdentName(s.Name)
+		if newName := rsql.IdentName(s.NewName); strings.HasSuffix(newName, rebuildSuffix) {
+			step.Action, p.rebuildEnd = PlanRebuild, foldIdent(newName)
+		}
+	}
+	return step
+}
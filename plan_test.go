@@ -0,0 +1,96 @@
+package sqlt_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/james-darko/gort"
+	"github.com/james-darko/sqlt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAutoMigratePlan(t *testing.T) {
+	t.Parallel()
+	db := getTestDB(t)
+	defer db.Close()
+	ctx := gort.Context()
+
+	require.NoError(t, sqlt.ExecString(ctx, db, `
+CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, email TEXT);
+CREATE TABLE legacy (id INTEGER PRIMARY KEY);`))
+	schema := `
+CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT, name TEXT);
+CREATE INDEX idx_users_name ON users (name);`
+
+	plan, err := sqlt.AutoMigratePlan(ctx, db, strings.NewReader(schema), true)
+	require.NoError(t, err)
+	require.Len(t, plan.Steps, 3)
+	assert.Equal(t, sqlt.PlanRebuild, plan.Steps[0].Action)
+	assert.Equal(t, "users", plan.Steps[0].ObjectName)
+	assert.Len(t, plan.Steps[0].Statements, 4)
+	assert.Equal(t, sqlt.PlanStep{Action: sqlt.PlanCreate, ObjectType: "INDEX", ObjectName: "idx_users_name",
+		Statements: []string{`CREATE INDEX "idx_users_name" ON "users" ("name")`}}, plan.Steps[1])
+	assert.Equal(t, sqlt.PlanDrop, plan.Steps[2].Action)
+	assert.Equal(t, "legacy", plan.Steps[2].ObjectName)
+	assert.True(t, objectExists(t, db, "table", "legacy"), "the plan must not be applied")
+
+	require.NoError(t, sqlt.ExecString(ctx, db, plan.String()))
+	assert.NoError(t, sqlt.VerifyString(ctx, db, schema))
+}
+
+func TestAutoMigratePlan_MatchesAutoMigrate(t *testing.T) {
+	t.Parallel()
+	db := getTestDB(t)
+	defer db.Close()
+	ctx := gort.Context()
+
+	require.NoError(t, sqlt.ExecString(ctx, db, `
+CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, email TEXT);
+CREATE TABLE legacy (id INTEGER PRIMARY KEY);`))
+	schema := `
+CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT, name TEXT);
+CREATE INDEX idx_users_name ON users (name);
+CREATE TABLE posts (id INTEGER PRIMARY KEY, user_id INTEGER);`
+	opts := []sqlt.MigrateOption{sqlt.WithDeferredIndexes()}
+
+	plan, err := sqlt.AutoMigratePlan(ctx, db, strings.NewReader(schema), true, opts...)
+	require.NoError(t, err)
+	assert.Equal(t, "idx_users_name", plan.Steps[len(plan.Steps)-1].ObjectName, "deferred index builds run last")
+
+	var log strings.Builder
+	require.NoError(t, sqlt.AutoMigrate(ctx, db, strings.NewReader(schema), true, append(opts, sqlt.WithSQLLog(&log))...))
+	assert.Equal(t, plan.String(), log.String())
+}
+
+func TestAutoMigratePlan_Baseline(t *testing.T) {
+	t.Parallel()
+	db := getTestDB(t)
+	defer db.Close()
+	ctx := gort.Context()
+
+	require.NoError(t, sqlt.ExecString(ctx, db, driftBaseline))
+	_, err := db.Exec("CREATE INDEX idx_out_of_band ON users (id, name)")
+	require.NoError(t, err)
+
+	_, err = sqlt.AutoMigratePlan(ctx, db, strings.NewReader(driftBaseline), true, sqlt.WithBaseline(strings.NewReader(driftBaseline)))
+	var driftErr *sqlt.DriftError
+	require.True(t, errors.As(err, &driftErr), "expected DriftError, got %v", err)
+	assert.Equal(t, "idx_out_of_band", driftErr.Changes[0].ObjectName)
+}
+
+func TestAutoMigratePlan_Dialect(t *testing.T) {
+	t.Parallel()
+	db := getTestDB(t)
+	defer db.Close()
+	ctx := gort.Context()
+
+	dialect := sqlt.WithDialect(fetchedDialect{Dialect: sqlt.Postgres, schema: postgresUsers()})
+	plan, err := sqlt.AutoMigratePlan(ctx, db, strings.NewReader(postgresUsersSchema+"CREATE INDEX users_email ON users (email);"), false, dialect)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"CREATE INDEX users_email ON users (email)"}, plan.Statements())
+
+	_, err = sqlt.AutoMigratePlan(ctx, db, strings.NewReader(postgresUsersSchema), false, sqlt.WithDialect(sqlt.MySQL))
+	assert.ErrorIs(t, err, sqlt.ErrUnsupportedDriver)
+}
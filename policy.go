@@ -0,0 +1,106 @@
+package sqlt
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// WithQueryTimeout bounds each Exec, Get and Select statement on the database and in its transactions,
+// including its retries, to d. NamedExecBatch is bounded as a whole. The Query, QueryRow, NamedQuery
+// and SelectSeq methods are not bounded, as the rows they return outlive the call.
+// Use WithTimeout to override it for single statements.
+func WithQueryTimeout(d time.Duration) OpenOption {
+	return func(db *sqlxDB) {
+		db.timeout = d
+	}
+}
+
+// WithRetry retries Exec, Get and Select statements on the database up to attempts times, waiting delay
+// between attempts, while they fail because the database is busy or locked. NamedExecBatch is retried
+// as a whole. Statements in transactions are not retried, as the transaction may have to be rolled back,
+// and neither are the methods returning rows. Use WithNoRetry to disable retries for single statements.
+func WithRetry(attempts int, delay time.Duration) OpenOption {
+	return func(db *sqlxDB) {
+		db.retries = attempts
+		db.retryDelay = delay
+	}
+}
+
+type noRetryKey struct{}
+
+type timeoutKey struct{}
+
+type unsafeScanKey struct{}
+
+// WithNoRetry returns a context disabling the WithRetry policy for the statements executed with it,
+// e.g. for writes that must fail fast.
+func WithNoRetry(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noRetryKey{}, true)
+}
+
+// WithTimeout returns a context overriding the WithQueryTimeout policy for the statements executed
+// with it, e.g. for long analytics queries. A d of zero disables the timeout.
+// Unlike context.WithTimeout, the timeout starts with each statement, not when the context is created.
+func WithTimeout(ctx context.Context, d time.Duration) context.Context {
+	return context.WithValue(ctx, timeoutKey{}, d)
+}
+
+// WithUnsafeScan returns a context making the Get, Select and Query methods executed with it scan rows
+// into structs missing fields for some of the columns, ignoring those columns, as DB.Unsafe does.
+// This lets a wide SELECT * scan into a narrower struct.
+func WithUnsafeScan(ctx context.Context) context.Context {
+	return context.WithValue(ctx, unsafeScanKey{}, true)
+}
+
+// unsafeScan reports whether ctx was returned by WithUnsafeScan.
+func unsafeScan(ctx context.Context) bool {
+	return ctx.Value(unsafeScanKey{}) != nil
+}
+
+// statementContext returns ctx bounded by its WithTimeout override, or else by timeout.
+func statementContext(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if d, ok := ctx.Value(timeoutKey{}).(time.Duration); ok {
+		timeout = d
+	}
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// run calls fn with the timeout and retry policies of the database and ctx applied.
+func (s *sqlxDB) run(ctx context.Context, fn func(ctx context.Context) error) error {
+	ctx, cancel := statementContext(ctx, s.timeout)
+	defer cancel()
+	retries := s.retries
+	if ctx.Value(noRetryKey{}) != nil {
+		retries = 0
+	}
+	for attempt := 0; ; attempt++ {
+		err := fn(ctx)
+		if err == nil || attempt >= retries || !isBusy(err) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(s.retryDelay):
+		}
+	}
+}
+
+// run calls fn with the timeout policy of the database and ctx applied. Statements in a
+// transaction are not retried.
+func (tx *txWrapper) run(ctx context.Context, fn func(ctx context.Context) error) error {
+	ctx, cancel := statementContext(ctx, tx.timeout)
+	defer cancel()
+	return fn(ctx)
+}
+
+// isBusy reports whether err is SQLite failing because another connection holds a lock.
+func isBusy(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "database is locked") || strings.Contains(msg, "database table is locked") ||
+		strings.Contains(msg, "SQLITE_BUSY") || strings.Contains(msg, "SQLITE_LOCKED")
+}
@@ -0,0 +1,307 @@
+package sqlt
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Postgres is the dialect of PostgreSQL databases, see Dialect. It manages the tables and indexes of the
+// current schema (the first schema of the search_path). Identifiers are folded to lower case as PostgreSQL
+// folds unquoted names, so schemas must not rely on quoted mixed-case names. Declare SERIAL columns,
+// or INTEGER and BIGINT with a DEFAULT (nextval(...)); identity columns are not accepted by the parser,
+// nor are multi-word types such as "timestamp with time zone", use timestamptz instead.
+var Postgres Dialect = postgresDialect{}
+
+type postgresDialect struct{}
+
+func (postgresDialect) Name() string { return "postgres" }
+
+// postgresColumnsQuery lists the columns of the ordinary and partitioned tables of the current schema.
+const postgresColumnsQuery = `SELECT c.relname AS table_name, a.attname AS column_name,
+	format_type(a.atttypid, a.atttypmod) AS data_type, a.attnotnull AS not_null,
+	pg_get_expr(d.adbin, d.adrelid) AS column_default,
+	COALESCE((SELECT co.collname FROM pg_catalog.pg_collation co WHERE co.oid = a.attcollation AND a.attcollation <> t.typcollation), '') AS collation
+FROM pg_catalog.pg_attribute a
+JOIN pg_catalog.pg_class c ON c.oid = a.attrelid
+JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+JOIN pg_catalog.pg_type t ON t.oid = a.atttypid
+LEFT JOIN pg_catalog.pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+WHERE n.nspname = current_schema() AND c.relkind IN ('r', 'p') AND a.attnum > 0 AND NOT a.attisdropped
+ORDER BY c.relname, a.attnum`
+
+// postgresIndexesQuery lists the indexes of the tables of the current schema with their key columns,
+// separated by the unit separator, and the type of the constraint they back, if any.
+const postgresIndexesQuery = `SELECT i.relname AS index_name, t.relname AS table_name, ix.indisunique AS is_unique,
+	COALESCE((SELECT con.contype::text FROM pg_catalog.pg_constraint con WHERE con.conindid = ix.indexrelid AND con.contype IN ('p', 'u')), '') AS constraint_type,
+	array_to_string(ARRAY(
+		SELECT pg_get_indexdef(ix.indexrelid, k + 1, true) || CASE WHEN ix.indoption[k] & 1 = 1 THEN ' DESC' ELSE '' END
+		FROM generate_series(0, ix.indnkeyatts - 1) AS k ORDER BY k), chr(31)) AS keys,
+	COALESCE(pg_get_expr(ix.indpred, ix.indrelid), '') AS predicate,
+	pg_get_indexdef(ix.indexrelid) AS sql
+FROM pg_catalog.pg_index ix
+JOIN pg_catalog.pg_class i ON i.oid = ix.indexrelid
+JOIN pg_catalog.pg_class t ON t.oid = ix.indrelid
+JOIN pg_catalog.pg_namespace n ON n.oid = t.relnamespace
+WHERE n.nspname = current_schema() AND t.relkind IN ('r', 'p')
+ORDER BY i.relname`
+
+type postgresColumn struct {
+	TableName     string  `db:"table_name"`
+	ColumnName    string  `db:"column_name"`
+	DataType      string  `db:"data_type"`
+	NotNull       bool    `db:"not_null"`
+	ColumnDefault *string `db:"column_default"`
+	Collation     string  `db:"collation"`
+}
+
+type postgresIndex struct {
+	IndexName      string `db:"index_name"`
+	TableName      string `db:"table_name"`
+	IsUnique       bool   `db:"is_unique"`
+	ConstraintType string `db:"constraint_type"`
+	Keys           string `db:"keys"`
+	Predicate      string `db:"predicate"`
+	SQL            string `db:"sql"`
+}
+
+func (d postgresDialect) FetchSchema(ctx context.Context, db Reader) (*SchemaDefinition, error) {
+	var columns []postgresColumn
+	if err := db.SelectContext(ctx, &columns, postgresColumnsQuery); err != nil {
+		return nil, fmt.Errorf("could not read columns: %w", err)
+	}
+	var indexes []postgresIndex
+	if err := db.SelectContext(ctx, &indexes, postgresIndexesQuery); err != nil {
+		return nil, fmt.Errorf("could not read indexes: %w", err)
+	}
+	s := &SchemaDefinition{
+		Tables:   make(map[string]*TableDefinition),
+		Indexes:  make(map[string]*IndexDefinition),
+		Views:    make(map[string]*ViewDefinition),
+		Triggers: make(map[string]*TriggerDefinition),
+	}
+	for _, c := range columns {
+		table := s.Table(c.TableName)
+		if table == nil {
+			table = &TableDefinition{Name: c.TableName}
+			s.Tables[s.key(c.TableName)] = table
+		}
+		table.Columns = append(table.Columns, &ColumnDefinition{
+			Name:         c.ColumnName,
+			Type:         c.DataType,
+			NotNull:      c.NotNull,
+			DefaultValue: c.ColumnDefault,
+			Collation:    c.Collation,
+		})
+	}
+	for _, i := range indexes {
+		table := s.Table(i.TableName)
+		if table == nil {
+			continue
+		}
+		index := &IndexDefinition{Name: i.IndexName, Table: i.TableName, Unique: i.IsUnique, Where: i.Predicate, Origin: "c", SQL: i.SQL}
+		for _, key := range strings.Split(i.Keys, "\x1f") {
+			column, desc := strings.CutSuffix(key, " DESC")
+			index.Columns = append(index.Columns, column)
+			index.Keys = append(index.Keys, IndexKey{Column: column, Desc: desc})
+		}
+		switch i.ConstraintType {
+		case "p":
+			index.Origin = "pk"
+			table.PrimaryKey = index.Columns
+			for _, name := range index.Columns {
+				if col := table.Column(name); col != nil {
+					col.PrimaryKey = true
+				}
+			}
+		case "u":
+			index.Origin = "u"
+			table.UniqueConstraints = append(table.UniqueConstraints, index.Columns)
+		default:
+			s.Indexes[s.key(index.Name)] = index
+			continue
+		}
+		table.ImplicitIndexes = append(table.ImplicitIndexes, index)
+	}
+	for _, table := range s.Tables {
+		table.SQL = d.CreateTable(table)
+	}
+	s.intern()
+	return s, nil
+}
+
+// postgresTypeAliases maps the names of PostgreSQL types to the names format_type reports.
+var postgresTypeAliases = map[string]string{
+	"int":         "integer",
+	"int4":        "integer",
+	"serial":      "integer",
+	"serial4":     "integer",
+	"int8":        "bigint",
+	"bigserial":   "bigint",
+	"serial8":     "bigint",
+	"int2":        "smallint",
+	"smallserial": "smallint",
+	"serial2":     "smallint",
+	"bool":        "boolean",
+	"varchar":     "character varying",
+	"char":        "character",
+	"bpchar":      "character",
+	"float":       "double precision",
+	"float8":      "double precision",
+	"float4":      "real",
+	"decimal":     "numeric",
+	"timestamp":   "timestamp without time zone",
+	"timestamptz": "timestamp with time zone",
+	"time":        "time without time zone",
+	"timetz":      "time with time zone",
+}
+
+// postgresTypePattern splits a type into its name, modifiers and array brackets.
+var postgresTypePattern = regexp.MustCompile(`^([a-z0-9_ ]+?)\s*(\([0-9, ]+\))?\s*((?:\[\])*)$`)
+
+func (postgresDialect) NormalizeType(decl string) string {
+	decl = strings.ToLower(strings.Join(strings.Fields(decl), " "))
+	m := postgresTypePattern.FindStringSubmatch(decl)
+	if m == nil {
+		return decl
+	}
+	name, modifiers, array := m[1], strings.ReplaceAll(m[2], " ", ""), m[3]
+	if alias, ok := postgresTypeAliases[name]; ok {
+		name = alias
+	}
+	if name == "character" && modifiers == "" {
+		modifiers = "(1)"
+	}
+	if base, zone, ok := strings.Cut(name, " with"); ok && modifiers != "" {
+		// format_type puts the precision of time types before the time zone.
+		return base + modifiers + " with" + zone + array
+	}
+	return name + modifiers + array
+}
+
+var (
+	// postgresCast matches a type cast, e.g. ::text or ::character varying(10).
+	postgresCast = regexp.MustCompile(`::(?:"[^"]*"|[a-z_][a-z0-9_]*)(?: (?:varying|precision|with(?:out)? time zone))?(?:\([0-9, ]+\))?(?:\[\])*`)
+	// postgresQuotedIdent matches a quoted lower-case identifier.
+	postgresQuotedIdent = regexp.MustCompile(`"([a-z_][a-z0-9_$]*)"`)
+	// postgresParenTerm matches a single identifier, number or string in parentheses that do not call a function.
+	postgresParenTerm = regexp.MustCompile(`(^|[^a-z0-9_$])\(([a-z_][a-z0-9_$.]*|[0-9.]+|'[^']*')\)`)
+	whitespace        = regexp.MustCompile(`\s+`)
+)
+
+func (postgresDialect) NormalizeExpr(expr string) string {
+	expr = postgresCast.ReplaceAllString(postgresExpr(expr), "")
+	for {
+		next := postgresParenTerm.ReplaceAllString(expr, "$1$2")
+		next = trimOuterParens(next)
+		if next == expr {
+			break
+		}
+		expr = next
+	}
+	if strings.HasPrefix(expr, "nextval(") {
+		return ""
+	}
+	return expr
+}
+
+// postgresExpr folds expr to lower case and collapses its white space outside string literals,
+// and unquotes the identifiers that need no quotes.
+func postgresExpr(expr string) string {
+	parts := strings.Split(expr, "'")
+	for i := 0; i < len(parts); i += 2 {
+		parts[i] = strings.ToLower(whitespace.ReplaceAllString(parts[i], " "))
+		parts[i] = postgresQuotedIdent.ReplaceAllStringFunc(parts[i], func(quoted string) string {
+			return postgresIdent(quoted[1 : len(quoted)-1])
+		})
+	}
+	return strings.TrimSpace(strings.Join(parts, "'"))
+}
+
+// trimOuterParens removes parentheses enclosing all of expr.
+func trimOuterParens(expr string) string {
+	for strings.HasPrefix(expr, "(") && strings.HasSuffix(expr, ")") {
+		depth := 0
+		for i, r := range expr {
+			switch r {
+			case '(':
+				depth++
+			case ')':
+				depth--
+			}
+			if depth == 0 && i < len(expr)-1 {
+				return expr
+			}
+		}
+		expr = expr[1 : len(expr)-1]
+	}
+	return expr
+}
+
+// postgresIdentPattern matches the identifiers PostgreSQL accepts unquoted.
+var postgresIdentPattern = regexp.MustCompile(`^[a-z_][a-z0-9_$]*$`)
+
+// postgresIdent folds name to lower case and quotes it if PostgreSQL would not accept it unquoted.
+func postgresIdent(name string) string {
+	name = strings.ToLower(name)
+	if postgresIdentPattern.MatchString(name) && !postgresReserved[name] {
+		return name
+	}
+	return quoteIdent(name)
+}
+
+// postgresReserved lists the reserved key words likely to be used as names.
+var postgresReserved = map[string]bool{
+	"all": true, "and": true, "any": true, "as": true, "asc": true, "check": true, "column": true,
+	"constraint": true, "default": true, "desc": true, "distinct": true, "do": true, "end": true,
+	"for": true, "from": true, "group": true, "limit": true, "order": true, "primary": true,
+	"references": true, "select": true, "table": true, "to": true, "unique": true, "user": true,
+	"when": true, "where": true, "with": true,
+}
+
+func (postgresDialect) CreateTable(table *TableDefinition) string {
+	return createTableSQL(table, postgresIdent, postgresExpr)
+}
+
+func (postgresDialect) DropTable(table *TableDefinition) string {
+	return "DROP TABLE " + postgresIdent(table.Name)
+}
+
+func (postgresDialect) AddColumn(table string, col *ColumnDefinition) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", postgresIdent(table), columnSQL(col, postgresIdent))
+}
+
+func (postgresDialect) DropColumn(table, column string) string {
+	return fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", postgresIdent(table), postgresIdent(column))
+}
+
+func (postgresDialect) CreateIndex(index *IndexDefinition) string {
+	keys := make([]string, len(index.Columns))
+	for i, col := range index.Columns {
+		key := index.key(i)
+		keys[i] = postgresIdent(col)
+		if !postgresIdentPattern.MatchString(strings.ToLower(col)) {
+			keys[i] = postgresExpr(col)
+		}
+		if key.Collation != "" {
+			keys[i] += " COLLATE " + quoteIdent(key.Collation)
+		}
+		if key.Desc {
+			keys[i] += " DESC"
+		}
+	}
+	sql := "CREATE INDEX "
+	if index.Unique {
+		sql = "CREATE UNIQUE INDEX "
+	}
+	sql += fmt.Sprintf("%s ON %s (%s)", postgresIdent(index.Name), postgresIdent(index.Table), strings.Join(keys, ", "))
+	if index.Where != "" {
+		sql += " WHERE " + postgresExpr(index.Where)
+	}
+	return sql
+}
+
+func (postgresDialect) DropIndex(index *IndexDefinition) string {
+	return "DROP INDEX " + postgresIdent(index.Name)
+}
@@ -0,0 +1,243 @@
+package sqlt
+
+import (
+	"fmt"
+	"strings"
+
+	rsql "github.com/rqlite/sql"
+)
+
+// WithBackfill sets the expressions AutoMigrate fills new columns with, keyed by "table.column",
+// e.g. {"users.tier": "'free'"}. A table whose schema only adds columns to the database table,
+// at least one of them backfilled, is rebuilt with the new columns populated instead of returning
+// a *SchemaConflictError. Expressions are evaluated against the rows of the old table, so they may
+// refer to its columns. New columns without an expression must be nullable or have a default.
+func WithBackfill(exprs map[string]string) MigrateOption {
+	return func(cfg *migrateConfig) {
+		if cfg.backfill == nil {
+			cfg.backfill = make(map[string]string, len(exprs))
+		}
+		for key, expr := range exprs {
+			table, column, _ := strings.Cut(key, ".")
+			cfg.backfill[foldIdent(table)+"."+foldIdent(column)] = expr
+		}
+	}
+}
+
+// backfilledColumns returns the columns and select expressions filling schemaTable from the rows
+// of dbTable when schemaTable only adds backfilled, nullable or defaulted columns to dbTable.
+// ok is false if the table cannot be rebuilt that way or no backfill applies to it.
+func (cfg *migrateConfig) backfilledColumns(dbTable, schemaTable *rsql.CreateTableStatement, strictness Strictness) (columns, exprs []string, ok bool) {
+	if len(cfg.backfill) == 0 {
+		return nil, nil, false
+	}
+	dbColumns := make(map[string]bool, len(dbTable.Columns))
+	for _, col := range dbTable.Columns {
+		dbColumns[foldIdent(col.Name.Name)] = true
+	}
+	table := newTableDefinition(schemaTable)
+	trimmed := *schemaTable
+	trimmed.Columns = nil
+	backfilled := false
+	for i, col := range schemaTable.Columns {
+		name := col.Name.Name
+		if dbColumns[foldIdent(name)] {
+			trimmed.Columns = append(trimmed.Columns, col)
+			columns = append(columns, quoteIdent(name))
+			exprs = append(exprs, quoteIdent(name))
+			continue
+		}
+		if expr, found := cfg.backfill[foldIdent(table.Name)+"."+foldIdent(name)]; found && table.Columns[i].Hidden < 2 {
+			columns = append(columns, quoteIdent(name))
+			exprs = append(exprs, expr)
+			backfilled = true
+			continue
+		}
+		if requiresValue(table.Columns[i]) {
+			return nil, nil, false
+		}
+	}
+	if !backfilled {
+		return nil, nil, false
+	}
+	match, _, err := compareStatements(dbTable, &trimmed, strictness)
+	if err != nil || match == statementMatchNoMatch {
+		return nil, nil, false
+	}
+	return columns, exprs, true
+}
+
+// WithRenames declares columns renamed in the schema, keyed by "table.old" with the new name as value,
+// e.g. {"users.fullname": "name"}, so AutoMigrate keeps their data instead of reporting the old column
+// as extra and the new one as missing. Columns are renamed with ALTER TABLE RENAME COLUMN when the
+// renames are the only change to the table and the database supports it, otherwise the table is rebuilt
+// with the renamed columns copied over, as long as the schema only reorders or adds nullable or
+// defaulted columns besides. Renames whose old column is gone from the database are ignored.
+func WithRenames(renames map[string]string) MigrateOption {
+	return func(cfg *migrateConfig) {
+		if cfg.renames == nil {
+			cfg.renames = make(map[string]map[string]string, len(renames))
+		}
+		for key, to := range renames {
+			table, from, _ := strings.Cut(key, ".")
+			table = foldIdent(table)
+			if cfg.renames[table] == nil {
+				cfg.renames[table] = make(map[string]string)
+			}
+			cfg.renames[table][foldIdent(from)] = to
+		}
+	}
+}
+
+// renamedColumns returns dbTable with the columns renamed by WithRenames given their new name,
+// and the renames applying to it: the old column exists in dbTable, and only the new one in schemaTable.
+func (cfg *migrateConfig) renamedColumns(dbTable, schemaTable *rsql.CreateTableStatement) (*rsql.CreateTableStatement, []columnRename) {
+	tableRenames := cfg.renames[foldIdent(dbTable.Name.Name)]
+	if len(tableRenames) == 0 {
+		return nil, nil
+	}
+	old, schema := newTableDefinition(dbTable), newTableDefinition(schemaTable)
+	renamed := *dbTable
+	renamed.Columns = make([]*rsql.ColumnDefinition, len(dbTable.Columns))
+	var applied []columnRename
+	for i, col := range dbTable.Columns {
+		renamed.Columns[i] = col
+		to, ok := tableRenames[foldIdent(col.Name.Name)]
+		if !ok || schema.Column(to) == nil || schema.Column(col.Name.Name) != nil || old.Column(to) != nil {
+			continue
+		}
+		renamedCol := *col
+		renamedCol.Name = &rsql.Ident{Name: to, Quoted: true}
+		renamed.Columns[i] = &renamedCol
+		applied = append(applied, columnRename{from: col.Name.Name, to: to})
+	}
+	if len(applied) == 0 {
+		return nil, nil
+	}
+	return &renamed, applied
+}
+
+// renameColumns migrates dbTable to schemaTable if the renames declared with WithRenames, with the
+// columns schemaTable reorders or adds, are all that differ. It reports whether it did, and whether
+// it rebuilt the table to do so.
+func (cfg *migrateConfig) renameColumns(tx execer, dbTable, schemaTable *rsql.CreateTableStatement, strictness Strictness, caps *Capabilities) (ok, rebuilt bool, err error) {
+	renamed, renames := cfg.renamedColumns(dbTable, schemaTable)
+	if renamed == nil {
+		return false, false, nil
+	}
+	table := schemaTable.Name.Name
+	if match, _, err := compareStatements(renamed, schemaTable, strictness); err == nil && match == statementMatchExact && caps.RenameColumn {
+		for _, r := range renames {
+			if _, err := tx.Exec(fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s", quoteIdent(table), quoteIdent(r.from), quoteIdent(r.to))); err != nil {
+				return false, false, fmt.Errorf("AutoMigrate: error renaming column %s of table %s to %s: %w", r.from, table, r.to, err)
+			}
+		}
+		return true, false, nil
+	}
+
+	old, schema := newTableDefinition(renamed), newTableDefinition(schemaTable)
+	from := make(map[string]string, len(renames))
+	for _, r := range renames {
+		from[foldIdent(r.to)] = r.from
+	}
+	trimmed := *schemaTable
+	trimmed.Columns = nil
+	var columns, exprs []string
+	for i, col := range schema.Columns {
+		oldCol := old.Column(col.Name)
+		if oldCol == nil {
+			if requiresValue(col) {
+				return false, false, nil
+			}
+			continue
+		}
+		trimmed.Columns = append(trimmed.Columns, schemaTable.Columns[i])
+		if oldCol.Hidden >= 2 || col.Hidden >= 2 {
+			continue
+		}
+		expr := quoteIdent(col.Name)
+		if name, ok := from[foldIdent(col.Name)]; ok {
+			expr = quoteIdent(name)
+		}
+		columns, exprs = append(columns, quoteIdent(col.Name)), append(exprs, expr)
+	}
+	if match, _, err := compareStatements(renamed, &trimmed, strictness); err != nil || match == statementMatchNoMatch {
+		return false, false, nil
+	}
+	if err := rebuildTable(tx, table, schemaTable, columns, exprs); err != nil {
+		return false, false, err
+	}
+	return true, true, nil
+}
+
+// rebuildSuffix ends the temporary name rebuildTable renames the old table to.
+const rebuildSuffix = "_temp_reorder_sqlt"
+
+// rebuildTable replaces table by a new one created by stmt, filling the given columns of the new
+// table with the select expressions evaluated against the rows of the old one. Without columns,
+// the new table is left empty.
+func rebuildTable(tx execer, table string, stmt rsql.Statement, columns, exprs []string) error {
+	tempTable := table + rebuildSuffix
+	qTable, qTempTable := quoteIdent(table), quoteIdent(tempTable)
+	if _, err := tx.Exec(fmt.Sprintf("ALTER TABLE %s RENAME TO %s", qTable, qTempTable)); err != nil {
+		return fmt.Errorf("AutoMigrate: error renaming table %s to %s for rebuild: %w", table, tempTable, err)
+	}
+	if _, err := tx.Exec(statementSQL(stmt)); err != nil {
+		return fmt.Errorf("AutoMigrate: error creating new table %s for rebuild: %w. SQL: %s", table, err, statementSQL(stmt))
+	}
+	if len(columns) > 0 {
+		insertSQL := fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s", qTable, strings.Join(columns, ", "), strings.Join(exprs, ", "), qTempTable)
+		if _, err := tx.Exec(insertSQL); err != nil {
+			return fmt.Errorf("AutoMigrate: error copying data to rebuilt table %s: %w. SQL: %s", table, err, insertSQL)
+		}
+	}
+	if _, err := tx.Exec(fmt.Sprintf("DROP TABLE %s", qTempTable)); err != nil {
+		return fmt.Errorf("AutoMigrate: error dropping temporary table %s for rebuild: %w", tempTable, err)
+	}
+	return nil
+}
+
+// WithRebuildSelect sets the SELECT statements filling tables AutoMigrate rebuilds, keyed by table name,
+// instead of copying the columns the old and new table have in common. Each query reads the old table by
+// its name, e.g. "SELECT id, substr(name, 1, instr(name, ' ') - 1), substr(name, instr(name, ' ') + 1) FROM users",
+// and returns one column per column of the new table, in order, leaving out generated columns.
+// A table with a query is rebuilt whenever it differs from its schema, including changes that would
+// otherwise return a *SchemaConflictError. Tables matching their schema are left untouched.
+func WithRebuildSelect(selects map[string]string) MigrateOption {
+	return func(cfg *migrateConfig) {
+		if cfg.rebuildSelects == nil {
+			cfg.rebuildSelects = make(map[string]string, len(selects))
+		}
+		for table, query := range selects {
+			cfg.rebuildSelects[foldIdent(table)] = query
+		}
+	}
+}
+
+// rebuildTableSelect replaces table by a new one created by stmt and filled by query, which reads the old table.
+func rebuildTableSelect(tx execer, table string, stmt *rsql.CreateTableStatement, query string) error {
+	newTable := "_sqlt_new_" + table
+	create := *stmt
+	create.Name = &rsql.Ident{Name: newTable, Quoted: true}
+	if _, err := tx.Exec(statementSQL(&create)); err != nil {
+		return fmt.Errorf("AutoMigrate: error creating new table %s for rebuild: %w. SQL: %s", table, err, statementSQL(&create))
+	}
+	var columns []string
+	for _, col := range newTableDefinition(stmt).Columns {
+		if col.Hidden < 2 {
+			columns = append(columns, quoteIdent(col.Name))
+		}
+	}
+	query = strings.TrimRight(strings.TrimSpace(query), ";")
+	insertSQL := fmt.Sprintf("INSERT INTO %s (%s) %s", quoteIdent(newTable), strings.Join(columns, ", "), query)
+	if _, err := tx.Exec(insertSQL); err != nil {
+		return fmt.Errorf("AutoMigrate: error copying data to rebuilt table %s: %w. SQL: %s", table, err, insertSQL)
+	}
+	if _, err := tx.Exec("DROP TABLE " + quoteIdent(table)); err != nil {
+		return fmt.Errorf("AutoMigrate: error dropping table %s for rebuild: %w", table, err)
+	}
+	if _, err := tx.Exec(fmt.Sprintf("ALTER TABLE %s RENAME TO %s", quoteIdent(newTable), quoteIdent(table))); err != nil {
+		return fmt.Errorf("AutoMigrate: error renaming table %s to %s for rebuild: %w", newTable, table, err)
+	}
+	return nil
+}
@@ -0,0 +1,249 @@
+package sqlt
+
+import (
+	"context"
+	"fmt"
+)
+
+// recordingTx collects the statements successfully executed through it.
+type recordingTx struct {
+	Tx
+	executed *[]string
+}
+
+func (tx *recordingTx) record(query string, err error) {
+	if err == nil {
+		*tx.executed = append(*tx.executed, query)
+	}
+}
+
+// execBatch executes the statements of b in one call with ctx and records each of them.
+func (tx *recordingTx) execBatch(ctx context.Context, b *statementBatch) error {
+	if len(b.stmts) == 0 {
+		return nil
+	}
+	if _, err := tx.Tx.ExecContext(ctx, b.sql()); err != nil {
+		return fmt.Errorf("error executing batched statements: %w", err)
+	}
+	*tx.executed = append(*tx.executed, b.stmts...)
+	return nil
+}
+
+func (tx *recordingTx) Exec(query string, args ...any) (Result, error) {
+	res, err := tx.Tx.Exec(query, args...)
+	tx.record(query, err)
+	return res, err
+}
+
+func (tx *recordingTx) MustExec(query string, args ...any) Result {
+	res := tx.Tx.MustExec(query, args...)
+	tx.record(query, nil)
+	return res
+}
+
+func (tx *recordingTx) ExecContext(ctx context.Context, query string, args ...any) (Result, error) {
+	res, err := tx.Tx.ExecContext(ctx, query, args...)
+	tx.record(query, err)
+	return res, err
+}
+
+func (tx *recordingTx) IDExecContext(ctx context.Context, query string, args ...any) (int64, error) {
+	id, err := tx.Tx.IDExecContext(ctx, query, args...)
+	tx.record(query, err)
+	return id, err
+}
+
+func (tx *recordingTx) AffectedExecContext(ctx context.Context, query string, args ...any) (int, error) {
+	n, err := tx.Tx.AffectedExecContext(ctx, query, args...)
+	tx.record(query, err)
+	return n, err
+}
+
+func (tx *recordingTx) IDExec(query string, args ...any) (int64, error) {
+	id, err := tx.Tx.IDExec(query, args...)
+	tx.record(query, err)
+	return id, err
+}
+
+func (tx *recordingTx) AffectedExec(query string, args ...any) (int, error) {
+	n, err := tx.Tx.AffectedExec(query, args...)
+	tx.record(query, err)
+	return n, err
+}
+
+func (tx *recordingTx) NamedExec(query string, arg any) (Result, error) {
+	res, err := tx.Tx.NamedExec(query, arg)
+	tx.record(query, err)
+	return res, err
+}
+
+func (tx *recordingTx) NamedExecBatch(query string, slice any) (Result, error) {
+	res, err := tx.Tx.NamedExecBatch(query, slice)
+	tx.record(query, err)
+	return res, err
+}
+
+func (tx *recordingTx) NamedExecBatchContext(ctx context.Context, query string, slice any) (Result, error) {
+	res, err := tx.Tx.NamedExecBatchContext(ctx, query, slice)
+	tx.record(query, err)
+	return res, err
+}
+
+func (tx *recordingTx) ExecReturning(dest any, query string, args ...any) error {
+	err := tx.Tx.ExecReturning(dest, query, args...)
+	tx.record(query, err)
+	return err
+}
+
+func (tx *recordingTx) ExecReturningContext(ctx context.Context, dest any, query string, args ...any) error {
+	err := tx.Tx.ExecReturningContext(ctx, dest, query, args...)
+	tx.record(query, err)
+	return err
+}
+
+func (tx *recordingTx) NamedExecReturning(dest any, query string, arg any) error {
+	err := tx.Tx.NamedExecReturning(dest, query, arg)
+	tx.record(query, err)
+	return err
+}
+
+func (tx *recordingTx) NamedExecReturningContext(ctx context.Context, dest any, query string, arg any) error {
+	err := tx.Tx.NamedExecReturningContext(ctx, dest, query, arg)
+	tx.record(query, err)
+	return err
+}
+
+// recordingDB collects the statements successfully executed through it,
+// including those executed in transactions it starts once they commit.
+type recordingDB struct {
+	DB
+	executed *[]string
+	// prepare, if set, runs first in every transaction started through it.
+	prepare func(Handle) error
+}
+
+func (db *recordingDB) record(query string, err error) {
+	if err == nil {
+		*db.executed = append(*db.executed, query)
+	}
+}
+
+// tx runs fn in the transaction started by begin, keeping the statements it executed only if
+// the transaction commits, so those of a rolled-back transaction are not reported as applied.
+func (db *recordingDB) tx(begin func(func(tx Tx) error) error, fn func(tx Tx) error) error {
+	var executed []string
+	err := begin(func(tx Tx) error {
+		// A retried transaction starts over.
+		executed = executed[:0]
+		if db.prepare != nil {
+			if err := db.prepare(tx); err != nil {
+				return err
+			}
+		}
+		return fn(&recordingTx{Tx: tx, executed: &executed})
+	})
+	if err == nil {
+		*db.executed = append(*db.executed, executed...)
+	}
+	return err
+}
+
+func (db *recordingDB) Exec(query string, args ...any) (Result, error) {
+	res, err := db.DB.Exec(query, args...)
+	db.record(query, err)
+	return res, err
+}
+
+func (db *recordingDB) MustExec(query string, args ...any) Result {
+	res := db.DB.MustExec(query, args...)
+	db.record(query, nil)
+	return res
+}
+
+func (db *recordingDB) ExecContext(ctx context.Context, query string, args ...any) (Result, error) {
+	res, err := db.DB.ExecContext(ctx, query, args...)
+	db.record(query, err)
+	return res, err
+}
+
+func (db *recordingDB) IDExec(query string, args ...any) (int64, error) {
+	id, err := db.DB.IDExec(query, args...)
+	db.record(query, err)
+	return id, err
+}
+
+func (db *recordingDB) IDExecContext(ctx context.Context, query string, args ...any) (int64, error) {
+	id, err := db.DB.IDExecContext(ctx, query, args...)
+	db.record(query, err)
+	return id, err
+}
+
+func (db *recordingDB) AffectedExec(query string, args ...any) (int, error) {
+	n, err := db.DB.AffectedExec(query, args...)
+	db.record(query, err)
+	return n, err
+}
+
+func (db *recordingDB) AffectedExecContext(ctx context.Context, query string, args ...any) (int, error) {
+	n, err := db.DB.AffectedExecContext(ctx, query, args...)
+	db.record(query, err)
+	return n, err
+}
+
+func (db *recordingDB) NamedExec(query string, arg any) (Result, error) {
+	res, err := db.DB.NamedExec(query, arg)
+	db.record(query, err)
+	return res, err
+}
+
+func (db *recordingDB) NamedExecBatch(query string, slice any) (Result, error) {
+	res, err := db.DB.NamedExecBatch(query, slice)
+	db.record(query, err)
+	return res, err
+}
+
+func (db *recordingDB) NamedExecBatchContext(ctx context.Context, query string, slice any) (Result, error) {
+	res, err := db.DB.NamedExecBatchContext(ctx, query, slice)
+	db.record(query, err)
+	return res, err
+}
+
+func (db *recordingDB) ExecReturning(dest any, query string, args ...any) error {
+	err := db.DB.ExecReturning(dest, query, args...)
+	db.record(query, err)
+	return err
+}
+
+func (db *recordingDB) ExecReturningContext(ctx context.Context, dest any, query string, args ...any) error {
+	err := db.DB.ExecReturningContext(ctx, dest, query, args...)
+	db.record(query, err)
+	return err
+}
+
+func (db *recordingDB) NamedExecReturning(dest any, query string, arg any) error {
+	err := db.DB.NamedExecReturning(dest, query, arg)
+	db.record(query, err)
+	return err
+}
+
+func (db *recordingDB) NamedExecReturningContext(ctx context.Context, dest any, query string, arg any) error {
+	err := db.DB.NamedExecReturningContext(ctx, dest, query, arg)
+	db.record(query, err)
+	return err
+}
+
+func (db *recordingDB) Tx(fn func(tx Tx) error) error {
+	return db.tx(db.DB.Tx, fn)
+}
+
+func (db *recordingDB) TxImm(fn func(tx Tx) error) error {
+	return db.tx(db.DB.TxImm, fn)
+}
+
+func (db *recordingDB) Txc(ctx context.Context, fn func(tx Tx) error) error {
+	return db.tx(func(fn func(tx Tx) error) error { return db.DB.Txc(ctx, fn) }, fn)
+}
+
+func (db *recordingDB) TxcImm(ctx context.Context, fn func(tx Tx) error) error {
+	return db.tx(func(fn func(tx Tx) error) error { return db.DB.TxcImm(ctx, fn) }, fn)
+}
@@ -0,0 +1,43 @@
+package sqlt
+
+import "database/sql"
+
+// ParamRedactor returns the value to log in place of the argument v of a query.
+// i is the position of the argument and name its name for sql.NamedArg, or "" for positional arguments.
+type ParamRedactor func(i int, name string, v any) any
+
+// RedactParams sets the policy RedactedArgs applies to query arguments, so code logging the arguments
+// of its queries, such as hooks or slow-query logs, can mask PII-bearing ones in one place instead of
+// not logging arguments at all. sqlt itself never logs or reports arguments: a QueryError only names
+// the query.
+func RedactParams(fn ParamRedactor) OpenOption {
+	return func(db *sqlxDB) {
+		db.redact = fn
+	}
+}
+
+// RedactedArgs returns args with the RedactParams policy of db applied, for callers logging them.
+// Arguments are returned unchanged if db has no policy or was not opened by this package.
+func RedactedArgs(db DB, args ...any) []any {
+	s, ok := db.(*sqlxDB)
+	if !ok {
+		return args
+	}
+	return redactArgs(s.redact, args)
+}
+
+func redactArgs(fn ParamRedactor, args []any) []any {
+	if fn == nil || len(args) == 0 {
+		return args
+	}
+	redacted := make([]any, len(args))
+	for i, arg := range args {
+		if named, ok := arg.(sql.NamedArg); ok {
+			named.Value = fn(i, named.Name, named.Value)
+			redacted[i] = named
+			continue
+		}
+		redacted[i] = fn(i, "", arg)
+	}
+	return redacted
+}
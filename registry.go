@@ -0,0 +1,35 @@
+package sqlt
+
+import (
+	"fmt"
+	"sync"
+)
+
+// namedDBs holds the databases registered with RegisterDB by name.
+var namedDBs sync.Map // map[string]DB
+
+// RegisterDB registers db under name, e.g. a tenant ID, so it can be looked up with NamedDB.
+// It fails if a database is already registered under name.
+func RegisterDB(name string, db DB) error {
+	if _, loaded := namedDBs.LoadOrStore(name, db); loaded {
+		return fmt.Errorf("database %s is already registered", name)
+	}
+	return nil
+}
+
+// NamedDB returns the database registered under name, or nil.
+func NamedDB(name string) DB {
+	if db, ok := namedDBs.Load(name); ok {
+		return db.(DB)
+	}
+	return nil
+}
+
+// UnregisterDB removes the database registered under name and returns it, or nil.
+// The database is not closed.
+func UnregisterDB(name string) DB {
+	if db, ok := namedDBs.LoadAndDelete(name); ok {
+		return db.(DB)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package sqlt
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// MigrationRequirement restricts the databases a migration runs on, so a single MigrationMap can serve
+// databases that differ in supported DDL, e.g. local sqlite3 in development and remote libsql in production.
+// See Requires.
+type MigrationRequirement struct {
+	// MinSQLiteVersion is the lowest SQLite version the migration runs on, e.g. "3.35.0", or empty for any.
+	MinSQLiteVersion string
+	// Drivers lists the driver names the migration runs on, e.g. "libsql", or is empty for any.
+	Drivers []string
+	// Check, if set, reports whether the capabilities of the database allow the migration.
+	Check func(*Capabilities) bool
+	// Skip makes a migration on a database not meeting the requirement only advance the version by one
+	// instead of failing with a *RequirementError.
+	Skip bool
+}
+
+// RequirementError is returned by a migration wrapped with Requires on a database not meeting its requirement.
+type RequirementError struct {
+	// Reason describes the unmet requirement.
+	Reason string
+}
+
+func (e *RequirementError) Error() string {
+	return "migration requirement not met: " + e.Reason
+}
+
+// Requires wraps fn so it only runs on databases meeting req. On other databases it fails with a
+// *RequirementError, or with req.Skip set, advances the version by one without running fn.
+func Requires(req MigrationRequirement, fn MigrationFunc) MigrationFunc {
+	return func(ctx context.Context, db DB) error {
+		reason, err := req.unmet(ctx, db)
+		if err != nil {
+			return err
+		}
+		if reason == "" {
+			return fn(ctx, db)
+		}
+		if !req.Skip {
+			return &RequirementError{Reason: reason}
+		}
+		migrationLogf(ctx)("skipping migration: %s", reason)
+		return updateVersion(ctx, db, "version + 1")
+	}
+}
+
+// unmet returns why db does not meet the requirement, or "" if it does.
+func (req MigrationRequirement) unmet(ctx context.Context, db DB) (string, error) {
+	if len(req.Drivers) > 0 && !slices.Contains(req.Drivers, db.DriverName()) {
+		return fmt.Sprintf("driver %s is not %s", db.DriverName(), strings.Join(req.Drivers, " or ")), nil
+	}
+	if req.MinSQLiteVersion == "" && req.Check == nil {
+		return "", nil
+	}
+	caps, err := db.Capabilities(ctx)
+	if err != nil {
+		return "", err
+	}
+	if req.MinSQLiteVersion != "" {
+		minVersion, err := parseSQLiteVersion(req.MinSQLiteVersion)
+		if err != nil {
+			return "", err
+		}
+		version, err := parseSQLiteVersion(caps.SQLiteVersion)
+		if err != nil {
+			return "", err
+		}
+		if version < minVersion {
+			return fmt.Sprintf("SQLite %s is older than %s", caps.SQLiteVersion, req.MinSQLiteVersion), nil
+		}
+	}
+	if req.Check != nil && !req.Check(caps) {
+		return "capability check failed", nil
+	}
+	return "", nil
+}
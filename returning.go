@@ -0,0 +1,59 @@
+package sqlt
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"regexp"
+)
+
+var returningClause = regexp.MustCompile(`(?i)\bRETURNING\b`)
+
+// hasReturning reports whether query has a RETURNING clause.
+// It does not parse the query, so the word inside a string literal also counts.
+func hasReturning(query string) bool {
+	return returningClause.MatchString(query)
+}
+
+// namedExecReturning implements NamedExecReturning for DB and Tx.
+func namedExecReturning(ctx context.Context, h Handle, dest any, query string, arg any) error {
+	q, args, err := h.BindNamed(query, arg)
+	if err != nil {
+		return fmt.Errorf("NamedExecReturning: %w", err)
+	}
+	return execReturning(ctx, h, dest, q, args)
+}
+
+// execReturning implements ExecReturning for DB and Tx.
+//
+// Queries with a RETURNING clause are scanned into dest like Get, or like Select if dest
+// points to a slice. Other queries are executed and their last insert rowid is stored in dest.
+func execReturning(ctx context.Context, h Handle, dest any, query string, args []any) error {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return fmt.Errorf("ExecReturning: dest must be a non-nil pointer, got %T", dest)
+	}
+	if hasReturning(query) {
+		if v.Elem().Kind() == reflect.Slice {
+			return h.SelectContext(ctx, dest, query, args...)
+		}
+		return h.GetContext(ctx, dest, query, args...)
+	}
+	elem := v.Elem()
+	if !elem.CanInt() && !elem.CanUint() && !(elem.Kind() == reflect.Interface && elem.NumMethod() == 0) {
+		return fmt.Errorf("ExecReturning: cannot store the last insert rowid in %s, add a RETURNING clause", elem.Type())
+	}
+	id, err := h.IDExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	switch {
+	case elem.CanInt():
+		elem.SetInt(id)
+	case elem.CanUint():
+		elem.SetUint(uint64(id))
+	default:
+		elem.Set(reflect.ValueOf(id))
+	}
+	return nil
+}
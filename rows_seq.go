@@ -1,14 +1,33 @@
 package sqlt
 
-import "github.com/jmoiron/sqlx"
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/jmoiron/sqlx/reflectx"
+)
 
 type RowsSeq struct {
 	err  error
 	rows *sqlx.Rows
+	// ctx is checked between rows when the sequence was started with a context.
+	ctx         context.Context
+	ignoreExtra bool
 }
 
 var emptySeq = func(func(any) bool) {}
 
+// IgnoreExtraColumns makes Iter skip the result columns no field of its destination maps to,
+// instead of failing with a *ScanError. It returns e for chaining before Iter.
+func (e *RowsSeq) IgnoreExtraColumns() *RowsSeq {
+	e.ignoreExtra = true
+	return e
+}
+
 func (e *RowsSeq) Iter(dest any) func(func(any) bool) {
 	if e.err != nil {
 		return emptySeq
@@ -18,7 +37,12 @@ func (e *RowsSeq) Iter(dest any) func(func(any) bool) {
 	} else {
 		return func(fn func(any) bool) {
 			for e.rows.Next() {
-				if err := e.rows.StructScan(dest); err != nil {
+				if err := e.ctxErr(); err != nil {
+					e.err = err
+					e.rows.Close()
+					return
+				}
+				if err := e.scan(dest); err != nil {
 					e.err = err
 					e.rows.Close()
 					return
@@ -37,6 +61,82 @@ func (e *RowsSeq) Iter(dest any) func(func(any) bool) {
 	}
 }
 
+// scan scans the current row into dest, skipping unmapped columns if IgnoreExtraColumns was called.
+func (e *RowsSeq) scan(dest any) error {
+	v := reflect.ValueOf(dest)
+	isStruct := v.Kind() == reflect.Pointer && !v.IsNil() && v.Elem().Kind() == reflect.Struct
+	if !e.ignoreExtra || !isStruct {
+		err := e.rows.StructScan(dest)
+		if err != nil && isStruct {
+			if columns, colErr := e.rows.Columns(); colErr == nil {
+				return scanError(columns, e.rows.Mapper.TypeMap(v.Elem().Type()), err)
+			}
+		}
+		return err
+	}
+	columns, err := e.rows.Columns()
+	if err != nil {
+		return err
+	}
+	v = v.Elem()
+	values := make([]any, len(columns))
+	for i, traversal := range e.rows.Mapper.TraversalsByName(v.Type(), columns) {
+		if len(traversal) == 0 {
+			values[i] = new(any)
+		} else {
+			values[i] = reflectx.FieldByIndexes(v, traversal).Addr().Interface()
+		}
+	}
+	return e.rows.Scan(values...)
+}
+
+// ctxErr returns the error of the sequence's context once it is done.
+func (e *RowsSeq) ctxErr() error {
+	if e.ctx == nil {
+		return nil
+	}
+	return e.ctx.Err()
+}
+
 func (e *RowsSeq) Err() error {
 	return e.err
 }
+
+// ScanError is returned by RowsSeq when a row cannot be scanned into its destination struct,
+// listing the result columns no field maps to and the fields no column maps to.
+// It unwraps to the sqlx error.
+type ScanError struct {
+	// UnmatchedColumns lists the result columns without a destination field.
+	UnmatchedColumns []string
+	// UnmatchedFields lists the names the mapper gives to the top-level fields missing from the result.
+	UnmatchedFields []string
+	Err             error
+}
+
+func (e *ScanError) Error() string {
+	if len(e.UnmatchedColumns) == 0 {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%v (columns without field: %s; fields without column: %s)", e.Err,
+		strings.Join(e.UnmatchedColumns, ", "), strings.Join(e.UnmatchedFields, ", "))
+}
+
+func (e *ScanError) Unwrap() error {
+	return e.Err
+}
+
+// scanError describes the mismatch between the result columns and the fields of the destination struct.
+func scanError(columns []string, fields *reflectx.StructMap, err error) error {
+	scanErr := &ScanError{Err: err}
+	for _, column := range columns {
+		if fields.GetByPath(column) == nil {
+			scanErr.UnmatchedColumns = append(scanErr.UnmatchedColumns, column)
+		}
+	}
+	for _, field := range fields.Tree.Children {
+		if field != nil && !field.Embedded && !slices.Contains(columns, field.Path) {
+			scanErr.UnmatchedFields = append(scanErr.UnmatchedFields, field.Path)
+		}
+	}
+	return scanErr
+}
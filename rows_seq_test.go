@@ -1,11 +1,13 @@
 package sqlt_test
 
 import (
+	"context"
 	"testing"
 
 	"github.com/james-darko/sqlt"
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestSeq(t *testing.T) {
@@ -42,3 +44,63 @@ func TestSeq(t *testing.T) {
 	assert.Equal(t, "Bob", results[1].Name, "Second result should be Bob")
 	assert.Equal(t, "Charlie", results[2].Name, "Third result should be Charlie")
 }
+
+func TestSeqContextCancel(t *testing.T) {
+	t.Parallel()
+	db, err := sqlt.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+	_, err = db.Exec(`CREATE TABLE test (id INTEGER PRIMARY KEY, name TEXT)`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO test (name) VALUES ('Alice'), ('Bob'), ('Charlie')`)
+	require.NoError(t, err)
+
+	type destType struct {
+		ID   int    `db:"id"`
+		Name string `db:"name"`
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var dest destType
+	var names []string
+	rows := db.SelectInSeqContext(ctx, `SELECT id, name FROM test WHERE id IN (?) ORDER BY id`, []int{1, 2, 3})
+	for range rows.Iter(&dest) {
+		names = append(names, dest.Name)
+		cancel()
+	}
+	assert.ErrorIs(t, rows.Err(), context.Canceled)
+	assert.Equal(t, []string{"Alice"}, names)
+}
+
+func TestSeqColumnMismatch(t *testing.T) {
+	t.Parallel()
+	db, err := sqlt.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+	_, err = db.Exec(`CREATE TABLE test (id INTEGER PRIMARY KEY, name TEXT, email TEXT)`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO test (name, email) VALUES ('Alice', 'alice@example.com')`)
+	require.NoError(t, err)
+
+	type destType struct {
+		ID    int    `db:"id"`
+		Name  string `db:"name"`
+		Phone string `db:"phone"`
+	}
+	var dest destType
+	rows := db.SelectSeq(`SELECT id, name, email FROM test`)
+	for range rows.Iter(&dest) {
+	}
+	var scanErr *sqlt.ScanError
+	require.ErrorAs(t, rows.Err(), &scanErr)
+	assert.Equal(t, []string{"email"}, scanErr.UnmatchedColumns)
+	assert.Equal(t, []string{"phone"}, scanErr.UnmatchedFields)
+
+	var names []string
+	rows = db.SelectSeq(`SELECT id, name, email FROM test`).IgnoreExtraColumns()
+	for range rows.Iter(&dest) {
+		names = append(names, dest.Name)
+	}
+	require.NoError(t, rows.Err())
+	assert.Equal(t, []string{"Alice"}, names)
+}
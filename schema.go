@@ -0,0 +1,642 @@
+package sqlt
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	rsql "github.com/rqlite/sql"
+)
+
+// SchemaDefinition is a structured model of the objects in a schema.
+// Each map is keyed by object name, folded to lower case unless the schema was parsed with WithCaseSensitiveNames.
+// Use the lookup methods such as Table to find an object by name.
+// Names and type names are interned, so schemas kept in memory share their identical strings.
+type SchemaDefinition struct {
+	Tables   map[string]*TableDefinition   `json:"tables"`
+	Indexes  map[string]*IndexDefinition   `json:"indexes"`
+	Views    map[string]*ViewDefinition    `json:"views"`
+	Triggers map[string]*TriggerDefinition `json:"triggers"`
+
+	// CaseSensitive reports whether object names are matched exactly instead of case-insensitively.
+	CaseSensitive bool `json:"case_sensitive,omitempty"`
+}
+
+// TableDefinition describes a table.
+type TableDefinition struct {
+	Name    string              `json:"name"`
+	Columns []*ColumnDefinition `json:"columns"`
+	// PrimaryKey lists the primary key columns, whether declared on a column or on the table.
+	PrimaryKey        []string                `json:"primary_key,omitempty"`
+	UniqueConstraints [][]string              `json:"unique_constraints,omitempty"`
+	ForeignKeys       []*ForeignKeyDefinition `json:"foreign_keys,omitempty"`
+	Checks            []string                `json:"checks,omitempty"`
+	WithoutRowid      bool                    `json:"without_rowid,omitempty"`
+	Strict            bool                    `json:"strict,omitempty"`
+	// ImplicitIndexes lists the indexes SQLite created for PRIMARY KEY and UNIQUE constraints.
+	// It is only filled in by FetchDBSchema.
+	ImplicitIndexes []*IndexDefinition `json:"implicit_indexes,omitempty"`
+	SQL             string             `json:"sql"`
+}
+
+// ColumnDefinition describes a table column.
+type ColumnDefinition struct {
+	Name       string `json:"name"`
+	Type       string `json:"type,omitempty"`
+	NotNull    bool   `json:"not_null,omitempty"`
+	PrimaryKey bool   `json:"primary_key,omitempty"`
+	Unique     bool   `json:"unique,omitempty"`
+	// DefaultValue is the SQL of the default expression, or nil if the column has no default.
+	DefaultValue *string `json:"default_value,omitempty"`
+	Collation    string  `json:"collation,omitempty"`
+	// RowidAlias reports whether the column is an INTEGER PRIMARY KEY aliasing the rowid.
+	RowidAlias bool `json:"rowid_alias,omitempty"`
+	// Hidden follows the hidden flag of PRAGMA table_xinfo: 0 for ordinary columns,
+	// 1 for hidden virtual table columns, 2 for VIRTUAL and 3 for STORED generated columns.
+	Hidden int `json:"hidden,omitempty"`
+}
+
+// ForeignKeyDefinition describes a foreign key, declared on a column or on the table.
+type ForeignKeyDefinition struct {
+	Columns        []string `json:"columns"`
+	ForeignTable   string   `json:"foreign_table"`
+	ForeignColumns []string `json:"foreign_columns,omitempty"`
+	OnUpdate       string   `json:"on_update,omitempty"`
+	OnDelete       string   `json:"on_delete,omitempty"`
+}
+
+// IndexDefinition describes an index.
+type IndexDefinition struct {
+	Name    string   `json:"name"`
+	Table   string   `json:"table"`
+	Columns []string `json:"columns"`
+	// Keys holds the sort order and collation of each of Columns. It is only filled in for
+	// indexes created with CREATE INDEX.
+	Keys   []IndexKey `json:"keys,omitempty"`
+	Unique bool       `json:"unique,omitempty"`
+	// Where is the SQL of the partial index condition, or empty.
+	Where string `json:"where,omitempty"`
+	// Origin is "c" for indexes created with CREATE INDEX, "u" for UNIQUE constraints and "pk" for primary keys.
+	// It is only filled in by FetchDBSchema.
+	Origin string `json:"origin,omitempty"`
+	SQL    string `json:"sql"`
+}
+
+// IndexKey describes a column or expression of an index.
+type IndexKey struct {
+	Column string `json:"column"`
+	Desc   bool   `json:"desc,omitempty"`
+	// Collation is the collation named in the index, or empty for the collation of the column.
+	Collation string `json:"collation,omitempty"`
+}
+
+// Equal reports whether i and other index the same table on the same keys in the same way,
+// ignoring their names and the formatting of their SQL. Identifiers and collations are compared
+// case-insensitively, and ASC is the same as no sort order.
+func (i *IndexDefinition) Equal(other *IndexDefinition) bool {
+	if foldIdent(i.Table) != foldIdent(other.Table) || i.Unique != other.Unique || i.Where != other.Where ||
+		len(i.Columns) != len(other.Columns) {
+		return false
+	}
+	for n, col := range i.Columns {
+		key, o := i.key(n), other.key(n)
+		if foldIdent(col) != foldIdent(other.Columns[n]) || key.Desc != o.Desc || foldIdent(key.Collation) != foldIdent(o.Collation) {
+			return false
+		}
+	}
+	return true
+}
+
+// key returns the key of the nth column, or an ascending key with the column's collation if it is unknown.
+func (i *IndexDefinition) key(n int) IndexKey {
+	if n < len(i.Keys) {
+		return i.Keys[n]
+	}
+	return IndexKey{Column: i.Columns[n]}
+}
+
+// ViewDefinition describes a view.
+type ViewDefinition struct {
+	Name string `json:"name"`
+	SQL  string `json:"sql"`
+}
+
+// TriggerDefinition describes a trigger.
+type TriggerDefinition struct {
+	Name  string `json:"name"`
+	Table string `json:"table,omitempty"`
+	SQL   string `json:"sql"`
+}
+
+// SchemaOption configures ParseSchemaReader and FetchDBSchema.
+type SchemaOption func(*schemaConfig)
+
+type schemaConfig struct {
+	caseSensitive bool
+}
+
+// WithCaseSensitiveNames matches object names exactly. By default names are matched
+// case-insensitively like SQLite does, so "Users" and "users" are the same object.
+func WithCaseSensitiveNames() SchemaOption {
+	return func(cfg *schemaConfig) {
+		cfg.caseSensitive = true
+	}
+}
+
+// foldIdent folds an identifier the way SQLite compares them: ASCII letters case-insensitively, everything else exactly.
+func foldIdent(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'A' && r <= 'Z' {
+			return r + ('a' - 'A')
+		}
+		return r
+	}, name)
+}
+
+// key returns the map key for an object name.
+func (s *SchemaDefinition) key(name string) string {
+	if s.CaseSensitive {
+		return name
+	}
+	return foldIdent(name)
+}
+
+// Table returns the named table, or nil.
+func (s *SchemaDefinition) Table(name string) *TableDefinition {
+	return s.Tables[s.key(name)]
+}
+
+// Index returns the named index, or nil.
+func (s *SchemaDefinition) Index(name string) *IndexDefinition {
+	return s.Indexes[s.key(name)]
+}
+
+// View returns the named view, or nil.
+func (s *SchemaDefinition) View(name string) *ViewDefinition {
+	return s.Views[s.key(name)]
+}
+
+// Trigger returns the named trigger, or nil.
+func (s *SchemaDefinition) Trigger(name string) *TriggerDefinition {
+	return s.Triggers[s.key(name)]
+}
+
+// ParseSchemaReader parses the object definitions in schema. Data statements are skipped.
+// Defining an object twice, including names differing only in case, is an error.
+func ParseSchemaReader(schema io.Reader, opts ...SchemaOption) (*SchemaDefinition, error) {
+	stmts, err := parseSchemaStatements(schema)
+	if err != nil {
+		return nil, err
+	}
+	s, err := newSchemaDefinition(stmts, opts)
+	if err != nil {
+		return nil, err
+	}
+	s.intern()
+	return s, nil
+}
+
+// FetchDBSchema returns the definitions of the objects in the database.
+// Internal objects are skipped, see masterRows.
+//
+// Tables are parsed from their SQL and then cross-checked against PRAGMA table_xinfo, index_list,
+// index_info and foreign_key_list, so nullability, primary keys, rowid aliases, implicit indexes
+// foreign keys, default values and hidden columns reflect what SQLite actually built.
+// Everything is read with a single query.
+func FetchDBSchema(ctx context.Context, db DB, opts ...SchemaOption) (*SchemaDefinition, error) {
+	return fetchDBSchema(ctx, db, opts)
+}
+
+func fetchDBSchema(ctx context.Context, db Reader, opts []SchemaOption) (*SchemaDefinition, error) {
+	stmts, pragmas, err := schemaSnapshot(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	s, err := newSchemaDefinition(stmts, opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, table := range s.Tables {
+		if p, ok := pragmas[table.Name]; ok {
+			applyTablePragmas(table, p)
+		}
+	}
+	for _, index := range s.Indexes {
+		index.Origin = "c"
+	}
+	s.intern()
+	return s, nil
+}
+
+// Tables implements DB.Tables.
+func (s *sqlxDB) Tables(ctx context.Context) ([]*TableDefinition, error) {
+	schema, err := FetchDBSchema(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	return sortedByName(schema.Tables, func(t *TableDefinition) string { return t.Name }), nil
+}
+
+// Columns implements DB.Columns.
+func (s *sqlxDB) Columns(ctx context.Context, table string) ([]*ColumnDefinition, error) {
+	_, def, err := s.table(ctx, table)
+	if err != nil {
+		return nil, err
+	}
+	return def.Columns, nil
+}
+
+// Indexes implements DB.Indexes.
+func (s *sqlxDB) Indexes(ctx context.Context, table string) ([]*IndexDefinition, error) {
+	schema, def, err := s.table(ctx, table)
+	if err != nil {
+		return nil, err
+	}
+	indexes := slices.Clone(def.ImplicitIndexes)
+	for _, index := range schema.Indexes {
+		if schema.key(index.Table) == schema.key(def.Name) {
+			indexes = append(indexes, index)
+		}
+	}
+	slices.SortFunc(indexes, func(a, b *IndexDefinition) int { return strings.Compare(a.Name, b.Name) })
+	return indexes, nil
+}
+
+// table fetches the schema and looks up table in it.
+func (s *sqlxDB) table(ctx context.Context, table string) (*SchemaDefinition, *TableDefinition, error) {
+	schema, err := FetchDBSchema(ctx, s)
+	if err != nil {
+		return nil, nil, err
+	}
+	def := schema.Table(table)
+	if def == nil {
+		return nil, nil, fmt.Errorf("table %s not found", table)
+	}
+	return schema, def, nil
+}
+
+func newSchemaDefinition(stmts []rsql.Statement, opts []SchemaOption) (*SchemaDefinition, error) {
+	var cfg schemaConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	s := &SchemaDefinition{
+		Tables:        make(map[string]*TableDefinition),
+		Indexes:       make(map[string]*IndexDefinition),
+		Views:         make(map[string]*ViewDefinition),
+		Triggers:      make(map[string]*TriggerDefinition),
+		CaseSensitive: cfg.caseSensitive,
+	}
+	for _, stmt := range stmts {
+		if err := s.add(stmt); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// objectType returns the type of the object with the given name, or "" if there is none.
+func (s *SchemaDefinition) objectType(name string) string {
+	switch {
+	case s.Table(name) != nil:
+		return "table"
+	case s.Index(name) != nil:
+		return "index"
+	case s.View(name) != nil:
+		return "view"
+	case s.Trigger(name) != nil:
+		return "trigger"
+	}
+	return ""
+}
+
+// checkName returns an error if an object named name already exists.
+// Tables, indexes, views and triggers share one namespace in SQLite.
+func (s *SchemaDefinition) checkName(name, objType string) error {
+	existingType := s.objectType(name)
+	if existingType == "" {
+		return nil
+	}
+	if existingType != objType {
+		return fmt.Errorf("name collision: %s %q has the same name as an existing %s", objType, name, existingType)
+	}
+	return fmt.Errorf("duplicate %s %q", objType, name)
+}
+
+func (s *SchemaDefinition) add(stmt rsql.Statement) error {
+	name, err := getStatementName(stmt)
+	if err != nil {
+		return err
+	}
+	if err := s.checkName(name, strings.ToLower(getObjectType(stmt))); err != nil {
+		return err
+	}
+	switch stmt := stmt.(type) {
+	case *rsql.CreateTableStatement:
+		s.Tables[s.key(name)] = newTableDefinition(stmt)
+	case *rsql.CreateIndexStatement:
+		s.Indexes[s.key(name)] = newIndexDefinition(stmt)
+	case *rsql.CreateViewStatement:
+		s.Views[s.key(name)] = &ViewDefinition{Name: name, SQL: statementSQL(stmt)}
+	case *rsql.CreateTriggerStatement:
+		trigger := &TriggerDefinition{Name: name, SQL: statementSQL(stmt)}
+		if stmt.Table != nil {
+			trigger.Table = stmt.Table.Name
+		}
+		s.Triggers[s.key(name)] = trigger
+	}
+	return nil
+}
+
+func newTableDefinition(stmt *rsql.CreateTableStatement) *TableDefinition {
+	table := &TableDefinition{
+		Name:         stmt.Name.Name,
+		WithoutRowid: stmt.Without.IsValid(),
+		Strict:       stmt.Strict.IsValid(),
+		SQL:          statementSQL(stmt),
+	}
+	for _, col := range stmt.Columns {
+		column := &ColumnDefinition{Name: col.Name.Name}
+		if col.Type != nil {
+			column.Type = col.Type.String()
+		}
+		for _, c := range col.Constraints {
+			switch c := c.(type) {
+			case *rsql.PrimaryKeyConstraint:
+				column.PrimaryKey = true
+				table.PrimaryKey = append(table.PrimaryKey, column.Name)
+			case *rsql.NotNullConstraint:
+				column.NotNull = true
+			case *rsql.UniqueConstraint:
+				column.Unique = true
+			case *rsql.DefaultConstraint:
+				def := c.Expr.String()
+				column.DefaultValue = &def
+			case *rsql.CollateConstraint:
+				column.Collation = c.Collation.Name
+			case *rsql.GeneratedConstraint:
+				column.Hidden = 2
+				if c.Stored.IsValid() {
+					column.Hidden = 3
+				}
+			case *rsql.CheckConstraint:
+				table.Checks = append(table.Checks, c.Expr.String())
+			case *rsql.ForeignKeyConstraint:
+				fk := newForeignKeyDefinition(c)
+				fk.Columns = []string{column.Name}
+				table.ForeignKeys = append(table.ForeignKeys, fk)
+			}
+		}
+		table.Columns = append(table.Columns, column)
+	}
+	for _, c := range stmt.Constraints {
+		switch c := c.(type) {
+		case *rsql.PrimaryKeyConstraint:
+			table.PrimaryKey = identNames(c.Columns)
+			for _, name := range table.PrimaryKey {
+				if col := table.Column(name); col != nil {
+					col.PrimaryKey = true
+				}
+			}
+		case *rsql.UniqueConstraint:
+			cols := make([]string, len(c.Columns))
+			for i, col := range c.Columns {
+				cols[i] = indexedColumnName(col)
+			}
+			table.UniqueConstraints = append(table.UniqueConstraints, cols)
+		case *rsql.CheckConstraint:
+			table.Checks = append(table.Checks, c.Expr.String())
+		case *rsql.ForeignKeyConstraint:
+			fk := newForeignKeyDefinition(c)
+			fk.Columns = identNames(c.Columns)
+			table.ForeignKeys = append(table.ForeignKeys, fk)
+		}
+	}
+	return table
+}
+
+// Column returns the named column, matched case-insensitively, or nil.
+func (t *TableDefinition) Column(name string) *ColumnDefinition {
+	for _, col := range t.Columns {
+		if foldIdent(col.Name) == foldIdent(name) {
+			return col
+		}
+	}
+	return nil
+}
+
+func newForeignKeyDefinition(c *rsql.ForeignKeyConstraint) *ForeignKeyDefinition {
+	fk := &ForeignKeyDefinition{
+		ForeignTable:   c.ForeignTable.Name,
+		ForeignColumns: identNames(c.ForeignColumns),
+	}
+	for _, arg := range c.Args {
+		action := arg.String()
+		if arg.OnUpdate.IsValid() {
+			fk.OnUpdate = strings.TrimPrefix(action, "ON UPDATE ")
+		} else {
+			fk.OnDelete = strings.TrimPrefix(action, "ON DELETE ")
+		}
+	}
+	return fk
+}
+
+func newIndexDefinition(stmt *rsql.CreateIndexStatement) *IndexDefinition {
+	index := &IndexDefinition{
+		Name:   stmt.Name.Name,
+		Unique: stmt.Unique.IsValid(),
+		SQL:    statementSQL(stmt),
+	}
+	if stmt.Table != nil {
+		index.Table = stmt.Table.Name
+	}
+	for _, col := range stmt.Columns {
+		index.Columns = append(index.Columns, indexedColumnName(col))
+		key := IndexKey{Column: indexedColumnName(col), Desc: col.Desc.IsValid()}
+		if col.Collation != nil {
+			key.Collation = col.Collation.Name
+		}
+		index.Keys = append(index.Keys, key)
+	}
+	if stmt.WhereExpr != nil {
+		index.Where = stmt.WhereExpr.String()
+	}
+	return index
+}
+
+// indexedColumnName returns the column name of an indexed column, or the SQL of an indexed expression.
+func indexedColumnName(col *rsql.IndexedColumn) string {
+	if ident, ok := col.X.(*rsql.Ident); ok {
+		return ident.Name
+	}
+	return col.X.String()
+}
+
+func identNames(idents []*rsql.Ident) []string {
+	if len(idents) == 0 {
+		return nil
+	}
+	names := make([]string, len(idents))
+	for i, ident := range idents {
+		names[i] = ident.Name
+	}
+	return names
+}
+
+// Encode writes s to w as JSON, so it can be cached or processed outside Go. See DecodeSchema.
+func (s *SchemaDefinition) Encode(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
+
+// SQL returns the statements creating the objects of s, tables first, then indexes, views and triggers,
+// each sorted by name, so s can be passed to AutoMigrate or Verify.
+func (s *SchemaDefinition) SQL() string {
+	var b strings.Builder
+	write := func(sql string) {
+		b.WriteString(sql)
+		b.WriteString(";\n")
+	}
+	for _, table := range sortedByName(s.Tables, func(t *TableDefinition) string { return t.Name }) {
+		write(table.SQL)
+	}
+	for _, index := range sortedByName(s.Indexes, func(i *IndexDefinition) string { return i.Name }) {
+		write(index.SQL)
+	}
+	for _, view := range sortedByName(s.Views, func(v *ViewDefinition) string { return v.Name }) {
+		write(view.SQL)
+	}
+	for _, trigger := range sortedByName(s.Triggers, func(t *TriggerDefinition) string { return t.Name }) {
+		write(trigger.SQL)
+	}
+	return b.String()
+}
+
+// DecodeSchema reads a SchemaDefinition written by Encode.
+// The maps are rekeyed from the object names, so the keys in the JSON are not significant,
+// and names are checked for duplicates and collisions like ParseSchemaReader does.
+func DecodeSchema(r io.Reader) (*SchemaDefinition, error) {
+	var decoded SchemaDefinition
+	if err := json.NewDecoder(r).Decode(&decoded); err != nil {
+		return nil, fmt.Errorf("could not decode schema: %w", err)
+	}
+	s := &SchemaDefinition{
+		Tables:        make(map[string]*TableDefinition, len(decoded.Tables)),
+		Indexes:       make(map[string]*IndexDefinition, len(decoded.Indexes)),
+		Views:         make(map[string]*ViewDefinition, len(decoded.Views)),
+		Triggers:      make(map[string]*TriggerDefinition, len(decoded.Triggers)),
+		CaseSensitive: decoded.CaseSensitive,
+	}
+	for key, table := range decoded.Tables {
+		if table == nil {
+			return nil, fmt.Errorf("could not decode schema: table %q is null", key)
+		}
+		if slices.Contains(table.Columns, nil) || slices.Contains(table.ForeignKeys, nil) || slices.Contains(table.ImplicitIndexes, nil) {
+			return nil, fmt.Errorf("could not decode schema: table %s has a null column, foreign key or implicit index", table.Name)
+		}
+		if err := s.checkName(table.Name, "table"); err != nil {
+			return nil, err
+		}
+		s.Tables[s.key(table.Name)] = table
+	}
+	for key, index := range decoded.Indexes {
+		if index == nil {
+			return nil, fmt.Errorf("could not decode schema: index %q is null", key)
+		}
+		if err := s.checkName(index.Name, "index"); err != nil {
+			return nil, err
+		}
+		s.Indexes[s.key(index.Name)] = index
+	}
+	for key, view := range decoded.Views {
+		if view == nil {
+			return nil, fmt.Errorf("could not decode schema: view %q is null", key)
+		}
+		if err := s.checkName(view.Name, "view"); err != nil {
+			return nil, err
+		}
+		s.Views[s.key(view.Name)] = view
+	}
+	for key, trigger := range decoded.Triggers {
+		if trigger == nil {
+			return nil, fmt.Errorf("could not decode schema: trigger %q is null", key)
+		}
+		if err := s.checkName(trigger.Name, "trigger"); err != nil {
+			return nil, err
+		}
+		s.Triggers[s.key(trigger.Name)] = trigger
+	}
+	s.intern()
+	return s, nil
+}
+
+// Normalize rewrites s into the canonical form sqlt compares schemas in, so two normalized
+// definitions are equal when sqlt considers the schemas to match. Column order is not significant
+// to that comparison, so columns are sorted by name, as are the primary key, unique constraint columns,
+// unique constraints, checks and foreign keys. Type names are upper-cased with INT spelled INTEGER,
+// and each object's SQL is re-rendered from its parsed form.
+func (s *SchemaDefinition) Normalize() error {
+	for _, table := range s.Tables {
+		slices.SortFunc(table.Columns, func(a, b *ColumnDefinition) int {
+			return cmp.Compare(foldIdent(a.Name), foldIdent(b.Name))
+		})
+		for _, col := range table.Columns {
+			col.Type = normalizeTypeName(col.Type)
+		}
+		slices.Sort(table.PrimaryKey)
+		for _, cols := range table.UniqueConstraints {
+			slices.Sort(cols)
+		}
+		slices.SortFunc(table.UniqueConstraints, func(a, b []string) int {
+			return cmp.Compare(strings.Join(a, ","), strings.Join(b, ","))
+		})
+		slices.Sort(table.Checks)
+		slices.SortFunc(table.ForeignKeys, func(a, b *ForeignKeyDefinition) int {
+			return cmp.Compare(fmt.Sprint(*a), fmt.Sprint(*b))
+		})
+		sql, err := canonicalSQL(table.SQL)
+		if err != nil {
+			return fmt.Errorf("table %s: %w", table.Name, err)
+		}
+		table.SQL = sql
+	}
+	for _, index := range s.Indexes {
+		sql, err := canonicalSQL(index.SQL)
+		if err != nil {
+			return fmt.Errorf("index %s: %w", index.Name, err)
+		}
+		index.SQL = sql
+	}
+	for _, view := range s.Views {
+		sql, err := canonicalSQL(view.SQL)
+		if err != nil {
+			return fmt.Errorf("view %s: %w", view.Name, err)
+		}
+		view.SQL = sql
+	}
+	for _, trigger := range s.Triggers {
+		sql, err := canonicalSQL(trigger.SQL)
+		if err != nil {
+			return fmt.Errorf("trigger %s: %w", trigger.Name, err)
+		}
+		trigger.SQL = sql
+	}
+	return nil
+}
+
+// canonicalSQL parses sql and renders it back, which normalizes quoting, spacing and keyword case.
+func canonicalSQL(sql string) (string, error) {
+	if sql == "" {
+		return "", nil
+	}
+	stmt, err := rsql.NewParser(strings.NewReader(sql)).ParseStatement()
+	if err != nil {
+		return "", fmt.Errorf("could not parse SQL: %w", err)
+	}
+	return statementSQL(stmt), nil
+}
@@ -0,0 +1,325 @@
+package sqlt_test
+
+import (
+	"strings"
+	"testing"
+	"unsafe"
+
+	"github.com/james-darko/gort"
+	"github.com/james-darko/sqlt"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSchemaReader(t *testing.T) {
+	t.Parallel()
+	schema, err := sqlt.ParseSchemaReader(strings.NewReader(`
+CREATE TABLE Users (
+	id INTEGER PRIMARY KEY,
+	email TEXT NOT NULL UNIQUE,
+	tier TEXT DEFAULT 'free',
+	team_id INTEGER REFERENCES teams (id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX idx_users_email ON Users (email) WHERE email IS NOT NULL;
+CREATE VIEW user_emails AS SELECT email FROM Users;`))
+	require.NoError(t, err)
+
+	users := schema.Table("users")
+	require.NotNil(t, users, "table lookup should be case-insensitive")
+	assert.Equal(t, "Users", users.Name)
+	assert.Equal(t, []string{"id"}, users.PrimaryKey)
+	require.Len(t, users.Columns, 4)
+	assert.True(t, users.Column("email").NotNull)
+	assert.True(t, users.Column("email").Unique)
+	require.NotNil(t, users.Column("tier").DefaultValue)
+	assert.Equal(t, "'free'", *users.Column("tier").DefaultValue)
+	require.Len(t, users.ForeignKeys, 1)
+	assert.Equal(t, "teams", users.ForeignKeys[0].ForeignTable)
+	assert.Equal(t, "CASCADE", users.ForeignKeys[0].OnDelete)
+
+	index := schema.Index("IDX_USERS_EMAIL")
+	require.NotNil(t, index)
+	assert.True(t, index.Unique)
+	assert.Equal(t, []string{"email"}, index.Columns)
+	assert.NotEmpty(t, index.Where)
+	assert.NotNil(t, schema.View("user_emails"))
+}
+
+func TestParseSchemaReader_CaseInsensitiveDuplicates(t *testing.T) {
+	t.Parallel()
+	schemaSQL := `
+CREATE TABLE Users (id INTEGER PRIMARY KEY);
+CREATE TABLE users (id INTEGER PRIMARY KEY);`
+
+	_, err := sqlt.ParseSchemaReader(strings.NewReader(schemaSQL))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "duplicate table")
+
+	schema, err := sqlt.ParseSchemaReader(strings.NewReader(schemaSQL), sqlt.WithCaseSensitiveNames())
+	require.NoError(t, err)
+	assert.Len(t, schema.Tables, 2)
+	assert.Nil(t, schema.Table("USERS"))
+}
+
+func TestFetchDBSchema(t *testing.T) {
+	t.Parallel()
+	db := getTestDB(t)
+	defer db.Close()
+	ctx := gort.Context()
+
+	require.NoError(t, sqlt.ExecString(ctx, db, `
+CREATE TABLE events (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT);
+CREATE TRIGGER events_trg AFTER INSERT ON events BEGIN SELECT 1; END;`))
+	schema, err := sqlt.FetchDBSchema(ctx, db)
+	require.NoError(t, err)
+	assert.Len(t, schema.Tables, 1, "sqlite_sequence must not be part of the schema")
+	require.NotNil(t, schema.Trigger("events_trg"))
+	assert.Equal(t, "events", schema.Trigger("events_trg").Table)
+}
+
+func TestFetchDBSchema_Pragmas(t *testing.T) {
+	t.Parallel()
+	db := getTestDB(t)
+	defer db.Close()
+	ctx := gort.Context()
+
+	require.NoError(t, sqlt.ExecString(ctx, db, `
+CREATE TABLE teams (id INTEGER PRIMARY KEY, slug TEXT UNIQUE);
+CREATE TABLE members (
+	team_id INTEGER,
+	user_id INTEGER,
+	PRIMARY KEY (user_id, team_id),
+	FOREIGN KEY (team_id) REFERENCES teams (id) ON DELETE CASCADE
+);
+CREATE INDEX members_team ON members (team_id);`))
+	schema, err := sqlt.FetchDBSchema(ctx, db)
+	require.NoError(t, err)
+
+	teams := schema.Table("teams")
+	require.NotNil(t, teams)
+	assert.True(t, teams.Column("id").RowidAlias)
+	require.Len(t, teams.ImplicitIndexes, 1)
+	assert.Equal(t, "u", teams.ImplicitIndexes[0].Origin)
+	assert.Equal(t, []string{"slug"}, teams.ImplicitIndexes[0].Columns)
+
+	members := schema.Table("members")
+	require.NotNil(t, members)
+	assert.Equal(t, []string{"user_id", "team_id"}, members.PrimaryKey)
+	assert.False(t, members.Column("user_id").RowidAlias)
+	require.Len(t, members.ForeignKeys, 1)
+	assert.Equal(t, "teams", members.ForeignKeys[0].ForeignTable)
+	assert.Equal(t, []string{"team_id"}, members.ForeignKeys[0].Columns)
+	assert.Equal(t, []string{"id"}, members.ForeignKeys[0].ForeignColumns)
+	assert.Equal(t, "CASCADE", members.ForeignKeys[0].OnDelete)
+	require.Len(t, members.ImplicitIndexes, 1)
+	assert.Equal(t, "pk", members.ImplicitIndexes[0].Origin)
+	assert.Equal(t, "c", schema.Index("members_team").Origin)
+}
+
+func TestFetchDBSchema_DefaultsAndGeneratedColumns(t *testing.T) {
+	t.Parallel()
+	db := getTestDB(t)
+	defer db.Close()
+	ctx := gort.Context()
+
+	_, err := db.ExecContext(ctx, `CREATE TABLE items (
+	id INTEGER PRIMARY KEY,
+	price INTEGER DEFAULT (1+2),
+	label TEXT DEFAULT 'none',
+	total INTEGER GENERATED ALWAYS AS (price * 2) STORED,
+	half INTEGER AS (price / 2)
+)`)
+	require.NoError(t, err)
+	schema, err := sqlt.FetchDBSchema(ctx, db)
+	require.NoError(t, err)
+
+	items := schema.Table("items")
+	require.NotNil(t, items)
+	require.NotNil(t, items.Column("price").DefaultValue)
+	assert.Equal(t, "1+2", *items.Column("price").DefaultValue)
+	require.NotNil(t, items.Column("label").DefaultValue)
+	assert.Equal(t, "'none'", *items.Column("label").DefaultValue)
+	assert.Nil(t, items.Column("id").DefaultValue)
+	assert.Equal(t, 0, items.Column("price").Hidden)
+	assert.Equal(t, 3, items.Column("total").Hidden)
+	assert.Equal(t, 2, items.Column("half").Hidden)
+}
+
+func TestParseSchemaReader_CrossTypeNameCollision(t *testing.T) {
+	t.Parallel()
+	_, err := sqlt.ParseSchemaReader(strings.NewReader(`
+CREATE TABLE reports (id INTEGER PRIMARY KEY);
+CREATE VIEW Reports AS SELECT 1;`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `view "Reports" has the same name as an existing table`)
+}
+
+func TestSchemaDefinition_JSONRoundTrip(t *testing.T) {
+	t.Parallel()
+	schema, err := sqlt.ParseSchemaReader(strings.NewReader(`
+CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT DEFAULT 'anon') STRICT;
+CREATE INDEX idx_users_name ON users (name);
+CREATE TRIGGER users_trg AFTER DELETE ON users BEGIN SELECT 1; END;`))
+	require.NoError(t, err)
+
+	var buf strings.Builder
+	require.NoError(t, schema.Encode(&buf))
+	assert.Contains(t, buf.String(), `"strict": true`)
+
+	decoded, err := sqlt.DecodeSchema(strings.NewReader(buf.String()))
+	require.NoError(t, err)
+	assert.Equal(t, schema, decoded)
+}
+
+func TestDecodeSchema_RekeysObjects(t *testing.T) {
+	t.Parallel()
+	decoded, err := sqlt.DecodeSchema(strings.NewReader(`{"tables": {"x": {"name": "Users", "columns": [], "sql": ""}}}`))
+	require.NoError(t, err)
+	assert.NotNil(t, decoded.Table("users"))
+
+	_, err = sqlt.DecodeSchema(strings.NewReader(`{"tables": {"a": {"name": "dup"}}, "views": {"b": {"name": "DUP"}}}`))
+	assert.ErrorContains(t, err, "name collision")
+}
+
+func TestDecodeSchema_NullEntries(t *testing.T) {
+	t.Parallel()
+	for _, input := range []string{
+		`{"tables": {"a": null}}`,
+		`{"indexes": {"a": null}}`,
+		`{"views": {"a": null}}`,
+		`{"triggers": {"a": null}}`,
+		`{"tables": {"a": {"name": "a", "columns": [null]}}}`,
+		`{"tables": {"a": {"name": "a", "columns": [], "foreign_keys": [null]}}}`,
+	} {
+		_, err := sqlt.DecodeSchema(strings.NewReader(input))
+		assert.ErrorContains(t, err, "could not decode schema", input)
+	}
+}
+
+func TestSchemaDefinition_Normalize(t *testing.T) {
+	t.Parallel()
+	a, err := sqlt.ParseSchemaReader(strings.NewReader(`
+create table t (b int, a text, UNIQUE (b, a), PRIMARY KEY (b, a));`))
+	require.NoError(t, err)
+	b, err := sqlt.ParseSchemaReader(strings.NewReader(`
+CREATE TABLE "t" (
+	"a" TEXT,
+	"b" INTEGER,
+	UNIQUE ("a", "b"),
+	PRIMARY KEY ("a", "b")
+);`))
+	require.NoError(t, err)
+
+	require.NoError(t, a.Normalize())
+	require.NoError(t, b.Normalize())
+	assert.Equal(t, b.Table("t").Columns, a.Table("t").Columns)
+	assert.Equal(t, []string{"a", "b"}, a.Table("t").PrimaryKey)
+	assert.Equal(t, b.Table("t").UniqueConstraints, a.Table("t").UniqueConstraints)
+	assert.Equal(t, "INTEGER", a.Table("t").Column("b").Type)
+}
+
+func TestDescribeSchema(t *testing.T) {
+	t.Parallel()
+	db := getTestDB(t)
+	defer db.Close()
+	ctx := gort.Context()
+
+	require.NoError(t, sqlt.ExecString(ctx, db, `
+CREATE TABLE teams (id INTEGER PRIMARY KEY, name TEXT NOT NULL DEFAULT 'x');
+CREATE TABLE members (team_id INTEGER, FOREIGN KEY (team_id) REFERENCES teams (id) ON DELETE CASCADE);
+CREATE INDEX members_team ON members (team_id);
+CREATE TRIGGER teams_trg AFTER DELETE ON teams BEGIN SELECT 1; END;
+CREATE VIEW team_names AS SELECT name FROM teams;
+INSERT INTO teams (name) VALUES ('a'), ('b');`))
+
+	var buf strings.Builder
+	require.NoError(t, sqlt.DescribeSchema(ctx, db, &buf))
+	assert.Equal(t, `table members (0 rows)
+  column team_id INTEGER
+  foreign key (team_id) references teams (id) on delete CASCADE
+  index members_team (team_id)
+table teams (2 rows)
+  column id INTEGER PRIMARY KEY
+  column name TEXT NOT NULL DEFAULT 'x'
+  trigger teams_trg
+view team_names
+`, buf.String())
+
+	buf.Reset()
+	require.NoError(t, sqlt.DescribeSchema(ctx, db, &buf, sqlt.WithoutRowCounts(), sqlt.WithObjectSQL()))
+	assert.Contains(t, buf.String(), "table teams\n")
+	assert.Contains(t, buf.String(), "    CREATE VIEW")
+}
+
+func TestDB_Introspection(t *testing.T) {
+	t.Parallel()
+	db := getTestDB(t)
+	defer db.Close()
+	ctx := gort.Context()
+
+	require.NoError(t, sqlt.ExecString(ctx, db, `
+CREATE TABLE teams (id INTEGER PRIMARY KEY, slug TEXT UNIQUE);
+CREATE TABLE members (team_id INTEGER, name TEXT NOT NULL);
+CREATE INDEX members_team ON members (team_id);`))
+
+	tables, err := db.Tables(ctx)
+	require.NoError(t, err)
+	require.Len(t, tables, 2)
+	assert.Equal(t, "members", tables[0].Name)
+	assert.Equal(t, "teams", tables[1].Name)
+
+	columns, err := db.Columns(ctx, "MEMBERS")
+	require.NoError(t, err)
+	require.Len(t, columns, 2)
+	assert.Equal(t, "name", columns[1].Name)
+	assert.True(t, columns[1].NotNull)
+
+	indexes, err := db.Indexes(ctx, "members")
+	require.NoError(t, err)
+	require.Len(t, indexes, 1)
+	assert.Equal(t, "members_team", indexes[0].Name)
+	indexes, err = db.Indexes(ctx, "teams")
+	require.NoError(t, err)
+	require.Len(t, indexes, 1)
+	assert.Equal(t, "u", indexes[0].Origin)
+
+	_, err = db.Columns(ctx, "missing")
+	assert.ErrorContains(t, err, "table missing not found")
+}
+
+func TestIndexDefinition_Equal(t *testing.T) {
+	t.Parallel()
+	schema, err := sqlt.ParseSchemaReader(strings.NewReader(`
+CREATE INDEX a ON t (x, y);
+CREATE INDEX b ON T (X ASC, "y");
+CREATE INDEX c ON t (x DESC, y);
+CREATE INDEX d ON t (x COLLATE NOCASE, y);
+CREATE INDEX e ON t (x COLLATE nocase, y);`))
+	require.NoError(t, err)
+
+	c := schema.Index("c")
+	require.Len(t, c.Keys, 2)
+	assert.Equal(t, sqlt.IndexKey{Column: "x", Desc: true}, c.Keys[0])
+	assert.Equal(t, "NOCASE", schema.Index("d").Keys[0].Collation)
+
+	assert.True(t, schema.Index("a").Equal(schema.Index("b")))
+	assert.False(t, schema.Index("a").Equal(c))
+	assert.False(t, schema.Index("a").Equal(schema.Index("d")))
+	assert.True(t, schema.Index("d").Equal(schema.Index("e")))
+}
+
+func TestParseSchemaReader_InternsNames(t *testing.T) {
+	t.Parallel()
+	schema, err := sqlt.ParseSchemaReader(strings.NewReader(`
+CREATE TABLE a (id INTEGER PRIMARY KEY, user_id INTEGER REFERENCES users (id));
+CREATE TABLE b (id INTEGER PRIMARY KEY, user_id INTEGER REFERENCES users (id));
+CREATE INDEX idx_b_user ON b (user_id);`))
+	require.NoError(t, err)
+
+	a, b := schema.Table("a").Column("user_id"), schema.Table("b").Column("user_id")
+	assert.Same(t, unsafe.StringData(a.Name), unsafe.StringData(b.Name))
+	assert.Same(t, unsafe.StringData(a.Type), unsafe.StringData(b.Type))
+	assert.Same(t, unsafe.StringData(a.Name), unsafe.StringData(schema.Index("idx_b_user").Columns[0]))
+	assert.Same(t, unsafe.StringData(schema.Table("a").ForeignKeys[0].ForeignTable), unsafe.StringData(schema.Table("b").ForeignKeys[0].ForeignTable))
+}
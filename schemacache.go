@@ -0,0 +1,57 @@
+package sqlt
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	rsql "github.com/rqlite/sql"
+)
+
+// schemaFiles caches the parsed statements of schema files by path, so repeated
+// VerifyFromEnv and FullLoadDB calls do not re-parse an unchanged schema.
+// Unlike the LoadDB cache it is not cleared by ResetDB.
+var schemaFiles sync.Map // map[string]*schemaFile
+
+type schemaFile struct {
+	modTime time.Time
+	size    int64
+	hash    [sha256.Size]byte
+	stmts   []rsql.Statement
+}
+
+// loadSchemaFile returns the parsed schema statements in the file at path.
+// The cached statements are reused while the file's modification time and size are unchanged,
+// or when the file was touched but its content hash still matches.
+// The returned statements are shared and must not be modified.
+func loadSchemaFile(path string) ([]rsql.Statement, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open schema file %s: %w", path, err)
+	}
+	var cached *schemaFile
+	if v, ok := schemaFiles.Load(path); ok {
+		cached = v.(*schemaFile)
+		if cached.modTime.Equal(info.ModTime()) && cached.size == info.Size() {
+			return cached.stmts, nil
+		}
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read schema file %s: %w", path, err)
+	}
+	entry := &schemaFile{modTime: info.ModTime(), size: info.Size(), hash: sha256.Sum256(content)}
+	if cached != nil && cached.hash == entry.hash {
+		entry.stmts = cached.stmts
+	} else {
+		entry.stmts, err = parseSchemaStatements(bytes.NewReader(content))
+		if err != nil {
+			return nil, fmt.Errorf("schema file %s: %w", path, err)
+		}
+	}
+	schemaFiles.Store(path, entry)
+	return entry.stmts, nil
+}
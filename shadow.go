@@ -0,0 +1,80 @@
+package sqlt
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SyncTriggers returns triggers copying every insert, update and delete on table from into table to,
+// for expand/contract migrations moving data to a new shadow table while the old application version
+// still writes the old one. Columns are matched by name; columns only in to keep their values on updates.
+// Rows are matched by the primary key of to, which must consist of columns of both tables, or by rowid
+// if to has no primary key. Copy the existing rows once the triggers are installed, e.g. with
+// INSERT OR IGNORE INTO to (...) SELECT ... FROM from.
+//
+// The triggers only copy from the old table to the new one; drop them once the old version is gone.
+func SyncTriggers(from, to *TableDefinition) ([]string, error) {
+	var columns []string
+	for _, col := range to.Columns {
+		if from.Column(col.Name) != nil && col.Hidden < 2 {
+			columns = append(columns, col.Name)
+			continue
+		}
+		if requiresValue(col) {
+			return nil, fmt.Errorf("column %s of %s is NOT NULL without default and missing from %s", col.Name, to.Name, from.Name)
+		}
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("tables %s and %s have no column in common", from.Name, to.Name)
+	}
+	key := to.PrimaryKey
+	for _, col := range key {
+		if from.Column(col) == nil {
+			return nil, fmt.Errorf("primary key column %s of %s is missing from %s", col, to.Name, from.Name)
+		}
+	}
+	if len(key) == 0 {
+		if from.WithoutRowid || to.WithoutRowid {
+			return nil, fmt.Errorf("table %s has no primary key to match rows of WITHOUT ROWID tables", to.Name)
+		}
+		key = []string{"rowid"}
+		columns = append([]string{"rowid"}, columns...)
+	}
+
+	qFrom, qTo := quoteIdent(from.Name), quoteIdent(to.Name)
+	quoted := make([]string, len(columns))
+	values := make([]string, len(columns))
+	sets := make([]string, len(columns))
+	for i, col := range columns {
+		quoted[i] = quoteIdent(col)
+		values[i] = "NEW." + quoteIdent(col)
+		sets[i] = fmt.Sprintf("%s = NEW.%s", quoteIdent(col), quoteIdent(col))
+	}
+	match := func(row string) string {
+		conds := make([]string, len(key))
+		for i, col := range key {
+			conds[i] = fmt.Sprintf("%s = %s.%s", quoteIdent(col), row, quoteIdent(col))
+		}
+		return strings.Join(conds, " AND ")
+	}
+	insert := fmt.Sprintf("INSERT OR IGNORE INTO %s (%s) VALUES (%s);", qTo, strings.Join(quoted, ", "), strings.Join(values, ", "))
+	prefix := fmt.Sprintf("_sqlt_sync_%s_%s_", from.Name, to.Name)
+	return []string{
+		fmt.Sprintf("CREATE TRIGGER %s AFTER INSERT ON %s FOR EACH ROW BEGIN %s END",
+			quoteIdent(prefix+"insert"), qFrom, insert),
+		fmt.Sprintf("CREATE TRIGGER %s AFTER UPDATE ON %s FOR EACH ROW BEGIN UPDATE %s SET %s WHERE %s; %s END",
+			quoteIdent(prefix+"update"), qFrom, qTo, strings.Join(sets, ", "), match("OLD"), insert),
+		fmt.Sprintf("CREATE TRIGGER %s AFTER DELETE ON %s FOR EACH ROW BEGIN DELETE FROM %s WHERE %s; END",
+			quoteIdent(prefix+"delete"), qFrom, qTo, match("OLD")),
+	}, nil
+}
+
+// SyncColumnTriggers returns triggers keeping columns from and to of table in sync in both directions,
+// for expand/contract migrations renaming a column while the old and new application versions both run.
+// See PlanExpandContract, which uses them for WithRenamedColumn.
+func SyncColumnTriggers(table *TableDefinition, from, to string) ([]string, error) {
+	if table.Column(from) == nil {
+		return nil, fmt.Errorf("column %s not found in table %s", from, table.Name)
+	}
+	return dualWriteColumnTriggers(table, from, to), nil
+}
@@ -0,0 +1,113 @@
+package sqlt
+
+import (
+	"strings"
+)
+
+// splitStatements splits a script into statements at semicolons without parsing it, for scripts
+// the parser cannot handle. Semicolons in string literals, quoted identifiers and comments are
+// skipped. Inside CREATE TRIGGER, a semicolon only ends the statement after the END keyword,
+// the same rule sqlite3_complete uses. The terminating semicolons and trailing comments are
+// removed from the statements and statements holding only comments are dropped.
+func splitStatements(script string) []string {
+	var stmts []string
+	start := 0
+	// words holds the first three words of the current statement, last its last token if it is a word.
+	var words []string
+	var last string
+	// codeEnd is the end of the last token that is not a comment, so statements do not end in one.
+	codeEnd := 0
+	hasCode := false
+	flush := func(next int) {
+		if hasCode {
+			stmts = append(stmts, strings.TrimSpace(script[start:codeEnd]))
+		}
+		start = next
+		words = words[:0]
+		last = ""
+		hasCode = false
+	}
+	for i := 0; i < len(script); {
+		c := script[i]
+		switch {
+		case c == '-' && strings.HasPrefix(script[i:], "--"):
+			end := strings.IndexByte(script[i:], '\n')
+			if end < 0 {
+				end = len(script) - i
+			}
+			i += end
+		case c == '/' && strings.HasPrefix(script[i:], "/*"):
+			end := strings.Index(script[i+2:], "*/")
+			if end < 0 {
+				i = len(script)
+			} else {
+				i += end + 4
+			}
+		case c == '\'' || c == '"' || c == '`' || c == '[':
+			closing := c
+			if c == '[' {
+				closing = ']'
+			}
+			j := i + 1
+			for j < len(script) {
+				if script[j] == closing {
+					// A doubled quote is an escaped quote.
+					if closing != ']' && j+1 < len(script) && script[j+1] == closing {
+						j += 2
+						continue
+					}
+					break
+				}
+				j++
+			}
+			i = min(j+1, len(script))
+			codeEnd = i
+			hasCode = true
+			last = ""
+		case c == ';':
+			i++
+			if isTriggerStart(words) && last != "END" {
+				codeEnd = i
+				continue
+			}
+			flush(i)
+		case isIdentByte(c):
+			j := i
+			for j < len(script) && isIdentByte(script[j]) {
+				j++
+			}
+			word := strings.ToUpper(script[i:j])
+			if len(words) < 3 {
+				words = append(words, word)
+			}
+			last = word
+			hasCode = true
+			i = j
+			codeEnd = i
+		default:
+			i++
+			if c != ' ' && c != '\t' && c != '\n' && c != '\r' {
+				hasCode = true
+				last = ""
+				codeEnd = i
+			}
+		}
+	}
+	flush(len(script))
+	return stmts
+}
+
+// isTriggerStart reports whether a statement beginning with words is CREATE [TEMP] TRIGGER.
+func isTriggerStart(words []string) bool {
+	if len(words) < 2 || words[0] != "CREATE" {
+		return false
+	}
+	if words[1] == "TEMP" || words[1] == "TEMPORARY" {
+		return len(words) > 2 && words[2] == "TRIGGER"
+	}
+	return words[1] == "TRIGGER"
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c == '$' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
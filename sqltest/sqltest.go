@@ -0,0 +1,130 @@
+// Package sqltest checks sqlt migrations in tests. The "sqlite3" driver must be registered by the caller.
+package sqltest
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/james-darko/sqlt"
+)
+
+// Option configures CheckMigrations.
+type Option func(*config)
+
+type config struct {
+	down sqlt.MigrationMap
+	seed func(ctx context.Context, db sqlt.DB, version int) error
+}
+
+// WithDown sets the down migrations, keyed by the version they migrate from, e.g. as returned by
+// sqlt.MigrationsFromFS. Each must restore the schema of the version its up migration started from.
+func WithDown(down sqlt.MigrationMap) Option {
+	return func(cfg *config) {
+		cfg.down = down
+	}
+}
+
+// WithSeed sets a function inserting data into the database at version before an up migration
+// is re-applied, so migrations are checked on populated tables.
+func WithSeed(seed func(ctx context.Context, db sqlt.DB, version int) error) Option {
+	return func(cfg *config) {
+		cfg.seed = seed
+	}
+}
+
+// CheckMigrations applies the up migrations to a new in-memory database one version at a time, starting from
+// the lowest version, and fails t if a migration fails or does not advance the version. After each up migration
+// with a down migration, see WithDown, the down migration must restore the previous version and schema, and the
+// up migration, re-applied on seeded data, see WithSeed, must produce the same schema again, which catches
+// irreversible and non-idempotent migrations. Finally the database must match schema, see sqlt.Verify.
+//
+// The database starts with a version table holding the lowest version, as sqlt.Migrate expects.
+func CheckMigrations(t testing.TB, schema string, up sqlt.MigrationMap, opts ...Option) {
+	t.Helper()
+	var cfg config
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	ctx := context.Background()
+	db, err := sqlt.OpenMemory(t.Name())
+	if err != nil {
+		t.Fatalf("could not open database: %v", err)
+	}
+	defer db.Close()
+	if len(up) == 0 {
+		t.Fatal("no migrations")
+	}
+	versions := make([]int, 0, len(up))
+	for version := range up {
+		versions = append(versions, version)
+	}
+	slices.Sort(versions)
+	if _, err := db.ExecContext(ctx, "CREATE TABLE version (version INTEGER NOT NULL); INSERT INTO version VALUES (?)", versions[0]); err != nil {
+		t.Fatalf("could not create version table: %v", err)
+	}
+
+	from := versions[0]
+	for {
+		fn, ok := up[from]
+		if !ok {
+			break
+		}
+		before := dumpSchema(t, db)
+		to := migrateOnce(t, db, from, fn)
+		after := dumpSchema(t, db)
+		if down, ok := cfg.down[to]; ok {
+			if got := migrateOnce(t, db, to, down); got != from {
+				t.Fatalf("down migration from v%d: got version v%d, want v%d", to, got, from)
+			}
+			if restored := dumpSchema(t, db); restored != before {
+				t.Fatalf("down migration from v%d does not restore the schema of v%d:\ngot:\n%s\nwant:\n%s", to, from, restored, before)
+			}
+			if cfg.seed != nil {
+				if err := cfg.seed(ctx, db, from); err != nil {
+					t.Fatalf("could not seed v%d: %v", from, err)
+				}
+			}
+			if got := migrateOnce(t, db, from, fn); got != to {
+				t.Fatalf("re-applied migration from v%d: got version v%d, want v%d", from, got, to)
+			}
+			if reapplied := dumpSchema(t, db); reapplied != after {
+				t.Fatalf("re-applied migration from v%d produces another schema:\ngot:\n%s\nwant:\n%s", from, reapplied, after)
+			}
+		}
+		from = to
+	}
+	if err := sqlt.Verify(ctx, db, strings.NewReader(schema)); err != nil {
+		t.Fatalf("schema after v%d: %v", from, err)
+	}
+}
+
+// migrateOnce runs the migration fn from version with sqlt.Migrate and returns the version it migrated to.
+func migrateOnce(t testing.TB, db sqlt.DB, version int, fn func(context.Context, sqlt.DB) error) int {
+	t.Helper()
+	if err := sqlt.Migrate(context.Background(), db, sqlt.MigrationMap{version: fn}); err != nil {
+		t.Fatalf("migration from v%d: %v", version, err)
+	}
+	var got int
+	if err := db.Get(&got, "SELECT version FROM version"); err != nil {
+		t.Fatalf("could not read version: %v", err)
+	}
+	if got == version {
+		t.Fatalf("migration from v%d did not change the version", version)
+	}
+	return got
+}
+
+// dumpSchema returns the normalized schema of db, for comparing schemas.
+func dumpSchema(t testing.TB, db sqlt.DB) string {
+	t.Helper()
+	schema, err := sqlt.FetchDBSchema(context.Background(), db)
+	if err != nil {
+		t.Fatalf("could not read schema: %v", err)
+	}
+	if err := schema.Normalize(); err != nil {
+		t.Fatalf("could not normalize schema: %v", err)
+	}
+	return schema.SQL()
+}
@@ -0,0 +1,91 @@
+package sqltest_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/james-darko/sqlt"
+	"github.com/james-darko/sqlt/sqltest"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+// recorder records the failure of a check instead of failing the test.
+type recorder struct {
+	testing.TB
+	failure string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Fatal(args ...any) {
+	r.failure = fmt.Sprint(args...)
+	panic(r)
+}
+
+func (r *recorder) Fatalf(format string, args ...any) {
+	r.failure = fmt.Sprintf(format, args...)
+	panic(r)
+}
+
+// check runs CheckMigrations and returns its failure, or "".
+func check(t *testing.T, schema string, up, down sqlt.MigrationMap) (failure string) {
+	r := &recorder{TB: t}
+	defer func() {
+		if v := recover(); v != nil && v != r {
+			panic(v)
+		}
+		failure = r.failure
+	}()
+	sqltest.CheckMigrations(r, schema, up, sqltest.WithDown(down), sqltest.WithSeed(func(ctx context.Context, db sqlt.DB, version int) error {
+		if version == 1 {
+			_, err := db.ExecContext(ctx, "INSERT INTO users (name) VALUES ('ada'), ('ada')")
+			return err
+		}
+		return nil
+	}))
+	return ""
+}
+
+func exec(sql string) sqlt.MigrationFunc {
+	return func(ctx context.Context, db sqlt.DB) error {
+		_, err := db.ExecContext(ctx, sql+"; UPDATE version SET version = version + 1")
+		return err
+	}
+}
+
+func execDown(sql string) sqlt.MigrationFunc {
+	return func(ctx context.Context, db sqlt.DB) error {
+		_, err := db.ExecContext(ctx, sql+"; UPDATE version SET version = version - 1")
+		return err
+	}
+}
+
+const schema = `CREATE TABLE version (version INTEGER NOT NULL);
+CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, email TEXT NOT NULL DEFAULT '');`
+
+func TestCheckMigrations(t *testing.T) {
+	up := sqlt.MigrationMap{
+		0: exec("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)"),
+		1: exec("ALTER TABLE users ADD COLUMN email TEXT NOT NULL DEFAULT ''"),
+	}
+	down := sqlt.MigrationMap{
+		1: execDown("DROP TABLE users"),
+		2: execDown("ALTER TABLE users DROP COLUMN email"),
+	}
+	assert.Empty(t, check(t, schema, up, down))
+
+	down[2] = execDown("SELECT 1")
+	assert.Contains(t, check(t, schema, up, down), "down migration from v2 does not restore the schema of v1")
+
+	// The seeded duplicates break the unique index when the migration is re-applied.
+	down[2] = execDown("DROP INDEX idx_users_name; ALTER TABLE users DROP COLUMN email")
+	up[1] = exec("ALTER TABLE users ADD COLUMN email TEXT NOT NULL DEFAULT ''; CREATE UNIQUE INDEX idx_users_name ON users (name)")
+	assert.Contains(t, check(t, schema, up, down), "migration from v1: ")
+
+	up[1] = exec("SELECT 1")
+	delete(down, 2)
+	delete(down, 1)
+	assert.Contains(t, check(t, schema, up, down), "schema after v2: ")
+}
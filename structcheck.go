@@ -0,0 +1,115 @@
+package sqlt
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/jmoiron/sqlx/reflectx"
+)
+
+// Mismatch describes a struct field that does not fit the column it maps to, see CheckStructsAgainstSchema.
+type Mismatch struct {
+	// Model is the name of the struct type.
+	Model string
+	Table string
+	// Field and Column are empty if the table is missing.
+	Field  string
+	Column string
+	// Kind is "missing table", "missing column", "type" or "nullability".
+	Kind    string
+	Details string
+}
+
+func (m Mismatch) String() string {
+	name := m.Model
+	if m.Field != "" {
+		name += "." + m.Field
+	}
+	if m.Details == "" {
+		return fmt.Sprintf("%s %s", m.Kind, name)
+	}
+	return fmt.Sprintf("%s %s: %s", m.Kind, name, m.Details)
+}
+
+// tableNamer is implemented by models naming their table, see CheckStructsAgainstSchema.
+type tableNamer interface {
+	TableName() string
+}
+
+// compatibleAffinities lists the column type affinities the values of a Go kind are stored and scanned as.
+var compatibleAffinities = map[reflect.Kind][]string{
+	reflect.Bool:    {"INTEGER", "NUMERIC"},
+	reflect.Int:     {"INTEGER", "NUMERIC"},
+	reflect.Int8:    {"INTEGER", "NUMERIC"},
+	reflect.Int16:   {"INTEGER", "NUMERIC"},
+	reflect.Int32:   {"INTEGER", "NUMERIC"},
+	reflect.Int64:   {"INTEGER", "NUMERIC"},
+	reflect.Uint:    {"INTEGER", "NUMERIC"},
+	reflect.Uint8:   {"INTEGER", "NUMERIC"},
+	reflect.Uint16:  {"INTEGER", "NUMERIC"},
+	reflect.Uint32:  {"INTEGER", "NUMERIC"},
+	reflect.Uint64:  {"INTEGER", "NUMERIC"},
+	reflect.Float32: {"REAL", "INTEGER", "NUMERIC"},
+	reflect.Float64: {"REAL", "INTEGER", "NUMERIC"},
+	reflect.String:  {"TEXT", "NUMERIC"},
+}
+
+// CheckStructsAgainstSchema returns the fields of models, struct values or pointers to them, that do not fit
+// the schema. The table of a model is named by its TableName method if it has one, or else by the default
+// mapper applied to its type name. Fields are mapped to columns as by Select, and must map to an existing
+// column whose type affinity holds the field's values. A field scanning a nullable column must be a pointer,
+// a []byte or implement sql.Scanner, like sql.NullString. Time and sql.Scanner fields are not checked for type.
+// Call it from a test so changes to the schema that break the models are caught.
+func CheckStructsAgainstSchema(schema *SchemaDefinition, models ...any) []Mismatch {
+	var mismatches []Mismatch
+	mapper := structMapper()
+	for _, model := range models {
+		t := reflectx.Deref(reflect.TypeOf(model))
+		tableName := (*defaultMapper.Load())(t.Name())
+		if namer, ok := model.(tableNamer); ok {
+			tableName = namer.TableName()
+		}
+		table := schema.Table(tableName)
+		if table == nil {
+			mismatches = append(mismatches, Mismatch{Model: t.Name(), Table: tableName, Kind: "missing table"})
+			continue
+		}
+		for _, field := range scannedFields(mapper.TypeMap(t).Tree.Children) {
+			m := Mismatch{Model: t.Name(), Table: table.Name, Field: field.Field.Name, Column: field.Name}
+			col := table.Column(field.Name)
+			if col == nil {
+				m.Kind = "missing column"
+				m.Details = fmt.Sprintf("no column %s in table %s", field.Name, table.Name)
+				mismatches = append(mismatches, m)
+				continue
+			}
+			fieldType := field.Field.Type
+			scanner := reflect.PointerTo(fieldType).Implements(scannerType)
+			if affinity := typeAffinityOf(col.Type); !scanner && affinity != "BLOB" && fieldType != timeType {
+				kind := reflectx.Deref(fieldType).Kind()
+				compatible, known := compatibleAffinities[kind]
+				if kind == reflect.Slice && reflectx.Deref(fieldType).Elem().Kind() == reflect.Uint8 {
+					compatible, known = []string{"TEXT"}, true
+				}
+				if known && !slices.Contains(compatible, affinity) {
+					m.Kind = "type"
+					m.Details = fmt.Sprintf("%s field for %s column (%s)", fieldType, col.Type, strings.ToLower(affinity))
+					mismatches = append(mismatches, m)
+					continue
+				}
+			}
+			rowidAlias := col.RowidAlias || col.PrimaryKey && strings.EqualFold(col.Type, "INTEGER")
+			// NULL is scanned into pointers and interfaces as nil, and into []byte as a nil slice.
+			nullableField := scanner || fieldType.Kind() == reflect.Pointer || fieldType.Kind() == reflect.Interface ||
+				fieldType.Kind() == reflect.Slice && fieldType.Elem().Kind() == reflect.Uint8
+			if !col.NotNull && !rowidAlias && !nullableField {
+				m.Kind = "nullability"
+				m.Details = fmt.Sprintf("%s field for nullable column", fieldType)
+				mismatches = append(mismatches, m)
+			}
+		}
+	}
+	return mismatches
+}
@@ -0,0 +1,54 @@
+package sqlt_test
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/james-darko/sqlt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type checkedUser struct {
+	ID        int64          `db:"id"`
+	Name      string         `db:"name"`
+	Email     sql.NullString `db:"email"`
+	Age       string         `db:"age"`
+	Nickname  string         `db:"nickname"`
+	Avatar    []byte         `db:"avatar"`
+	CreatedAt time.Time      `db:"created_at"`
+	Missing   *string        `db:"missing"`
+}
+
+func (checkedUser) TableName() string { return "users" }
+
+type post struct {
+	ID int64 `db:"id"`
+}
+
+type comment struct {
+	ID int64 `db:"id"`
+}
+
+func TestCheckStructsAgainstSchema(t *testing.T) {
+	t.Parallel()
+	schema, err := sqlt.ParseSchemaReader(strings.NewReader(`
+CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL, email TEXT, age INTEGER NOT NULL,
+	nickname TEXT, avatar BLOB, created_at DATETIME NOT NULL);
+CREATE TABLE post (id INTEGER PRIMARY KEY);`))
+	require.NoError(t, err)
+
+	mismatches := sqlt.CheckStructsAgainstSchema(schema, checkedUser{}, &post{}, comment{})
+	var got []string
+	for _, m := range mismatches {
+		got = append(got, m.String())
+	}
+	assert.Equal(t, []string{
+		"type checkedUser.Age: string field for INTEGER column (integer)",
+		"nullability checkedUser.Nickname: string field for nullable column",
+		"missing column checkedUser.Missing: no column missing in table users",
+		"missing table comment",
+	}, got)
+}
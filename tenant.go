@@ -0,0 +1,173 @@
+package sqlt
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultParallelism is the number of databases MigrateAll migrates at the same time unless set with WithParallelism.
+const DefaultParallelism = 8
+
+// WithParallelism sets the number of databases MigrateAll migrates at the same time. Defaults to DefaultParallelism.
+func WithParallelism(n int) MigrateOption {
+	return func(cfg *migrateConfig) {
+		cfg.parallelism = n
+	}
+}
+
+// TenantResult is the outcome of migrating one of the databases passed to MigrateAll.
+type TenantResult struct {
+	// Index is the position of the database in the slice passed to MigrateAll.
+	Index    int
+	Err      error
+	Duration time.Duration
+}
+
+// MigrateAll runs AutoMigrate with schema on every database of dbs, e.g. one per tenant,
+// migrating up to WithParallelism databases at the same time. The schema is parsed once and shared.
+// A failing database does not stop the others; the returned results hold the outcome of every database,
+// in the order of dbs, and the error is an ErrTenantMigrations listing the failed ones.
+// Databases not yet started when ctx is canceled fail with the context's error.
+func MigrateAll(ctx context.Context, dbs []DB, schema io.Reader, allowTableDeletes bool, opts ...MigrateOption) ([]TenantResult, error) {
+	cfg := newMigrateConfig(opts)
+	stmts := cfg.schemaStmts
+	if stmts == nil {
+		var err error
+		if stmts, err = parseSchemaStatements(schema); err != nil {
+			return nil, fmt.Errorf("MigrateAll: %w", err)
+		}
+	}
+	opts = append(slices.Clip(opts), func(cfg *migrateConfig) {
+		cfg.schemaStmts = stmts
+	})
+	parallelism := cfg.parallelism
+	if parallelism <= 0 {
+		parallelism = DefaultParallelism
+	}
+
+	results := make([]TenantResult, len(dbs))
+	sem := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+	for i, db := range dbs {
+		results[i].Index = i
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			results[i].Err = ctx.Err()
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			started := time.Now()
+			results[i].Err = AutoMigrate(ctx, db, strings.NewReader(""), allowTableDeletes, opts...)
+			results[i].Duration = time.Since(started)
+		}()
+	}
+	wg.Wait()
+
+	var failed []TenantResult
+	for _, r := range results {
+		if r.Err != nil {
+			failed = append(failed, r)
+		}
+	}
+	if len(failed) > 0 {
+		return results, ErrTenantMigrations{Failed: failed, Total: len(dbs)}
+	}
+	return results, nil
+}
+
+// TenantFactory creates the database of a new tenant for ProvisionTenant.
+type TenantFactory interface {
+	CreateTenantDB(ctx context.Context, tenantID string) (DB, error)
+}
+
+// TenantFactoryFunc adapts a function to a TenantFactory.
+type TenantFactoryFunc func(ctx context.Context, tenantID string) (DB, error)
+
+// CreateTenantDB calls f.
+func (f TenantFactoryFunc) CreateTenantDB(ctx context.Context, tenantID string) (DB, error) {
+	return f(ctx, tenantID)
+}
+
+// FileTenantFactory returns a TenantFactory creating each tenant's database as the SQLite file
+// <tenantID>.db in dir, opened with the "sqlite3" driver and foreign keys enabled.
+// It fails if the file already exists.
+func FileTenantFactory(dir string, opts ...OpenOption) TenantFactory {
+	return TenantFactoryFunc(func(ctx context.Context, tenantID string) (DB, error) {
+		if tenantID == "" || strings.ContainsAny(tenantID, `/\`) || tenantID == "." || tenantID == ".." {
+			return nil, fmt.Errorf("invalid tenant ID %q", tenantID)
+		}
+		path := filepath.Join(dir, tenantID+".db")
+		if _, err := os.Stat(path); err == nil {
+			return nil, fmt.Errorf("database of tenant %s already exists: %s", tenantID, path)
+		}
+		return Open("sqlite3", "file:"+path+"?_foreign_keys=on", opts...)
+	})
+}
+
+// WithSchemaVersion makes ProvisionTenant stamp version in the version table of the new database,
+// see WithVersionTable, so Migrate continues from there. The schema must create the version table.
+func WithSchemaVersion(version int) MigrateOption {
+	return func(cfg *migrateConfig) {
+		cfg.schemaVersion = &version
+	}
+}
+
+// ProvisionTenant creates the database of a new tenant with factory, migrates it to schema with AutoMigrate,
+// runs seed on it if not nil, stamps the version set with WithSchemaVersion, if any, and registers it
+// under tenantID, see NamedDB. The version is stamped last, so a tenant whose provisioning failed has none.
+// On failure the database is closed but not removed.
+func ProvisionTenant(ctx context.Context, factory TenantFactory, tenantID string, schema io.Reader, seed func(context.Context, DB) error, opts ...MigrateOption) (DB, error) {
+	if NamedDB(tenantID) != nil {
+		return nil, fmt.Errorf("ProvisionTenant: database %s is already registered", tenantID)
+	}
+	cfg := newMigrateConfig(opts)
+	db, err := factory.CreateTenantDB(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("ProvisionTenant: could not create database of tenant %s: %w", tenantID, err)
+	}
+	if err := provisionTenant(ctx, db, schema, seed, cfg, opts); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("ProvisionTenant: tenant %s: %w", tenantID, err)
+	}
+	if err := RegisterDB(tenantID, db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("ProvisionTenant: %w", err)
+	}
+	return db, nil
+}
+
+func provisionTenant(ctx context.Context, db DB, schema io.Reader, seed func(context.Context, DB) error, cfg *migrateConfig, opts []MigrateOption) error {
+	if err := AutoMigrate(ctx, db, schema, false, opts...); err != nil {
+		return err
+	}
+	if seed != nil {
+		if err := seed(ctx, db); err != nil {
+			return fmt.Errorf("could not seed database: %w", err)
+		}
+	}
+	if cfg.schemaVersion == nil {
+		return nil
+	}
+	return db.Txc(ctx, func(tx Tx) error {
+		if _, err := tx.ExecContext(AllowUnboundedWrite(ctx), "DELETE FROM "+cfg.versionTable); err != nil {
+			return fmt.Errorf("could not stamp version: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, "INSERT INTO "+cfg.versionTable+" (version) VALUES (?)", *cfg.schemaVersion); err != nil {
+			return fmt.Errorf("could not stamp version: %w", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,85 @@
+package sqlt_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/james-darko/gort"
+	"github.com/james-darko/sqlt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateAll(t *testing.T) {
+	t.Parallel()
+	ctx := gort.Context()
+
+	dbs := make([]sqlt.DB, 5)
+	for i := range dbs {
+		db, err := sqlt.OpenMemory(fmt.Sprintf("%s_%d", t.Name(), i))
+		require.NoError(t, err)
+		defer db.Close()
+		dbs[i] = db
+	}
+	require.NoError(t, sqlt.ExecString(ctx, dbs[3], `CREATE TABLE users (id INTEGER PRIMARY KEY, name INTEGER);`))
+
+	schema := `CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT);
+CREATE INDEX idx_users_name ON users (name);`
+	results, err := sqlt.MigrateAll(ctx, dbs, strings.NewReader(schema), false, sqlt.WithParallelism(2))
+	var failures sqlt.ErrTenantMigrations
+	require.ErrorAs(t, err, &failures)
+	require.Len(t, failures.Failed, 1)
+	assert.Equal(t, 3, failures.Failed[0].Index)
+	assert.ErrorContains(t, err, "1 of 5 databases failed to migrate: database 3: ")
+	var conflict *sqlt.SchemaConflictError
+	assert.ErrorAs(t, err, &conflict)
+
+	require.Len(t, results, len(dbs))
+	for i, r := range results {
+		assert.Equal(t, i, r.Index)
+		if i == 3 {
+			continue
+		}
+		assert.NoError(t, r.Err)
+		assert.NoError(t, sqlt.VerifyString(ctx, dbs[i], schema))
+	}
+}
+
+func TestProvisionTenant(t *testing.T) {
+	t.Parallel()
+	ctx := gort.Context()
+	factory := sqlt.FileTenantFactory(t.TempDir())
+	tenantID := "provision_" + t.Name()
+	t.Cleanup(func() {
+		if db := sqlt.UnregisterDB(tenantID); db != nil {
+			db.Close()
+		}
+	})
+
+	schema := `CREATE TABLE version (version INTEGER NOT NULL);
+CREATE TABLE plans (id INTEGER PRIMARY KEY, name TEXT NOT NULL);`
+	seed := func(ctx context.Context, db sqlt.DB) error {
+		_, err := db.ExecContext(ctx, "INSERT INTO plans (name) VALUES ('free'), ('pro')")
+		return err
+	}
+	db, err := sqlt.ProvisionTenant(ctx, factory, tenantID, strings.NewReader(schema), seed, sqlt.WithSchemaVersion(3))
+	require.NoError(t, err)
+	assert.Same(t, db, sqlt.NamedDB(tenantID))
+	require.NoError(t, sqlt.VerifyAt(ctx, db, strings.NewReader(schema), 3))
+	var plans int
+	require.NoError(t, db.Get(&plans, "SELECT COUNT(*) FROM plans"))
+	assert.Equal(t, 2, plans)
+
+	_, err = sqlt.ProvisionTenant(ctx, factory, tenantID, strings.NewReader(schema), nil)
+	assert.ErrorContains(t, err, "already registered")
+
+	failing := tenantID + "_failing"
+	_, err = sqlt.ProvisionTenant(ctx, factory, failing, strings.NewReader(schema), func(context.Context, sqlt.DB) error {
+		return errors.New("boom")
+	}, sqlt.WithSchemaVersion(3))
+	assert.ErrorContains(t, err, "could not seed database: boom")
+	assert.Nil(t, sqlt.NamedDB(failing))
+}
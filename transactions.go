@@ -2,18 +2,38 @@ package sqlt
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 )
 
-func transaction(ctx context.Context, db *sqlx.DB, imm bool, fn func(conn Tx) error) (rErr error) {
-	tx, err := db.BeginTxx(ctx, nil)
+func transaction(ctx context.Context, s *sqlxDB, imm bool, fn func(conn Tx) error) error {
+	return transactionOn(ctx, s, s.db, imm, fn)
+}
+
+// beginner starts transactions, on any connection of a pool or on a pinned connection.
+type beginner interface {
+	BeginTxx(ctx context.Context, opts *sql.TxOptions) (*sqlx.Tx, error)
+}
+
+// transactionOn runs fn in a transaction of s started with b.
+func transactionOn(ctx context.Context, s *sqlxDB, b beginner, imm bool, fn func(conn Tx) error) (rErr error) {
+	start := time.Now()
+	tx, err := b.BeginTxx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("failed to start transaction: %w", err)
+		return fmt.Errorf("failed to start transaction: %w", s.lockError(err, time.Since(start), false))
 	}
 
+	t := &txWrapper{
+		tx:      tx,
+		guard:   s.guard,
+		timeout: s.timeout,
+		writers: &s.state.writers,
+	}
+	defer t.release()
 	defer func() {
 		if panicValue := recover(); panicValue != nil {
 			if rollbackErr := tx.Rollback(); rollbackErr != nil {
@@ -30,20 +50,48 @@ func transaction(ctx context.Context, db *sqlx.DB, imm bool, fn func(conn Tx) er
 		}
 	}()
 	if imm {
-		_, _ = tx.Exec("UPDATE begin_immediate SET v = 1")
-	}
-	t := &txWrapper{
-		tx: tx,
+		if _, err := tx.Exec("UPDATE begin_immediate SET v = 1"); err == nil {
+			t.markWriting()
+		}
 	}
 	err = fn(t)
 	if err != nil {
+		if t.lockWait > 0 {
+			err = s.lockError(err, t.lockWait, t.writing)
+		}
 		if rollbackErr := tx.Rollback(); rollbackErr != nil {
 			return fmt.Errorf("failed to rollback transaction: %w - %w", rollbackErr, err)
 		}
 		return err
 	} else {
-		return tx.Commit()
+		start := time.Now()
+		if err := tx.Commit(); err != nil {
+			return s.lockError(err, time.Since(start), t.writing)
+		}
+		return nil
+	}
+}
+
+// lockError returns err as a *LockError with diagnostics if it is a busy or locked error.
+// self is set if the failing transaction counts among the writers of the database.
+func (s *sqlxDB) lockError(err error, waited time.Duration, self bool) error {
+	if !isBusy(err) {
+		return err
+	}
+	lerr := &LockError{Err: err, Waited: waited}
+	others := s.state.writers.Load()
+	if self {
+		others--
+	}
+	lerr.WriteLockHeld = others > 0
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	var ms int64
+	if s.db.GetContext(ctx, &ms, "PRAGMA busy_timeout") == nil {
+		lerr.BusyTimeout = time.Duration(ms) * time.Millisecond
 	}
+	_ = s.db.GetContext(ctx, &lerr.JournalMode, "PRAGMA journal_mode")
+	return lerr
 }
 
 // func transaction(ctx context.Context, db *sqlx.DB, imm bool, fn func(conn Tx) error) (rErr error) {
@@ -0,0 +1,217 @@
+// Package turso is a client for the Turso platform API, managing the lifecycle of libsql databases:
+// creating and deleting databases, creating their auth tokens and listing their instances.
+//
+// Client.TenantFactory plugs it into sqlt.ProvisionTenant, so tenant databases can be created on Turso
+// and migrated in one step. Opening the databases requires the "libsql" driver to be registered, e.g. by
+// importing github.com/tursodatabase/libsql-client-go/libsql.
+package turso
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/james-darko/sqlt"
+)
+
+// DefaultBaseURL is the URL of the Turso platform API.
+const DefaultBaseURL = "https://api.turso.tech"
+
+// Client calls the Turso platform API for an organization.
+type Client struct {
+	// BaseURL defaults to DefaultBaseURL.
+	BaseURL string
+	// Organization is the slug of the organization owning the databases.
+	Organization string
+	// Token is a platform API token, see `turso auth api-tokens mint`.
+	Token string
+	// HTTPClient defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// NewClient returns a client for the databases of organization, authenticated with the platform API token.
+func NewClient(organization, token string) *Client {
+	return &Client{Organization: organization, Token: token}
+}
+
+// Database is a database of the organization.
+type Database struct {
+	ID       string `json:"DbId"`
+	Name     string `json:"Name"`
+	Hostname string `json:"Hostname"`
+	Group    string `json:"group,omitempty"`
+}
+
+// URL returns the libsql URL of the database.
+func (d Database) URL() string {
+	return "libsql://" + d.Hostname
+}
+
+// Instance is a location serving a database, its primary or a replica.
+type Instance struct {
+	UUID     string `json:"uuid"`
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Region   string `json:"region"`
+	Hostname string `json:"hostname"`
+}
+
+// Error is returned for a request the API did not answer with success.
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("turso: %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// CreateDatabase creates the database name in group.
+func (c *Client) CreateDatabase(ctx context.Context, name, group string) (Database, error) {
+	var resp struct {
+		Database Database `json:"database"`
+	}
+	body := map[string]string{"name": name, "group": group}
+	if err := c.do(ctx, http.MethodPost, c.orgPath("databases"), body, &resp); err != nil {
+		return Database{}, err
+	}
+	return resp.Database, nil
+}
+
+// GetDatabase returns the database name.
+func (c *Client) GetDatabase(ctx context.Context, name string) (Database, error) {
+	var resp struct {
+		Database Database `json:"database"`
+	}
+	if err := c.do(ctx, http.MethodGet, c.orgPath("databases", name), nil, &resp); err != nil {
+		return Database{}, err
+	}
+	return resp.Database, nil
+}
+
+// DeleteDatabase deletes the database name with all its data.
+func (c *Client) DeleteDatabase(ctx context.Context, name string) error {
+	return c.do(ctx, http.MethodDelete, c.orgPath("databases", name), nil, nil)
+}
+
+// CreateToken creates an auth token for the database name. expiration is a duration such as "2w1d30m",
+// or "never". With readOnly set, the token cannot write.
+func (c *Client) CreateToken(ctx context.Context, name, expiration string, readOnly bool) (string, error) {
+	query := url.Values{}
+	if expiration != "" {
+		query.Set("expiration", expiration)
+	}
+	if readOnly {
+		query.Set("authorization", "read-only")
+	} else {
+		query.Set("authorization", "full-access")
+	}
+	var resp struct {
+		JWT string `json:"jwt"`
+	}
+	if err := c.do(ctx, http.MethodPost, c.orgPath("databases", name, "auth", "tokens")+"?"+query.Encode(), nil, &resp); err != nil {
+		return "", err
+	}
+	return resp.JWT, nil
+}
+
+// ListInstances returns the instances serving the database name, its primary and replicas.
+func (c *Client) ListInstances(ctx context.Context, name string) ([]Instance, error) {
+	var resp struct {
+		Instances []Instance `json:"instances"`
+	}
+	if err := c.do(ctx, http.MethodGet, c.orgPath("databases", name, "instances"), nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Instances, nil
+}
+
+// Open creates a full access token for the database name and opens it with the "libsql" driver.
+func (c *Client) Open(ctx context.Context, name string, opts ...sqlt.OpenOption) (sqlt.DB, error) {
+	db, err := c.GetDatabase(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return c.open(ctx, db, opts)
+}
+
+func (c *Client) open(ctx context.Context, db Database, opts []sqlt.OpenOption) (sqlt.DB, error) {
+	token, err := c.CreateToken(ctx, db.Name, "never", false)
+	if err != nil {
+		return nil, err
+	}
+	return sqlt.Open("libsql", db.URL()+"?authToken="+url.QueryEscape(token), opts...)
+}
+
+// TenantFactory returns a sqlt.TenantFactory creating each tenant's database on Turso in group,
+// named after the tenant ID, and opening it with a new full access token.
+func (c *Client) TenantFactory(group string, opts ...sqlt.OpenOption) sqlt.TenantFactory {
+	return sqlt.TenantFactoryFunc(func(ctx context.Context, tenantID string) (sqlt.DB, error) {
+		db, err := c.CreateDatabase(ctx, tenantID, group)
+		if err != nil {
+			return nil, err
+		}
+		return c.open(ctx, db, opts)
+	})
+}
+
+func (c *Client) orgPath(elems ...string) string {
+	path := "/v1/organizations/" + url.PathEscape(c.Organization)
+	for _, elem := range elems {
+		path += "/" + url.PathEscape(elem)
+	}
+	return path
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body, result any) error {
+	var reqBody io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(b)
+	}
+	baseURL := c.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	req, err := http.NewRequestWithContext(ctx, method, baseURL+path, reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("turso: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		content, _ := io.ReadAll(resp.Body)
+		if json.Unmarshal(content, &apiErr) != nil || apiErr.Error == "" {
+			apiErr.Error = string(bytes.TrimSpace(content))
+		}
+		return &Error{StatusCode: resp.StatusCode, Message: apiErr.Error}
+	}
+	if result == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("turso: could not decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package turso_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/james-darko/gort"
+	"github.com/james-darko/sqlt/turso"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient(t *testing.T) {
+	t.Parallel()
+	ctx := gort.Context()
+	var deleted string
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/organizations/acme/databases", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, map[string]string{"name": "tenant1", "group": "default"}, body)
+		w.Write([]byte(`{"database": {"DbId": "id1", "Name": "tenant1", "Hostname": "tenant1-acme.turso.io"}}`))
+	})
+	mux.HandleFunc("POST /v1/organizations/acme/databases/tenant1/auth/tokens", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "read-only", r.URL.Query().Get("authorization"))
+		assert.Equal(t, "1d", r.URL.Query().Get("expiration"))
+		w.Write([]byte(`{"jwt": "token1"}`))
+	})
+	mux.HandleFunc("GET /v1/organizations/acme/databases/tenant1/instances", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"instances": [{"uuid": "u1", "name": "ams", "type": "primary", "region": "ams", "hostname": "ams.tenant1-acme.turso.io"}]}`))
+	})
+	mux.HandleFunc("DELETE /v1/organizations/acme/databases/{name}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("name") != "tenant1" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": "database not found"}`))
+			return
+		}
+		deleted = r.PathValue("name")
+		w.Write([]byte(`{"database": "tenant1"}`))
+	})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	client := turso.NewClient("acme", "secret")
+	client.BaseURL = srv.URL
+
+	db, err := client.CreateDatabase(ctx, "tenant1", "default")
+	require.NoError(t, err)
+	assert.Equal(t, turso.Database{ID: "id1", Name: "tenant1", Hostname: "tenant1-acme.turso.io"}, db)
+	assert.Equal(t, "libsql://tenant1-acme.turso.io", db.URL())
+
+	token, err := client.CreateToken(ctx, "tenant1", "1d", true)
+	require.NoError(t, err)
+	assert.Equal(t, "token1", token)
+
+	instances, err := client.ListInstances(ctx, "tenant1")
+	require.NoError(t, err)
+	require.Len(t, instances, 1)
+	assert.Equal(t, "primary", instances[0].Type)
+
+	require.NoError(t, client.DeleteDatabase(ctx, "tenant1"))
+	assert.Equal(t, "tenant1", deleted)
+
+	err = client.DeleteDatabase(ctx, "missing")
+	var apiErr *turso.Error
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+	assert.Equal(t, "database not found", apiErr.Message)
+
+	client.Token = "wrong"
+	_, err = client.ListInstances(ctx, "tenant1")
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusUnauthorized, apiErr.StatusCode)
+}
@@ -8,33 +8,17 @@ import (
 	"github.com/jmoiron/sqlx"
 )
 
+// Tx is a transaction started by one of the DB.Tx methods. Its query helpers are those of Handle.
 type Tx interface {
+	Handle
+	// SQLXTx returns the underlying *sqlx.Tx, an escape hatch for sqlx features sqlt does not wrap
+	// such as Stmtx. Do not commit or roll it back, the transaction is still owned by sqlt.
+	SQLXTx() *sqlx.Tx
+	// CaptureChanges records the changes made to tables in this transaction, see ChangeCapture.
+	CaptureChanges(tables []string) (*ChangeCapture, error)
 	// Commit() error
 	// Rollback() error
-	Exec(query string, args ...any) (Result, error)
-	MustExec(query string, args ...any) Result
-	IDExec(query string, args ...any) (int64, error)
-	AffectedExec(query string, args ...any) (int, error)
-	Query(query string, args ...any) (*sqlx.Rows, error)
-	MustQuery(query string, args ...any) *sqlx.Rows
-	QueryRow(query string, args ...any) *sqlx.Row
-	MustQueryRow(query string, args ...any) *sqlx.Row
-	Get(dest any, query string, args ...any) error
-	GetIn(dest any, query string, args ...any) error
-	MustGet(dest any, query string, args ...any)
-	MustGetIn(dest any, query string, args ...any)
-	Select(dest any, query string, args ...any) error
-	MustSelect(dest any, query string, args ...any)
-	SelectIn(dest any, query string, args ...any) error
-	SelectSeq(query string, args ...any) *RowsSeq
-	SelectInSeq(query string, args ...any) *RowsSeq
-	MustSelectIn(dest any, query string, args ...any)
-	NamedExec(query string, arg any) (Result, error)
-	// Prepare(query string) (*sql.Stmt, error)
-	// Preparex(query string) (*sqlx.Stmt, error)
 	// Stmtx(st any) *sqlx.Stmt
-	Rebind(query string) string
-	DriverName() string
 }
 
 type sqlxTx struct {
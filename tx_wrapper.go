@@ -2,28 +2,234 @@ package sqlt
 
 import (
 	"context" // Added import for context
+	"database/sql"
+	"sync/atomic"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 )
 
 type txWrapper struct {
 	tx *sqlx.Tx
+	// stmts caches prepared statements by query for exec. A nil entry marks a query seen once.
+	stmts map[string]*sqlx.Stmt
+	// guard is set if the database was opened with WithGuard.
+	guard bool
+	// timeout is the WithQueryTimeout of the database.
+	timeout time.Duration
+	// writers is the counter of writing transactions of the database, incremented once writing is set.
+	writers *atomic.Int32
+	writing bool
+	// lockWait is how long the last statement failing with a busy or locked error ran.
+	lockWait time.Duration
+}
+
+// reader returns the sqlx transaction for the read queries executed with ctx, see WithUnsafeScan.
+func (tx *txWrapper) reader(ctx context.Context) *sqlx.Tx {
+	if unsafeScan(ctx) {
+		return tx.tx.Unsafe()
+	}
+	return tx.tx
+}
+
+// markWriting counts the transaction among the writers of the database.
+func (tx *txWrapper) markWriting() {
+	if !tx.writing && tx.writers != nil {
+		tx.writing = true
+		tx.writers.Add(1)
+	}
+}
+
+// release removes the transaction from the writers of the database once it ended.
+func (tx *txWrapper) release() {
+	if tx.writing {
+		tx.writing = false
+		tx.writers.Add(-1)
+	}
+}
+
+// exec runs query, reusing a prepared statement once the same query with arguments is
+// executed a second time in the transaction, which speeds up bulk inserts and updates.
+// Queries without arguments may hold several statements and are never prepared.
+// Statements bound to a transaction are closed when it is committed or rolled back.
+func (tx *txWrapper) exec(ctx context.Context, query string, args []any) (sql.Result, error) {
+	if err := checkGuard(ctx, tx.guard, query); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	var r sql.Result
+	err := tx.run(ctx, func(ctx context.Context) error {
+		var err error
+		r, err = tx.execStmt(ctx, query, args)
+		return err
+	})
+	if err != nil {
+		if isBusy(err) {
+			tx.lockWait = time.Since(start)
+		}
+		return nil, err
+	}
+	tx.markWriting()
+	return r, nil
+}
+
+func (tx *txWrapper) execStmt(ctx context.Context, query string, args []any) (sql.Result, error) {
+	if len(args) == 0 {
+		return tx.tx.ExecContext(ctx, query)
+	}
+	stmt, seen := tx.stmts[query]
+	if stmt != nil {
+		return stmt.ExecContext(ctx, args...)
+	}
+	if tx.stmts == nil {
+		tx.stmts = make(map[string]*sqlx.Stmt)
+	}
+	if !seen {
+		tx.stmts[query] = nil
+		return tx.tx.ExecContext(ctx, query, args...)
+	}
+	stmt, err := tx.tx.PreparexContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	tx.stmts[query] = stmt
+	return stmt.ExecContext(ctx, args...)
+}
+
+func (tx *txWrapper) SQLXTx() *sqlx.Tx {
+	return tx.tx
 }
 
 func (tx *txWrapper) Exec(query string, args ...any) (Result, error) {
-	r, err := tx.tx.Exec(query, args...)
+	r, err := tx.exec(context.Background(), query, args)
 	if err != nil {
 		return nil, err
 	}
 	return sqltResult{r}, nil
 }
 
+func (tx *txWrapper) ExecContext(ctx context.Context, query string, args ...any) (Result, error) {
+	r, err := tx.exec(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+	return sqltResult{r}, nil
+}
+
+func (tx *txWrapper) IDExecContext(ctx context.Context, query string, args ...any) (int64, error) {
+	r, err := tx.exec(ctx, query, args)
+	if err != nil {
+		return 0, err
+	}
+	return r.LastInsertId()
+}
+
+func (tx *txWrapper) AffectedExecContext(ctx context.Context, query string, args ...any) (int, error) {
+	r, err := tx.exec(ctx, query, args)
+	if err != nil {
+		return 0, err
+	}
+	rowsAffected, err := r.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(rowsAffected), nil
+}
+
+// ExecReturning and its variants run through Get, Select or IDExec, which apply the guard.
+func (tx *txWrapper) ExecReturning(dest any, query string, args ...any) error {
+	return execReturning(context.Background(), tx, dest, query, args)
+}
+
+func (tx *txWrapper) ExecReturningContext(ctx context.Context, dest any, query string, args ...any) error {
+	return execReturning(ctx, tx, dest, query, args)
+}
+
+func (tx *txWrapper) NamedExecReturning(dest any, query string, arg any) error {
+	return namedExecReturning(context.Background(), tx, dest, query, arg)
+}
+
+func (tx *txWrapper) NamedExecReturningContext(ctx context.Context, dest any, query string, arg any) error {
+	return namedExecReturning(ctx, tx, dest, query, arg)
+}
+
+func (tx *txWrapper) NamedExecBatch(query string, slice any) (Result, error) {
+	return tx.NamedExecBatchContext(context.Background(), query, slice)
+}
+
+func (tx *txWrapper) NamedExecBatchContext(ctx context.Context, query string, slice any) (Result, error) {
+	if err := checkGuard(ctx, tx.guard, query); err != nil {
+		return nil, err
+	}
+	var res Result
+	err := tx.run(ctx, func(ctx context.Context) error {
+		var err error
+		res, err = namedExecBatch(ctx, tx, query, slice)
+		return err
+	})
+	return res, err
+}
+
+func (tx *txWrapper) QueryContext(ctx context.Context, query string, args ...any) (*sqlx.Rows, error) {
+	if err := checkGuard(ctx, tx.guard, query); err != nil {
+		return nil, err
+	}
+	return tx.reader(ctx).QueryxContext(ctx, query, args...)
+}
+
+func (tx *txWrapper) GetInContext(ctx context.Context, dest any, query string, args ...any) error {
+	if err := checkGuard(ctx, tx.guard, query); err != nil {
+		return err
+	}
+	q, p, err := sqlx.In(query, args...)
+	if err != nil {
+		return err
+	}
+	return tx.run(ctx, func(ctx context.Context) error {
+		return tx.reader(ctx).GetContext(ctx, dest, q, p...)
+	})
+}
+
+func (tx *txWrapper) SelectInContext(ctx context.Context, dest any, query string, args ...any) error {
+	if err := checkGuard(ctx, tx.guard, query); err != nil {
+		return err
+	}
+	q, p, err := sqlx.In(query, args...)
+	if err != nil {
+		return err
+	}
+	return tx.run(ctx, func(ctx context.Context) error {
+		return tx.reader(ctx).SelectContext(ctx, dest, q, p...)
+	})
+}
+
+func (tx *txWrapper) BindNamed(query string, arg any) (string, []any, error) {
+	return tx.tx.BindNamed(query, arg)
+}
+
+func (tx *txWrapper) NamedQuery(query string, arg any) (*sqlx.Rows, error) {
+	if err := checkGuard(context.Background(), tx.guard, query); err != nil {
+		return nil, err
+	}
+	return tx.tx.NamedQuery(query, arg)
+}
+
 func (tx *txWrapper) GetContext(ctx context.Context, dest any, query string, args ...any) error {
-	return tx.tx.GetContext(ctx, dest, query, args...)
+	if err := checkGuard(ctx, tx.guard, query); err != nil {
+		return err
+	}
+	return tx.run(ctx, func(ctx context.Context) error {
+		return tx.reader(ctx).GetContext(ctx, dest, query, args...)
+	})
 }
 
 func (tx *txWrapper) SelectContext(ctx context.Context, dest any, query string, args ...any) error {
-	return tx.tx.SelectContext(ctx, dest, query, args...)
+	if err := checkGuard(ctx, tx.guard, query); err != nil {
+		return err
+	}
+	return tx.run(ctx, func(ctx context.Context) error {
+		return tx.reader(ctx).SelectContext(ctx, dest, query, args...)
+	})
 }
 
 func (tx *txWrapper) MustExec(query string, args ...any) Result {
@@ -35,7 +241,7 @@ func (tx *txWrapper) MustExec(query string, args ...any) Result {
 }
 
 func (tx *txWrapper) IDExec(query string, args ...any) (int64, error) {
-	r, err := tx.tx.Exec(query, args...)
+	r, err := tx.exec(context.Background(), query, args)
 	if err != nil {
 		return 0, err
 	}
@@ -47,7 +253,7 @@ func (tx *txWrapper) IDExec(query string, args ...any) (int64, error) {
 }
 
 func (tx *txWrapper) AffectedExec(query string, args ...any) (int, error) {
-	r, err := tx.tx.Exec(query, args...)
+	r, err := tx.exec(context.Background(), query, args)
 	if err != nil {
 		return 0, err
 	}
@@ -59,11 +265,7 @@ func (tx *txWrapper) AffectedExec(query string, args ...any) (int, error) {
 }
 
 func (tx *txWrapper) Query(query string, args ...any) (*sqlx.Rows, error) {
-	r, err := tx.tx.Queryx(query, args...)
-	if err != nil {
-		return nil, err
-	}
-	return r, nil
+	return tx.QueryContext(context.Background(), query, args...)
 }
 
 func (tx *txWrapper) MustQuery(query string, args ...any) *sqlx.Rows {
@@ -75,6 +277,9 @@ func (tx *txWrapper) MustQuery(query string, args ...any) *sqlx.Rows {
 }
 
 func (tx *txWrapper) QueryRow(query string, args ...any) *sqlx.Row {
+	if err := checkGuard(context.Background(), tx.guard, query); err != nil {
+		return errRow(err)
+	}
 	r := tx.tx.QueryRowx(query, args...)
 	if r == nil {
 		return nil
@@ -82,20 +287,37 @@ func (tx *txWrapper) QueryRow(query string, args ...any) *sqlx.Row {
 	return r
 }
 
+// MustQueryRow panics with the query error instead of deferring it to Scan.
 func (tx *txWrapper) MustQueryRow(query string, args ...any) *sqlx.Row {
-	r := tx.QueryRow(query, args...)
-	if r == nil {
-		panic(Error{nil})
+	return Mustv(tx.QueryRowContext(context.Background(), query, args...))
+}
+
+func (tx *txWrapper) QueryRowContext(ctx context.Context, query string, args ...any) (*sqlx.Row, error) {
+	if err := checkGuard(ctx, tx.guard, query); err != nil {
+		return nil, queryError(query, err)
 	}
-	return r
+	row := tx.reader(ctx).QueryRowxContext(ctx, query, args...)
+	if err := row.Err(); err != nil {
+		return nil, queryError(query, err)
+	}
+	return row, nil
 }
 
-func (tx *txWrapper) Get(dest any, query string, args ...any) error {
-	err := tx.tx.Get(dest, query, args...)
-	if err != nil {
-		return err
+func (tx *txWrapper) GetRow(dest any, query string, args ...any) error {
+	return tx.GetRowContext(context.Background(), dest, query, args...)
+}
+
+func (tx *txWrapper) GetRowContext(ctx context.Context, dest any, query string, args ...any) error {
+	if err := checkGuard(ctx, tx.guard, query); err != nil {
+		return queryError(query, err)
 	}
-	return nil
+	return queryError(query, tx.run(ctx, func(ctx context.Context) error {
+		return tx.reader(ctx).GetContext(ctx, dest, query, args...)
+	}))
+}
+
+func (tx *txWrapper) Get(dest any, query string, args ...any) error {
+	return tx.GetContext(context.Background(), dest, query, args...)
 }
 
 func (tx *txWrapper) MustGet(dest any, query string, args ...any) {
@@ -106,11 +328,7 @@ func (tx *txWrapper) MustGet(dest any, query string, args ...any) {
 }
 
 func (tx *txWrapper) GetIn(dest any, query string, args ...any) error {
-	p, q, err := sqlx.In(query, args...)
-	if err != nil {
-		return err
-	}
-	return tx.tx.Get(dest, p, q...)
+	return tx.GetInContext(context.Background(), dest, query, args...)
 }
 
 func (tx *txWrapper) MustGetIn(dest any, query string, args ...any) {
@@ -121,11 +339,7 @@ func (tx *txWrapper) MustGetIn(dest any, query string, args ...any) {
 }
 
 func (tx *txWrapper) Select(dest any, query string, args ...any) error {
-	err := tx.tx.Select(dest, query, args...)
-	if err != nil {
-		return err
-	}
-	return nil
+	return tx.SelectContext(context.Background(), dest, query, args...)
 }
 
 func (tx *txWrapper) SelectSeq(query string, args ...any) *RowsSeq {
@@ -148,6 +362,23 @@ func (tx *txWrapper) SelectInSeq(query string, args ...any) *RowsSeq {
 	}
 }
 
+func (tx *txWrapper) SelectSeqContext(ctx context.Context, query string, args ...any) *RowsSeq {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	return &RowsSeq{
+		rows: rows,
+		err:  err,
+		ctx:  ctx,
+	}
+}
+
+func (tx *txWrapper) SelectInSeqContext(ctx context.Context, query string, args ...any) *RowsSeq {
+	p, q, err := sqlx.In(query, args...)
+	if err != nil {
+		return &RowsSeq{err: err}
+	}
+	return tx.SelectSeqContext(ctx, p, q...)
+}
+
 func (tx *txWrapper) MustSelect(dest any, query string, args ...any) {
 	err := tx.Select(dest, query, args...)
 	if err != nil {
@@ -171,6 +402,24 @@ func (tx *txWrapper) Rollback() error {
 	return nil
 }
 
+func (tx *txWrapper) Prepare(query string) (*sqlx.Stmt, error) {
+	return tx.Preparex(query)
+}
+
+func (tx *txWrapper) Preparex(query string) (*sqlx.Stmt, error) {
+	if err := checkGuard(context.Background(), tx.guard, query); err != nil {
+		return nil, err
+	}
+	return tx.tx.Preparex(query)
+}
+
+func (tx *txWrapper) PrepareNamed(query string) (*sqlx.NamedStmt, error) {
+	if err := checkGuard(context.Background(), tx.guard, query); err != nil {
+		return nil, err
+	}
+	return tx.tx.PrepareNamed(query)
+}
+
 func (tx *txWrapper) DriverName() string {
 	return tx.tx.DriverName()
 }
@@ -180,11 +429,7 @@ func (tx *txWrapper) Rebind(query string) string {
 }
 
 func (tx *txWrapper) SelectIn(dest any, query string, args ...any) error {
-	p, q, err := sqlx.In(query, args...)
-	if err != nil {
-		return err
-	}
-	return tx.tx.Select(dest, p, q...)
+	return tx.SelectInContext(context.Background(), dest, query, args...)
 }
 
 func (tx *txWrapper) MustSelectIn(dest any, query string, args ...any) {
@@ -195,9 +440,22 @@ func (tx *txWrapper) MustSelectIn(dest any, query string, args ...any) {
 }
 
 func (tx *txWrapper) NamedExec(query string, arg any) (Result, error) {
-	r, err := tx.tx.NamedExec(query, arg)
+	if err := checkGuard(context.Background(), tx.guard, query); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	var r sql.Result
+	err := tx.run(context.Background(), func(ctx context.Context) error {
+		var err error
+		r, err = tx.tx.NamedExecContext(ctx, query, arg)
+		return err
+	})
 	if err != nil {
+		if isBusy(err) {
+			tx.lockWait = time.Since(start)
+		}
 		return nil, err
 	}
+	tx.markWriting()
 	return sqltResult{r}, nil
 }
@@ -0,0 +1,51 @@
+package sqlt
+
+import (
+	"testing"
+
+	"github.com/james-darko/gort"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestTxWrapper_StatementCache(t *testing.T) {
+	db, err := OpenMemory(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ctx := gort.Context()
+	if _, err := db.ExecContext(ctx, "CREATE TABLE items (id INTEGER PRIMARY KEY, n INTEGER)"); err != nil {
+		t.Fatal(err)
+	}
+
+	const insert = "INSERT INTO items (n) VALUES (?)"
+	err = db.Txc(ctx, func(tx Tx) error {
+		w := tx.(*txWrapper)
+		for i := range 1000 {
+			if _, err := tx.Exec(insert, i); err != nil {
+				return err
+			}
+			if i == 0 && w.stmts[insert] != nil {
+				t.Error("query was prepared on first use")
+			}
+		}
+		if w.stmts[insert] == nil {
+			t.Error("repeated query was not prepared")
+		}
+		if _, err := tx.Exec("UPDATE items SET n = n + 1; UPDATE items SET n = n + 1"); err != nil {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sum int
+	if err := db.GetContext(ctx, &sum, "SELECT SUM(n) FROM items"); err != nil {
+		t.Fatal(err)
+	}
+	if want := 999*1000/2 + 2000; sum != want {
+		t.Fatalf("sum is %d, want %d", sum, want)
+	}
+}
@@ -0,0 +1,400 @@
+package sqlt
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	rsql "github.com/rqlite/sql"
+)
+
+// QueryProblem is a problem ValidateQueries found in a query.
+type QueryProblem struct {
+	// Name is the key of the query.
+	Name string
+	// Kind is "parse", "unknown table" or "unknown column".
+	Kind string
+	// Identifier is the unknown table or column as written in the query, or empty for parse errors.
+	Identifier string
+	// Err is the *ParseError of a parse problem.
+	Err error
+}
+
+func (p QueryProblem) String() string {
+	if p.Err != nil {
+		return fmt.Sprintf("query %s: %v", p.Name, p.Err)
+	}
+	return fmt.Sprintf("query %s: %s %s", p.Name, p.Kind, p.Identifier)
+}
+
+// ErrInvalidQueries is returned by ValidateQueries with every problem found in the queries.
+type ErrInvalidQueries struct {
+	Problems []QueryProblem
+}
+
+// Error returns a message listing the problems.
+func (e ErrInvalidQueries) Error() string {
+	problems := make([]string, len(e.Problems))
+	for i, p := range e.Problems {
+		problems[i] = p.String()
+	}
+	return fmt.Sprintf("%d query problems: %s", len(e.Problems), strings.Join(problems, "; "))
+}
+
+// ValidateQueries parses queries, keyed by a name used in the problems reported, and checks that
+// the tables and columns they reference exist in schema. It returns an ErrInvalidQueries listing
+// the queries that do not parse and the unknown tables and columns, ordered by query name, or nil.
+// Call it at startup or from a test so typos in queries are caught before they are run.
+//
+// Columns of views, subqueries, common table expressions and table-valued functions are not known,
+// so any column is accepted from them. Statements other than SELECT, INSERT, UPDATE and DELETE
+// are not checked.
+func ValidateQueries(queries map[string]string, schema *SchemaDefinition) error {
+	var problems []QueryProblem
+	names := make([]string, 0, len(queries))
+	for name := range queries {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		v := &queryValidator{schema: schema, name: name}
+		parser := rsql.NewParser(strings.NewReader(queries[name]))
+		for {
+			stmt, err := parseSchemaStatement(parser)
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				v.problems = append(v.problems, QueryProblem{Name: name, Kind: "parse", Err: parseError(err)})
+				break
+			}
+			v.statement(stmt)
+		}
+		problems = append(problems, v.problems...)
+	}
+	if len(problems) > 0 {
+		return ErrInvalidQueries{Problems: problems}
+	}
+	return nil
+}
+
+// queryValidator collects the problems of one query.
+type queryValidator struct {
+	schema   *SchemaDefinition
+	name     string
+	problems []QueryProblem
+}
+
+// queryScope holds the names a part of a query can reference: its sources, result column aliases
+// and common table expressions. Names not found are looked up in the parent, the enclosing query.
+type queryScope struct {
+	parent  *queryScope
+	sources []querySource
+	aliases []string
+	ctes    []string
+}
+
+// querySource is a table or subquery in a FROM clause, or the table of a statement.
+type querySource struct {
+	name string
+	// table is nil if the columns of the source are not known.
+	table *TableDefinition
+}
+
+func (v *queryValidator) report(kind, identifier string) {
+	p := QueryProblem{Name: v.name, Kind: kind, Identifier: identifier}
+	if !slices.Contains(v.problems, p) {
+		v.problems = append(v.problems, p)
+	}
+}
+
+func (v *queryValidator) statement(stmt rsql.Statement) {
+	switch stmt := stmt.(type) {
+	case *rsql.SelectStatement:
+		v.selectStatement(stmt, nil)
+	case *rsql.InsertStatement:
+		scope := v.with(stmt.WithClause, nil)
+		if stmt.Select != nil {
+			v.selectStatement(stmt.Select, scope)
+		}
+		for _, list := range stmt.ValueLists {
+			v.expr(list, scope)
+		}
+		table := v.table(stmt.Table, scope)
+		for _, col := range stmt.Columns {
+			v.column(table, col)
+		}
+		name := stmt.Table.Name
+		if stmt.Alias != nil {
+			name = stmt.Alias.Name
+		}
+		target := &queryScope{parent: scope, sources: []querySource{{name: name, table: table}, {name: "excluded", table: table}}}
+		if upsert := stmt.UpsertClause; upsert != nil {
+			for _, col := range upsert.Columns {
+				v.expr(col.X, target)
+			}
+			v.expr(upsert.WhereExpr, target)
+			v.assignments(upsert.Assignments, table, target)
+			v.expr(upsert.UpdateWhereExpr, target)
+		}
+		v.returning(stmt.ReturningClause, target)
+	case *rsql.UpdateStatement:
+		scope := v.with(stmt.WithClause, nil)
+		target := &queryScope{parent: scope}
+		table := v.source(stmt.Table, target)
+		v.assignments(stmt.Assignments, table, target)
+		v.expr(stmt.WhereExpr, target)
+		v.returning(stmt.ReturningClause, target)
+	case *rsql.DeleteStatement:
+		scope := v.with(stmt.WithClause, nil)
+		target := &queryScope{parent: scope}
+		v.source(stmt.Table, target)
+		v.expr(stmt.WhereExpr, target)
+		for _, term := range stmt.OrderingTerms {
+			v.expr(term.X, target)
+		}
+		v.expr(stmt.LimitExpr, target)
+		v.expr(stmt.OffsetExpr, target)
+		v.returning(stmt.ReturningClause, target)
+	}
+}
+
+// with returns a scope holding the common table expressions of clause, or parent if there are none.
+func (v *queryValidator) with(clause *rsql.WithClause, parent *queryScope) *queryScope {
+	if clause == nil {
+		return parent
+	}
+	scope := &queryScope{parent: parent}
+	for _, cte := range clause.CTEs {
+		scope.ctes = append(scope.ctes, foldIdent(cte.TableName.Name))
+	}
+	for _, cte := range clause.CTEs {
+		v.selectStatement(cte.Select, scope)
+	}
+	return scope
+}
+
+func (v *queryValidator) selectStatement(stmt *rsql.SelectStatement, parent *queryScope) {
+	parent = v.with(stmt.WithClause, parent)
+	for ; stmt != nil; stmt = stmt.Compound {
+		scope := &queryScope{parent: parent}
+		for _, list := range stmt.ValueLists {
+			v.expr(list, scope)
+		}
+		if stmt.Source != nil {
+			v.source(stmt.Source, scope)
+		}
+		for _, col := range stmt.Columns {
+			if col.Alias != nil {
+				scope.aliases = append(scope.aliases, foldIdent(col.Alias.Name))
+			}
+		}
+		v.resultColumns(stmt.Columns, scope)
+		v.expr(stmt.WhereExpr, scope)
+		for _, expr := range stmt.GroupByExprs {
+			v.expr(expr, scope)
+		}
+		v.expr(stmt.HavingExpr, scope)
+		for _, window := range stmt.Windows {
+			v.window(window.Definition, scope)
+		}
+		for _, term := range stmt.OrderingTerms {
+			v.expr(term.X, scope)
+		}
+		v.expr(stmt.LimitExpr, scope)
+		v.expr(stmt.OffsetExpr, scope)
+	}
+}
+
+// source adds the tables of src to scope, checking the expressions it contains, and returns the
+// table of a single table source, or nil.
+func (v *queryValidator) source(src rsql.Source, scope *queryScope) *TableDefinition {
+	switch src := src.(type) {
+	case *rsql.QualifiedTableName:
+		table := v.table(src.Name, scope)
+		scope.sources = append(scope.sources, querySource{name: src.TableName(), table: table})
+		return table
+	case *rsql.QualifiedTableFunctionName:
+		for _, arg := range src.Args {
+			v.expr(arg, scope)
+		}
+		scope.sources = append(scope.sources, querySource{name: src.TableName()})
+	case *rsql.ParenSource:
+		if sel, ok := src.X.(*rsql.SelectStatement); ok {
+			v.selectStatement(sel, scope.parent)
+			scope.sources = append(scope.sources, querySource{name: rsql.IdentName(src.Alias)})
+		} else {
+			v.source(src.X, scope)
+		}
+	case *rsql.JoinClause:
+		v.source(src.X, scope)
+		v.source(src.Y, scope)
+		switch c := src.Constraint.(type) {
+		case *rsql.OnConstraint:
+			v.expr(c.X, scope)
+		case *rsql.UsingConstraint:
+			for _, col := range c.Columns {
+				v.expr(col, scope)
+			}
+		}
+	}
+	return nil
+}
+
+// table returns the schema table named by ident, or nil for a view or common table expression,
+// reporting unknown tables.
+func (v *queryValidator) table(ident *rsql.Ident, scope *queryScope) *TableDefinition {
+	for s := scope; s != nil; s = s.parent {
+		if slices.Contains(s.ctes, foldIdent(ident.Name)) {
+			return nil
+		}
+	}
+	if table := v.schema.Table(ident.Name); table != nil {
+		return table
+	}
+	if v.schema.View(ident.Name) == nil {
+		v.report("unknown table", ident.Name)
+	}
+	return nil
+}
+
+// column reports col if it is not a column of table. Nothing is reported for a nil table.
+func (v *queryValidator) column(table *TableDefinition, col *rsql.Ident) {
+	if table != nil && !hasColumn(table, col.Name) {
+		v.report("unknown column", col.Name)
+	}
+}
+
+// hasColumn reports whether name is a column of table, or the rowid of a rowid table.
+func hasColumn(table *TableDefinition, name string) bool {
+	if table.Column(name) != nil {
+		return true
+	}
+	switch foldIdent(name) {
+	case "rowid", "oid", "_rowid_":
+		return !table.WithoutRowid
+	}
+	return false
+}
+
+func (v *queryValidator) assignments(assignments []*rsql.Assignment, table *TableDefinition, scope *queryScope) {
+	for _, a := range assignments {
+		for _, col := range a.Columns {
+			v.column(table, col)
+		}
+		v.expr(a.Expr, scope)
+	}
+}
+
+func (v *queryValidator) returning(clause *rsql.ReturningClause, scope *queryScope) {
+	if clause != nil {
+		v.resultColumns(clause.Columns, scope)
+	}
+}
+
+func (v *queryValidator) resultColumns(columns []*rsql.ResultColumn, scope *queryScope) {
+	for _, col := range columns {
+		if ref, ok := col.Expr.(*rsql.QualifiedRef); ok && ref.Star.IsValid() {
+			if _, found := scope.lookupSource(ref.Table.Name); !found {
+				v.report("unknown table", ref.Table.Name)
+			}
+			continue
+		}
+		v.expr(col.Expr, scope)
+	}
+}
+
+func (v *queryValidator) window(def *rsql.WindowDefinition, scope *queryScope) {
+	if def == nil {
+		return
+	}
+	for _, expr := range def.Partitions {
+		v.expr(expr, scope)
+	}
+	for _, term := range def.OrderingTerms {
+		v.expr(term.X, scope)
+	}
+}
+
+func (v *queryValidator) expr(expr rsql.Expr, scope *queryScope) {
+	switch expr := expr.(type) {
+	case *rsql.Ident:
+		if !scope.resolves(expr.Name) {
+			v.report("unknown column", expr.Name)
+		}
+	case *rsql.QualifiedRef:
+		src, found := scope.lookupSource(expr.Table.Name)
+		if !found {
+			v.report("unknown table", expr.Table.Name)
+		} else if src.table != nil && expr.Column != nil && !hasColumn(src.table, expr.Column.Name) {
+			v.report("unknown column", expr.Table.Name+"."+expr.Column.Name)
+		}
+	case *rsql.BinaryExpr:
+		v.expr(expr.X, scope)
+		v.expr(expr.Y, scope)
+	case *rsql.UnaryExpr:
+		v.expr(expr.X, scope)
+	case *rsql.ParenExpr:
+		v.expr(expr.X, scope)
+	case *rsql.CastExpr:
+		v.expr(expr.X, scope)
+	case *rsql.CaseExpr:
+		v.expr(expr.Operand, scope)
+		for _, block := range expr.Blocks {
+			v.expr(block.Condition, scope)
+			v.expr(block.Body, scope)
+		}
+		v.expr(expr.ElseExpr, scope)
+	case *rsql.Call:
+		for _, arg := range expr.Args {
+			v.expr(arg, scope)
+		}
+		if expr.Filter != nil {
+			v.expr(expr.Filter.X, scope)
+		}
+		if expr.Over != nil {
+			v.window(expr.Over.Definition, scope)
+		}
+	case *rsql.ExprList:
+		for _, e := range expr.Exprs {
+			v.expr(e, scope)
+		}
+	case *rsql.Range:
+		v.expr(expr.X, scope)
+		v.expr(expr.Y, scope)
+	case *rsql.Exists:
+		v.selectStatement(expr.Select, scope)
+	case rsql.SelectExpr:
+		v.selectStatement(expr.SelectStatement, scope)
+	}
+}
+
+// resolves reports whether an unqualified column name can be resolved in s or its parents.
+// A source whose columns are not known resolves any name.
+func (s *queryScope) resolves(name string) bool {
+	for ; s != nil; s = s.parent {
+		if slices.Contains(s.aliases, foldIdent(name)) {
+			return true
+		}
+		for _, src := range s.sources {
+			if src.table == nil || hasColumn(src.table, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// lookupSource returns the source named name in s or its parents.
+func (s *queryScope) lookupSource(name string) (querySource, bool) {
+	for ; s != nil; s = s.parent {
+		for _, src := range s.sources {
+			if foldIdent(src.name) == foldIdent(name) {
+				return src, true
+			}
+		}
+	}
+	return querySource{}, false
+}
@@ -0,0 +1,62 @@
+package sqlt_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/james-darko/sqlt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateQueries(t *testing.T) {
+	t.Parallel()
+	schema, err := sqlt.ParseSchemaReader(strings.NewReader(`
+CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL, email TEXT);
+CREATE TABLE orders (id INTEGER PRIMARY KEY, user_id INTEGER REFERENCES users(id), total REAL);
+CREATE VIEW big_orders AS SELECT * FROM orders WHERE total > 100;`))
+	require.NoError(t, err)
+
+	valid := map[string]string{
+		"byID":     "SELECT id, name FROM users WHERE id = :id",
+		"join":     "SELECT u.name, o.total AS amount FROM users u JOIN orders o ON o.user_id = u.id ORDER BY amount",
+		"star":     "SELECT u.*, rowid FROM users AS u",
+		"sub":      "SELECT name FROM users WHERE EXISTS (SELECT 1 FROM orders WHERE orders.user_id = users.id)",
+		"in":       "SELECT name FROM users WHERE id IN (SELECT user_id FROM orders WHERE total > ?)",
+		"cte":      "WITH spent AS (SELECT user_id, sum(total) AS s FROM orders GROUP BY user_id) SELECT s, anything FROM spent",
+		"view":     "SELECT whatever FROM big_orders",
+		"derived":  "SELECT t.n FROM (SELECT count(*) AS n FROM users) AS t",
+		"insert":   "INSERT INTO users (name, email) VALUES (?, ?) ON CONFLICT (id) DO UPDATE SET name = excluded.name RETURNING id",
+		"update":   "UPDATE users SET email = lower(email) WHERE id = ?",
+		"delete":   "DELETE FROM orders WHERE user_id NOT IN (SELECT id FROM users)",
+		"function": "SELECT value FROM users, json_each(users.email)",
+	}
+	require.NoError(t, sqlt.ValidateQueries(valid, schema))
+
+	err = sqlt.ValidateQueries(map[string]string{
+		"a": "SELECT nmae FROM users",
+		"b": "SELECT id FROM userz",
+		"c": "SELECT u.id, o.totl FROM users u JOIN orders o ON o.user_id = x.id",
+		"d": "INSERT INTO users (name, mail) VALUES (?, ?)",
+		"e": "UPDATE orders SET totl = 0 WHERE id = ?",
+		"f": "SELECT FROM",
+	}, schema)
+	var invalid sqlt.ErrInvalidQueries
+	require.True(t, errors.As(err, &invalid))
+	var got []string
+	for _, p := range invalid.Problems {
+		got = append(got, p.Name+" "+p.Kind+" "+p.Identifier)
+	}
+	assert.Equal(t, []string{
+		"a unknown column nmae",
+		"b unknown table userz",
+		"c unknown table x",
+		"c unknown column o.totl",
+		"d unknown column mail",
+		"e unknown column totl",
+		"f parse ",
+	}, got)
+	var perr *sqlt.ParseError
+	assert.True(t, errors.As(invalid.Problems[6].Err, &perr))
+}
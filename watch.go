@@ -0,0 +1,83 @@
+package sqlt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// WatchInterval is how often Watch polls PRAGMA data_version.
+const WatchInterval = 250 * time.Millisecond
+
+// ChangeEvent is sent by Watch when the database changed.
+type ChangeEvent struct {
+	// Tables are the watched tables that may have changed, or nil if all tables are watched.
+	// data_version does not tell which tables a commit wrote, so every watched table is listed.
+	Tables []string
+	// DataVersion is the PRAGMA data_version after the change.
+	DataVersion int64
+	// Err is set on the last event if watching failed. The channel is closed after it.
+	Err error
+}
+
+// Watch implements DB.Watch.
+//
+// SQLite update hooks only see the writes made on their own connection, which a connection pool
+// makes useless, so changes are detected by polling PRAGMA data_version on a dedicated connection.
+// It changes whenever another connection or process commits. Its value is only comparable on the
+// same connection, so the connection is held until ctx is done and the pool must allow another one
+// for the other queries: Watch fails with a pool limited to one open connection.
+func (s *sqlxDB) Watch(ctx context.Context, tables []string) <-chan ChangeEvent {
+	events := make(chan ChangeEvent, 1)
+	go func() {
+		defer close(events)
+		send := func(ev ChangeEvent) bool {
+			select {
+			case events <- ev:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		if s.db.Stats().MaxOpenConnections == 1 {
+			send(ChangeEvent{Err: errors.New("could not watch: the watch connection needs MaxOpenConns above 1")})
+			return
+		}
+		conn, err := s.db.Connx(ctx)
+		if err != nil {
+			send(ChangeEvent{Err: fmt.Errorf("could not get watch connection: %w", err)})
+			return
+		}
+		defer conn.Close()
+		var version int64
+		if err := conn.GetContext(ctx, &version, "PRAGMA data_version"); err != nil {
+			send(ChangeEvent{Err: fmt.Errorf("could not read data_version: %w", err)})
+			return
+		}
+		ticker := time.NewTicker(WatchInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			var current int64
+			if err := conn.GetContext(ctx, &current, "PRAGMA data_version"); err != nil {
+				if ctx.Err() == nil {
+					send(ChangeEvent{Err: fmt.Errorf("could not read data_version: %w", err)})
+				}
+				return
+			}
+			if current == version {
+				continue
+			}
+			version = current
+			if !send(ChangeEvent{Tables: tables, DataVersion: current}) {
+				return
+			}
+		}
+	}()
+	return events
+}